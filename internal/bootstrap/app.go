@@ -9,8 +9,10 @@ import (
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/database"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/router"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/scheduler"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/worker"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
 
@@ -49,10 +51,14 @@ func New() *App {
 		logger.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	if err := services.NewMCPServerService(db, cfg).CheckHeaderSecret(context.Background()); err != nil {
+		logger.Fatalf("Invalid MCP server configuration: %v", err)
+	}
+
 	InitRedisCache(cfg)
 	InitProjectRedisData(db)
 
-	taskScheduler := scheduler.NewScheduler(db)
+	taskScheduler := scheduler.NewScheduler(db, cfg)
 	if err := taskScheduler.Start(); err != nil {
 		logger.Fatalf("Failed to start scheduler: %v", err)
 	}
@@ -113,5 +119,9 @@ func (a *App) Shutdown() {
 		logger.Warnf("Worker shutdown error: %v", err)
 	}
 
+	if cache.GlobalTieredCache != nil {
+		_ = cache.GlobalTieredCache.Close()
+	}
+
 	logger.Info("Server shutdown complete")
 }
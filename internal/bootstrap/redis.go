@@ -9,14 +9,16 @@ import (
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/ratelimit"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/redis"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
 
 	"gorm.io/gorm"
 )
 
 // InitRedisCache 初始化Redis客户端并建立缓存连接。
-// 当Redis不可用时会优雅降级，相关功能（幂等性等）将被禁用。
+// 当Redis不可用时会优雅降级，相关功能（幂等性、限流等）将被禁用。
 func InitRedisCache(cfg *config.Config) {
 	if cfg.RedisHost == "" {
 		logger.Warnf("Redis host not configured, idempotency feature will be disabled")
@@ -37,8 +39,10 @@ func InitRedisCache(cfg *config.Config) {
 		return
 	}
 
-	cache.NewRedisCache(redisCli)
-	logger.Infof("Redis cache initialized successfully, idempotency feature enabled")
+	remote := cache.NewRedisCache(redisCli)
+	cache.NewTieredCache(redisCli, remote, constant.LocalCacheCapacity, constant.LocalCacheTTL, constant.CacheInvalidationChannel)
+	ratelimit.NewRedisLimiter(redisCli)
+	logger.Infof("Redis cache initialized successfully, idempotency and rate limit features enabled")
 }
 
 // InitProjectRedisData 将项目相关的热数据预热到Redis中，
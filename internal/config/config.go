@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -22,10 +24,11 @@ type Runtime struct {
 type Config struct {
 	Runtime `yaml:",inline"`
 
-	Database `yaml:"database"`
-	Redis    `yaml:"redis"`
-	MinIO    `yaml:"minio"`
-	LLM      `yaml:"llm"`
+	Database   `yaml:"database"`
+	Redis      `yaml:"redis"`
+	MinIO      `yaml:"minio"`
+	LLM        `yaml:"llm"`
+	Moderation `yaml:"moderation"`
 
 	JWTSecret          string        `yaml:"jwt_secret" env:"JWT_SECRET"`
 	RefreshTokenSecret string        `yaml:"refresh_token_secret" env:"REFRESH_TOKEN_SECRET" envDefault:""`
@@ -92,12 +95,90 @@ type MinIO struct {
 	BucketName     string `yaml:"bucket_name" env:"BUCKET_NAME" envDefault:"yqlx"`
 }
 
+// Moderation 内容审核配置，敏感词由管理员在后台维护，这里只配置可选的外部分类服务
+type Moderation struct {
+	// ClassifierURL 外部内容分类服务地址，为空时只使用敏感词词典
+	ClassifierURL    string `yaml:"classifier_url" env:"MODERATION_CLASSIFIER_URL" envDefault:""`
+	ClassifierAPIKey string `yaml:"classifier_api_key" env:"MODERATION_CLASSIFIER_API_KEY" envDefault:""`
+	// ClassifierThreshold 分类分数不低于该值视为命中
+	ClassifierThreshold float64       `yaml:"classifier_threshold" env:"MODERATION_CLASSIFIER_THRESHOLD" envDefault:"0.8"`
+	ClassifierTimeout   time.Duration `yaml:"classifier_timeout" env:"MODERATION_CLASSIFIER_TIMEOUT" envDefault:"3s"`
+}
+
 type LLM struct {
 	RAGFlowMCPURL string `yaml:"ragflow_mcp_url" env:"RAGFLOW_MCP_URL" envDefault:""` // e.g., "http://localhost:8080/mcp/sse"
 	RAGFlowAPIKey string `yaml:"ragflow_api_key" env:"RAGFLOW_API_KEY" envDefault:""`
 	Model         string `yaml:"llm_model" env:"LLM_MODEL" envDefault:"gpt-4"`
 	APIKey        string `yaml:"llm_api_key" env:"LLM_API_KEY" envDefault:""`
 	BaseURL       string `yaml:"llm_base_url" env:"LLM_BASE_URL" envDefault:""`
+	// MCPSecretKey 加密外部 MCP 服务认证请求头的密钥，有服务保存了请求头时必须配置，否则启动失败；更换后需重新填写已有服务的请求头
+	MCPSecretKey string `yaml:"mcp_secret_key" env:"LLM_MCP_SECRET_KEY" envDefault:""`
+	// HistoryTokenBudget 发送给模型的历史消息 token 预算，超出部分会被滚动摘要
+	HistoryTokenBudget int `yaml:"history_token_budget" env:"LLM_HISTORY_TOKEN_BUDGET" envDefault:"24000"`
+	// Vision 默认模型是否支持图片输入，仅在未配置 Profiles 时生效
+	Vision bool `yaml:"llm_vision" env:"LLM_VISION" envDefault:"false"`
+	// Profiles 多模型配置，按 Priority 升序依次故障转移；为空时使用上面的 Model/APIKey/BaseURL
+	Profiles []LLMProfile `yaml:"profiles"`
+	// ProfilesJSON 以 JSON 数组形式通过环境变量提供的多模型配置，仅在 Profiles 为空时生效
+	ProfilesJSON string `yaml:"-" env:"LLM_PROFILES" envDefault:""`
+}
+
+// LLMProfile 命名的模型配置
+type LLMProfile struct {
+	Name        string `yaml:"name" json:"name"`
+	DisplayName string `yaml:"display_name" json:"display_name"`
+	Description string `yaml:"description" json:"description"`
+	Model       string `yaml:"model" json:"model"`
+	APIKey      string `yaml:"api_key" json:"api_key"`
+	BaseURL     string `yaml:"base_url" json:"base_url"`
+	// Priority 数值越小优先级越高
+	Priority int `yaml:"priority" json:"priority"`
+	// FirstTokenTimeoutSeconds 等待首个 token 的超时秒数，超时后切换到下一个模型，0 表示使用默认值
+	FirstTokenTimeoutSeconds int `yaml:"first_token_timeout_seconds" json:"first_token_timeout_seconds"`
+	// Selectable 是否允许用户为对话手动选择该模型
+	Selectable bool `yaml:"selectable" json:"selectable"`
+	// AllowedRoles 允许选择该模型的角色，为空表示所有用户
+	AllowedRoles []string `yaml:"allowed_roles" json:"allowed_roles"`
+	// Vision 是否支持图片输入，带图片的请求只会发送给支持图片的模型
+	Vision bool `yaml:"vision" json:"vision"`
+}
+
+// DefaultLLMProfileName 未配置多模型时，由单模型配置生成的默认模型名称
+const DefaultLLMProfileName = "default"
+
+// ModelProfiles 返回按优先级排序的模型配置列表
+func (l LLM) ModelProfiles() ([]LLMProfile, error) {
+	profiles := slices.Clone(l.Profiles)
+	if len(profiles) == 0 && l.ProfilesJSON != "" {
+		if err := json.Unmarshal([]byte(l.ProfilesJSON), &profiles); err != nil {
+			return nil, fmt.Errorf("failed to parse LLM_PROFILES: %w", err)
+		}
+	}
+	if len(profiles) == 0 {
+		return []LLMProfile{{
+			Name:       DefaultLLMProfileName,
+			Model:      l.Model,
+			APIKey:     l.APIKey,
+			BaseURL:    l.BaseURL,
+			Selectable: true,
+			Vision:     l.Vision,
+		}}, nil
+	}
+
+	seen := make(map[string]struct{}, len(profiles))
+	for _, p := range profiles {
+		if p.Name == "" || p.Model == "" {
+			return nil, fmt.Errorf("llm profile requires name and model")
+		}
+		if _, ok := seen[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate llm profile %q", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+	}
+	slices.SortStableFunc(profiles, func(a, b LLMProfile) int {
+		return a.Priority - b.Priority
+	})
+	return profiles, nil
 }
 
 // NewConfig initializes and return the configuration by reading environment variables.
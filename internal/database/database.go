@@ -85,5 +85,14 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.Dictionary{},
 		&models.Conversation{},
 		&models.ConversationMessage{},
+		&models.LLMUsageDaily{},
+		&models.ConversationShare{},
+		&models.ChatPersona{},
+		&models.ChatPersonaPromptVersion{},
+		&models.ChatMessageFeedback{},
+		&models.SensitiveWord{},
+		&models.ModerationRecord{},
+		&models.MCPToolCallAudit{},
+		&models.MCPServer{},
 	)
 }
@@ -8,7 +8,8 @@ import (
 
 // CreateConversationRequest 创建对话请求
 type CreateConversationRequest struct {
-	Title string `json:"title" binding:"required,max=200"`
+	Title     string `json:"title" binding:"required,max=200"`
+	PersonaID uint   `json:"persona_id" binding:"omitempty"` // 助手人设，0 表示默认助手
 }
 
 // UpdateConversationRequest 更新对话请求
@@ -22,6 +23,25 @@ type ListConversationsRequest struct {
 	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
 }
 
+// SearchConversationsRequest 对话全文检索请求
+type SearchConversationsRequest struct {
+	Keyword  string `form:"keyword" binding:"required,min=2,max=100"` // 关键词，多个关键词以空格分隔且需同时命中；中文按 2 字分词，单字无法检索
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=50"`
+}
+
+// ConversationSearchHit 对话检索命中
+type ConversationSearchHit struct {
+	ConversationID uint      `json:"conversation_id"`
+	MessageID      uint      `json:"message_id"` // 0 表示命中对话标题
+	Title          string    `json:"title"`
+	Role           string    `json:"role"`    // 命中消息的角色，标题命中为空
+	Snippet        string    `json:"snippet"` // 已转义 HTML 的片段，命中词以 <em></em> 标记
+	Score          float64   `json:"score"`
+	ActiveBranch   bool      `json:"active_branch"` // 命中消息是否位于对话当前分支，标题命中为 true
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 // ConversationResponse 对话响应
 type ConversationResponse struct {
 	ID            uint       `json:"id"`
@@ -29,6 +49,8 @@ type ConversationResponse struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 	LastMessageAt *time.Time `json:"last_message_at"`
+	ModelProfile  string     `json:"model_profile"` // 对话指定的模型，空表示默认
+	PersonaID     uint       `json:"persona_id"`    // 对话使用的助手人设，0 表示默认助手
 }
 
 // ConversationListResponse 对话列表响应
@@ -50,9 +72,10 @@ type EinoMessage struct {
 // ChatRequest 聊天请求（支持新对话和恢复中断的对话）
 type ChatRequest struct {
 	ConversationID uint            `json:"conversation_id" binding:"required,gt=0"`
-	Message        *schema.Message `json:"message" binding:"omitempty"`       // 新消息（新对话时必填）
-	CheckpointID   string          `json:"checkpoint_id" binding:"omitempty"` // 恢复中断时的checkpoint ID
-	ResumeInput    string          `json:"resume_input" binding:"omitempty"`  // 恢复时提供的用户输入（如工具需要的额外信息）
+	Message        *schema.Message `json:"message" binding:"omitempty"`               // 新消息（新对话时必填）
+	CheckpointID   string          `json:"checkpoint_id" binding:"omitempty"`         // 恢复中断时的checkpoint ID
+	ResumeInput    string          `json:"resume_input" binding:"omitempty"`          // 恢复时提供的用户输入（如工具需要的额外信息，工具执行确认时 approve 表示同意）
+	Attachments    []string        `json:"attachments" binding:"omitempty,dive,uuid"` // 随新消息发送的附件资源ID（先通过附件上传接口获取）
 }
 
 // IsResume 判断是否是恢复请求
@@ -60,8 +83,257 @@ func (r *ChatRequest) IsResume() bool {
 	return r.CheckpointID != ""
 }
 
+// ChatAttachmentRef 消息附件引用
+type ChatAttachmentRef struct {
+	ResourceID string `json:"resource_id"`
+	FileName   string `json:"file_name"`
+	MimeType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+	Kind       string `json:"kind"`                // image 或 document
+	Chunks     int    `json:"chunks,omitempty"`    // 文档提取后的分块数
+	Truncated  bool   `json:"truncated,omitempty"` // 文档文本是否因超出上限被截断
+}
+
+// RegenerateMessageRequest 从指定消息重新生成请求
+type RegenerateMessageRequest struct {
+	Content string `json:"content" binding:"omitempty,max=10000"` // 编辑后的问题内容，仅对用户消息有效；为空则直接重新生成回复
+}
+
+// SwitchBranchRequest 切换分支请求
+type SwitchBranchRequest struct {
+	MessageID uint `json:"message_id" binding:"required,gt=0"` // 目标分支上的任意消息ID，切换到该消息下最新的分支末端
+}
+
+// CreateConversationShareRequest 创建对话分享请求
+type CreateConversationShareRequest struct {
+	Title          string `json:"title" binding:"omitempty,max=200"`            // 分享标题，默认使用对话标题
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,max=720"` // 有效期（小时），0 表示永久有效
+}
+
+// ListConversationSharesRequest 分享列表请求
+type ListConversationSharesRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// ConversationShareResponse 分享信息（分享者视角）
+type ConversationShareResponse struct {
+	Slug           string     `json:"slug"`
+	ConversationID uint       `json:"conversation_id"`
+	Title          string     `json:"title"`
+	MessageCount   int        `json:"message_count"`
+	ViewCount      int64      `json:"view_count"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// SharedMessage 分享快照中的消息，仅保留用户与助手的正文
+type SharedMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SharedConversationResponse 公开访问的分享内容
+type SharedConversationResponse struct {
+	Title     string          `json:"title"`
+	Messages  []SharedMessage `json:"messages"`
+	ViewCount int64           `json:"view_count"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ChatPersonaResponse 用户可选的助手人设
+type ChatPersonaResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateChatPersonaRequest 创建助手人设请求
+type CreateChatPersonaRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	Description  string   `json:"description" binding:"max=500"`
+	SystemPrompt string   `json:"system_prompt" binding:"required,max=20000"`
+	AllowedTools []string `json:"allowed_tools" binding:"omitempty,max=100,dive,required,max=128"` // 为空表示不限制
+	ModelProfile string   `json:"model_profile" binding:"omitempty,max=64"`
+	Temperature  *float32 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	Sort         int      `json:"sort"`
+	IsEnabled    *bool    `json:"is_enabled"` // 默认启用
+}
+
+// UpdateChatPersonaRequest 更新助手人设请求，未提供的字段保持不变；修改提示词会生成新版本
+type UpdateChatPersonaRequest struct {
+	Name             *string   `json:"name" binding:"omitempty,min=1,max=64"`
+	Description      *string   `json:"description" binding:"omitempty,max=500"`
+	SystemPrompt     *string   `json:"system_prompt" binding:"omitempty,min=1,max=20000"`
+	AllowedTools     *[]string `json:"allowed_tools" binding:"omitempty,max=100,dive,required,max=128"`
+	ModelProfile     *string   `json:"model_profile" binding:"omitempty,max=64"`
+	Temperature      *float32  `json:"temperature" binding:"omitempty,min=0,max=2"`
+	ClearTemperature bool      `json:"clear_temperature"` // 为 true 时恢复模型默认温度
+	Sort             *int      `json:"sort"`
+	IsEnabled        *bool     `json:"is_enabled"`
+}
+
+// ExportConversationRequest 导出对话请求
+type ExportConversationRequest struct {
+	// Format 导出格式：json（默认）、markdown、html、jsonl
+	Format string `form:"format" binding:"omitempty,oneof=json markdown html jsonl"`
+}
+
+// ExportConversationsArchiveRequest 管理员批量导出匿名化对话请求，按对话创建日期筛选
+type ExportConversationsArchiveRequest struct {
+	StartDate string `form:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `form:"end_date" binding:"required,datetime=2006-01-02"`
+	// Format 归档内的文件格式：jsonl（默认，单个 conversations.jsonl）、markdown、html（每个对话一个文件）
+	Format string `form:"format" binding:"omitempty,oneof=markdown html jsonl"`
+}
+
 // ExportConversationResponse 导出对话响应
 type ExportConversationResponse struct {
 	Conversation ConversationResponse `json:"conversation"`
 	Messages     []*schema.Message    `json:"messages"`
 }
+
+// PurchaseLLMQuotaRequest 使用积分购买 AI 对话额度请求
+type PurchaseLLMQuotaRequest struct {
+	Packs int `json:"packs" binding:"required,min=1,max=100"` // 购买的额度包数量
+}
+
+// LLMUsageResponse 当前用户当日 AI 对话用量
+type LLMUsageResponse struct {
+	Date             string `json:"date"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+	RequestCount     int    `json:"request_count"`
+	Unlimited        bool   `json:"unlimited"`        // 角色不受额度限制
+	DailyQuota       int64  `json:"daily_quota"`      // 角色每日额度
+	ExtraQuota       int64  `json:"extra_quota"`      // 当日购买的额外额度
+	RemainingTokens  int64  `json:"remaining_tokens"` // 当日剩余额度，不受限制时为 0
+	PackTokens       int64  `json:"pack_tokens"`      // 每个额度包包含的 token 数
+	PackPoints       uint   `json:"pack_points"`      // 每个额度包消耗的积分
+	MaxPacksPerDay   int    `json:"max_packs_per_day"`
+}
+
+// LLMUsageReportRequest 管理员 AI 对话用量报表请求
+type LLMUsageReportRequest struct {
+	StartDate string `form:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `form:"end_date" binding:"required,datetime=2006-01-02"`
+	UserID    uint   `form:"user_id" binding:"omitempty"`
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// LLMUsageReportItem 用户在统计区间内的 AI 对话用量
+type LLMUsageReportItem struct {
+	UserID           uint  `json:"user_id"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	RequestCount     int64 `json:"request_count"`
+	ExtraQuota       int64 `json:"extra_quota"`
+	ActiveDays       int64 `json:"active_days"`
+}
+
+// ChatModelResponse 可选模型
+type ChatModelResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	IsDefault   bool   `json:"is_default"` // 优先级最高的默认模型
+	Healthy     bool   `json:"healthy"`    // 近期是否连续调用失败
+}
+
+// SetConversationModelRequest 为对话指定模型请求
+type SetConversationModelRequest struct {
+	ModelProfile string `json:"model_profile" binding:"omitempty,max=64"` // 为空表示恢复默认
+}
+
+// SubmitMessageFeedbackRequest 评价助手回答请求，重复提交覆盖之前的评价
+type SubmitMessageFeedbackRequest struct {
+	Rating  int8     `json:"rating" binding:"required,oneof=-1 1"` // 1=赞，-1=踩
+	Tags    []string `json:"tags" binding:"omitempty,max=8,unique,dive,oneof=helpful accurate wrong unsafe incomplete off_topic outdated tool_error"`
+	Comment string   `json:"comment" binding:"omitempty,max=1000"`
+}
+
+// MessageFeedbackResponse 用户对某条助手回答的评价
+type MessageFeedbackResponse struct {
+	MessageID uint      `json:"message_id"`
+	Rating    int8      `json:"rating"`
+	Tags      []string  `json:"tags"`
+	Comment   string    `json:"comment"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListFeedbackReviewRequest 管理员评价复核队列请求，默认列出待复核的差评
+type ListFeedbackReviewRequest struct {
+	Rating       *int8  `form:"rating" binding:"omitempty,oneof=-1 1"`
+	ReviewStatus *int8  `form:"review_status" binding:"omitempty,oneof=0 1 2"` // 0=待复核（默认），1=已处理，2=已忽略
+	Tag          string `form:"tag" binding:"omitempty,max=32"`
+	PersonaID    *uint  `form:"persona_id"`
+	ModelProfile string `form:"model_profile" binding:"omitempty,max=64"`
+	Page         int    `form:"page" binding:"omitempty,min=1"`
+	PageSize     int    `form:"page_size" binding:"omitempty,min=1,max=50"`
+}
+
+// FeedbackContextMessage 复核时展示的上下文消息
+type FeedbackContextMessage struct {
+	MessageID uint     `json:"message_id"`
+	Role      string   `json:"role"`
+	Content   string   `json:"content"`
+	ToolName  string   `json:"tool_name,omitempty"`
+	ToolCalls []string `json:"tool_calls,omitempty"` // 助手发起调用的工具名称
+}
+
+// FeedbackReviewItem 复核队列中的一条评价，附带被评价回答所在的一轮对话
+type FeedbackReviewItem struct {
+	ID             uint                     `json:"id"`
+	MessageID      uint                     `json:"message_id"`
+	ConversationID uint                     `json:"conversation_id"`
+	UserID         uint                     `json:"user_id"`
+	Rating         int8                     `json:"rating"`
+	Tags           []string                 `json:"tags"`
+	Comment        string                   `json:"comment"`
+	PersonaID      uint                     `json:"persona_id"`
+	ModelProfile   string                   `json:"model_profile"`
+	ToolNames      []string                 `json:"tool_names"`
+	ReviewStatus   int8                     `json:"review_status"`
+	ReviewNote     string                   `json:"review_note"`
+	ReviewedBy     uint                     `json:"reviewed_by"`
+	ReviewedAt     *time.Time               `json:"reviewed_at"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Context        []FeedbackContextMessage `json:"context"` // 从本轮用户提问到被评价的回答
+}
+
+// ReviewFeedbackRequest 处理复核队列中的评价
+type ReviewFeedbackRequest struct {
+	ReviewStatus int8   `json:"review_status" binding:"oneof=1 2"` // 1=已处理，2=已忽略
+	ReviewNote   string `json:"review_note" binding:"omitempty,max=500"`
+}
+
+// FeedbackStatsRequest 评价统计请求，按评价创建日期筛选
+type FeedbackStatsRequest struct {
+	StartDate string `form:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `form:"end_date" binding:"required,datetime=2006-01-02"`
+}
+
+// FeedbackStatsItem 某一维度取值下的评价汇总
+type FeedbackStatsItem struct {
+	Key       string           `json:"key"` // 人设ID、模型配置名或工具名；默认人设为 "0"，未知模型为空字符串
+	Total     int64            `json:"total"`
+	Up        int64            `json:"up"`
+	Down      int64            `json:"down"`
+	DownRate  float64          `json:"down_rate"` // 差评占比
+	TagCounts map[string]int64 `json:"tag_counts"`
+}
+
+// FeedbackStatsResponse 按人设、模型、工具汇总的评价统计
+type FeedbackStatsResponse struct {
+	Total     int64               `json:"total"`
+	Up        int64               `json:"up"`
+	Down      int64               `json:"down"`
+	ByPersona []FeedbackStatsItem `json:"by_persona"`
+	ByModel   []FeedbackStatsItem `json:"by_model"`
+	ByTool    []FeedbackStatsItem `json:"by_tool"`
+}
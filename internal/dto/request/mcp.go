@@ -0,0 +1,36 @@
+package request
+
+// ListMCPToolCallsRequest MCP 工具调用审计记录列表请求
+type ListMCPToolCallsRequest struct {
+	UserID    uint   `form:"user_id" json:"user_id"`                                                  // 用户ID
+	ToolName  string `form:"tool_name" json:"tool_name"`                                              // 工具名称
+	Status    string `form:"status" json:"status" binding:"omitempty,oneof=success tool_error error"` // 结果状态
+	StartDate string `form:"start_date" json:"start_date"`                                            // 开始日期 YYYY-MM-DD
+	EndDate   string `form:"end_date" json:"end_date"`                                                // 结束日期 YYYY-MM-DD（含当天）
+	Page      int    `form:"page" json:"page" binding:"min=1"`                                        // 页码
+	PageSize  int    `form:"page_size" json:"page_size" binding:"min=1,max=100"`                      // 每页数量
+}
+
+// CreateMCPServerRequest 接入外部 MCP 服务请求
+type CreateMCPServerRequest struct {
+	Name         string            `json:"name" binding:"required,max=64"`                                  // 服务名称，不能与内置服务 yqlx、ragflow 重名
+	Description  string            `json:"description" binding:"max=500"`                                   // 服务简介
+	URL          string            `json:"url" binding:"required,url,max=500"`                              // streamable HTTP 地址
+	Headers      map[string]string `json:"headers" binding:"omitempty,max=20"`                              // 认证请求头，加密保存且不会在接口中返回
+	IsEnabled    *bool             `json:"is_enabled"`                                                      // 默认启用
+	AllowedRoles []string          `json:"allowed_roles" binding:"omitempty,max=50,dive,required,max=64"`   // 允许使用的角色，为空表示所有用户
+	AllowedTools []string          `json:"allowed_tools" binding:"omitempty,max=200,dive,required,max=128"` // 允许加载的工具，为空表示不限制
+	DeniedTools  []string          `json:"denied_tools" binding:"omitempty,max=200,dive,required,max=128"`  // 禁止加载的工具
+}
+
+// UpdateMCPServerRequest 更新外部 MCP 服务请求，未提供的字段保持不变
+type UpdateMCPServerRequest struct {
+	Name         *string            `json:"name" binding:"omitempty,min=1,max=64"`
+	Description  *string            `json:"description" binding:"omitempty,max=500"`
+	URL          *string            `json:"url" binding:"omitempty,url,max=500"`
+	Headers      *map[string]string `json:"headers" binding:"omitempty,max=20"` // 提供时整体替换，传空对象清除
+	IsEnabled    *bool              `json:"is_enabled"`
+	AllowedRoles *[]string          `json:"allowed_roles" binding:"omitempty,max=50,dive,required,max=64"`
+	AllowedTools *[]string          `json:"allowed_tools" binding:"omitempty,max=200,dive,required,max=128"`
+	DeniedTools  *[]string          `json:"denied_tools" binding:"omitempty,max=200,dive,required,max=128"`
+}
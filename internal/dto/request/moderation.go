@@ -0,0 +1,44 @@
+package request
+
+// CreateSensitiveWordsRequest 批量添加敏感词请求，已存在的词会被跳过
+type CreateSensitiveWordsRequest struct {
+	Words    []string `json:"words" binding:"required,min=1,max=1000,dive,required,max=100"` // 敏感词列表
+	Category string   `json:"category" binding:"max=32"`                                     // 分类
+}
+
+// UpdateSensitiveWordRequest 更新敏感词请求
+type UpdateSensitiveWordRequest struct {
+	Category  *string `json:"category" binding:"omitempty,max=32"` // 分类
+	IsEnabled *bool   `json:"is_enabled"`                          // 是否启用
+}
+
+// ListSensitiveWordsRequest 敏感词列表请求
+type ListSensitiveWordsRequest struct {
+	Keyword   string `form:"keyword" json:"keyword"`                             // 关键词（模糊匹配）
+	Category  string `form:"category" json:"category"`                           // 分类
+	IsEnabled *bool  `form:"is_enabled" json:"is_enabled"`                       // 是否启用
+	Page      int    `form:"page" json:"page" binding:"min=1"`                   // 页码
+	PageSize  int    `form:"page_size" json:"page_size" binding:"min=1,max=100"` // 每页数量
+}
+
+// ListModerationRecordsRequest 审核记录列表请求
+type ListModerationRecordsRequest struct {
+	Surface      string `form:"surface" json:"surface" binding:"omitempty,oneof=chat_input chat_output teacher_review contribution material_desc"` // 审核场景
+	Action       string `form:"action" json:"action" binding:"omitempty,oneof=block mask review"`                                                  // 执行的处理
+	ReviewStatus *int8  `form:"review_status" json:"review_status" binding:"omitempty,oneof=0 1 2 3"`                                              // 复核状态
+	UserID       uint   `form:"user_id" json:"user_id"`                                                                                            // 用户ID
+	Page         int    `form:"page" json:"page" binding:"min=1"`                                                                                  // 页码
+	PageSize     int    `form:"page_size" json:"page_size" binding:"min=1,max=100"`                                                                // 每页数量
+}
+
+// ReviewModerationRecordRequest 人工复核审核记录请求
+type ReviewModerationRecordRequest struct {
+	ReviewStatus int8   `json:"review_status" binding:"oneof=1 2"` // 1=确认无问题，2=确认违规
+	ReviewNote   string `json:"review_note" binding:"max=500"`     // 复核备注
+}
+
+// CheckModerationRequest 按场景策略试审文本请求（不记录审核记录）
+type CheckModerationRequest struct {
+	Surface string `json:"surface" binding:"required,oneof=chat_input chat_output teacher_review contribution material_desc"` // 审核场景
+	Text    string `json:"text" binding:"required,max=10000"`                                                                 // 待审核文本
+}
@@ -1,5 +1,7 @@
 package request
 
+import "time"
+
 // CreateRoleRequest 创建角色
 type CreateRoleRequest struct {
 	RoleTag     string `json:"role_tag" binding:"required"`
@@ -29,3 +31,9 @@ type UpdateRolePermissionsRequest struct {
 type UpdateUserRolesRequest struct {
 	RoleIDs []uint `json:"role_ids" binding:"required"`
 }
+
+// GrantUserRoleRequest 授予用户角色请求（可设置过期时间）
+type GrantUserRoleRequest struct {
+	RoleID    uint       `json:"role_id" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // 过期时间，为空表示永久有效
+}
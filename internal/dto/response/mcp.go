@@ -0,0 +1,9 @@
+package response
+
+import "github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+
+// MCPServerResponse 外部 MCP 服务信息，认证请求头只返回名称
+type MCPServerResponse struct {
+	models.MCPServer
+	HeaderNames []string `json:"header_names"`
+}
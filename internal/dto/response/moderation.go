@@ -0,0 +1,16 @@
+package response
+
+import "github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/moderation"
+
+// CreateSensitiveWordsResponse 批量添加敏感词结果
+type CreateSensitiveWordsResponse struct {
+	Created int `json:"created"` // 新增数量
+	Skipped int `json:"skipped"` // 已存在或重复而跳过的数量
+}
+
+// ModerationCheckResponse 试审结果
+type ModerationCheckResponse struct {
+	Action string           `json:"action"` // 命中后执行的处理，未命中为空
+	Text   string           `json:"text"`   // 处理后的文本
+	Hits   []moderation.Hit `json:"hits"`   // 命中明细
+}
@@ -1,6 +1,10 @@
 package response
 
-import "github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+import (
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+)
 
 // RoleWithPermissionsResponse 角色及其权限列表响应
 type RoleWithPermissionsResponse struct {
@@ -19,3 +23,38 @@ type RoleWithUsersResponse struct {
 	UserCount int         `json:"user_count"` // 拥有该角色的用户数量
 	UserIDs   []uint      `json:"user_ids"`   // 拥有该角色的用户ID列表（basic_user除外）
 }
+
+// ExpiringRoleGrantResponse 即将到期的临时角色授权
+type ExpiringRoleGrantResponse struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	RoleID    uint      `json:"role_id"`
+	RoleTag   string    `json:"role_tag"`
+	RoleName  string    `json:"role_name"`
+	Nickname  string    `json:"nickname"`
+	RealName  string    `json:"real_name"`
+	StudentID string    `json:"student_id"`
+	GrantedBy uint      `json:"granted_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RBACPolicyBindingChange 某个角色的权限绑定变更
+type RBACPolicyBindingChange struct {
+	RoleTag        string   `json:"role_tag"`
+	PermissionTags []string `json:"permission_tags"`
+}
+
+// RBACPolicyDiffResponse RBAC 策略与数据库当前配置的差异
+type RBACPolicyDiffResponse struct {
+	DryRun               bool                      `json:"dry_run"`     // 是否仅预览，未写入数据库
+	HasChanges           bool                      `json:"has_changes"` // 是否存在需要应用的变更
+	PermissionsCreated   []string                  `json:"permissions_created"`
+	PermissionsUpdated   []string                  `json:"permissions_updated"`
+	RolesCreated         []string                  `json:"roles_created"`
+	RolesUpdated         []string                  `json:"roles_updated"`
+	BindingsAdded        []RBACPolicyBindingChange `json:"bindings_added"`
+	BindingsRemoved      []RBACPolicyBindingChange `json:"bindings_removed"`
+	UnmanagedRoles       []string                  `json:"unmanaged_roles"`       // 数据库中存在但策略未声明的角色，不会被修改
+	UnmanagedPermissions []string                  `json:"unmanaged_permissions"` // 数据库中存在但策略未声明的权限，不会被修改
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
@@ -10,17 +15,18 @@ import (
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
 
-	json "github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
 )
 
 type ChatHandler struct {
-	service *services.ChatService
+	service      *services.ChatService
+	usageService *services.LLMUsageService
 }
 
-func NewChatHandler(service *services.ChatService) *ChatHandler {
+func NewChatHandler(service *services.ChatService, usageService *services.LLMUsageService) *ChatHandler {
 	return &ChatHandler{
-		service: service,
+		service:      service,
+		usageService: usageService,
 	}
 }
 
@@ -33,7 +39,7 @@ func (h *ChatHandler) CreateConversation(c *gin.Context) {
 	}
 
 	userID := helper.GetUserID(c)
-	conv, err := h.service.CreateConversation(c.Request.Context(), userID, req.Title)
+	conv, err := h.service.CreateConversation(c.Request.Context(), userID, req.Title, req.PersonaID)
 	if err != nil {
 		logger.ErrorGin(c, map[string]any{
 			"action": "service-create-conversation",
@@ -49,6 +55,8 @@ func (h *ChatHandler) CreateConversation(c *gin.Context) {
 		CreatedAt:     conv.CreatedAt,
 		UpdatedAt:     conv.UpdatedAt,
 		LastMessageAt: conv.LastMessageAt,
+		ModelProfile:  conv.ModelProfile,
+		PersonaID:     conv.PersonaID,
 	})
 }
 
@@ -86,6 +94,8 @@ func (h *ChatHandler) ListConversations(c *gin.Context) {
 			CreatedAt:     conv.CreatedAt,
 			UpdatedAt:     conv.UpdatedAt,
 			LastMessageAt: conv.LastMessageAt,
+			ModelProfile:  conv.ModelProfile,
+			PersonaID:     conv.PersonaID,
 		}
 	}
 
@@ -97,6 +107,34 @@ func (h *ChatHandler) ListConversations(c *gin.Context) {
 	})
 }
 
+// SearchConversations 全文检索对话标题与消息
+func (h *ChatHandler) SearchConversations(c *gin.Context) {
+	var req dto.SearchConversationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	hits, total, err := h.service.SearchConversations(c.Request.Context(), helper.GetUserID(c), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":  "service-search-conversations",
+			"keyword": req.Keyword,
+			"error":   err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.PageSuccessResponse(c, hits, total, req.Page, req.PageSize)
+}
+
 // DeleteConversation 删除对话
 func (h *ChatHandler) DeleteConversation(c *gin.Context) {
 	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -148,6 +186,168 @@ func (h *ChatHandler) UpdateConversation(c *gin.Context) {
 	helper.SuccessResponse(c, "ok")
 }
 
+// SetConversationModel 为对话指定模型
+func (h *ChatHandler) SetConversationModel(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.SetConversationModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	userID := helper.GetUserID(c)
+	if err := h.service.SetConversationModel(c.Request.Context(), userID, uint(conversationID), req.ModelProfile); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-set-conversation-model",
+			"conversation_id": conversationID,
+			"model_profile":   req.ModelProfile,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// ListPersonas 列出可选的助手人设
+func (h *ChatHandler) ListPersonas(c *gin.Context) {
+	personas, err := h.service.ListChatPersonas(c.Request.Context())
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-chat-personas",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, personas)
+}
+
+// AdminListPersonas 管理员列出全部助手人设
+func (h *ChatHandler) AdminListPersonas(c *gin.Context) {
+	personas, err := h.service.AdminListChatPersonas(c.Request.Context())
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-admin-list-chat-personas",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, personas)
+}
+
+// AdminCreatePersona 管理员创建助手人设
+func (h *ChatHandler) AdminCreatePersona(c *gin.Context) {
+	var req dto.CreateChatPersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	persona, err := h.service.CreateChatPersona(c.Request.Context(), helper.GetUserID(c), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-create-chat-persona",
+			"name":   req.Name,
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, persona)
+}
+
+// AdminUpdatePersona 管理员更新助手人设，修改提示词会生成新版本
+func (h *ChatHandler) AdminUpdatePersona(c *gin.Context) {
+	personaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.UpdateChatPersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	persona, err := h.service.UpdateChatPersona(c.Request.Context(), helper.GetUserID(c), uint(personaID), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":     "service-update-chat-persona",
+			"persona_id": personaID,
+			"error":      err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, persona)
+}
+
+// AdminDeletePersona 管理员删除助手人设
+func (h *ChatHandler) AdminDeletePersona(c *gin.Context) {
+	personaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.DeleteChatPersona(c.Request.Context(), uint(personaID)); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":     "service-delete-chat-persona",
+			"persona_id": personaID,
+			"error":      err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// AdminListPersonaVersions 管理员查看人设提示词历史版本
+func (h *ChatHandler) AdminListPersonaVersions(c *gin.Context) {
+	personaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	versions, err := h.service.ListChatPersonaVersions(c.Request.Context(), uint(personaID))
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":     "service-list-chat-persona-versions",
+			"persona_id": personaID,
+			"error":      err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, versions)
+}
+
+// ListChatModels 列出当前用户可选的模型
+func (h *ChatHandler) ListChatModels(c *gin.Context) {
+	models, err := h.service.ListChatModels(c.Request.Context(), helper.GetUserID(c))
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, models)
+}
+
 // ChooseConversation 选择对话并返回历史消息
 func (h *ChatHandler) ChooseConversation(c *gin.Context) {
 	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -179,7 +379,30 @@ func (h *ChatHandler) ExportConversation(c *gin.Context) {
 		return
 	}
 
+	var req dto.ExportConversationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
 	userID := helper.GetUserID(c)
+	if req.Format != "" && req.Format != constant.ChatExportFormatJSON {
+		file, err := h.service.ExportConversationFile(c.Request.Context(), userID, uint(conversationID), req.Format)
+		if err != nil {
+			logger.ErrorGin(c, map[string]any{
+				"action":          "service-export-conversation-file",
+				"conversation_id": conversationID,
+				"format":          req.Format,
+				"error":           err.Error(),
+			})
+			helper.HandleError(c, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+file.FileName+`"`)
+		c.Data(http.StatusOK, file.ContentType, file.Data)
+		return
+	}
+
 	conv, messages, err := h.service.ExportConversation(c.Request.Context(), userID, uint(conversationID))
 	if err != nil {
 		logger.ErrorGin(c, map[string]any{
@@ -198,11 +421,97 @@ func (h *ChatHandler) ExportConversation(c *gin.Context) {
 			CreatedAt:     conv.CreatedAt,
 			UpdatedAt:     conv.UpdatedAt,
 			LastMessageAt: conv.LastMessageAt,
+			ModelProfile:  conv.ModelProfile,
+			PersonaID:     conv.PersonaID,
 		},
 		Messages: messages,
 	})
 }
 
+// ExportConversationsArchive 管理员按日期区间批量导出匿名化对话，以 ZIP 流式返回
+func (h *ChatHandler) ExportConversationsArchive(c *gin.Context) {
+	var req dto.ExportConversationsArchiveRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	fileName, write, err := h.service.ExportConversationsArchive(c.Request.Context(), &req)
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Status(http.StatusOK)
+	if err := write(c.Writer); err != nil {
+		// 响应头已发送，无法再返回错误；归档缺少目录区，客户端解压时会报错
+		logger.ErrorGin(c, map[string]any{
+			"action":     "service-export-conversations-archive",
+			"start_date": req.StartDate,
+			"end_date":   req.EndDate,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// UploadAttachment 上传对话附件（图片或 PDF/文本文档）
+func (h *ChatHandler) UploadAttachment(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, constant.ChatAttachmentMaxUploadBytes)
+	file, err := c.FormFile("file")
+	if err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			helper.HandleErrCode(c, constant.ConversationAttachmentTooLarge)
+			return
+		}
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	mimeType := file.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = attachmentMIMEByExtension(file.Filename)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "upload_chat_attachment",
+			"message":   "打开上传文件失败",
+			"file_name": file.Filename,
+			"error":     err.Error(),
+		})
+		helper.HandleErrCode(c, constant.StoreFileOpenFailed)
+		return
+	}
+	defer src.Close()
+
+	ref, err := h.service.UploadChatAttachment(c.Request.Context(), helper.GetUserID(c), src, file.Filename, mimeType, file.Size)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "service-upload-chat-attachment",
+			"file_name": file.Filename,
+			"mime_type": mimeType,
+			"error":     err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, ref)
+}
+
+// attachmentMIMEByExtension 客户端未提供类型时按扩展名推断
+func attachmentMIMEByExtension(fileName string) string {
+	switch ext := strings.ToLower(filepath.Ext(fileName)); ext {
+	case ".md", ".markdown":
+		return "text/markdown"
+	default:
+		return mime.TypeByExtension(ext)
+	}
+}
+
 // StreamConversation SSE 流式对话（支持新对话和恢复中断的对话）
 func (h *ChatHandler) StreamConversation(c *gin.Context) {
 	var req dto.ChatRequest
@@ -219,11 +528,18 @@ func (h *ChatHandler) StreamConversation(c *gin.Context) {
 
 	userID := helper.GetUserID(c)
 
+	// 当日 AI 对话额度检查
+	if err := h.usageService.CheckQuota(c.Request.Context(), userID); err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+
 	outputChan, errChan, err := h.service.StreamChat(
 		c.Request.Context(),
 		userID,
 		req.ConversationID,
 		req.Message,
+		req.Attachments,
 		helper.GetAuthorizationToken(c),
 		req.CheckpointID,
 		req.ResumeInput,
@@ -240,6 +556,208 @@ func (h *ChatHandler) StreamConversation(c *gin.Context) {
 		return
 	}
 
+	writeChatSSE(c, req.ConversationID, outputChan, errChan)
+}
+
+// ReattachStream 断线后续传进行中或刚结束的一轮对话（SSE），从 Last-Event-ID 之后的事件开始
+func (h *ChatHandler) ReattachStream(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	// 小程序等无法自定义请求头的客户端可通过查询参数传递
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	outputChan, err := h.service.ReattachChatStream(c.Request.Context(), helper.GetUserID(c), uint(conversationID), lastEventID)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-reattach-stream",
+			"conversation_id": conversationID,
+			"last_event_id":   lastEventID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	writeChatSSE(c, uint(conversationID), outputChan, nil)
+}
+
+// RegenerateMessage 从指定消息重新生成回复（SSE），原有分支保留
+func (h *ChatHandler) RegenerateMessage(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.RegenerateMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	userID := helper.GetUserID(c)
+
+	// 当日 AI 对话额度检查
+	if err := h.usageService.CheckQuota(c.Request.Context(), userID); err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+
+	outputChan, errChan, err := h.service.RegenerateChat(
+		c.Request.Context(),
+		userID,
+		uint(conversationID),
+		uint(messageID),
+		req.Content,
+		helper.GetAuthorizationToken(c),
+	)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-regenerate-message",
+			"conversation_id": conversationID,
+			"message_id":      messageID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	writeChatSSE(c, uint(conversationID), outputChan, errChan)
+}
+
+// SwitchBranch 切换对话的当前分支并返回切换后的消息
+func (h *ChatHandler) SwitchBranch(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.SwitchBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	userID := helper.GetUserID(c)
+	messages, err := h.service.SwitchBranch(c.Request.Context(), userID, uint(conversationID), req.MessageID)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-switch-branch",
+			"conversation_id": conversationID,
+			"message_id":      req.MessageID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, messages)
+}
+
+// CreateShare 为对话当前分支创建公开分享链接
+func (h *ChatHandler) CreateShare(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.CreateConversationShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	userID := helper.GetUserID(c)
+	share, err := h.service.CreateConversationShare(c.Request.Context(), userID, uint(conversationID), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-create-conversation-share",
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, share)
+}
+
+// ListShares 列出当前用户创建的分享
+func (h *ChatHandler) ListShares(c *gin.Context) {
+	var req dto.ListConversationSharesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	shares, total, err := h.service.ListConversationShares(c.Request.Context(), helper.GetUserID(c), req.Page, req.PageSize)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-conversation-shares",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.PageSuccessResponse(c, shares, total, req.Page, req.PageSize)
+}
+
+// RevokeShare 撤销分享链接
+func (h *ChatHandler) RevokeShare(c *gin.Context) {
+	slug := c.Param("slug")
+	if err := h.service.RevokeConversationShare(c.Request.Context(), helper.GetUserID(c), slug); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-revoke-conversation-share",
+			"slug":   slug,
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// GetSharedConversation 公开查看分享的对话快照（无需登录）
+func (h *ChatHandler) GetSharedConversation(c *gin.Context) {
+	slug := c.Param("slug")
+	shared, err := h.service.GetSharedConversation(c.Request.Context(), slug)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-get-shared-conversation",
+			"slug":   slug,
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, shared)
+}
+
+// writeChatSSE 将对话输出以 SSE 写回客户端，直到输出结束、出错或客户端断开
+func writeChatSSE(c *gin.Context, conversationID uint, outputChan <-chan string, errChan <-chan error) {
 	// 设置 SSE 响应头
 	c.Writer.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -249,6 +767,16 @@ func (h *ChatHandler) StreamConversation(c *gin.Context) {
 
 	c.Writer.Flush()
 
+	// 提前返回（客户端断开或写失败）时继续消费输出，后台本轮运行不会因通道阻塞而停止
+	defer func() {
+		if outputChan != nil {
+			go func(ch <-chan string) {
+				for range ch {
+				}
+			}(outputChan)
+		}
+	}()
+
 	for outputChan != nil || errChan != nil {
 		select {
 		case msg, ok := <-outputChan:
@@ -261,40 +789,157 @@ func (h *ChatHandler) StreamConversation(c *gin.Context) {
 				logger.ErrorGin(c, map[string]any{
 					"action":          "sse-message-write-failed",
 					"written_bytes":   write,
-					"conversation_id": req.ConversationID,
+					"conversation_id": conversationID,
 					"error":           err.Error(),
 				})
 				return
 			}
 			c.Writer.Flush()
-		case err, ok := <-errChan:
+		case _, ok := <-errChan:
+			// 错误事件已作为带序号的事件经 outputChan 输出，这里只等待通道关闭
 			if !ok {
 				errChan = nil
-				continue
-			}
-			if err == nil {
-				continue
-			}
-			errorEvent := map[string]interface{}{
-				"type":  "error",
-				"error": err.Error(),
-			}
-			if data, jsonErr := json.Marshal(errorEvent); jsonErr == nil {
-				write, err := c.Writer.Write([]byte("data: " + string(data) + "\n\n"))
-				if err != nil {
-					logger.ErrorGin(c, map[string]any{
-						"action":          "sse-error-message-write-failed",
-						"written_bytes":   write,
-						"conversation_id": req.ConversationID,
-						"error":           err.Error(),
-					})
-					return
-				}
-				c.Writer.Flush()
 			}
-			return
 		case <-c.Request.Context().Done():
 			return
 		}
 	}
 }
+
+// SubmitFeedback 评价助手回答（赞/踩、标签与说明），重复提交覆盖之前的评价
+func (h *ChatHandler) SubmitFeedback(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.SubmitMessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	feedback, err := h.service.SubmitMessageFeedback(c.Request.Context(), helper.GetUserID(c), uint(conversationID), uint(messageID), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-submit-message-feedback",
+			"conversation_id": conversationID,
+			"message_id":      messageID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, feedback)
+}
+
+// DeleteFeedback 撤回对助手回答的评价
+func (h *ChatHandler) DeleteFeedback(c *gin.Context) {
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.DeleteMessageFeedback(c.Request.Context(), helper.GetUserID(c), uint(conversationID), uint(messageID)); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":          "service-delete-message-feedback",
+			"conversation_id": conversationID,
+			"message_id":      messageID,
+			"error":           err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// AdminListFeedback 管理员评价复核队列（默认待复核的差评）
+func (h *ChatHandler) AdminListFeedback(c *gin.Context) {
+	var req dto.ListFeedbackReviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	items, total, err := h.service.ListFeedbackReview(c.Request.Context(), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-feedback-review",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.PageSuccessResponse(c, items, total, req.Page, req.PageSize)
+}
+
+// AdminReviewFeedback 管理员处理复核队列中的评价
+func (h *ChatHandler) AdminReviewFeedback(c *gin.Context) {
+	feedbackID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req dto.ReviewFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.ReviewFeedback(c.Request.Context(), helper.GetUserID(c), uint(feedbackID), &req); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":      "service-review-feedback",
+			"feedback_id": feedbackID,
+			"error":       err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// AdminFeedbackStats 按人设、模型、工具汇总评价
+func (h *ChatHandler) AdminFeedbackStats(c *gin.Context) {
+	var req dto.FeedbackStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	stats, err := h.service.FeedbackStats(c.Request.Context(), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":     "service-feedback-stats",
+			"start_date": req.StartDate,
+			"end_date":   req.EndDate,
+			"error":      err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, stats)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	json "github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadAttachmentRejectsOversizedBody(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "big.txt")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write(bytes.Repeat([]byte("a"), constant.ChatAttachmentMaxUploadBytes)); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/attachments", (&ChatHandler{}).UploadAttachment)
+	req := httptest.NewRequest(http.MethodPost, "/attachments", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp dto.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || resp.StatusCode != int(constant.ConversationAttachmentTooLarge) {
+		t.Fatalf("status = %d, code = %d", w.Code, resp.StatusCode)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type LLMUsageHandler struct {
+	service *services.LLMUsageService
+}
+
+func NewLLMUsageHandler(service *services.LLMUsageService) *LLMUsageHandler {
+	return &LLMUsageHandler{service: service}
+}
+
+// GetMyUsage 获取当前用户当日 AI 对话用量与额度
+func (h *LLMUsageHandler) GetMyUsage(c *gin.Context) {
+	usage, err := h.service.GetUserUsage(c.Request.Context(), helper.GetUserID(c))
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, usage)
+}
+
+// PurchaseQuota 使用积分购买当日额外额度
+func (h *LLMUsageHandler) PurchaseQuota(c *gin.Context) {
+	var req dto.PurchaseLLMQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	usage, err := h.service.PurchaseQuota(c.Request.Context(), helper.GetUserID(c), req.Packs)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-purchase-llm-quota",
+			"packs":  req.Packs,
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, usage)
+}
+
+// GetUsageReport 管理员按用户汇总 AI 对话用量
+func (h *LLMUsageHandler) GetUsageReport(c *gin.Context) {
+	var req dto.LLMUsageReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+
+	items, total, err := h.service.GetUsageReport(c.Request.Context(), &req)
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.PageSuccessResponse(c, items, total, req.Page, req.PageSize)
+}
@@ -278,7 +278,7 @@ func (h *MaterialHandler) UpdateMaterialDesc(c *gin.Context) {
 		return
 	}
 
-	if err := h.materialService.UpdateMaterialDesc(c.Request.Context(), md5, &req); err != nil {
+	if err := h.materialService.UpdateMaterialDesc(c.Request.Context(), helper.GetUserID(c), md5, &req); err != nil {
 		helper.HandleError(c, err)
 		return
 	}
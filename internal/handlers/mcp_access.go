@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// addTool 注册工具并声明可见所需的权限，tools/list 只列出调用者拥有其中任一权限的工具。
+// 按 action 区分的细粒度校验仍由各工具处理器的 requirePermission 完成。
+func (th *mcpToolHandlers) addTool(s *server.MCPServer, tool mcp.Tool, handler server.ToolHandlerFunc, permission string, more ...string) {
+	th.declareTool(tool.Name, append([]string{permission}, more...))
+	s.AddTool(tool, handler)
+}
+
+// addPublicTool 注册所有登录用户可见的工具
+func (th *mcpToolHandlers) addPublicTool(s *server.MCPServer, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	th.declareTool(tool.Name, []string{})
+	s.AddTool(tool, handler)
+}
+
+func (th *mcpToolHandlers) declareTool(name string, permissions []string) {
+	if th.toolPermissions == nil {
+		th.toolPermissions = make(map[string][]string)
+	}
+	th.toolPermissions[name] = permissions
+}
+
+// filterTools 按调用者的权限快照过滤 tools/list。未通过 addTool/addPublicTool 声明权限的工具一律隐藏，
+// 快照获取失败时只返回公开工具
+func (th *mcpToolHandlers) filterTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	userID := getUserFromContext(ctx)
+	snap, err := th.rbacService.GetUserPermissionSnapshot(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "mcp_tools_filter",
+			"message": "获取用户权限快照失败，仅列出公开工具",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		permissions, declared := th.toolPermissions[tool.Name]
+		if !declared {
+			continue
+		}
+		if len(permissions) == 0 || (snap != nil && slices.ContainsFunc(permissions, snap.HasPermission)) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+// auditToolCall 记录每次 tools/call 的调用者、工具、参数摘要、耗时和结果状态
+func (th *mcpToolHandlers) auditToolCall(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, req)
+
+		record := &models.MCPToolCallAudit{
+			UserID:        getUserFromContext(ctx),
+			ToolName:      req.Params.Name,
+			ArgumentsHash: hashToolArguments(req.GetArguments()),
+			DurationMs:    time.Since(start).Milliseconds(),
+			Status:        constant.MCPToolCallStatusSuccess,
+		}
+		switch {
+		case err != nil:
+			record.Status = constant.MCPToolCallStatusError
+			record.ErrorMessage = truncateRunes(err.Error(), 500)
+		case result != nil && result.IsError:
+			record.Status = constant.MCPToolCallStatusToolError
+			for _, content := range result.Content {
+				if text, ok := content.(mcp.TextContent); ok {
+					record.ErrorMessage = truncateRunes(text.Text, 500)
+					break
+				}
+			}
+		}
+
+		// 审计写入不阻塞工具返回
+		go func(ctx context.Context) {
+			if err := th.auditService.RecordToolCall(ctx, record); err != nil {
+				logger.WarnCtx(ctx, map[string]any{
+					"action":    "mcp_tool_call_audit",
+					"message":   "写入MCP工具调用审计记录失败",
+					"tool_name": record.ToolName,
+					"error":     err.Error(),
+				})
+			}
+		}(context.WithoutCancel(ctx))
+
+		return result, err
+	}
+}
+
+// hashToolArguments 对参数做规范化 JSON（对象键有序）后计算 SHA-256
+func hashToolArguments(arguments map[string]any) string {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		data = nil
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
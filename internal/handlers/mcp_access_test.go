@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/glebarez/sqlite"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// memoryCache 只实现 Get/Set/Delete 的内存缓存
+type memoryCache struct {
+	cache.Cache
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *memoryCache) Set(ctx context.Context, key, value string, expiration *time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// newMCPTestDB 创建 SQLite 数据库并迁移给定模型
+func newMCPTestDB(t *testing.T, dst ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "mcp.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.Callback().Raw().Before("gorm:raw").Register("mcp_test:sqlite_ddl", adaptMySQLDDLForSQLite); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	if len(dst) > 0 {
+		if err := db.AutoMigrate(dst...); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	}
+	return db
+}
+
+// adaptMySQLDDLForSQLite 调整模型上 MySQL 专用的建表语句：
+// 全文索引按普通索引创建；int unsigned 主键改为 INTEGER，SQLite 只对 INTEGER 主键自增。
+func adaptMySQLDDLForSQLite(tx *gorm.DB) {
+	sql := tx.Statement.SQL.String()
+	switch {
+	case strings.HasPrefix(sql, "CREATE FULLTEXT INDEX"):
+		sql = strings.Replace(sql, "CREATE FULLTEXT INDEX", "CREATE INDEX", 1)
+	case strings.HasPrefix(sql, "CREATE TABLE"):
+		sql = strings.Replace(sql, "`id` int unsigned", "`id` integer", 1)
+	default:
+		return
+	}
+	tx.Statement.SQL.Reset()
+	tx.Statement.SQL.WriteString(sql)
+}
+
+// newMCPTestRBAC 以缓存中的权限快照构造 RBACService，未写入快照的用户会回落到没有 RBAC 表的数据库并报错
+func newMCPTestRBAC(t *testing.T, snapshots map[uint]services.UserPermissionSnapshot) *services.RBACService {
+	t.Helper()
+	mem := &memoryCache{values: map[string]string{}}
+	for userID, snap := range snapshots {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			t.Fatalf("marshal snapshot: %v", err)
+		}
+		mem.values[fmt.Sprintf("rbac:user:%d:permissions", userID)] = string(data)
+	}
+	prev := cache.GlobalTieredCache
+	cache.GlobalTieredCache = mem
+	t.Cleanup(func() { cache.GlobalTieredCache = prev })
+	return services.NewRBACService(newMCPTestDB(t))
+}
+
+func mcpUserContext(userID uint) context.Context {
+	return context.WithValue(context.Background(), mcpContextKey(constant.MCPUserIDKey), userID)
+}
+
+func toolNames(tools []mcp.Tool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestMCPFilterTools(t *testing.T) {
+	th := &mcpToolHandlers{
+		rbacService: newMCPTestRBAC(t, map[uint]services.UserPermissionSnapshot{
+			1: {PermissionTags: []string{constant.PermissionCountdown}},
+			2: {IsAdmin: true},
+		}),
+	}
+	s := server.NewMCPServer("test", "0.0.1")
+	noop := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) { return nil, nil }
+	th.addPublicTool(s, mcp.NewTool("heroes"), noop)
+	th.addTool(s, mcp.NewTool("countdown"), noop, constant.PermissionCountdown)
+	th.addTool(s, mcp.NewTool("materials"), noop, constant.PermissionMaterialGet, constant.PermissionMaterialRate)
+	// 直接注册、未声明权限的工具
+	s.AddTool(mcp.NewTool("undeclared"), noop)
+
+	all := []mcp.Tool{mcp.NewTool("heroes"), mcp.NewTool("countdown"), mcp.NewTool("materials"), mcp.NewTool("undeclared")}
+	tests := []struct {
+		name   string
+		userID uint
+		want   []string
+	}{
+		{name: "matching permission", userID: 1, want: []string{"heroes", "countdown"}},
+		{name: "admin sees declared tools only", userID: 2, want: []string{"heroes", "countdown", "materials"}},
+		{name: "snapshot unavailable", userID: 3, want: []string{"heroes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toolNames(th.filterTools(mcpUserContext(tt.userID), all))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("filterTools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPRegisteredToolsDeclarePermissions(t *testing.T) {
+	th := &mcpToolHandlers{}
+	s := server.NewMCPServer("test", "0.0.1")
+	th.registerTools(s)
+	th.registerAdminTools(s)
+
+	for name := range s.ListTools() {
+		if _, ok := th.toolPermissions[name]; !ok {
+			t.Errorf("tool %q is registered without declaring its permissions", name)
+		}
+	}
+	if len(th.toolPermissions) != len(s.ListTools()) {
+		t.Fatalf("declared %d tools, registered %d", len(th.toolPermissions), len(s.ListTools()))
+	}
+}
+
+func TestMCPAuditToolCall(t *testing.T) {
+	db := newMCPTestDB(t, &models.MCPToolCallAudit{})
+	th := &mcpToolHandlers{auditService: services.NewMCPAuditService(db)}
+
+	tests := []struct {
+		name        string
+		tool        string
+		handler     server.ToolHandlerFunc
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name: "success",
+			tool: "countdown",
+			handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText("ok"), nil
+			},
+			wantStatus: constant.MCPToolCallStatusSuccess,
+		},
+		{
+			name: "tool error",
+			tool: "points",
+			handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultError("积分不足"), nil
+			},
+			wantStatus:  constant.MCPToolCallStatusToolError,
+			wantMessage: "积分不足",
+		},
+		{
+			name: "handler error",
+			tool: "materials",
+			handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return nil, errors.New("权限不足: material.get")
+			},
+			wantStatus:  constant.MCPToolCallStatusError,
+			wantMessage: "权限不足: material.get",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mcp.CallToolRequest{}
+			req.Params.Name = tt.tool
+			req.Params.Arguments = map[string]any{"b": 2, "a": 1}
+			_, _ = th.auditToolCall(tt.handler)(mcpUserContext(7), req)
+
+			var record models.MCPToolCallAudit
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				err := db.Where("tool_name = ?", tt.tool).First(&record).Error
+				if err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("audit record not written: %v", err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if record.UserID != 7 || record.Status != tt.wantStatus || record.ErrorMessage != tt.wantMessage {
+				t.Fatalf("audit record = %+v", record)
+			}
+			if record.ArgumentsHash != hashToolArguments(map[string]any{"a": 1, "b": 2}) {
+				t.Fatalf("arguments hash should not depend on key order: %s", record.ArgumentsHash)
+			}
+		})
+	}
+}
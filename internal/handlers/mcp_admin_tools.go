@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCPAdminHandler 后台 MCP 端点，为运营和管理员提供审核队列处理和统计查询工具
+type MCPAdminHandler struct {
+	httpServer *server.StreamableHTTPServer
+}
+
+// NewMCPAdminHandler 创建后台 MCP 处理器，与面向学生的 MCP 端点使用独立的 MCP Server
+func NewMCPAdminHandler(
+	rbacService *services.RBACService,
+	reviewService *services.ReviewService,
+	contributionService *services.ContributionService,
+	notificationService *services.NotificationService,
+	statService *services.StatService,
+	auditService *services.MCPAuditService,
+) *MCPAdminHandler {
+	th := &mcpToolHandlers{
+		rbacService:         rbacService,
+		reviewService:       reviewService,
+		contributionService: contributionService,
+		notificationService: notificationService,
+		statService:         statService,
+		auditService:        auditService,
+	}
+
+	mcpServer := server.NewMCPServer("gojxust-admin-mcp-server", "0.1.0",
+		server.WithToolFilter(th.filterTools),
+		server.WithToolHandlerMiddleware(th.auditToolCall),
+	)
+	th.registerAdminTools(mcpServer)
+
+	return &MCPAdminHandler{httpServer: server.NewStreamableHTTPServer(mcpServer)}
+}
+
+// Handle 处理后台 MCP 请求，角色由路由上的 RequireRole 校验，具体操作仍按权限标签逐项校验
+func (h *MCPAdminHandler) Handle(c *gin.Context) {
+	userID := helper.GetUserID(c)
+	if userID == 0 {
+		helper.HandleErrCode(c, constant.AuthMissingUserContext)
+		return
+	}
+
+	// 服务层按角色区分数据范围（如投稿列表），需要把 RequireRole 注入的权限信息带入工具调用
+	ctx := context.WithValue(c.Request.Context(), mcpContextKey(constant.MCPUserIDKey), userID)
+	ctx = utils.WithUserRBAC(ctx, utils.GetUserRoles(c), utils.GetUserPermissions(c), utils.IsAdmin(c))
+
+	h.httpServer.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// registerAdminTools registers moderation and statistics tools for back-office users
+func (th *mcpToolHandlers) registerAdminTools(s *server.MCPServer) {
+	th.addTool(s,
+		mcp.NewTool("moderationQueues",
+			mcp.WithDescription("审核队列概览 - 统计待审核的教师评价、投稿和通知数量，只包含调用者有权处理的队列"),
+			mcp.WithInputSchema[ModerationQueuesParams](),
+		),
+		th.handleModerationQueues,
+		constant.PermissionReviewManage, constant.PermissionContributionManage, constant.PermissionNotificationGetAdmin,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("reviewModeration",
+			mcp.WithDescription("教师评价审核 - 获取评价列表、评价详情，审核通过或拒绝评价"),
+			mcp.WithInputSchema[ReviewModerationParams](),
+		),
+		th.handleReviewModeration,
+		constant.PermissionReviewManage,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("contributionModeration",
+			mcp.WithDescription("投稿审核 - 获取全部用户的投稿列表、投稿详情，采纳或拒绝投稿，或获取投稿统计"),
+			mcp.WithInputSchema[ContributionModerationParams](),
+		),
+		th.handleContributionModeration,
+		constant.PermissionContributionManage,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("notificationModeration",
+			mcp.WithDescription("通知审核 - 获取后台通知列表、通知详情及审核进度，同意或拒绝待审核通知，或获取通知统计"),
+			mcp.WithInputSchema[NotificationModerationParams](),
+		),
+		th.handleNotificationModeration,
+		constant.PermissionNotificationGetAdmin, constant.PermissionNotificationApprove,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("adminStats",
+			mcp.WithDescription("后台统计 - 系统及各项目在线人数，按用户统计倒数日、学习任务、GPA备份数量"),
+			mcp.WithInputSchema[AdminStatsParams](),
+		),
+		th.handleAdminStats,
+		constant.PermissionStatisticManage,
+	)
+}
+
+// ============== Admin Tool Parameter Structs ==============
+
+// ModerationQueuesParams - no params needed
+type ModerationQueuesParams struct{}
+
+// ReviewModerationParams for review moderation tool
+type ReviewModerationParams struct {
+	Action      string `json:"action" jsonschema:"操作类型: list(评价列表) / get(评价详情) / approve(审核通过) / reject(审核拒绝)"`
+	ID          uint   `json:"id,omitempty" jsonschema:"评价ID，当action为get/approve/reject时必填"`
+	Status      *int8  `json:"status,omitempty" jsonschema:"状态: 0全部 1待审核 2已通过 3已拒绝，仅list使用，默认1"`
+	TeacherName string `json:"teacher_name,omitempty" jsonschema:"教师姓名关键词，仅list使用"`
+	AdminNote   string `json:"admin_note,omitempty" jsonschema:"审核备注，approve/reject使用"`
+	Page        int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size        int    `json:"size,omitempty" jsonschema:"每页数量，默认10"`
+}
+
+// ContributionModerationParams for contribution moderation tool
+type ContributionModerationParams struct {
+	Action     string  `json:"action" jsonschema:"操作类型: list(投稿列表) / get(投稿详情) / review(审核) / stats(投稿统计)"`
+	ID         uint    `json:"id,omitempty" jsonschema:"投稿ID，当action为get/review时必填"`
+	Status     *uint8  `json:"status,omitempty" jsonschema:"list时为状态过滤: 0全部 1待审核 2已采纳 3已拒绝，默认1；review时为审核结果: 2采纳 3拒绝，必填"`
+	UserID     *uint   `json:"user_id,omitempty" jsonschema:"投稿用户ID，仅list使用"`
+	ReviewNote *string `json:"review_note,omitempty" jsonschema:"审核备注，最多500字，仅review使用"`
+	Points     *uint   `json:"points,omitempty" jsonschema:"采纳时奖励积分1-100，仅review使用"`
+	Title      *string `json:"title,omitempty" jsonschema:"采纳时修改后的标题，最多200字"`
+	Content    *string `json:"content,omitempty" jsonschema:"采纳时修改后的内容"`
+	Categories *[]int  `json:"categories,omitempty" jsonschema:"采纳时修改后的分类ID列表"`
+	Page       int     `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size       int     `json:"size,omitempty" jsonschema:"每页数量，默认20"`
+}
+
+// NotificationModerationParams for notification moderation tool
+type NotificationModerationParams struct {
+	Action  string `json:"action" jsonschema:"操作类型: list(通知列表) / get(通知详情) / approve(审核) / stats(通知统计)"`
+	ID      uint   `json:"id,omitempty" jsonschema:"通知ID，当action为get/approve时必填"`
+	Status  *uint8 `json:"status,omitempty" jsonschema:"list时为状态过滤: 0全部(不含已删除) 1草稿 2待审核 3已发布，默认2；approve时为审核结果: 1同意 2拒绝，必填"`
+	Keyword string `json:"keyword,omitempty" jsonschema:"标题关键词，仅list使用"`
+	Note    string `json:"note,omitempty" jsonschema:"审核备注，最多500字，仅approve使用"`
+	Page    int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size    int    `json:"size,omitempty" jsonschema:"每页数量，默认20"`
+}
+
+// AdminStatsParams for admin stats tool
+type AdminStatsParams struct {
+	Action string `json:"action" jsonschema:"操作类型: online(系统在线人数) / projects_online(各项目在线人数) / countdowns(按用户统计倒数日) / study_tasks(按用户统计学习任务) / gpa_backups(按用户统计GPA备份)"`
+	Page   int    `json:"page,omitempty" jsonschema:"页码，默认1，按用户统计时使用"`
+	Size   int    `json:"size,omitempty" jsonschema:"每页数量，默认20，按用户统计时使用"`
+}
+
+// ============== Admin Tool Handlers ==============
+
+// normalizeAdminPage 规范化分页参数，每页数量最多100
+func normalizeAdminPage(page, size, defaultSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = defaultSize
+	}
+	return page, size
+}
+
+func (th *mcpToolHandlers) handleModerationQueues(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+	snap, err := th.rbacService.GetUserPermissionSnapshot(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户权限失败: %w", err)
+	}
+
+	// 只统计调用者有权处理的队列，分页大小取1仅用于获取总数
+	queues := make(map[string]int64)
+	if snap.HasPermission(constant.PermissionReviewManage) {
+		_, total, err := th.reviewService.GetReviews(ctx, 1, 1, "", models.TeacherReviewStatusPending)
+		if err != nil {
+			return nil, fmt.Errorf("统计待审核评价失败: %w", err)
+		}
+		queues["reviews"] = total
+	}
+	if snap.HasPermission(constant.PermissionContributionManage) {
+		status := uint8(models.UserContributionStatusPending)
+		page, err := th.contributionService.GetContributions(ctx, userID, &request.GetContributionsRequest{Page: 1, Size: 1, Status: &status})
+		if err != nil {
+			return nil, fmt.Errorf("统计待审核投稿失败: %w", err)
+		}
+		queues["contributions"] = page.Total
+	}
+	if snap.HasPermission(constant.PermissionNotificationGetAdmin) {
+		status := uint8(models.NotificationStatusPending)
+		page, err := th.notificationService.GetAdminNotifications(ctx, &request.GetNotificationsRequest{Page: 1, Size: 1, Status: &status})
+		if err != nil {
+			return nil, fmt.Errorf("统计待审核通知失败: %w", err)
+		}
+		queues["notifications"] = page.Total
+	}
+
+	data, _ := sonic.Marshal(map[string]any{"pending": queues})
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleReviewModeration(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionReviewManage); err != nil {
+		return nil, err
+	}
+
+	var params ReviewModerationParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	var result any
+	var err error
+	switch params.Action {
+	case "list":
+		page, size := normalizeAdminPage(params.Page, params.Size, 10)
+		status := models.TeacherReviewStatusPending
+		if params.Status != nil {
+			status = models.TeacherReviewStatus(*params.Status)
+		}
+		var reviews []models.TeacherReview
+		var total int64
+		reviews, total, err = th.reviewService.GetReviews(ctx, page, size, params.TeacherName, status)
+		result = map[string]any{"data": reviews, "total": total, "page": page, "size": size}
+	case "get":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供评价ID")
+		}
+		result, err = th.reviewService.GetReviewByID(ctx, params.ID)
+	case "approve":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供评价ID")
+		}
+		err = th.reviewService.ApproveReview(ctx, params.ID, params.AdminNote)
+		result = map[string]any{"message": "审核通过"}
+	case "reject":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供评价ID")
+		}
+		err = th.reviewService.RejectReview(ctx, params.ID, params.AdminNote)
+		result = map[string]any{"message": "审核拒绝"}
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("处理教师评价失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleContributionModeration(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionContributionManage); err != nil {
+		return nil, err
+	}
+
+	var params ContributionModerationParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	var result any
+	var err error
+	switch params.Action {
+	case "list":
+		page, size := normalizeAdminPage(params.Page, params.Size, 20)
+		status := params.Status
+		if status == nil {
+			pending := uint8(models.UserContributionStatusPending)
+			status = &pending
+		} else if *status == 0 {
+			status = nil
+		}
+		result, err = th.contributionService.GetContributions(ctx, userID, &request.GetContributionsRequest{Page: page, Size: size, Status: status, UserID: params.UserID})
+	case "get":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供投稿ID")
+		}
+		result, err = th.contributionService.GetContributionByID(ctx, params.ID, userID)
+	case "review":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供投稿ID")
+		}
+		// 与 REST 接口 ReviewContributionRequest 的 binding 规则保持一致
+		if params.Status == nil || (*params.Status != uint8(models.UserContributionStatusApproved) && *params.Status != uint8(models.UserContributionStatusRejected)) {
+			return nil, fmt.Errorf("审核结果只能为2(采纳)或3(拒绝)")
+		}
+		if params.ReviewNote != nil && utf8.RuneCountInString(*params.ReviewNote) > 500 {
+			return nil, fmt.Errorf("审核备注不能超过500字")
+		}
+		if params.Points != nil && (*params.Points < 1 || *params.Points > 100) {
+			return nil, fmt.Errorf("奖励积分范围为1-100")
+		}
+		if params.Title != nil && utf8.RuneCountInString(*params.Title) > 200 {
+			return nil, fmt.Errorf("标题不能超过200字")
+		}
+		err = th.contributionService.ReviewContribution(ctx, params.ID, userID, &request.ReviewContributionRequest{
+			Status:     *params.Status,
+			ReviewNote: params.ReviewNote,
+			Points:     params.Points,
+			Title:      params.Title,
+			Content:    params.Content,
+			Categories: params.Categories,
+		})
+		result = map[string]any{"message": "投稿审核完成"}
+	case "stats":
+		result, err = th.contributionService.GetAdminContributionStats(ctx)
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("处理投稿失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleNotificationModeration(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params NotificationModerationParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	// 审核与查看使用不同的权限标签，与 REST 接口一致
+	permission := constant.PermissionNotificationGetAdmin
+	if params.Action == "approve" {
+		permission = constant.PermissionNotificationApprove
+	}
+	if err := th.requirePermission(ctx, permission); err != nil {
+		return nil, err
+	}
+
+	userID := getUserFromContext(ctx)
+	var result any
+	var err error
+	switch params.Action {
+	case "list":
+		page, size := normalizeAdminPage(params.Page, params.Size, 20)
+		status := params.Status
+		if status == nil {
+			pending := uint8(models.NotificationStatusPending)
+			status = &pending
+		} else if *status == 0 {
+			status = nil
+		}
+		result, err = th.notificationService.GetAdminNotifications(ctx, &request.GetNotificationsRequest{Page: page, Size: size, Status: status, Keyword: params.Keyword})
+	case "get":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供通知ID")
+		}
+		result, err = th.notificationService.GetNotificationAdminByID(ctx, params.ID)
+	case "approve":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供通知ID")
+		}
+		if params.Status == nil || (*params.Status != uint8(request.NotificationApprovalStatusRequestApproved) && *params.Status != uint8(request.NotificationApprovalStatusRequestRejected)) {
+			return nil, fmt.Errorf("审核结果只能为1(同意)或2(拒绝)")
+		}
+		if utf8.RuneCountInString(params.Note) > 500 {
+			return nil, fmt.Errorf("审核备注不能超过500字")
+		}
+		err = th.notificationService.ApproveNotification(ctx, params.ID, userID, &request.ApproveNotificationRequest{
+			Status: request.NotificationApprovalStatusRequest(*params.Status),
+			Note:   params.Note,
+		})
+		result = map[string]any{"message": "审核完成"}
+	case "stats":
+		result, err = th.notificationService.GetNotificationStats(ctx)
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("处理通知失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleAdminStats(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionStatisticManage); err != nil {
+		return nil, err
+	}
+
+	var params AdminStatsParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	var result any
+	var err error
+	switch params.Action {
+	case "online":
+		var count int64
+		count, err = th.statService.GetSystemOnlineCount(ctx)
+		result = map[string]any{"online_count": count}
+	case "projects_online":
+		result, err = th.statService.GetAllProjectsOnlineCount(ctx)
+	case "countdowns", "study_tasks", "gpa_backups":
+		page, size := normalizeAdminPage(params.Page, params.Size, 20)
+		var list []response.AdminUserCountStatResponse
+		var total int64
+		switch params.Action {
+		case "countdowns":
+			list, total, err = th.statService.GetCountdownCountsByUser(ctx, page, size)
+		case "study_tasks":
+			list, total, err = th.statService.GetStudyTaskCountsByUser(ctx, page, size)
+		default:
+			list, total, err = th.statService.GetGPABackupCountsByUser(ctx, page, size)
+		}
+		result = map[string]any{"data": list, "total": total, "page": page, "size": size}
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取统计数据失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type MCPAuditHandler struct {
+	service *services.MCPAuditService
+}
+
+func NewMCPAuditHandler(service *services.MCPAuditService) *MCPAuditHandler {
+	return &MCPAuditHandler{service: service}
+}
+
+// ListToolCalls 管理员分页查询 MCP 工具调用审计记录
+func (h *MCPAuditHandler) ListToolCalls(c *gin.Context) {
+	req := request.ListMCPToolCallsRequest{Page: 1, PageSize: 20}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	records, total, err := h.service.ListToolCalls(c.Request.Context(), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-mcp-tool-calls",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.PageSuccessResponse(c, records, total, req.Page, req.PageSize)
+}
@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+
+	"github.com/bytedance/sonic"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerCampusTools registers tools for materials, question bank, points, GPA backups,
+// pomodoro, dictionary, organizations, contributions and fail rate ranking
+func (th *mcpToolHandlers) registerCampusTools(s *server.MCPServer) {
+	th.addTool(s,
+		mcp.NewTool("materials",
+			mcp.WithDescription("资料库 - 搜索资料、获取资料详情或为资料评分"),
+			mcp.WithInputSchema[MaterialsParams](),
+		),
+		th.handleMaterials,
+		constant.PermissionMaterialGet, constant.PermissionMaterialRate,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("questionBank",
+			mcp.WithDescription("题库 - 获取题库项目列表、项目题目ID列表、题目详情，或记录一次做题"),
+			mcp.WithInputSchema[QuestionBankParams](),
+		),
+		th.handleQuestionBank,
+		constant.PermissionQuestion,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("points",
+			mcp.WithDescription("积分 - 获取当前积分、积分变动记录或积分统计"),
+			mcp.WithInputSchema[PointsParams](),
+		),
+		th.handlePoints,
+		constant.PermissionPointGet,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("gpaBackup",
+			mcp.WithDescription("GPA备份 - 获取备份列表、备份详情或删除备份"),
+			mcp.WithInputSchema[GPABackupParams](),
+		),
+		th.handleGPABackup,
+		constant.PermissionUserGet, constant.PermissionUserUpdate,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("pomodoro",
+			mcp.WithDescription("番茄钟统计 - 获取当前用户完成的番茄钟次数或排行榜"),
+			mcp.WithInputSchema[PomodoroParams](),
+		),
+		th.handlePomodoro,
+		constant.PermissionPomodoro,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("dictionary",
+			mcp.WithDescription("词典 - 随机获取一个单词或查询指定单词"),
+			mcp.WithInputSchema[DictionaryParams](),
+		),
+		th.handleDictionary,
+		constant.PermissionDictionary,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("organizations",
+			mcp.WithDescription("校园组织 - 按名称、类型、归属、校区筛选组织列表或获取组织详情"),
+			mcp.WithInputSchema[OrganizationsParams](),
+		),
+		th.handleOrganizations,
+		constant.PermissionOrganizationGet,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("contributions",
+			mcp.WithDescription("我的投稿 - 获取投稿列表、投稿详情或投稿统计"),
+			mcp.WithInputSchema[ContributionsParams](),
+		),
+		th.handleContributions,
+		constant.PermissionContributionGet,
+	)
+
+	th.addTool(s,
+		mcp.NewTool("failRateRanking",
+			mcp.WithDescription("挂科率排行 - 按挂科率从高到低列出课程，可按课程名关键词筛选"),
+			mcp.WithInputSchema[FailRateRankingParams](),
+		),
+		th.handleFailRateRanking,
+		constant.PermissionFailRate,
+	)
+}
+
+// ============== Campus Tool Parameter Structs ==============
+
+// MaterialsParams for materials tool
+type MaterialsParams struct {
+	Action   string `json:"action" jsonschema:"操作类型: search(搜索) / detail(详情) / rate(评分)"`
+	Keywords string `json:"keywords,omitempty" jsonschema:"搜索关键词，当action为search时必填"`
+	MD5      string `json:"md5,omitempty" jsonschema:"资料MD5，当action为detail或rate时必填"`
+	Rating   int    `json:"rating,omitempty" jsonschema:"评分1-5，当action为rate时必填"`
+	Page     int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size     int    `json:"size,omitempty" jsonschema:"每页数量，默认20"`
+}
+
+// QuestionBankParams for question bank tool
+type QuestionBankParams struct {
+	Action     string `json:"action" jsonschema:"操作类型: projects(项目列表) / list(题目ID列表) / get(题目详情) / practice(记录做题)"`
+	ProjectID  uint   `json:"project_id,omitempty" jsonschema:"题库项目ID，当action为list时必填"`
+	QuestionID uint   `json:"question_id,omitempty" jsonschema:"题目ID，当action为get或practice时必填"`
+	Random     bool   `json:"random,omitempty" jsonschema:"题目ID列表是否乱序，默认顺序"`
+}
+
+// PointsParams for points tool
+type PointsParams struct {
+	Action string `json:"action" jsonschema:"操作类型: balance(当前积分) / transactions(变动记录) / stats(统计)"`
+	Type   *uint8 `json:"type,omitempty" jsonschema:"变动类型: 1获得 2消耗，仅transactions使用"`
+	Page   int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size   int    `json:"size,omitempty" jsonschema:"每页数量，默认20"`
+}
+
+// GPABackupParams for GPA backup tool
+type GPABackupParams struct {
+	Action string `json:"action" jsonschema:"操作类型: list(备份列表) / get(备份详情) / delete(删除备份)"`
+	ID     uint   `json:"id,omitempty" jsonschema:"备份ID，当action为get或delete时必填"`
+}
+
+// PomodoroParams for pomodoro tool
+type PomodoroParams struct {
+	Action string `json:"action" jsonschema:"操作类型: count(我的次数) / ranking(排行榜)"`
+}
+
+// DictionaryParams for dictionary tool
+type DictionaryParams struct {
+	Action string `json:"action" jsonschema:"操作类型: random(随机单词) / lookup(查询单词)"`
+	Word   string `json:"word,omitempty" jsonschema:"要查询的单词，当action为lookup时必填"`
+}
+
+// OrganizationsParams for organizations tool
+type OrganizationsParams struct {
+	Action           string `json:"action" jsonschema:"操作类型: list(组织列表) / get(组织详情)"`
+	ID               uint   `json:"id,omitempty" jsonschema:"组织ID，当action为get时必填"`
+	Query            string `json:"query,omitempty" jsonschema:"名称关键词"`
+	OrganizationType string `json:"organization_type,omitempty" jsonschema:"组织类型"`
+	Affiliation      string `json:"affiliation,omitempty" jsonschema:"归属单位"`
+	Campus           string `json:"campus,omitempty" jsonschema:"校区"`
+	Page             int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size             int    `json:"size,omitempty" jsonschema:"每页数量，默认10"`
+}
+
+// ContributionsParams for contributions tool
+type ContributionsParams struct {
+	Action string `json:"action" jsonschema:"操作类型: list(投稿列表) / get(投稿详情) / stats(投稿统计)"`
+	ID     uint   `json:"id,omitempty" jsonschema:"投稿ID，当action为get时必填"`
+	Status *uint8 `json:"status,omitempty" jsonschema:"状态: 1待审核 2已采纳 3已拒绝，仅list使用"`
+	Page   int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size   int    `json:"size,omitempty" jsonschema:"每页数量，默认20"`
+}
+
+// FailRateRankingParams for fail rate ranking tool
+type FailRateRankingParams struct {
+	Keyword string `json:"keyword,omitempty" jsonschema:"课程名关键词，不填则在全部课程中排行"`
+	Page    int    `json:"page,omitempty" jsonschema:"页码，默认1"`
+	Size    int    `json:"size,omitempty" jsonschema:"每页数量，默认10"`
+}
+
+// ============== Campus Tool Handlers ==============
+
+func (th *mcpToolHandlers) handleMaterials(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params MaterialsParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	switch params.Action {
+	case "search":
+		if err := th.requirePermission(ctx, constant.PermissionMaterialGet); err != nil {
+			return nil, err
+		}
+		if params.Keywords == "" {
+			return nil, fmt.Errorf("请提供搜索关键词")
+		}
+		page, size := params.Page, params.Size
+		if page <= 0 {
+			page = 1
+		}
+		if size <= 0 || size > 100 {
+			size = 20
+		}
+		result, err := th.materialService.SearchMaterials(ctx, &request.MaterialSearchRequest{Keywords: params.Keywords, Page: page, PageSize: size})
+		if err != nil {
+			return nil, fmt.Errorf("搜索资料失败: %w", err)
+		}
+		if err := th.materialService.CreateMaterialLog(ctx, userID, &request.MaterialLogCreateRequest{Type: 1, Keywords: params.Keywords}); err != nil {
+			return nil, fmt.Errorf("记录搜索日志失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "detail":
+		if err := th.requirePermission(ctx, constant.PermissionMaterialGet); err != nil {
+			return nil, err
+		}
+		if params.MD5 == "" {
+			return nil, fmt.Errorf("请提供资料MD5")
+		}
+		result, err := th.materialService.GetMaterialByMD5(ctx, params.MD5, &userID)
+		if err != nil {
+			return nil, fmt.Errorf("获取资料详情失败: %w", err)
+		}
+		if err := th.materialService.CreateMaterialLog(ctx, userID, &request.MaterialLogCreateRequest{Type: 2, MaterialMD5: params.MD5}); err != nil {
+			return nil, fmt.Errorf("记录查看日志失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "rate":
+		if err := th.requirePermission(ctx, constant.PermissionMaterialRate); err != nil {
+			return nil, err
+		}
+		if params.MD5 == "" {
+			return nil, fmt.Errorf("请提供资料MD5")
+		}
+		if params.Rating < 1 || params.Rating > 5 {
+			return nil, fmt.Errorf("评分需在1-5之间")
+		}
+		if err := th.materialService.RateMaterial(ctx, userID, params.MD5, params.Rating); err != nil {
+			return nil, fmt.Errorf("资料评分失败: %w", err)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(`{"message": "评分成功"}`)}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handleQuestionBank(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionQuestion); err != nil {
+		return nil, err
+	}
+
+	var params QuestionBankParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	switch params.Action {
+	case "projects":
+		result, err := th.questionService.GetProjects(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("获取题库项目失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "list":
+		if params.ProjectID == 0 {
+			return nil, fmt.Errorf("请提供题库项目ID")
+		}
+		result, err := th.questionService.GetQuestions(ctx, userID, &request.GetQuestionRequest{ProjectID: params.ProjectID, Random: params.Random})
+		if err != nil {
+			return nil, fmt.Errorf("获取题目列表失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "get":
+		if params.QuestionID == 0 {
+			return nil, fmt.Errorf("请提供题目ID")
+		}
+		result, err := th.questionService.GetQuestionByID(ctx, userID, params.QuestionID)
+		if err != nil {
+			return nil, fmt.Errorf("获取题目详情失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "practice":
+		if params.QuestionID == 0 {
+			return nil, fmt.Errorf("请提供题目ID")
+		}
+		if err := th.questionService.SubmitPractice(ctx, userID, &request.SubmitPracticeRequest{QuestionID: params.QuestionID, ProjectID: params.ProjectID}); err != nil {
+			return nil, fmt.Errorf("记录做题失败: %w", err)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(`{"message": "记录成功"}`)}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handlePoints(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionPointGet); err != nil {
+		return nil, err
+	}
+
+	var params PointsParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	var result any
+	var err error
+	switch params.Action {
+	case "balance":
+		result, err = th.pointsService.GetUserPoints(ctx, userID)
+	case "transactions":
+		page, size := params.Page, params.Size
+		if page <= 0 {
+			page = 1
+		}
+		if size <= 0 || size > 100 {
+			size = 20
+		}
+		if params.Type != nil && *params.Type == 0 {
+			params.Type = nil
+		}
+		result, err = th.pointsService.GetPointsTransactions(ctx, userID, &request.GetPointsTransactionsRequest{Page: page, Size: size, Type: params.Type})
+	case "stats":
+		result, err = th.pointsService.GetUserPointsStats(ctx, userID)
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取积分信息失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleGPABackup(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params GPABackupParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	switch params.Action {
+	case "list":
+		if err := th.requirePermission(ctx, constant.PermissionUserGet); err != nil {
+			return nil, err
+		}
+		result, err := th.gpaBackupService.ListBackups(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("获取GPA备份列表失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "get":
+		if err := th.requirePermission(ctx, constant.PermissionUserGet); err != nil {
+			return nil, err
+		}
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供备份ID")
+		}
+		result, err := th.gpaBackupService.GetBackupByID(ctx, userID, params.ID)
+		if err != nil {
+			return nil, fmt.Errorf("获取GPA备份详情失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "delete":
+		if err := th.requirePermission(ctx, constant.PermissionUserUpdate); err != nil {
+			return nil, err
+		}
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供备份ID")
+		}
+		if err := th.gpaBackupService.DeleteBackup(ctx, userID, params.ID); err != nil {
+			return nil, fmt.Errorf("删除GPA备份失败: %w", err)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(`{"message": "删除成功"}`)}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handlePomodoro(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionPomodoro); err != nil {
+		return nil, err
+	}
+
+	var params PomodoroParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	switch params.Action {
+	case "count":
+		count, err := th.pomodoroService.GetPomodoroCount(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("获取番茄钟次数失败: %w", err)
+		}
+		data, _ := sonic.Marshal(map[string]any{"pomodoro_count": count})
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "ranking":
+		result, err := th.pomodoroService.GetPomodoroRanking(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取番茄钟排行失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handleDictionary(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionDictionary); err != nil {
+		return nil, err
+	}
+
+	var params DictionaryParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	switch params.Action {
+	case "random":
+		result, err := th.dictionaryService.GetRandomWord(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取随机单词失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "lookup":
+		if params.Word == "" {
+			return nil, fmt.Errorf("请提供要查询的单词")
+		}
+		result, err := th.dictionaryService.LookupWord(ctx, params.Word)
+		if err != nil {
+			return nil, fmt.Errorf("查询单词失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handleOrganizations(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionOrganizationGet); err != nil {
+		return nil, err
+	}
+
+	var params OrganizationsParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	switch params.Action {
+	case "list":
+		page, size := params.Page, params.Size
+		if page <= 0 {
+			page = 1
+		}
+		if size <= 0 || size > 100 {
+			size = 10
+		}
+		list, total, err := th.organizationService.ListOrganizations(ctx, &request.ListOrganizationsRequest{
+			Query:            params.Query,
+			OrganizationType: params.OrganizationType,
+			Affiliation:      params.Affiliation,
+			Campus:           params.Campus,
+			Page:             page,
+			PageSize:         size,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取组织列表失败: %w", err)
+		}
+		data, _ := sonic.Marshal(map[string]any{"data": list, "total": total, "page": page, "size": size})
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	case "get":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供组织ID")
+		}
+		result, err := th.organizationService.GetOrganizationByID(ctx, params.ID)
+		if err != nil {
+			return nil, fmt.Errorf("获取组织详情失败: %w", err)
+		}
+		data, _ := sonic.Marshal(result)
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+}
+
+func (th *mcpToolHandlers) handleContributions(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionContributionGet); err != nil {
+		return nil, err
+	}
+
+	var params ContributionsParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return nil, fmt.Errorf("用户未认证")
+	}
+
+	var result any
+	var err error
+	switch params.Action {
+	case "list":
+		page, size := params.Page, params.Size
+		if page <= 0 {
+			page = 1
+		}
+		if size <= 0 || size > 100 {
+			size = 20
+		}
+		if params.Status != nil && *params.Status == 0 {
+			params.Status = nil
+		}
+		result, err = th.contributionService.GetContributions(ctx, userID, &request.GetContributionsRequest{Page: page, Size: size, Status: params.Status})
+	case "get":
+		if params.ID == 0 {
+			return nil, fmt.Errorf("请提供投稿ID")
+		}
+		result, err = th.contributionService.GetContributionByID(ctx, params.ID, userID)
+	case "stats":
+		result, err = th.contributionService.GetUserContributionStats(ctx, userID)
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", params.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取投稿信息失败: %w", err)
+	}
+	data, _ := sonic.Marshal(result)
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
+
+func (th *mcpToolHandlers) handleFailRateRanking(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionFailRate); err != nil {
+		return nil, err
+	}
+
+	var params FailRateRankingParams
+	if err := req.BindArguments(&params); err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+
+	page, size := params.Page, params.Size
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 10
+	}
+	// Search 按挂科率降序返回
+	list, total, err := th.failRateService.Search(ctx, params.Keyword, page, size)
+	if err != nil {
+		return nil, fmt.Errorf("查询挂科率排行失败: %w", err)
+	}
+	data, _ := sonic.Marshal(map[string]any{"data": list, "total": total, "page": page, "size": size})
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(data))}}, nil
+}
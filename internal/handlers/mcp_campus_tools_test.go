@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type campusToolHandler func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+// newCampusToolHandlers 用户 1 拥有全部校园工具权限，用户 2 没有任何权限
+func newCampusToolHandlers(t *testing.T) (*mcpToolHandlers, *gorm.DB) {
+	t.Helper()
+	rbac := newMCPTestRBAC(t, map[uint]services.UserPermissionSnapshot{
+		1: {PermissionTags: []string{
+			constant.PermissionMaterialGet, constant.PermissionMaterialRate, constant.PermissionQuestion,
+			constant.PermissionPointGet, constant.PermissionUserGet, constant.PermissionUserUpdate,
+			constant.PermissionPomodoro, constant.PermissionDictionary, constant.PermissionOrganizationGet,
+			constant.PermissionContributionGet, constant.PermissionFailRate,
+		}},
+		2: {},
+	})
+	db := newMCPTestDB(t,
+		&models.User{}, &models.Material{}, &models.MaterialDesc{}, &models.MaterialCategory{}, &models.MaterialLog{},
+		&models.QuestionProject{}, &models.Question{}, &models.UserProjectUsage{}, &models.UserQuestionUsage{},
+		&models.PointsTransaction{}, &models.GPABackup{}, &models.Dictionary{}, &models.Organization{},
+		&models.UserContribution{}, &models.NotificationCategory{}, &models.FailRate{},
+	)
+	for _, user := range []models.User{
+		{ID: 1, OpenID: "o1", Nickname: "同学甲", Points: 30, PomodoroCount: 5},
+		{ID: 2, OpenID: "o2", Nickname: "同学乙", PomodoroCount: 8},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	pointsService := services.NewPointsService(db)
+	return &mcpToolHandlers{
+		rbacService:         rbac,
+		failRateService:     services.NewFailRateService(db),
+		materialService:     services.NewMaterialService(db, nil),
+		questionService:     services.NewQuestionService(db),
+		pointsService:       pointsService,
+		gpaBackupService:    services.NewGPABackupService(db),
+		pomodoroService:     services.NewPomodoroService(db),
+		dictionaryService:   services.NewDictionaryService(db),
+		organizationService: services.NewOrganizationService(db),
+		contributionService: services.NewContributionService(db, pointsService, nil),
+	}, db
+}
+
+// callCampusTool 以指定用户调用工具，成功时将文本结果解析到 out
+func callCampusTool(t *testing.T, handler campusToolHandler, userID uint, args map[string]any, out any) error {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := handler(mcpUserContext(userID), req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("unexpected content %T", result.Content[0])
+	}
+	if err := json.Unmarshal([]byte(text.Text), out); err != nil {
+		t.Fatalf("decode result %q: %v", text.Text, err)
+	}
+	return nil
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, values ...any) {
+	t.Helper()
+	for _, value := range values {
+		if err := db.Create(value).Error; err != nil {
+			t.Fatalf("create %T: %v", value, err)
+		}
+	}
+}
+
+func TestMCPCampusToolsRequirePermission(t *testing.T) {
+	th, _ := newCampusToolHandlers(t)
+	tests := []struct {
+		name    string
+		handler campusToolHandler
+		args    map[string]any
+	}{
+		{name: "materials", handler: th.handleMaterials, args: map[string]any{"action": "search", "keywords": "高数"}},
+		{name: "questionBank", handler: th.handleQuestionBank, args: map[string]any{"action": "projects"}},
+		{name: "points", handler: th.handlePoints, args: map[string]any{"action": "balance"}},
+		{name: "gpaBackup", handler: th.handleGPABackup, args: map[string]any{"action": "list"}},
+		{name: "pomodoro", handler: th.handlePomodoro, args: map[string]any{"action": "count"}},
+		{name: "dictionary", handler: th.handleDictionary, args: map[string]any{"action": "lookup", "word": "apple"}},
+		{name: "organizations", handler: th.handleOrganizations, args: map[string]any{"action": "list"}},
+		{name: "contributions", handler: th.handleContributions, args: map[string]any{"action": "stats"}},
+		{name: "failRateRanking", handler: th.handleFailRateRanking, args: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := callCampusTool(t, tt.handler, 2, tt.args, nil)
+			if err == nil || !strings.Contains(err.Error(), "权限不足") {
+				t.Fatalf("expected permission error, got %v", err)
+			}
+			if err := callCampusTool(t, tt.handler, 1, map[string]any{"action": "unknown"}, nil); err == nil && tt.name != "failRateRanking" {
+				t.Fatal("unknown action should be rejected")
+			}
+		})
+	}
+}
+
+func TestMCPMaterialsTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.MaterialCategory{ID: 1, Name: "数学"},
+		&models.Material{ID: 1, MD5: "md5-a", FileName: "高数期末.pdf", FileSize: 1024, CategoryID: 1},
+		&models.MaterialDesc{MD5: "md5-a", Tags: "高数", TotalHotness: 10},
+	)
+
+	if err := callCampusTool(t, th.handleMaterials, 1, map[string]any{"action": "search"}, nil); err == nil {
+		t.Fatal("search without keywords should be rejected")
+	}
+	var search struct {
+		Materials []struct {
+			MD5 string `json:"md5"`
+		} `json:"materials"`
+		PageSize int `json:"page_size"`
+	}
+	if err := callCampusTool(t, th.handleMaterials, 1, map[string]any{"action": "search", "keywords": "高数", "size": 1000}, &search); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(search.Materials) != 1 || search.Materials[0].MD5 != "md5-a" || search.PageSize != 20 {
+		t.Fatalf("search result = %+v", search)
+	}
+
+	var detail struct {
+		FileName string `json:"file_name"`
+	}
+	if err := callCampusTool(t, th.handleMaterials, 1, map[string]any{"action": "detail", "md5": "md5-a"}, &detail); err != nil {
+		t.Fatalf("detail: %v", err)
+	}
+	if detail.FileName != "高数期末.pdf" {
+		t.Fatalf("detail = %+v", detail)
+	}
+
+	if err := callCampusTool(t, th.handleMaterials, 1, map[string]any{"action": "rate", "md5": "md5-a", "rating": 6}, nil); err == nil {
+		t.Fatal("rating out of range should be rejected")
+	}
+	if err := callCampusTool(t, th.handleMaterials, 1, map[string]any{"action": "rate", "md5": "md5-a", "rating": 5}, nil); err != nil {
+		t.Fatalf("rate: %v", err)
+	}
+
+	var logs int64
+	if err := db.Model(&models.MaterialLog{}).Where("user_id = ?", 1).Count(&logs).Error; err != nil || logs != 3 {
+		t.Fatalf("material logs = %d, %v", logs, err)
+	}
+}
+
+func TestMCPQuestionBankTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.QuestionProject{ID: 1, Name: "马原", IsActive: true},
+		&models.Question{ID: 1, ProjectID: 1, Type: models.QuestionTypeEssay, Title: "什么是物质", Answer: "客观实在", Sort: 1, IsActive: true},
+		&models.Question{ID: 2, ProjectID: 1, Type: models.QuestionTypeEssay, Title: "什么是意识", Answer: "人脑的机能", Sort: 2, IsActive: true},
+	)
+
+	var projects []struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "projects"}, &projects); err != nil {
+		t.Fatalf("projects: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Name != "马原" {
+		t.Fatalf("projects = %+v", projects)
+	}
+
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "list"}, nil); err == nil {
+		t.Fatal("list without project_id should be rejected")
+	}
+	var list struct {
+		QuestionIDs []uint `json:"question_ids"`
+	}
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "list", "project_id": 1}, &list); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.QuestionIDs) != 2 || list.QuestionIDs[0] != 1 {
+		t.Fatalf("question ids = %v", list.QuestionIDs)
+	}
+
+	var question struct {
+		Title  string `json:"title"`
+		Answer string `json:"answer"`
+	}
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "get", "question_id": 2}, &question); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if question.Title != "什么是意识" || question.Answer != "人脑的机能" {
+		t.Fatalf("question = %+v", question)
+	}
+
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "practice", "question_id": 1}, nil); err != nil {
+		t.Fatalf("practice: %v", err)
+	}
+	if err := callCampusTool(t, th.handleQuestionBank, 1, map[string]any{"action": "practice", "question_id": 99}, nil); err == nil {
+		t.Fatal("practice on a missing question should fail")
+	}
+}
+
+func TestMCPPointsTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.PointsTransaction{UserID: 1, Type: models.PointsTransactionTypeEarn, Source: models.PointsTransactionSourceDailyLogin, Points: 40},
+		&models.PointsTransaction{UserID: 1, Type: models.PointsTransactionTypeSpend, Source: models.PointsTransactionSourceRedeem, Points: 10},
+		&models.PointsTransaction{UserID: 2, Type: models.PointsTransactionTypeEarn, Source: models.PointsTransactionSourceDailyLogin, Points: 5},
+	)
+
+	var balance struct {
+		Points uint `json:"points"`
+	}
+	if err := callCampusTool(t, th.handlePoints, 1, map[string]any{"action": "balance"}, &balance); err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if balance.Points != 30 {
+		t.Fatalf("balance = %+v", balance)
+	}
+
+	var page struct {
+		Data  []map[string]any `json:"data"`
+		Total int64            `json:"total"`
+		Size  int              `json:"size"`
+	}
+	if err := callCampusTool(t, th.handlePoints, 1, map[string]any{"action": "transactions", "size": 1000}, &page); err != nil {
+		t.Fatalf("transactions: %v", err)
+	}
+	if page.Total != 2 || len(page.Data) != 2 || page.Size != 20 {
+		t.Fatalf("transactions = %+v", page)
+	}
+	if err := callCampusTool(t, th.handlePoints, 1, map[string]any{"action": "transactions", "type": 2}, &page); err != nil {
+		t.Fatalf("transactions by type: %v", err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("spend transactions = %+v", page)
+	}
+
+	var stats map[string]any
+	if err := callCampusTool(t, th.handlePoints, 1, map[string]any{"action": "stats"}, &stats); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats) == 0 {
+		t.Fatal("stats should not be empty")
+	}
+}
+
+func TestMCPGPABackupTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.GPABackup{ID: 1, UserID: 1, Title: "大一上", Data: datatypes.JSON(`{"gpa":3.5}`)},
+		&models.GPABackup{ID: 2, UserID: 2, Title: "别人的备份", Data: datatypes.JSON(`{}`)},
+	)
+
+	var list []struct {
+		ID    uint   `json:"id"`
+		Title string `json:"title"`
+	}
+	if err := callCampusTool(t, th.handleGPABackup, 1, map[string]any{"action": "list"}, &list); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != 1 {
+		t.Fatalf("list = %+v", list)
+	}
+
+	var backup struct {
+		Title string `json:"title"`
+	}
+	if err := callCampusTool(t, th.handleGPABackup, 1, map[string]any{"action": "get", "id": 1}, &backup); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if backup.Title != "大一上" {
+		t.Fatalf("backup = %+v", backup)
+	}
+	if err := callCampusTool(t, th.handleGPABackup, 1, map[string]any{"action": "get", "id": 2}, nil); err == nil {
+		t.Fatal("another user's backup should not be readable")
+	}
+	if err := callCampusTool(t, th.handleGPABackup, 1, map[string]any{"action": "delete"}, nil); err == nil {
+		t.Fatal("delete without id should be rejected")
+	}
+	if err := callCampusTool(t, th.handleGPABackup, 1, map[string]any{"action": "delete", "id": 1}, nil); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	var remaining int64
+	if err := db.Model(&models.GPABackup{}).Count(&remaining).Error; err != nil || remaining != 1 {
+		t.Fatalf("remaining backups = %d, %v", remaining, err)
+	}
+}
+
+func TestMCPPomodoroTool(t *testing.T) {
+	th, _ := newCampusToolHandlers(t)
+
+	var count struct {
+		Count uint `json:"pomodoro_count"`
+	}
+	if err := callCampusTool(t, th.handlePomodoro, 1, map[string]any{"action": "count"}, &count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count.Count != 5 {
+		t.Fatalf("count = %d", count.Count)
+	}
+
+	var ranking []struct {
+		PomodoroCount uint `json:"pomodoro_count"`
+	}
+	if err := callCampusTool(t, th.handlePomodoro, 1, map[string]any{"action": "ranking"}, &ranking); err != nil {
+		t.Fatalf("ranking: %v", err)
+	}
+	if len(ranking) != 2 || ranking[0].PomodoroCount != 8 {
+		t.Fatalf("ranking = %+v", ranking)
+	}
+}
+
+func TestMCPDictionaryTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db, &models.Dictionary{Word: "apple", Trans: datatypes.JSON(`["苹果"]`)})
+
+	if err := callCampusTool(t, th.handleDictionary, 1, map[string]any{"action": "lookup"}, nil); err == nil {
+		t.Fatal("lookup without word should be rejected")
+	}
+	var entry struct {
+		Word string `json:"word"`
+	}
+	if err := callCampusTool(t, th.handleDictionary, 1, map[string]any{"action": "lookup", "word": "apple"}, &entry); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if entry.Word != "apple" {
+		t.Fatalf("entry = %+v", entry)
+	}
+}
+
+func TestMCPOrganizationsTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.Organization{ID: 1, Name: "计算机协会", OrganizationType: "社团", Affiliation: "信息学院", Campus: "本部"},
+		&models.Organization{ID: 2, Name: "摄影协会", OrganizationType: "社团", Affiliation: "校团委", Campus: "黄金"},
+	)
+
+	var page struct {
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+		Total int64 `json:"total"`
+		Size  int   `json:"size"`
+	}
+	if err := callCampusTool(t, th.handleOrganizations, 1, map[string]any{"action": "list", "campus": "本部", "size": 1000}, &page); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 1 || page.Data[0].Name != "计算机协会" || page.Size != 10 {
+		t.Fatalf("list = %+v", page)
+	}
+
+	if err := callCampusTool(t, th.handleOrganizations, 1, map[string]any{"action": "get"}, nil); err == nil {
+		t.Fatal("get without id should be rejected")
+	}
+	var org struct {
+		Name string `json:"name"`
+	}
+	if err := callCampusTool(t, th.handleOrganizations, 1, map[string]any{"action": "get", "id": 2}, &org); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if org.Name != "摄影协会" {
+		t.Fatalf("org = %+v", org)
+	}
+}
+
+func TestMCPContributionsTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.UserContribution{ID: 1, UserID: 1, Title: "图书馆开放时间", Categories: datatypes.JSON(`[1]`), Status: models.UserContributionStatusPending},
+		&models.UserContribution{ID: 2, UserID: 1, Title: "食堂新窗口", Categories: datatypes.JSON(`[1]`), Status: models.UserContributionStatusApproved},
+		&models.UserContribution{ID: 3, UserID: 2, Title: "别人的投稿", Categories: datatypes.JSON(`[1]`), Status: models.UserContributionStatusPending},
+	)
+
+	var page struct {
+		Total int64 `json:"total"`
+		Size  int   `json:"size"`
+	}
+	if err := callCampusTool(t, th.handleContributions, 1, map[string]any{"action": "list", "size": 1000}, &page); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 2 || page.Size != 20 {
+		t.Fatalf("list = %+v", page)
+	}
+	if err := callCampusTool(t, th.handleContributions, 1, map[string]any{"action": "list", "status": 2}, &page); err != nil {
+		t.Fatalf("list by status: %v", err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("approved contributions = %+v", page)
+	}
+
+	var contribution struct {
+		Title string `json:"title"`
+	}
+	if err := callCampusTool(t, th.handleContributions, 1, map[string]any{"action": "get", "id": 2}, &contribution); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if contribution.Title != "食堂新窗口" {
+		t.Fatalf("contribution = %+v", contribution)
+	}
+	if err := callCampusTool(t, th.handleContributions, 1, map[string]any{"action": "get", "id": 3}, nil); err == nil {
+		t.Fatal("another user's contribution should not be readable")
+	}
+
+	var stats map[string]any
+	if err := callCampusTool(t, th.handleContributions, 1, map[string]any{"action": "stats"}, &stats); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats) == 0 {
+		t.Fatal("stats should not be empty")
+	}
+}
+
+func TestMCPFailRateRankingTool(t *testing.T) {
+	th, db := newCampusToolHandlers(t)
+	mustCreate(t, db,
+		&models.FailRate{CourseName: "高等数学A", Department: "理学院", Semester: "2024-2025-1", FailRate: 20.5},
+		&models.FailRate{CourseName: "高等数学B", Department: "理学院", Semester: "2024-2025-1", FailRate: 35.0},
+		&models.FailRate{CourseName: "大学英语", Department: "外语学院", Semester: "2024-2025-1", FailRate: 50.0},
+	)
+
+	var page struct {
+		Data []struct {
+			CourseName string  `json:"course_name"`
+			FailRate   float64 `json:"failrate"`
+		} `json:"data"`
+		Total int64 `json:"total"`
+		Size  int   `json:"size"`
+	}
+	if err := callCampusTool(t, th.handleFailRateRanking, 1, map[string]any{"keyword": "高等数学", "size": 1000}, &page); err != nil {
+		t.Fatalf("ranking: %v", err)
+	}
+	if page.Size != 10 {
+		t.Fatalf("size should be capped, got %d", page.Size)
+	}
+	if page.Total != 2 || page.Data[0].CourseName != "高等数学B" || page.Data[1].CourseName != "高等数学A" {
+		t.Fatalf("ranking = %+v", page)
+	}
+
+	if err := callCampusTool(t, th.handleFailRateRanking, 1, map[string]any{"size": 1}, &page); err != nil {
+		t.Fatalf("ranking: %v", err)
+	}
+	if page.Total != 3 || len(page.Data) != 1 || page.Data[0].CourseName != "大学英语" {
+		t.Fatalf("ranking = %+v", page)
+	}
+}
@@ -19,8 +19,9 @@ import (
 
 // MCPHandler handles MCP protocol requests for LLM tool calling
 type MCPHandler struct {
-	mcpServer  *server.MCPServer
-	httpServer *server.StreamableHTTPServer
+	mcpServer     *server.MCPServer
+	httpServer    *server.StreamableHTTPServer
+	subscriptions *mcpResourceSubscriptions
 }
 
 // mcpToolHandlers holds all services and provides tool handler methods
@@ -34,6 +35,19 @@ type mcpToolHandlers struct {
 	failRateService     *services.FailRateService
 	countdownService    *services.CountdownService
 	studyTaskService    *services.StudyTaskService
+	materialService     *services.MaterialService
+	questionService     *services.QuestionService
+	pointsService       *services.PointsService
+	gpaBackupService    *services.GPABackupService
+	pomodoroService     *services.PomodoroService
+	dictionaryService   *services.DictionaryService
+	organizationService *services.OrganizationService
+	contributionService *services.ContributionService
+	statService         *services.StatService
+	auditService        *services.MCPAuditService
+
+	// toolPermissions 注册时声明的工具权限，见 addTool
+	toolPermissions map[string][]string
 }
 
 // NewMCPHandler creates a new MCP handler with GoJxust service tools
@@ -47,6 +61,15 @@ func NewMCPHandler(
 	failRateService *services.FailRateService,
 	countdownService *services.CountdownService,
 	studyTaskService *services.StudyTaskService,
+	materialService *services.MaterialService,
+	questionService *services.QuestionService,
+	pointsService *services.PointsService,
+	gpaBackupService *services.GPABackupService,
+	pomodoroService *services.PomodoroService,
+	dictionaryService *services.DictionaryService,
+	organizationService *services.OrganizationService,
+	contributionService *services.ContributionService,
+	auditService *services.MCPAuditService,
 ) *MCPHandler {
 	// Create tool handlers with services
 	th := &mcpToolHandlers{
@@ -59,20 +82,41 @@ func NewMCPHandler(
 		failRateService:     failRateService,
 		countdownService:    countdownService,
 		studyTaskService:    studyTaskService,
+		materialService:     materialService,
+		questionService:     questionService,
+		pointsService:       pointsService,
+		gpaBackupService:    gpaBackupService,
+		pomodoroService:     pomodoroService,
+		dictionaryService:   dictionaryService,
+		organizationService: organizationService,
+		contributionService: contributionService,
+		auditService:        auditService,
 	}
 
+	subscriptions := newMCPResourceSubscriptions(th)
+
 	// Create MCP server with GoJxust implementation info
-	mcpServer := server.NewMCPServer("gojxust-mcp-server", "0.1.0")
+	mcpServer := server.NewMCPServer("gojxust-mcp-server", "0.1.0",
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+		server.WithHooks(subscriptions.hooks()),
+		server.WithToolFilter(th.filterTools),
+		server.WithToolHandlerMiddleware(th.auditToolCall),
+	)
+	subscriptions.mcpServer = mcpServer
 
-	// Register all tools
+	// Register all tools, resources and prompts
 	th.registerTools(mcpServer)
+	th.registerResources(mcpServer)
+	th.registerPrompts(mcpServer)
 
 	// Create streamable HTTP handler
 	httpServer := server.NewStreamableHTTPServer(mcpServer)
 
 	return &MCPHandler{
-		mcpServer:  mcpServer,
-		httpServer: httpServer,
+		mcpServer:     mcpServer,
+		httpServer:    httpServer,
+		subscriptions: subscriptions,
 	}
 }
 
@@ -88,6 +132,11 @@ func (h *MCPHandler) Handle(c *gin.Context) {
 	// Inject user info into request context for MCP tool handlers
 	ctx := context.WithValue(c.Request.Context(), mcpContextKey(constant.MCPUserIDKey), userID)
 
+	// Resource subscriptions are handled here since mcp-go does not implement them
+	if h.subscriptions.handleRequest(c, ctx) {
+		return
+	}
+
 	h.httpServer.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
 }
 
@@ -117,7 +166,7 @@ func (th *mcpToolHandlers) requirePermission(ctx context.Context, permission str
 
 // registerTools registers all MCP tools
 func (th *mcpToolHandlers) registerTools(s *server.MCPServer) {
-	s.AddTool(
+	th.addPublicTool(s,
 		mcp.NewTool("listHeroes",
 			mcp.WithDescription("列出英雄榜（列出所有为项目贡献代码、贡献学习资料的贡献者） - 获取所有显示的英雄名单"),
 			mcp.WithInputSchema[ListHeroesParams](),
@@ -125,69 +174,79 @@ func (th *mcpToolHandlers) registerTools(s *server.MCPServer) {
 		th.handleListHeroes,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("notifications",
 			mcp.WithDescription("通知管理 - 获取通知列表或获取单个通知详情"),
 			mcp.WithInputSchema[NotificationsParams](),
 		),
 		th.handleNotifications,
+		constant.PermissionNotificationGet,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("userProfile",
 			mcp.WithDescription("用户信息管理 - 获取或更新用户信息（昵称、真名、学院、专业、班级）"),
 			mcp.WithInputSchema[UserProfileParams](),
 		),
 		th.handleUserProfile,
+		constant.PermissionUserGet, constant.PermissionUserUpdate,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("teacherReview",
 			mcp.WithDescription("教师评价 - 创建教师评价或获取教师评价列表"),
 			mcp.WithInputSchema[TeacherReviewParams](),
 		),
 		th.handleTeacherReview,
+		constant.PermissionReviewCreate,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("getCourseTable",
 			mcp.WithDescription("获取用户课程表"),
 			mcp.WithInputSchema[GetCourseTableParams](),
 		),
 		th.handleGetCourseTable,
+		constant.PermissionCourseTableGet,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("editCourseCell",
 			mcp.WithDescription("编辑个人课程表中的单个格子"),
 			mcp.WithInputSchema[EditCourseCellParams](),
 		),
 		th.handleEditCourseCell,
+		constant.PermissionCourseTableUpdate,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("queryFailRate",
 			mcp.WithDescription("查询挂科率 - 如果不指定课程名则随机返回10条挂科率数据"),
 			mcp.WithInputSchema[QueryFailRateParams](),
 		),
 		th.handleQueryFailRate,
+		constant.PermissionFailRate,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("countdown",
 			mcp.WithDescription("倒数日管理 - 创建、获取、更新、删除倒数日"),
 			mcp.WithInputSchema[CountdownParams](),
 		),
 		th.handleCountdown,
+		constant.PermissionCountdown,
 	)
 
-	s.AddTool(
+	th.addTool(s,
 		mcp.NewTool("studyTask",
 			mcp.WithDescription("学习清单管理 - 创建、获取、更新、删除、统计学习任务"),
 			mcp.WithInputSchema[StudyTaskParams](),
 		),
 		th.handleStudyTask,
+		constant.PermissionStudyTask,
 	)
+
+	th.registerCampusTools(s)
 }
 
 // ============== Tool Parameter Structs ==============
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+
+	"github.com/bytedance/sonic"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerPrompts registers all MCP prompt templates
+func (th *mcpToolHandlers) registerPrompts(s *server.MCPServer) {
+	s.AddPrompt(
+		mcp.NewPrompt("plan_exam_week",
+			mcp.WithPromptDescription("规划考试周 - 结合指定周课表、即将到来的倒数日和待完成学习任务制定复习计划"),
+			mcp.WithArgument("semester", mcp.ArgumentDescription("学期，如2024-2025-1"), mcp.RequiredArgument()),
+			mcp.WithArgument("week", mcp.ArgumentDescription("考试所在教学周序号"), mcp.RequiredArgument()),
+		),
+		th.handlePlanExamWeek,
+	)
+
+	s.AddPrompt(
+		mcp.NewPrompt("summarize_today_notices",
+			mcp.WithPromptDescription("总结今日通知 - 汇总今天发布的通知和当前置顶通知"),
+		),
+		th.handleSummarizeTodayNotices,
+	)
+}
+
+func (th *mcpToolHandlers) handlePlanExamWeek(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	semester := strings.TrimSpace(req.Params.Arguments["semester"])
+	week, err := strconv.Atoi(strings.TrimSpace(req.Params.Arguments["week"]))
+	if semester == "" || err != nil || week <= 0 {
+		return nil, fmt.Errorf("请提供学期和有效的教学周序号")
+	}
+
+	messages := []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(fmt.Sprintf(
+			"我在 %s 学期第 %d 周考试。请根据下面的课表、倒数日和待完成学习任务，按天为我制定这一周的复习计划："+
+				"避开已有课程的时间段，优先安排临近的考试和高优先级任务，并指出时间冲突或来不及完成的事项。",
+			semester, week,
+		))),
+	}
+	uris := []string{
+		fmt.Sprintf("%s%s/week/%d", mcpCourseTableURIPrefix, semester, week),
+		mcpUpcomingCountdownsURI,
+		mcpOpenStudyTasksURI,
+	}
+	messages = append(messages, th.embedResources(ctx, uris)...)
+	return mcp.NewGetPromptResult("考试周复习计划", messages), nil
+}
+
+func (th *mcpToolHandlers) handleSummarizeTodayNotices(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	if err := th.requirePermission(ctx, constant.PermissionNotificationGet); err != nil {
+		return nil, err
+	}
+
+	page, err := th.notificationService.GetNotifications(ctx, &request.GetNotificationsRequest{Page: 1, Size: mcpResourceListMaxSize})
+	if err != nil {
+		return nil, fmt.Errorf("获取通知列表失败: %w", err)
+	}
+	notifications, _ := page.Data.([]response.NotificationSimpleResponse)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayNotices := make([]response.NotificationSimpleResponse, 0)
+	for _, n := range notifications {
+		if n.PublishedAt != nil && !n.PublishedAt.Before(today) {
+			todayNotices = append(todayNotices, n)
+		}
+	}
+	data, _ := sonic.Marshal(map[string]any{"date": today.Format("2006-01-02"), "data": todayNotices})
+
+	messages := []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(
+			"请总结今天发布的通知，按与我相关的紧急程度排序，列出每条通知的要点、截止时间和需要我采取的行动；"+
+				"置顶通知中仍未过期的事项也请一并提醒。今天没有新通知时直接说明。",
+		)),
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(string(data))),
+	}
+	messages = append(messages, th.embedResources(ctx, []string{mcpPinnedNotificationsURI})...)
+	return mcp.NewGetPromptResult("今日通知摘要", messages), nil
+}
+
+// embedResources 将资源作为嵌入资源附加到提示词，无权限或读取失败的资源跳过
+func (th *mcpToolHandlers) embedResources(ctx context.Context, uris []string) []mcp.PromptMessage {
+	messages := make([]mcp.PromptMessage, 0, len(uris))
+	for _, uri := range uris {
+		text, err := th.readResource(ctx, uri)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(
+			mcp.TextResourceContents{URI: uri, MIMEType: mcpResourceMIMEType, Text: text},
+		)))
+	}
+	return messages
+}
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCP 资源 URI
+const (
+	mcpCourseTableURIPrefix     = "yqlx://course-table/"
+	mcpCourseTableURITemplate   = "yqlx://course-table/{semester}/week/{week}"
+	mcpPinnedNotificationsURI   = "yqlx://notifications/pinned"
+	mcpUpcomingCountdownsURI    = "yqlx://countdowns/upcoming"
+	mcpOpenStudyTasksURI        = "yqlx://study-tasks/open"
+	mcpResourceListMaxSize      = 100
+	mcpResourceMIMEType         = "application/json"
+	mcpMethodResourcesSubscribe = "resources/subscribe"
+	mcpMethodResourcesUnsub     = "resources/unsubscribe"
+)
+
+// registerResources registers all MCP resources
+func (th *mcpToolHandlers) registerResources(s *server.MCPServer) {
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(mcpCourseTableURITemplate, "课程表（按周）",
+			mcp.WithTemplateDescription("当前用户指定学期、指定教学周的课程表，semester 如 2024-2025-1，week 为教学周序号"),
+			mcp.WithTemplateMIMEType(mcpResourceMIMEType),
+		),
+		th.handleReadResource,
+	)
+
+	s.AddResource(
+		mcp.NewResource(mcpPinnedNotificationsURI, "置顶通知",
+			mcp.WithResourceDescription("当前置顶的已发布通知"),
+			mcp.WithMIMEType(mcpResourceMIMEType),
+		),
+		th.handleReadResource,
+	)
+
+	s.AddResource(
+		mcp.NewResource(mcpUpcomingCountdownsURI, "即将到来的倒数日",
+			mcp.WithResourceDescription("当前用户今天及以后的倒数日，按目标日期升序"),
+			mcp.WithMIMEType(mcpResourceMIMEType),
+		),
+		th.handleReadResource,
+	)
+
+	s.AddResource(
+		mcp.NewResource(mcpOpenStudyTasksURI, "待完成学习任务",
+			mcp.WithResourceDescription("当前用户待完成的学习任务，按优先级和截止日期排序"),
+			mcp.WithMIMEType(mcpResourceMIMEType),
+		),
+		th.handleReadResource,
+	)
+}
+
+func (th *mcpToolHandlers) handleReadResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	text, err := th.readResource(ctx, req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, MIMEType: mcpResourceMIMEType, Text: text}}, nil
+}
+
+// readResource 读取当前用户的资源内容，资源读取、订阅变更检测和提示词模板共用
+func (th *mcpToolHandlers) readResource(ctx context.Context, uri string) (string, error) {
+	userID := getUserFromContext(ctx)
+	if userID == 0 {
+		return "", fmt.Errorf("用户未认证")
+	}
+
+	var result any
+	switch uri {
+	case mcpPinnedNotificationsURI:
+		if err := th.requirePermission(ctx, constant.PermissionNotificationGet); err != nil {
+			return "", err
+		}
+		pinned, err := th.pinnedNotifications(ctx)
+		if err != nil {
+			return "", err
+		}
+		result = map[string]any{"data": pinned}
+	case mcpUpcomingCountdownsURI:
+		if err := th.requirePermission(ctx, constant.PermissionCountdown); err != nil {
+			return "", err
+		}
+		countdowns, err := th.countdownService.GetCountdowns(ctx, userID)
+		if err != nil {
+			return "", fmt.Errorf("获取倒数日列表失败: %w", err)
+		}
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		upcoming := make([]response.CountdownResponse, 0, len(countdowns))
+		for _, countdown := range countdowns {
+			if !countdown.TargetDate.Before(today) {
+				upcoming = append(upcoming, countdown)
+			}
+		}
+		slices.SortFunc(upcoming, func(a, b response.CountdownResponse) int {
+			return a.TargetDate.Compare(b.TargetDate)
+		})
+		result = map[string]any{"data": upcoming}
+	case mcpOpenStudyTasksURI:
+		if err := th.requirePermission(ctx, constant.PermissionStudyTask); err != nil {
+			return "", err
+		}
+		status := uint8(constant.StudyTaskStatusPending)
+		tasks, err := th.studyTaskService.GetStudyTasks(ctx, userID, &request.GetStudyTasksRequest{Page: 1, Size: mcpResourceListMaxSize, Status: &status})
+		if err != nil {
+			return "", fmt.Errorf("获取学习任务列表失败: %w", err)
+		}
+		result = map[string]any{"data": tasks.Data, "total": tasks.Total}
+	default:
+		semester, week, ok := parseCourseTableURI(uri)
+		if !ok {
+			return "", fmt.Errorf("未知的资源: %s", uri)
+		}
+		if err := th.requirePermission(ctx, constant.PermissionCourseTableGet); err != nil {
+			return "", err
+		}
+		table, err := th.courseTableService.GetUserCourseTableForWeek(ctx, userID, semester, week)
+		if err != nil {
+			return "", fmt.Errorf("获取课程表失败: %w", err)
+		}
+		result = map[string]any{"class_id": table.ClassID, "semester": table.Semester, "week": week, "course_data": table.CourseData}
+	}
+
+	data, err := sonic.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("序列化资源失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// pinnedNotification 置顶通知资源只保留稳定字段，避免浏览量变化触发订阅更新
+type pinnedNotification struct {
+	ID          uint                                    `json:"id"`
+	Title       string                                  `json:"title"`
+	Categories  []response.NotificationCategoryResponse `json:"categories"`
+	Schedule    *models.ScheduleData                    `json:"schedule,omitempty"`
+	PinnedAt    *time.Time                              `json:"pinned_at"`
+	PublishedAt *time.Time                              `json:"published_at"`
+}
+
+func (th *mcpToolHandlers) pinnedNotifications(ctx context.Context) ([]pinnedNotification, error) {
+	// 通知列表按置顶优先排序，首页即可拿到全部置顶通知
+	page, err := th.notificationService.GetNotifications(ctx, &request.GetNotificationsRequest{Page: 1, Size: mcpResourceListMaxSize})
+	if err != nil {
+		return nil, fmt.Errorf("获取通知列表失败: %w", err)
+	}
+	notifications, _ := page.Data.([]response.NotificationSimpleResponse)
+	pinned := make([]pinnedNotification, 0)
+	for _, n := range notifications {
+		if !n.IsPinned {
+			continue
+		}
+		pinned = append(pinned, pinnedNotification{
+			ID:          n.ID,
+			Title:       n.Title,
+			Categories:  n.Categories,
+			Schedule:    n.Schedule,
+			PinnedAt:    n.PinnedAt,
+			PublishedAt: n.PublishedAt,
+		})
+	}
+	return pinned, nil
+}
+
+// parseCourseTableURI 解析 yqlx://course-table/{semester}/week/{week}
+func parseCourseTableURI(uri string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(uri, mcpCourseTableURIPrefix)
+	if !ok {
+		return "", 0, false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] != "week" {
+		return "", 0, false
+	}
+	week, err := strconv.Atoi(parts[2])
+	if err != nil || week <= 0 {
+		return "", 0, false
+	}
+	return parts[0], week, true
+}
+
+// mcpResourceSubscriptions 管理会话的资源订阅，定期检测资源内容变化并推送 notifications/resources/updated
+type mcpResourceSubscriptions struct {
+	th        *mcpToolHandlers
+	mcpServer *server.MCPServer
+
+	mu       sync.Mutex
+	owners   map[string]uint              // sessionID -> userID
+	sessions map[string]map[string]string // sessionID -> uri -> 内容摘要
+	polling  bool
+	// scheduled 已安排、尚未开始的按用户检测，同一用户最多一个
+	scheduled    map[uint]bool
+	refreshDelay time.Duration
+}
+
+func newMCPResourceSubscriptions(th *mcpToolHandlers) *mcpResourceSubscriptions {
+	return &mcpResourceSubscriptions{
+		th:           th,
+		owners:       make(map[string]uint),
+		sessions:     make(map[string]map[string]string),
+		scheduled:    make(map[uint]bool),
+		refreshDelay: constant.MCPResourceRefreshDelay,
+	}
+}
+
+// hooks 绑定会话所属用户、清理断开会话的订阅，并在工具调用后立即检测该用户订阅的资源
+func (r *mcpResourceSubscriptions) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		if userID := getUserFromContext(ctx); userID != 0 {
+			r.mu.Lock()
+			r.owners[session.SessionID()] = userID
+			r.mu.Unlock()
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		r.removeSession(session.SessionID())
+	})
+	hooks.AddAfterCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest, result any) {
+		if userID := getUserFromContext(ctx); userID != 0 {
+			r.scheduleRefresh(userID)
+		}
+	})
+	return hooks
+}
+
+// scheduleRefresh 延迟 refreshDelay 后检测用户订阅的资源。已有尚未开始的检测时直接复用，
+// 连续的工具调用只触发一次读取；用户没有订阅时不做任何事
+func (r *mcpResourceSubscriptions) scheduleRefresh(userID uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.scheduled[userID] || !r.hasSubscriptions(userID) {
+		return
+	}
+	r.scheduled[userID] = true
+	time.AfterFunc(r.refreshDelay, func() {
+		r.mu.Lock()
+		delete(r.scheduled, userID)
+		r.mu.Unlock()
+		r.refresh(func(owner uint) bool { return owner == userID })
+	})
+}
+
+// hasSubscriptions 调用方需持有 r.mu
+func (r *mcpResourceSubscriptions) hasSubscriptions(userID uint) bool {
+	for sessionID, uris := range r.sessions {
+		if len(uris) > 0 && r.owners[sessionID] == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleRequest 处理 resources/subscribe 与 resources/unsubscribe，mcp-go 未实现这两个方法。
+// 其他请求原样交给 MCP server，返回 false。
+func (r *mcpResourceSubscriptions) handleRequest(c *gin.Context, ctx context.Context) bool {
+	if c.Request.Method != http.MethodPost {
+		return false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, constant.MCPRequestMaxBodyBytes))
+	if err != nil {
+		status, msg := http.StatusBadRequest, "读取请求体失败"
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			status, msg = http.StatusRequestEntityTooLarge, "请求体过大"
+		}
+		c.JSON(status, mcp.NewJSONRPCError(mcp.NewRequestId(nil), mcp.INVALID_REQUEST, msg, nil))
+		return true
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var message struct {
+		ID     mcp.RequestId `json:"id"`
+		Method string        `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := sonic.Unmarshal(body, &message); err != nil {
+		return false
+	}
+	if message.Method != mcpMethodResourcesSubscribe && message.Method != mcpMethodResourcesUnsub {
+		return false
+	}
+
+	sessionID := c.GetHeader(server.HeaderKeySessionID)
+	if message.Method == mcpMethodResourcesSubscribe {
+		err = r.subscribe(ctx, sessionID, message.Params.URI)
+	} else {
+		err = r.unsubscribe(ctx, sessionID, message.Params.URI)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, mcp.NewJSONRPCError(message.ID, mcp.INVALID_PARAMS, err.Error(), nil))
+		return true
+	}
+	c.JSON(http.StatusOK, mcp.NewJSONRPCResultResponse(message.ID, mcp.EmptyResult{}))
+	return true
+}
+
+func (r *mcpResourceSubscriptions) subscribe(ctx context.Context, sessionID, uri string) error {
+	if err := r.checkOwner(ctx, sessionID); err != nil {
+		return err
+	}
+	// 订阅前读取一次，校验资源存在和权限，同时记录初始内容
+	text, err := r.th.readResource(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[sessionID] == nil {
+		r.sessions[sessionID] = make(map[string]string)
+	}
+	r.sessions[sessionID][uri] = digestResource(text)
+	if !r.polling {
+		r.polling = true
+		go r.poll()
+	}
+	return nil
+}
+
+func (r *mcpResourceSubscriptions) unsubscribe(ctx context.Context, sessionID, uri string) error {
+	if err := r.checkOwner(ctx, sessionID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions[sessionID], uri)
+	if len(r.sessions[sessionID]) == 0 {
+		delete(r.sessions, sessionID)
+	}
+	return nil
+}
+
+// checkOwner 会话必须已初始化且属于当前用户
+func (r *mcpResourceSubscriptions) checkOwner(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("缺少 %s 请求头", server.HeaderKeySessionID)
+	}
+	r.mu.Lock()
+	owner, ok := r.owners[sessionID]
+	r.mu.Unlock()
+	if !ok || owner != getUserFromContext(ctx) {
+		return fmt.Errorf("无效的会话: %s", sessionID)
+	}
+	return nil
+}
+
+func (r *mcpResourceSubscriptions) removeSession(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.owners, sessionID)
+	delete(r.sessions, sessionID)
+}
+
+// poll 存在订阅时定期检测，订阅全部取消后退出，下次订阅时重新启动
+func (r *mcpResourceSubscriptions) poll() {
+	ticker := time.NewTicker(constant.MCPResourcePollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.mu.Lock()
+		if len(r.sessions) == 0 {
+			r.polling = false
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+		r.refresh(func(uint) bool { return true })
+	}
+}
+
+// refresh 重新读取匹配用户订阅的资源，内容变化时通知对应会话
+func (r *mcpResourceSubscriptions) refresh(match func(userID uint) bool) {
+	type subscription struct {
+		sessionID string
+		userID    uint
+		uri       string
+		digest    string
+	}
+	r.mu.Lock()
+	var pending []subscription
+	for sessionID, uris := range r.sessions {
+		userID := r.owners[sessionID]
+		if !match(userID) {
+			continue
+		}
+		for uri, digest := range uris {
+			pending = append(pending, subscription{sessionID: sessionID, userID: userID, uri: uri, digest: digest})
+		}
+	}
+	r.mu.Unlock()
+
+	for _, sub := range pending {
+		ctx := context.WithValue(context.Background(), mcpContextKey(constant.MCPUserIDKey), sub.userID)
+		text, err := r.th.readResource(ctx, sub.uri)
+		if err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":  "mcp_resource_refresh",
+				"message": "读取已订阅的 MCP 资源失败",
+				"uri":     sub.uri,
+				"error":   err.Error(),
+			})
+			continue
+		}
+		digest := digestResource(text)
+		if digest == sub.digest {
+			continue
+		}
+
+		r.mu.Lock()
+		uris, ok := r.sessions[sub.sessionID]
+		if ok {
+			_, ok = uris[sub.uri]
+		}
+		if ok {
+			uris[sub.uri] = digest
+		}
+		r.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		err = r.mcpServer.SendNotificationToSpecificClient(sub.sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": sub.uri})
+		if errors.Is(err, server.ErrSessionNotFound) {
+			r.removeSession(sub.sessionID)
+		}
+	}
+}
+
+func digestResource(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
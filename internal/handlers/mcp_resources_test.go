@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
+)
+
+// fakeClientSession 已初始化的会话，通知写入带缓冲的通道
+type fakeClientSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func newFakeClientSession(id string) *fakeClientSession {
+	return &fakeClientSession{id: id, notifications: make(chan mcp.JSONRPCNotification, 10)}
+}
+
+func (f *fakeClientSession) Initialize()       {}
+func (f *fakeClientSession) Initialized() bool { return true }
+func (f *fakeClientSession) SessionID() string { return f.id }
+func (f *fakeClientSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return f.notifications
+}
+
+// newTestResourceSubscriptions 用户 1、2 拥有倒数日权限，会话 s1 属于用户 1
+func newTestResourceSubscriptions(t *testing.T) (*mcpResourceSubscriptions, *fakeClientSession, *gorm.DB) {
+	t.Helper()
+	perms := services.UserPermissionSnapshot{PermissionTags: []string{constant.PermissionCountdown}}
+	rbac := newMCPTestRBAC(t, map[uint]services.UserPermissionSnapshot{1: perms, 2: perms})
+	db := newMCPTestDB(t, &models.Countdown{})
+	th := &mcpToolHandlers{rbacService: rbac, countdownService: services.NewCountdownService(db)}
+
+	subs := newMCPResourceSubscriptions(th)
+	subs.mcpServer = server.NewMCPServer("test", "0.0.1", server.WithHooks(subs.hooks()))
+	session := newFakeClientSession("s1")
+	if err := subs.mcpServer.RegisterSession(mcpUserContext(1), session); err != nil {
+		t.Fatalf("register session: %v", err)
+	}
+	return subs, session, db
+}
+
+func TestParseCourseTableURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		semester string
+		week     int
+		ok       bool
+	}{
+		{uri: "yqlx://course-table/2024-2025-1/week/3", semester: "2024-2025-1", week: 3, ok: true},
+		{uri: "yqlx://course-table/2024-2025-1/week/0"},
+		{uri: "yqlx://course-table/2024-2025-1/week/-1"},
+		{uri: "yqlx://course-table/2024-2025-1/week/x"},
+		{uri: "yqlx://course-table/2024-2025-1/day/3"},
+		{uri: "yqlx://course-table//week/3"},
+		{uri: "yqlx://course-table/2024-2025-1/week/3/extra"},
+		{uri: "yqlx://countdowns/upcoming"},
+	}
+	for _, tt := range tests {
+		semester, week, ok := parseCourseTableURI(tt.uri)
+		if semester != tt.semester || week != tt.week || ok != tt.ok {
+			t.Errorf("parseCourseTableURI(%q) = %q, %d, %v", tt.uri, semester, week, ok)
+		}
+	}
+}
+
+func TestMCPResourceSubscriptionOwnership(t *testing.T) {
+	subs, _, _ := newTestResourceSubscriptions(t)
+	owner, other := mcpUserContext(1), mcpUserContext(2)
+
+	if err := subs.subscribe(owner, "", mcpUpcomingCountdownsURI); err == nil {
+		t.Fatal("subscribe without session id should fail")
+	}
+	if err := subs.subscribe(other, "s1", mcpUpcomingCountdownsURI); err == nil {
+		t.Fatal("subscribe to another user's session should fail")
+	}
+	if err := subs.subscribe(owner, "unknown", mcpUpcomingCountdownsURI); err == nil {
+		t.Fatal("subscribe to an unknown session should fail")
+	}
+	if err := subs.subscribe(owner, "s1", "yqlx://unknown"); err == nil {
+		t.Fatal("subscribe to an unknown resource should fail")
+	}
+	if err := subs.subscribe(owner, "s1", mcpUpcomingCountdownsURI); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+
+	if err := subs.unsubscribe(other, "s1", mcpUpcomingCountdownsURI); err == nil {
+		t.Fatal("unsubscribe from another user's session should fail")
+	}
+	if len(subs.sessions["s1"]) != 1 {
+		t.Fatalf("subscription removed by another user: %v", subs.sessions)
+	}
+	if err := subs.unsubscribe(owner, "s1", mcpUpcomingCountdownsURI); err != nil {
+		t.Fatalf("unsubscribe() error = %v", err)
+	}
+	if len(subs.sessions) != 0 {
+		t.Fatalf("sessions after unsubscribe = %v", subs.sessions)
+	}
+}
+
+func TestMCPResourceRefreshNotifiesOnDigestChange(t *testing.T) {
+	subs, session, db := newTestResourceSubscriptions(t)
+	if err := subs.subscribe(mcpUserContext(1), "s1", mcpUpcomingCountdownsURI); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	all := func(uint) bool { return true }
+
+	subs.refresh(all)
+	if len(session.notifications) != 0 {
+		t.Fatal("unchanged resource should not notify")
+	}
+
+	// 其他用户的数据不影响订阅内容
+	if err := db.Create(&models.Countdown{UserID: 2, Title: "四级", TargetDate: time.Now().AddDate(0, 1, 0)}).Error; err != nil {
+		t.Fatalf("create countdown: %v", err)
+	}
+	subs.refresh(all)
+	if len(session.notifications) != 0 {
+		t.Fatal("another user's change should not notify")
+	}
+
+	if err := db.Create(&models.Countdown{UserID: 1, Title: "期末", TargetDate: time.Now().AddDate(0, 0, 7)}).Error; err != nil {
+		t.Fatalf("create countdown: %v", err)
+	}
+	subs.refresh(all)
+	select {
+	case n := <-session.notifications:
+		if n.Method != mcp.MethodNotificationResourceUpdated || n.Params.AdditionalFields["uri"] != mcpUpcomingCountdownsURI {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	default:
+		t.Fatal("changed resource should notify")
+	}
+
+	subs.refresh(all)
+	if len(session.notifications) != 0 {
+		t.Fatal("digest should be updated after notifying")
+	}
+}
+
+func TestMCPResourceScheduleRefreshCoalesces(t *testing.T) {
+	subs, session, db := newTestResourceSubscriptions(t)
+	subs.refreshDelay = 50 * time.Millisecond
+
+	subs.scheduleRefresh(1)
+	if len(subs.scheduled) != 0 {
+		t.Fatal("user without subscriptions should not schedule a refresh")
+	}
+
+	if err := subs.subscribe(mcpUserContext(1), "s1", mcpUpcomingCountdownsURI); err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	if err := db.Create(&models.Countdown{UserID: 1, Title: "期末", TargetDate: time.Now().AddDate(0, 0, 7)}).Error; err != nil {
+		t.Fatalf("create countdown: %v", err)
+	}
+	for range 5 {
+		subs.scheduleRefresh(1)
+	}
+	subs.mu.Lock()
+	scheduled := len(subs.scheduled)
+	subs.mu.Unlock()
+	if scheduled != 1 {
+		t.Fatalf("scheduled refreshes = %d, want 1", scheduled)
+	}
+
+	select {
+	case <-session.notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduled refresh did not run")
+	}
+	subs.mu.Lock()
+	scheduled = len(subs.scheduled)
+	subs.mu.Unlock()
+	if scheduled != 0 {
+		t.Fatalf("scheduled refreshes after run = %d", scheduled)
+	}
+}
+
+func TestMCPResourceHandleRequestLimitsBody(t *testing.T) {
+	subs, _, _ := newTestResourceSubscriptions(t)
+	gin.SetMode(gin.TestMode)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"arguments":{"v":"` +
+		strings.Repeat("x", constant.MCPRequestMaxBodyBytes) + `"}}}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/mcp", strings.NewReader(body))
+	if !subs.handleRequest(c, mcpUserContext(1)) {
+		t.Fatal("oversized body should be answered directly")
+	}
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if subs.handleRequest(c, context.Background()) {
+		t.Fatal("other methods should be passed to the MCP server")
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type MCPServerHandler struct {
+	service *services.MCPServerService
+}
+
+func NewMCPServerHandler(service *services.MCPServerService) *MCPServerHandler {
+	return &MCPServerHandler{service: service}
+}
+
+// ListServers 管理员列出外部 MCP 服务
+func (h *MCPServerHandler) ListServers(c *gin.Context) {
+	servers, err := h.service.ListServers(c.Request.Context())
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-mcp-servers",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, servers)
+}
+
+// CreateServer 管理员接入外部 MCP 服务
+func (h *MCPServerHandler) CreateServer(c *gin.Context) {
+	var req request.CreateMCPServerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	server, err := h.service.CreateServer(c.Request.Context(), helper.GetUserID(c), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-create-mcp-server",
+			"name":   req.Name,
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, server)
+}
+
+// UpdateServer 管理员更新外部 MCP 服务
+func (h *MCPServerHandler) UpdateServer(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req request.UpdateMCPServerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	server, err := h.service.UpdateServer(c.Request.Context(), uint(serverID), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "service-update-mcp-server",
+			"server_id": serverID,
+			"error":     err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, server)
+}
+
+// DeleteServer 管理员删除外部 MCP 服务
+func (h *MCPServerHandler) DeleteServer(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.DeleteServer(c.Request.Context(), uint(serverID)); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "service-delete-mcp-server",
+			"server_id": serverID,
+			"error":     err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, "ok")
+}
+
+// CheckServerHealth 管理员立即检查外部 MCP 服务并刷新发现的工具
+func (h *MCPServerHandler) CheckServerHealth(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	server, err := h.service.CheckServerHealth(c.Request.Context(), uint(serverID))
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "service-check-mcp-server-health",
+			"server_id": serverID,
+			"error":     err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+
+	helper.SuccessResponse(c, server)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	service *services.ModerationService
+}
+
+func NewModerationHandler(service *services.ModerationService) *ModerationHandler {
+	return &ModerationHandler{service: service}
+}
+
+// ListSensitiveWords 管理员分页查询敏感词
+func (h *ModerationHandler) ListSensitiveWords(c *gin.Context) {
+	req := request.ListSensitiveWordsRequest{Page: 1, PageSize: 20}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	words, total, err := h.service.ListSensitiveWords(c.Request.Context(), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-sensitive-words",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.PageSuccessResponse(c, words, total, req.Page, req.PageSize)
+}
+
+// CreateSensitiveWords 管理员批量添加敏感词
+func (h *ModerationHandler) CreateSensitiveWords(c *gin.Context) {
+	var req request.CreateSensitiveWordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	result, err := h.service.CreateSensitiveWords(c.Request.Context(), helper.GetUserID(c), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-create-sensitive-words",
+			"count":  len(req.Words),
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, result)
+}
+
+// UpdateSensitiveWord 管理员更新敏感词分类或启用状态
+func (h *ModerationHandler) UpdateSensitiveWord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req request.UpdateSensitiveWordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.UpdateSensitiveWord(c.Request.Context(), uint(id), &req); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":  "service-update-sensitive-word",
+			"word_id": id,
+			"error":   err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, "ok")
+}
+
+// DeleteSensitiveWord 管理员删除敏感词
+func (h *ModerationHandler) DeleteSensitiveWord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.DeleteSensitiveWord(c.Request.Context(), uint(id)); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":  "service-delete-sensitive-word",
+			"word_id": id,
+			"error":   err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, "ok")
+}
+
+// GetPolicy 查看当前生效的各场景审核策略
+func (h *ModerationHandler) GetPolicy(c *gin.Context) {
+	helper.SuccessResponse(c, h.service.GetPolicy(c.Request.Context()))
+}
+
+// CheckText 按场景策略试审文本，用于验证词典与策略，不记录审核记录
+func (h *ModerationHandler) CheckText(c *gin.Context) {
+	var req request.CheckModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	helper.SuccessResponse(c, h.service.CheckText(c.Request.Context(), &req))
+}
+
+// ListRecords 管理员分页查询审核命中记录
+func (h *ModerationHandler) ListRecords(c *gin.Context) {
+	req := request.ListModerationRecordsRequest{Page: 1, PageSize: 20}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	records, total, err := h.service.ListRecords(c.Request.Context(), &req)
+	if err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action": "service-list-moderation-records",
+			"error":  err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.PageSuccessResponse(c, records, total, req.Page, req.PageSize)
+}
+
+// ReviewRecord 管理员人工复核审核记录
+func (h *ModerationHandler) ReviewRecord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	var req request.ReviewModerationRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+
+	if err := h.service.ReviewRecord(c.Request.Context(), helper.GetUserID(c), uint(id), &req); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "service-review-moderation-record",
+			"record_id": id,
+			"error":     err.Error(),
+		})
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, "ok")
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"io"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
@@ -177,6 +180,71 @@ func (h *RBACHandler) UpdateUserRoles(c *gin.Context) {
 	helper.SuccessResponse(c, gin.H{"user_id": userID})
 }
 
+// GrantUserRole 授予用户单个角色，可设置过期时间实现临时授权
+func (h *RBACHandler) GrantUserRole(c *gin.Context) {
+	userID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	var req request.GrantUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	operatorID := helper.GetUserID(c)
+	if err := h.svc.GrantRoleUntil(c.Request.Context(), userID, req.RoleID, operatorID, req.ExpiresAt); err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, gin.H{
+		"user_id":    userID,
+		"role_id":    req.RoleID,
+		"expires_at": req.ExpiresAt,
+	})
+}
+
+// RevokeUserRole 撤销用户单个角色
+func (h *RBACHandler) RevokeUserRole(c *gin.Context) {
+	userID, err := parseUintParam(c.Param("id"))
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	roleID, err := parseUintParam(c.Param("role_id"))
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return
+	}
+	if err := h.svc.RevokeRole(c.Request.Context(), userID, roleID); err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, gin.H{"user_id": userID, "role_id": roleID})
+}
+
+// ListExpiringRoleGrants 查询即将到期的临时角色授权，默认查询未来72小时
+func (h *RBACHandler) ListExpiringRoleGrants(c *gin.Context) {
+	withinHours := 72
+	if raw := c.Query("within_hours"); raw != "" {
+		val, err := strconv.Atoi(raw)
+		if err != nil || val <= 0 || val > 24*90 {
+			helper.HandleErrCode(c, constant.CommonBadRequest)
+			return
+		}
+		withinHours = val
+	}
+	grants, err := h.svc.ListExpiringRoleGrants(c.Request.Context(), time.Duration(withinHours)*time.Hour)
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, gin.H{
+		"within_hours": withinHours,
+		"grants":       grants,
+	})
+}
+
 // GetUserPermissions 获取用户权限列表
 func (h *RBACHandler) GetUserPermissions(c *gin.Context) {
 	userID, err := parseUintParam(c.Param("id"))
@@ -220,6 +288,74 @@ func (h *RBACHandler) ListRolesWithPermissions(c *gin.Context) {
 	})
 }
 
+// rbacPolicyMaxBytes 策略文件大小上限
+const rbacPolicyMaxBytes = 1 << 20
+
+// ExportPolicy 导出当前 RBAC 配置为 YAML 策略文件
+func (h *RBACHandler) ExportPolicy(c *gin.Context) {
+	policy, err := h.svc.ExportPolicy(c.Request.Context())
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	data, err := policy.ToYAML()
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonInternal, err))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="rbac_policy.yaml"`)
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// DiffPolicy 预览 YAML 策略文件与数据库当前配置的差异（dry run）
+func (h *RBACHandler) DiffPolicy(c *gin.Context) {
+	policy, ok := bindRBACPolicy(c)
+	if !ok {
+		return
+	}
+	diff, err := h.svc.DiffPolicy(c.Request.Context(), policy)
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, diff)
+}
+
+// ApplyPolicy 原子地应用 YAML 策略文件
+func (h *RBACHandler) ApplyPolicy(c *gin.Context) {
+	policy, ok := bindRBACPolicy(c)
+	if !ok {
+		return
+	}
+	diff, err := h.svc.ApplyPolicy(c.Request.Context(), policy)
+	if err != nil {
+		helper.HandleError(c, err)
+		return
+	}
+	helper.SuccessResponse(c, diff)
+}
+
+// bindRBACPolicy 从请求体读取并解析 YAML 策略文件
+func bindRBACPolicy(c *gin.Context) (*services.RBACPolicy, bool) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, rbacPolicyMaxBytes+1))
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		return nil, false
+	}
+	if len(data) > rbacPolicyMaxBytes {
+		tooLarge := apperr.New(constant.RBACPolicyInvalid)
+		tooLarge.Message = "策略文件不能超过 1MB"
+		helper.HandleError(c, tooLarge)
+		return nil, false
+	}
+	policy, err := services.ParseRBACPolicy(data)
+	if err != nil {
+		helper.HandleError(c, err)
+		return nil, false
+	}
+	return policy, true
+}
+
 func parseUintParam(raw string) (uint, error) {
 	val, err := strconv.ParseUint(raw, 10, 64)
 	if err != nil {
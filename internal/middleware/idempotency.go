@@ -2,12 +2,19 @@ package middleware
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
@@ -16,12 +23,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// IdempotencyResponse 缓存的响应结构
+// IdempotencyResponse 缓存的幂等记录：处理中时仅包含状态与请求指纹，完成后包含完整响应
 type IdempotencyResponse struct {
-	HTTPStatus int         `json:"http_status"`
-	BizCode    int         `json:"biz_code"`
-	Body       string      `json:"body"`
-	Headers    http.Header `json:"headers"`
+	Status      string      `json:"status"`
+	Fingerprint string      `json:"fingerprint"`
+	HTTPStatus  int         `json:"http_status"`
+	BizCode     int         `json:"biz_code"`
+	Body        string      `json:"body"`
+	Headers     http.Header `json:"headers"`
 }
 
 // responseWriter 包装gin的ResponseWriter以捕获响应
@@ -138,11 +147,20 @@ func idempotencyMiddlewareWithTTL(c *gin.Context, ca cache.Cache, strict bool, t
 
 	// 获取用户ID（如果已认证）
 	userID := helper.GetUserID(c)
-	var cacheKey string
+	var principal string
 	if userID != 0 {
-		cacheKey = fmt.Sprintf("%s%d:%s", constant.IdempotencyCachePrefix, userID, idempotencyKey)
+		principal = strconv.FormatUint(uint64(userID), 10)
 	} else {
-		cacheKey = fmt.Sprintf("%s%s:%s", constant.IdempotencyCachePrefix, c.ClientIP(), idempotencyKey)
+		principal = c.ClientIP()
+	}
+	cacheKey := fmt.Sprintf("%s%s:%s", constant.IdempotencyCachePrefix, principal, idempotencyKey)
+
+	// 计算请求指纹，用于识别同一个Key被用于不同请求
+	fingerprint, err := requestFingerprint(c, principal)
+	if err != nil {
+		helper.HandleError(c, apperr.Wrap(constant.CommonBadRequest, err))
+		c.Abort()
+		return
 	}
 
 	ctx := c.Request.Context()
@@ -163,8 +181,12 @@ func idempotencyMiddlewareWithTTL(c *gin.Context, ca cache.Cache, strict bool, t
 	}
 
 	if !locked {
-		helper.HandleErrCode(c, constant.CommonConflict)
-		c.Abort()
+		// 相同Key的请求正在处理中：指纹不一致视为Key复用，否则提示稍后重试
+		if record, ok := loadIdempotencyRecord(c, ca, cacheKey); ok && record.Fingerprint != "" && record.Fingerprint != fingerprint {
+			abortIdempotencyKeyReused(c, idempotencyKey)
+			return
+		}
+		abortIdempotencyProcessing(c)
 		return
 	}
 
@@ -181,20 +203,14 @@ func idempotencyMiddlewareWithTTL(c *gin.Context, ca cache.Cache, strict bool, t
 		}
 	}()
 
-	// 检查是否已有缓存的响应
-	cachedResponse, err := ca.Get(ctx, cacheKey)
-	if err == nil && cachedResponse != "" {
-		// 已有缓存，直接返回
-		var resp IdempotencyResponse
-		if err := json.Unmarshal([]byte(cachedResponse), &resp); err != nil {
-			logger.ErrorGin(c, map[string]any{
-				"action":          "idempotency_unmarshal",
-				"message":         "解析缓存响应失败",
-				"error":           err.Error(),
-				"cache_key":       cacheKey,
-				"idempotency_key": idempotencyKey,
-			})
-			c.Next()
+	// 检查是否已有幂等记录
+	if record, ok := loadIdempotencyRecord(c, ca, cacheKey); ok {
+		if record.Fingerprint != "" && record.Fingerprint != fingerprint {
+			abortIdempotencyKeyReused(c, idempotencyKey)
+			return
+		}
+		if record.Status == constant.IdempotencyStatusPending {
+			abortIdempotencyProcessing(c)
 			return
 		}
 
@@ -205,20 +221,25 @@ func idempotencyMiddlewareWithTTL(c *gin.Context, ca cache.Cache, strict bool, t
 		})
 
 		// 设置缓存的响应头
-		for key, values := range resp.Headers {
+		for key, values := range record.Headers {
 			for _, value := range values {
 				c.Header(key, value)
 			}
 		}
 		c.Header("X-Idempotency-Replayed", "true")
 
-		c.Data(resp.HTTPStatus, "application/json; charset=utf-8", []byte(resp.Body))
+		c.Data(record.HTTPStatus, "application/json; charset=utf-8", []byte(record.Body))
 		c.Abort()
 		return
 	}
 
-	// 标记请求正在处理
-	if _, err := ca.SetNX(ctx, cacheKey, constant.IdempotencyStatusPending, ttl); err != nil {
+	// 标记请求正在处理。锁只有 IdempotencyLockTimeout，处理时间更长的请求（如对话、导出）
+	// 依靠该标记拦截重试，因此有效期与幂等记录一致
+	pending, _ := json.Marshal(IdempotencyResponse{
+		Status:      constant.IdempotencyStatusPending,
+		Fingerprint: fingerprint,
+	})
+	if _, err := ca.SetNX(ctx, cacheKey, string(pending), ttl); err != nil {
 		logger.ErrorGin(c, map[string]any{
 			"action":          "idempotency_set_pending",
 			"message":         "设置处理状态失败",
@@ -245,10 +266,12 @@ func idempotencyMiddlewareWithTTL(c *gin.Context, ca cache.Cache, strict bool, t
 	if c.Writer.Status() < 400 && responseBizCode(writer.body.Bytes()) == int(constant.SuccessCode) {
 		// 仅缓存成功的响应
 		resp := IdempotencyResponse{
-			HTTPStatus: c.Writer.Status(),
-			BizCode:    responseBizCode(writer.body.Bytes()),
-			Body:       writer.body.String(),
-			Headers:    c.Writer.Header().Clone(),
+			Status:      constant.IdempotencyStatusDone,
+			Fingerprint: fingerprint,
+			HTTPStatus:  c.Writer.Status(),
+			BizCode:     responseBizCode(writer.body.Bytes()),
+			Body:        writer.body.String(),
+			Headers:     c.Writer.Header().Clone(),
 		}
 
 		respJSON, err := json.Marshal(resp)
@@ -304,3 +327,77 @@ func responseBizCode(body []byte) int {
 	}
 	return resp.StatusCode
 }
+
+// loadIdempotencyRecord 读取幂等记录，兼容旧版本直接写入的 pending 字符串
+func loadIdempotencyRecord(c *gin.Context, ca cache.Cache, cacheKey string) (*IdempotencyResponse, bool) {
+	cached, err := ca.Get(c.Request.Context(), cacheKey)
+	if err != nil || cached == "" {
+		return nil, false
+	}
+	if cached == constant.IdempotencyStatusPending {
+		return &IdempotencyResponse{Status: constant.IdempotencyStatusPending}, true
+	}
+	var record IdempotencyResponse
+	if err := json.Unmarshal([]byte(cached), &record); err != nil {
+		logger.ErrorGin(c, map[string]any{
+			"action":    "idempotency_unmarshal",
+			"message":   "解析缓存响应失败",
+			"error":     err.Error(),
+			"cache_key": cacheKey,
+		})
+		return nil, false
+	}
+	return &record, true
+}
+
+// requestFingerprint 基于请求方法、路径、调用方与请求体计算指纹。
+// 请求体读取后会被还原，后续处理器仍可正常读取。
+func requestFingerprint(c *gin.Context, principal string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", c.Request.Method, c.Request.URL.RequestURI(), principal)
+
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	body := c.Request.Body
+	prefix, err := io.ReadAll(io.LimitReader(body, constant.IdempotencyFingerprintMaxBody+1))
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+
+	hashed := prefix
+	if len(hashed) > constant.IdempotencyFingerprintMaxBody {
+		// 大请求体（如文件上传）只取前缀，并以 Content-Length 区分
+		hashed = hashed[:constant.IdempotencyFingerprintMaxBody]
+		fmt.Fprintf(h, "%d\n", c.Request.ContentLength)
+	}
+	// multipart 的 boundary 由客户端随机生成，重试时可能不同，计算前去除
+	if mediaType, params, err := mime.ParseMediaType(c.GetHeader("Content-Type")); err == nil &&
+		strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		hashed = bytes.ReplaceAll(hashed, []byte(params["boundary"]), nil)
+	}
+	h.Write(hashed)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func abortIdempotencyKeyReused(c *gin.Context, idempotencyKey string) {
+	logger.WarnGin(c, map[string]any{
+		"action":          "idempotency_check",
+		"message":         "幂等性Key被用于不同的请求",
+		"status":          "fingerprint_mismatch",
+		"idempotency_key": idempotencyKey,
+	})
+	helper.HandleErrCode(c, constant.CommonIdempotencyKeyReused)
+	c.Abort()
+}
+
+func abortIdempotencyProcessing(c *gin.Context) {
+	c.Header("Retry-After", strconv.Itoa(constant.IdempotencyRetryAfter))
+	helper.HandleErrCode(c, constant.CommonIdempotencyProcessing)
+	c.Abort()
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// clockCache 带过期时间的内存缓存，时间由测试推进
+type clockCache struct {
+	cache.Cache
+	mu      sync.Mutex
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newClockCache() *clockCache {
+	return &clockCache{
+		now:     time.Unix(1_700_000_000, 0),
+		values:  map[string]string{},
+		expires: map[string]time.Time{},
+	}
+}
+
+func (m *clockCache) advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
+
+func (m *clockCache) lookup(key string) (string, bool) {
+	if exp, ok := m.expires[key]; ok && !m.now.Before(exp) {
+		delete(m.values, key)
+		delete(m.expires, key)
+	}
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *clockCache) store(key, value string, ttl time.Duration) {
+	m.values[key] = value
+	if ttl > 0 {
+		m.expires[key] = m.now.Add(ttl)
+	} else {
+		delete(m.expires, key)
+	}
+}
+
+func (m *clockCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, _ := m.lookup(key)
+	return v, nil
+}
+
+func (m *clockCache) Set(ctx context.Context, key, value string, expiration *time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ttl := cache.DefaultExpiration
+	if expiration != nil {
+		ttl = *expiration
+	}
+	m.store(key, value, ttl)
+	return nil
+}
+
+func (m *clockCache) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lookup(key); ok {
+		return false, nil
+	}
+	m.store(key, value, expiration)
+	return true, nil
+}
+
+func (m *clockCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	delete(m.expires, key)
+	return nil
+}
+
+func (m *clockCache) Lock(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return m.SetNX(ctx, "lock:"+key, "1", expiration)
+}
+
+func (m *clockCache) Unlock(ctx context.Context, key string) error {
+	return m.Delete(ctx, "lock:"+key)
+}
+
+func newIdempotencyRouter(ca cache.Cache, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/submit", func(c *gin.Context) {
+		idempotencyMiddlewareWithTTL(c, ca, true, constant.IdempotencyExpiration)
+	}, handler)
+	return r
+}
+
+func postWithKey(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constant.IdempotencyKey, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencySlowHandlerBlocksRetryAfterLockExpires(t *testing.T) {
+	ca := newClockCache()
+	var router *gin.Engine
+	calls := 0
+	var retry *httptest.ResponseRecorder
+	router = newIdempotencyRouter(ca, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			// 处理耗时超过锁的有效期，期间客户端用同一个 Key 重试
+			ca.advance(constant.IdempotencyLockTimeout + time.Minute)
+			retry = postWithKey(router, "slow-key", `{"q":1}`)
+		}
+		helper.SuccessResponse(c, gin.H{"calls": calls})
+	})
+
+	first := postWithKey(router, "slow-key", `{"q":1}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, body %s", first.Code, first.Body.String())
+	}
+	if retry == nil || retry.Code != http.StatusConflict {
+		t.Fatalf("retry during processing should be rejected, got %+v", retry)
+	}
+	if calls != 1 {
+		t.Fatalf("handler ran %d times", calls)
+	}
+
+	replay := postWithKey(router, "slow-key", `{"q":1}`)
+	if replay.Header().Get("X-Idempotency-Replayed") != "true" || calls != 1 {
+		t.Fatalf("completed request should be replayed, calls=%d headers=%v", calls, replay.Header())
+	}
+}
+
+func TestIdempotencyFailedRequestAllowsRetry(t *testing.T) {
+	ca := newClockCache()
+	calls := 0
+	router := newIdempotencyRouter(ca, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			helper.HandleErrCode(c, constant.CommonInternal)
+			return
+		}
+		helper.SuccessResponse(c, nil)
+	})
+
+	if w := postWithKey(router, "retry-key", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d", w.Code)
+	}
+	if w := postWithKey(router, "retry-key", `{}`); w.Code != http.StatusOK || calls != 2 {
+		t.Fatalf("retry status = %d, calls = %d", w.Code, calls)
+	}
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	ca := newClockCache()
+	router := newIdempotencyRouter(ca, func(c *gin.Context) { helper.SuccessResponse(c, nil) })
+
+	if w := postWithKey(router, "reuse-key", `{"a":1}`); w.Code != http.StatusOK {
+		t.Fatalf("first status = %d", w.Code)
+	}
+	if w := postWithKey(router, "reuse-key", `{"a":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key with a different body should be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
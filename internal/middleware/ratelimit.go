@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 限流中间件
+// 按策略名称从 RateLimitService 获取规则（支持 SystemConfig 按路由、按角色覆盖），
+// 使用 Redis 滑动窗口计数，并返回标准 RateLimit-* 响应头。
+// Redis 不可用或限流判定失败时放行请求，仅记录日志。
+func RateLimit(rateLimitService *services.RateLimitService, policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision, err := rateLimitService.Check(c.Request.Context(), policy, helper.GetUserID(c), c.ClientIP())
+		if err != nil {
+			logger.WarnGin(c, map[string]any{
+				"action":  "rate_limit_check",
+				"message": "限流判定失败，放行请求",
+				"policy":  policy,
+				"error":   err.Error(),
+			})
+			c.Next()
+			return
+		}
+		if decision == nil {
+			c.Next()
+			return
+		}
+
+		result := decision.Result
+		resetSeconds := int(math.Ceil(result.ResetAfter.Seconds()))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(resetSeconds))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, decision.Rule.WindowSeconds))
+
+		if !result.Allowed {
+			logger.WarnGin(c, map[string]any{
+				"action":  "rate_limit_check",
+				"message": "请求触发限流",
+				"policy":  policy,
+				"limit":   result.Limit,
+			})
+			c.Header("Retry-After", strconv.Itoa(resetSeconds))
+			helper.HandleErrCode(c, constant.CommonTooManyRequests)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/ratelimit"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type testRedisCli struct {
+	cli goredis.UniversalClient
+}
+
+func (t *testRedisCli) GetRedisCli() goredis.UniversalClient { return t.cli }
+
+// newRateLimitRouter 使用 miniredis 限流器，limit > 0 时将 chat_share_view 覆盖为每 30 秒 limit 次
+func newRateLimitRouter(t *testing.T, limit int) *gin.Engine {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	prev := ratelimit.GlobalLimiter
+	ratelimit.NewRedisLimiter(&testRedisCli{cli: cli})
+	t.Cleanup(func() {
+		ratelimit.GlobalLimiter = prev
+		_ = cli.Close()
+	})
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "ratelimit.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if limit > 0 {
+		value := fmt.Sprintf(`{"chat_share_view":{"limit":%d,"window_seconds":30}}`, limit)
+		if err := db.Create(&models.SystemConfig{Key: constant.RateLimitConfigKey, Value: value}).Error; err != nil {
+			t.Fatalf("create config: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/share", RateLimit(services.NewRateLimitService(db, nil), constant.RateLimitPolicyChatShareView), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func getShare(r http.Handler) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/share", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := newRateLimitRouter(t, 2)
+
+	w := getShare(r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "30",
+		"RateLimit-Policy":    "2;w=30",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if w.Header().Get("Retry-After") != "" {
+		t.Error("allowed request should not carry Retry-After")
+	}
+
+	getShare(r)
+	w = getShare(r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over the limit = %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("limited response headers = %v", w.Header())
+	}
+}
+
+func TestRateLimitWithoutLimiterPassesThrough(t *testing.T) {
+	r := newRateLimitRouter(t, 0)
+	ratelimit.GlobalLimiter = nil
+
+	w := getShare(r)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("status = %d, headers = %v", w.Code, w.Header())
+	}
+}
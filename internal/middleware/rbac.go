@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"slices"
+
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/handlers/helper"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
@@ -56,3 +58,41 @@ func RequirePermission(rbac *services.RBACService, permissions ...string) gin.Ha
 		c.Abort()
 	}
 }
+
+// RequireRole 校验用户是否拥有指定角色（任一满足即可），管理员始终通过
+// 同时将权限信息注入到 context 中，供服务层使用
+func RequireRole(rbac *services.RBACService, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userIDVal, exists := c.Get("user_id")
+		if !exists {
+			helper.HandleErrCode(c, constant.AuthMissingUserContext)
+			c.Abort()
+			return
+		}
+		userID, ok := userIDVal.(uint)
+		if !ok {
+			helper.HandleErrCode(c, constant.CommonUnauthorized)
+			c.Abort()
+			return
+		}
+
+		snap, err := rbac.GetUserPermissionSnapshot(c, userID)
+		if err != nil {
+			helper.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set("user_roles", snap.RoleTags)
+		c.Set("user_permissions", snap.PermissionTags)
+		c.Set("is_admin", snap.IsAdmin)
+
+		if snap.IsAdmin || slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(snap.RoleTags, role) }) {
+			c.Next()
+			return
+		}
+
+		helper.HandleErrCode(c, constant.CommonForbidden)
+		c.Abort()
+	}
+}
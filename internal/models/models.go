@@ -537,28 +537,177 @@ func (Dictionary) TableName() string {
 
 // Conversation 对话会话模型
 type Conversation struct {
-	ID            uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:会话ID"`
-	UserID        uint           `json:"user_id" gorm:"not null;index:idx_user_updated;comment:用户ID"`
-	Title         string         `json:"title" gorm:"type:varchar(200);not null;comment:会话标题"`
-	CreatedAt     time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
-	UpdatedAt     time.Time      `json:"updated_at" gorm:"type:datetime;index:idx_user_updated;comment:更新时间"`
-	LastMessageAt *time.Time     `json:"last_message_at" gorm:"type:datetime;comment:最后消息时间"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"comment:软删除时间"`
+	ID               uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:会话ID"`
+	UserID           uint           `json:"user_id" gorm:"not null;index:idx_user_updated;comment:用户ID"`
+	Title            string         `json:"title" gorm:"type:varchar(200);not null;index:idx_conversation_title_fulltext,class:FULLTEXT,option:WITH PARSER ngram;comment:会话标题"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"type:datetime;index:idx_user_updated;comment:更新时间"`
+	LastMessageAt    *time.Time     `json:"last_message_at" gorm:"type:datetime;comment:最后消息时间"`
+	ModelProfile     string         `json:"model_profile" gorm:"type:varchar(64);not null;default:'';comment:用户为对话选择的模型配置名称，空表示默认"`
+	ActiveLeafID     uint           `json:"active_leaf_id" gorm:"not null;default:0;comment:当前分支末端消息ID，0表示尚未建立消息树"`
+	PersonaID        uint           `json:"persona_id" gorm:"not null;default:0;comment:助手人设ID，0表示默认助手"`
+	PersonaVersion   int            `json:"persona_version" gorm:"not null;default:0;comment:创建对话时人设提示词的版本"`
+	Summary          string         `json:"-" gorm:"type:longtext;comment:早期消息滚动摘要"`
+	SummaryMessageID uint           `json:"-" gorm:"not null;default:0;comment:摘要已覆盖的最后一条消息ID"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"comment:软删除时间"`
 }
 
 // ConversationMessage 对话消息模型
 type ConversationMessage struct {
 	ID               uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:消息ID"`
 	ConversationID   uint           `json:"conversation_id" gorm:"not null;index:idx_conversation_message_conversation_created,priority:1;index:idx_conversation_message_user_conversation,priority:2;comment:会话ID"`
+	ParentID         uint           `json:"parent_id" gorm:"not null;default:0;index:idx_conversation_message_parent;comment:父消息ID，0表示根消息"`
 	UserID           uint           `json:"user_id" gorm:"not null;index:idx_conversation_message_user_conversation,priority:1;comment:用户ID"`
 	CheckpointID     string         `json:"checkpoint_id" gorm:"type:varchar(100);index:idx_conversation_message_checkpoint;comment:Agent checkpoint ID"`
 	Role             string         `json:"role" gorm:"type:varchar(20);not null;index:idx_conversation_message_role;comment:消息角色"`
-	Content          string         `json:"content" gorm:"type:longtext;comment:消息正文"`
+	Content          string         `json:"content" gorm:"type:longtext;index:idx_conversation_message_content_fulltext,class:FULLTEXT,option:WITH PARSER ngram;comment:消息正文"`
 	ReasoningContent string         `json:"reasoning_content" gorm:"type:longtext;comment:推理内容"`
 	ToolCallID       string         `json:"tool_call_id" gorm:"type:varchar(191);comment:工具调用ID"`
 	ToolName         string         `json:"tool_name" gorm:"type:varchar(191);comment:工具名称"`
 	ToolCalls        datatypes.JSON `json:"tool_calls" gorm:"type:json;comment:工具调用列表"`
 	RawMessage       datatypes.JSON `json:"raw_message" gorm:"type:json;comment:eino schema.Message原始JSON"`
+	Attachments      datatypes.JSON `json:"attachments" gorm:"type:json;comment:附件引用列表"`
+	PromptTokens     int            `json:"prompt_tokens" gorm:"type:int;not null;default:0;comment:模型输入token数"`
+	CompletionTokens int            `json:"completion_tokens" gorm:"type:int;not null;default:0;comment:模型输出token数"`
+	TotalTokens      int            `json:"total_tokens" gorm:"type:int;not null;default:0;comment:模型总token数"`
+	ModelProfile     string         `json:"model_profile" gorm:"type:varchar(64);not null;default:'';comment:实际生成该消息的模型配置名称"`
 	CreatedAt        time.Time      `json:"created_at" gorm:"type:datetime;index:idx_conversation_message_conversation_created,priority:2;comment:创建时间"`
 	UpdatedAt        time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
 }
+
+// LLMUsageDaily 用户每日 AI 对话 token 用量
+type LLMUsageDaily struct {
+	ID               uint      `json:"id" gorm:"type:int unsigned;primaryKey;comment:记录ID"`
+	UserID           uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_llm_usage_user_date,priority:1;comment:用户ID"`
+	Date             time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_llm_usage_user_date,priority:2;index:idx_llm_usage_date;comment:统计日期"`
+	PromptTokens     int64     `json:"prompt_tokens" gorm:"type:bigint;not null;default:0;comment:模型输入token数"`
+	CompletionTokens int64     `json:"completion_tokens" gorm:"type:bigint;not null;default:0;comment:模型输出token数"`
+	TotalTokens      int64     `json:"total_tokens" gorm:"type:bigint;not null;default:0;comment:模型总token数"`
+	RequestCount     int       `json:"request_count" gorm:"type:int;not null;default:0;comment:模型调用次数"`
+	ExtraQuota       int64     `json:"extra_quota" gorm:"type:bigint;not null;default:0;comment:当日使用积分购买的额外token额度"`
+	CreatedAt        time.Time `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+}
+
+// ConversationShare 对话公开分享，保存创建时的只读快照
+type ConversationShare struct {
+	ID             uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:分享ID"`
+	Slug           string         `json:"slug" gorm:"type:varchar(32);not null;uniqueIndex:idx_conversation_share_slug;comment:分享链接标识"`
+	UserID         uint           `json:"user_id" gorm:"not null;index:idx_conversation_share_user;comment:分享者用户ID"`
+	ConversationID uint           `json:"conversation_id" gorm:"not null;index:idx_conversation_share_conversation;comment:会话ID"`
+	Title          string         `json:"title" gorm:"type:varchar(200);not null;comment:分享标题"`
+	Snapshot       datatypes.JSON `json:"-" gorm:"type:json;not null;comment:消息快照（已去除工具调用与推理过程）"`
+	MessageCount   int            `json:"message_count" gorm:"type:int;not null;default:0;comment:快照消息数"`
+	ViewCount      int64          `json:"view_count" gorm:"type:bigint;not null;default:0;comment:浏览次数"`
+	ExpiresAt      *time.Time     `json:"expires_at" gorm:"type:datetime;comment:过期时间，NULL表示永久有效"`
+	RevokedAt      *time.Time     `json:"revoked_at" gorm:"type:datetime;comment:撤销时间"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+}
+
+// ChatPersona 助手人设，由管理员维护；提示词每次修改生成新版本
+type ChatPersona struct {
+	ID            uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:人设ID"`
+	Name          string         `json:"name" gorm:"type:varchar(64);not null;uniqueIndex:idx_chat_persona_name;comment:人设名称"`
+	Description   string         `json:"description" gorm:"type:varchar(500);not null;default:'';comment:人设简介"`
+	SystemPrompt  string         `json:"system_prompt" gorm:"type:text;not null;comment:当前版本的系统提示词"`
+	PromptVersion int            `json:"prompt_version" gorm:"type:int;not null;default:1;comment:当前提示词版本"`
+	AllowedTools  datatypes.JSON `json:"allowed_tools" gorm:"type:json;comment:允许使用的工具名称列表，为空表示不限制"`
+	ModelProfile  string         `json:"model_profile" gorm:"type:varchar(64);not null;default:'';comment:优先使用的模型配置名称，空表示默认"`
+	Temperature   *float32       `json:"temperature" gorm:"type:decimal(3,2);comment:采样温度，NULL表示使用模型默认值"`
+	Sort          int            `json:"sort" gorm:"type:int;not null;default:0;comment:排序值"`
+	IsEnabled     bool           `json:"is_enabled" gorm:"type:tinyint(1);not null;default:1;comment:是否可供新对话选择"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"comment:软删除时间"`
+}
+
+// ChatMessageFeedback 用户对助手回答的评价，保存评价时的人设、模型与工具快照用于统计
+type ChatMessageFeedback struct {
+	ID             uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:评价ID"`
+	MessageID      uint           `json:"message_id" gorm:"not null;uniqueIndex:idx_chat_feedback_message;comment:被评价的助手消息ID"`
+	ConversationID uint           `json:"conversation_id" gorm:"not null;index:idx_chat_feedback_conversation;comment:会话ID"`
+	UserID         uint           `json:"user_id" gorm:"not null;index:idx_chat_feedback_user;comment:评价用户ID"`
+	Rating         int8           `json:"rating" gorm:"type:tinyint;not null;index:idx_chat_feedback_review,priority:1;comment:评价：1=赞，-1=踩"`
+	Tags           datatypes.JSON `json:"tags" gorm:"type:json;comment:评价标签列表"`
+	Comment        string         `json:"comment" gorm:"type:varchar(1000);not null;default:'';comment:评价说明"`
+	PersonaID      uint           `json:"persona_id" gorm:"not null;default:0;index:idx_chat_feedback_persona;comment:对话使用的助手人设ID"`
+	ModelProfile   string         `json:"model_profile" gorm:"type:varchar(64);not null;default:'';index:idx_chat_feedback_model;comment:生成回答的模型配置名称"`
+	ToolNames      datatypes.JSON `json:"tool_names" gorm:"type:json;comment:本轮回答调用过的工具名称列表"`
+	ReviewStatus   int8           `json:"review_status" gorm:"type:tinyint;not null;default:0;index:idx_chat_feedback_review,priority:2;comment:复核状态：0=待复核，1=已处理，2=已忽略"`
+	ReviewNote     string         `json:"review_note" gorm:"type:varchar(500);not null;default:'';comment:复核备注"`
+	ReviewedBy     uint           `json:"reviewed_by" gorm:"not null;default:0;comment:复核人用户ID"`
+	ReviewedAt     *time.Time     `json:"reviewed_at" gorm:"type:datetime;comment:复核时间"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;index:idx_chat_feedback_created;comment:创建时间"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+}
+
+// ChatPersonaPromptVersion 人设提示词历史版本，对话按创建时的版本使用提示词
+type ChatPersonaPromptVersion struct {
+	ID           uint      `json:"id" gorm:"type:int unsigned;primaryKey;comment:版本记录ID"`
+	PersonaID    uint      `json:"persona_id" gorm:"not null;uniqueIndex:idx_chat_persona_version,priority:1;comment:人设ID"`
+	Version      int       `json:"version" gorm:"type:int;not null;uniqueIndex:idx_chat_persona_version,priority:2;comment:版本号"`
+	SystemPrompt string    `json:"system_prompt" gorm:"type:text;not null;comment:系统提示词"`
+	CreatedBy    uint      `json:"created_by" gorm:"not null;default:0;comment:修改人用户ID"`
+	CreatedAt    time.Time `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+}
+
+// SensitiveWord 内容审核敏感词，由管理员维护
+type SensitiveWord struct {
+	ID        uint      `json:"id" gorm:"type:int unsigned;primaryKey;comment:敏感词ID"`
+	Word      string    `json:"word" gorm:"type:varchar(100);not null;uniqueIndex:idx_sensitive_word;comment:敏感词"`
+	Category  string    `json:"category" gorm:"type:varchar(32);not null;default:'';index:idx_sensitive_word_category;comment:分类"`
+	IsEnabled bool      `json:"is_enabled" gorm:"not null;default:true;comment:是否启用"`
+	CreatedBy uint      `json:"created_by" gorm:"not null;default:0;comment:添加人用户ID"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+}
+
+// ModerationRecord 内容审核命中记录，用于审计与人工复核
+type ModerationRecord struct {
+	ID           uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:记录ID"`
+	Surface      string         `json:"surface" gorm:"type:varchar(32);not null;index:idx_moderation_surface;comment:审核场景"`
+	Action       string         `json:"action" gorm:"type:varchar(16);not null;comment:执行的处理：block/mask/review"`
+	UserID       uint           `json:"user_id" gorm:"not null;default:0;index:idx_moderation_user;comment:提交内容的用户ID"`
+	TargetID     string         `json:"target_id" gorm:"type:varchar(64);not null;default:'';comment:被审核内容ID（对话ID、评价ID、投稿ID或资料MD5），被拦截时可能为空"`
+	Words        datatypes.JSON `json:"words" gorm:"type:json;comment:命中的敏感词或分类"`
+	Hits         datatypes.JSON `json:"hits" gorm:"type:json;comment:命中明细"`
+	Excerpt      string         `json:"excerpt" gorm:"type:varchar(1000);not null;default:'';comment:原文摘录"`
+	ReviewStatus int8           `json:"review_status" gorm:"type:tinyint;not null;default:0;index:idx_moderation_review;comment:复核状态：0=待复核，1=确认无问题，2=确认违规，3=无需复核"`
+	ReviewNote   string         `json:"review_note" gorm:"type:varchar(500);not null;default:'';comment:复核备注"`
+	ReviewedBy   uint           `json:"reviewed_by" gorm:"not null;default:0;comment:复核人用户ID"`
+	ReviewedAt   *time.Time     `json:"reviewed_at" gorm:"type:datetime;comment:复核时间"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"type:datetime;index:idx_moderation_created;comment:创建时间"`
+}
+
+// MCPToolCallAudit MCP 工具调用审计记录
+type MCPToolCallAudit struct {
+	ID            uint      `json:"id" gorm:"type:int unsigned;primaryKey;comment:记录ID"`
+	UserID        uint      `json:"user_id" gorm:"not null;index:idx_mcp_audit_user;comment:调用用户ID"`
+	ToolName      string    `json:"tool_name" gorm:"type:varchar(64);not null;index:idx_mcp_audit_tool;comment:工具名称"`
+	ArgumentsHash string    `json:"arguments_hash" gorm:"type:char(64);not null;comment:调用参数SHA-256"`
+	DurationMs    int64     `json:"duration_ms" gorm:"not null;default:0;comment:耗时（毫秒）"`
+	Status        string    `json:"status" gorm:"type:varchar(16);not null;index:idx_mcp_audit_status;comment:结果状态：success/tool_error/error"`
+	ErrorMessage  string    `json:"error_message" gorm:"type:varchar(500);not null;default:'';comment:错误信息"`
+	CreatedAt     time.Time `json:"created_at" gorm:"type:datetime;index:idx_mcp_audit_created;comment:调用时间"`
+}
+
+// MCPServer 管理员接入的外部 MCP 服务（streamable HTTP），对话时按角色加载其工具
+type MCPServer struct {
+	ID              uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:服务ID"`
+	Name            string         `json:"name" gorm:"type:varchar(64);not null;uniqueIndex:idx_mcp_server_name;comment:服务名称，用作工具来源标识"`
+	Description     string         `json:"description" gorm:"type:varchar(500);not null;default:'';comment:服务简介"`
+	URL             string         `json:"url" gorm:"type:varchar(500);not null;comment:streamable HTTP 地址"`
+	Headers         string         `json:"-" gorm:"type:text;comment:加密后的认证请求头JSON"`
+	IsEnabled       bool           `json:"is_enabled" gorm:"type:tinyint(1);not null;default:1;comment:是否启用"`
+	AllowedRoles    datatypes.JSON `json:"allowed_roles" gorm:"type:json;comment:允许使用的角色标识列表，为空表示所有用户"`
+	AllowedTools    datatypes.JSON `json:"allowed_tools" gorm:"type:json;comment:允许加载的工具名称列表，为空表示不限制"`
+	DeniedTools     datatypes.JSON `json:"denied_tools" gorm:"type:json;comment:禁止加载的工具名称列表"`
+	HealthStatus    string         `json:"health_status" gorm:"type:varchar(16);not null;default:'unknown';comment:健康状态：unknown/healthy/unhealthy"`
+	HealthError     string         `json:"health_error" gorm:"type:varchar(500);not null;default:'';comment:最近一次检查失败的原因"`
+	DiscoveredTools datatypes.JSON `json:"discovered_tools" gorm:"type:json;comment:最近一次检查发现的工具名称列表"`
+	LastCheckedAt   *time.Time     `json:"last_checked_at" gorm:"type:datetime;comment:最近一次健康检查时间"`
+	CreatedBy       uint           `json:"created_by" gorm:"not null;default:0;comment:创建人用户ID"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
+}
@@ -33,11 +33,21 @@ type UserRole struct {
 	ID        uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:记录ID"`
 	UserID    uint           `json:"user_id" gorm:"type:int unsigned;not null;comment:用户ID"`
 	RoleID    uint           `json:"role_id" gorm:"type:int unsigned;not null;comment:角色ID"`
+	GrantedBy uint           `json:"granted_by" gorm:"type:int unsigned;default:0;comment:授权人ID（0表示系统）"`
+	ExpiresAt *time.Time     `json:"expires_at" gorm:"type:datetime;index:idx_user_roles_expires_at;comment:过期时间，NULL表示永久有效"`
 	CreatedAt time.Time      `json:"created_at" gorm:"type:datetime;comment:创建时间"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"type:datetime;comment:更新时间"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"comment:软删除时间"`
 }
 
+// IsExpired 检查角色授权是否已过期
+func (r *UserRole) IsExpired() bool {
+	if r.ExpiresAt == nil {
+		return false // 永久有效
+	}
+	return time.Now().After(*r.ExpiresAt)
+}
+
 // RolePermission 角色与权限关联
 type RolePermission struct {
 	ID           uint           `json:"id" gorm:"type:int unsigned;primaryKey;comment:记录ID"`
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localLRU 进程内带过期时间的 LRU 缓存，作为 Redis 之前的一级缓存
+type localLRU struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type localEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+func newLocalLRU(capacity int) *localLRU {
+	return &localLRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+func (l *localLRU) get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*localEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.removeElement(elem)
+		return "", false
+	}
+	l.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (l *localLRU) set(key, value string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*localEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.ll.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&localEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.ll.Len() > l.capacity {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *localLRU) delete(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := l.items[key]; ok {
+			l.removeElement(elem)
+		}
+	}
+}
+
+func (l *localLRU) purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ll.Init()
+	l.items = make(map[string]*list.Element, l.capacity)
+}
+
+func (l *localLRU) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *localLRU) removeElement(elem *list.Element) {
+	l.ll.Remove(elem)
+	delete(l.items, elem.Value.(*localEntry).key)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	l := newLocalLRU(2)
+	l.set("a", "1", time.Minute)
+	l.set("b", "2", time.Minute)
+
+	// 访问 a 后 b 成为最久未使用的条目
+	if _, ok := l.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	l.set("c", "3", time.Minute)
+
+	if _, ok := l.get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if v, ok := l.get("a"); !ok || v != "1" {
+		t.Fatalf("get a = %q, %v", v, ok)
+	}
+	if v, ok := l.get("c"); !ok || v != "3" {
+		t.Fatalf("get c = %q, %v", v, ok)
+	}
+	if l.len() != 2 {
+		t.Fatalf("len = %d, want 2", l.len())
+	}
+}
+
+func TestLocalLRUExpiresAndDeletes(t *testing.T) {
+	l := newLocalLRU(10)
+	l.set("short", "1", 10*time.Millisecond)
+	l.set("long", "2", time.Minute)
+	l.set("skip", "3", 0)
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := l.get("short"); ok {
+		t.Fatal("expected short to expire")
+	}
+	if _, ok := l.get("skip"); ok {
+		t.Fatal("expected zero ttl entry to be skipped")
+	}
+
+	l.delete("long", "missing")
+	if _, ok := l.get("long"); ok {
+		t.Fatal("expected long to be deleted")
+	}
+	if l.len() != 0 {
+		t.Fatalf("len = %d, want 0", l.len())
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/redis"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	json "github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	rediscache "github.com/redis/go-redis/v9"
+)
+
+var _ Cache = (*tieredCache)(nil)
+
+// GlobalTieredCache 两级缓存（进程内 LRU + Redis）全局实例，Redis 不可用时为 nil。
+// 仅适用于读多写少的热点数据（权限快照、功能开关、系统配置等）；
+// 计数器、锁、集合等操作会直接透传到 Redis，但不要对同一个 key 混用 Get 与 Incr。
+var GlobalTieredCache Cache
+
+// tieredCache 在 Redis 之前增加进程内 LRU：
+// Get 优先读本地，未命中时回源 Redis 并回填本地；
+// Set/Delete 写入或删除两级缓存后，通过 Redis pub/sub 广播，让其他副本丢弃本地副本。
+type tieredCache struct {
+	Cache // 远端缓存（Redis），未覆盖的方法直接透传
+
+	local      *localLRU
+	localTTL   time.Duration
+	cli        redis.RedisClient
+	channel    string
+	instanceID string
+	pubsub     *rediscache.PubSub
+}
+
+// invalidationMessage 缓存失效广播消息
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// NewTieredCache 创建两级缓存并设置为全局两级缓存，同时订阅失效广播频道
+func NewTieredCache(cli redis.RedisClient, remote Cache, capacity int, localTTL time.Duration, channel string) Cache {
+	c := newTieredCache(cli, remote, capacity, localTTL, channel)
+	GlobalTieredCache = c
+	return c
+}
+
+func newTieredCache(cli redis.RedisClient, remote Cache, capacity int, localTTL time.Duration, channel string) *tieredCache {
+	c := &tieredCache{
+		Cache:      remote,
+		local:      newLocalLRU(capacity),
+		localTTL:   localTTL,
+		cli:        cli,
+		channel:    channel,
+		instanceID: uuid.NewString(),
+	}
+	c.pubsub = cli.GetRedisCli().Subscribe(context.Background(), channel)
+	go c.listenInvalidation()
+	return c
+}
+
+func (t *tieredCache) Get(ctx context.Context, key string) (string, error) {
+	if value, ok := t.local.get(key); ok {
+		return value, nil
+	}
+	value, err := t.Cache.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	t.local.set(key, value, t.localTTL)
+	return value, nil
+}
+
+func (t *tieredCache) Set(ctx context.Context, key string, value string, expiration *time.Duration) error {
+	err := t.Cache.Set(ctx, key, value, expiration)
+	if err != nil {
+		t.local.delete(key)
+	} else {
+		ttl := t.localTTL
+		if expiration != nil && *expiration > 0 && *expiration < ttl {
+			ttl = *expiration
+		}
+		t.local.set(key, value, ttl)
+	}
+	t.publishInvalidation(ctx, key)
+	return err
+}
+
+func (t *tieredCache) Delete(ctx context.Context, key string) error {
+	t.local.delete(key)
+	err := t.Cache.Delete(ctx, key)
+	t.publishInvalidation(ctx, key)
+	return err
+}
+
+// Close 仅关闭订阅，Redis 连接由远端缓存负责关闭
+func (t *tieredCache) Close() error {
+	t.local.purge()
+	return t.pubsub.Close()
+}
+
+// publishInvalidation 广播缓存失效消息
+func (t *tieredCache) publishInvalidation(ctx context.Context, keys ...string) {
+	payload, err := json.Marshal(invalidationMessage{Origin: t.instanceID, Keys: keys})
+	if err != nil {
+		return
+	}
+	if err := t.cli.GetRedisCli().Publish(ctx, t.channel, payload).Err(); err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "cache_invalidation_publish",
+			"message": "广播缓存失效消息失败",
+			"keys":    keys,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// listenInvalidation 处理其他副本广播的缓存失效消息，直到订阅关闭
+func (t *tieredCache) listenInvalidation() {
+	for msg := range t.pubsub.Channel() {
+		var inv invalidationMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			logger.Warnf("invalid cache invalidation message: %v", err)
+			continue
+		}
+		if inv.Origin == t.instanceID {
+			continue
+		}
+		t.local.delete(inv.Keys...)
+	}
+}
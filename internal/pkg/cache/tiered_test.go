@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const testInvalidationChannel = "cache:invalidation:test"
+
+type testRedisCli struct {
+	cli goredis.UniversalClient
+}
+
+func (t *testRedisCli) GetRedisCli() goredis.UniversalClient { return t.cli }
+
+// newTestTieredCaches 基于同一个 miniredis 创建 n 个两级缓存，模拟多个副本，不修改全局缓存
+func newTestTieredCaches(t *testing.T, n int) (*miniredis.Miniredis, []*tieredCache) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	caches := make([]*tieredCache, 0, n)
+	for range n {
+		cli := &testRedisCli{cli: goredis.NewClient(&goredis.Options{Addr: mr.Addr()})}
+		c := newTieredCache(cli, &redisCache{cli: cli}, 16, time.Minute, testInvalidationChannel)
+		t.Cleanup(func() {
+			_ = c.Close()
+			_ = cli.cli.Close()
+		})
+		caches = append(caches, c)
+	}
+	// 等待所有副本完成订阅，避免广播早于订阅
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub(testInvalidationChannel)[testInvalidationChannel] < n {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriptions not ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return mr, caches
+}
+
+func TestTieredCacheGet(t *testing.T) {
+	mr, caches := newTestTieredCaches(t, 1)
+	c := caches[0]
+	ctx := context.Background()
+
+	// 本地未命中时回源 Redis 并回填本地
+	mr.Set("k", "remote")
+	if v, err := c.Get(ctx, "k"); err != nil || v != "remote" {
+		t.Fatalf("Get() = %q, %v", v, err)
+	}
+	if v, ok := c.local.get("k"); !ok || v != "remote" {
+		t.Fatalf("local = %q, %v", v, ok)
+	}
+
+	// 命中本地时不再访问 Redis
+	mr.Set("k", "changed")
+	if v, err := c.Get(ctx, "k"); err != nil || v != "remote" {
+		t.Fatalf("Get() = %q, %v", v, err)
+	}
+
+	if _, err := c.Get(ctx, "missing"); err != goredis.Nil {
+		t.Fatalf("Get(missing) error = %v", err)
+	}
+	if _, ok := c.local.get("missing"); ok {
+		t.Fatal("miss should not be cached locally")
+	}
+}
+
+func TestTieredCacheSetAndDelete(t *testing.T) {
+	mr, caches := newTestTieredCaches(t, 1)
+	c := caches[0]
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "k", "v1", nil); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, err := mr.Get("k"); err != nil || v != "v1" {
+		t.Fatalf("redis = %q, %v", v, err)
+	}
+	if v, ok := c.local.get("k"); !ok || v != "v1" {
+		t.Fatalf("local = %q, %v", v, ok)
+	}
+
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if mr.Exists("k") {
+		t.Fatal("redis key should be deleted")
+	}
+	if _, ok := c.local.get("k"); ok {
+		t.Fatal("local key should be deleted")
+	}
+}
+
+func TestTieredCacheInvalidatesOtherReplicas(t *testing.T) {
+	_, caches := newTestTieredCaches(t, 2)
+	a, b := caches[0], caches[1]
+	ctx := context.Background()
+
+	waitEvicted := func(key string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, ok := a.local.get(key); !ok {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("local %q not invalidated", key)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if err := b.Set(ctx, "k", "v1", nil); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if v, err := a.Get(ctx, "k"); err != nil || v != "v1" {
+		t.Fatalf("Get() = %q, %v", v, err)
+	}
+
+	// 其他副本写入新值后，本副本丢弃本地旧值并读到新值
+	if err := b.Set(ctx, "k", "v2", nil); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	waitEvicted("k")
+	if v, err := a.Get(ctx, "k"); err != nil || v != "v2" {
+		t.Fatalf("Get() = %q, %v", v, err)
+	}
+
+	if err := b.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	waitEvicted("k")
+
+	// 本副本自己的广播不会清掉刚写入的本地值
+	if err := a.Set(ctx, "own", "v", nil); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := a.local.get("own"); !ok {
+		t.Fatal("own write should stay in the local cache")
+	}
+}
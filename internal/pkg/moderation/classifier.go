@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	json "github.com/bytedance/sonic"
+)
+
+var _ Moderator = (*HTTPClassifier)(nil)
+
+// HTTPClassifier 外部内容分类服务适配器。
+// 请求：POST {"text": "..."}，可选 Authorization: Bearer <apiKey>；
+// 响应：{"flagged": true, "categories": [{"name": "politics", "score": 0.93}]}。
+// 分数不低于阈值的分类记为命中；flagged 为 true 但没有分类达到阈值时记一条 "flagged" 命中。
+type HTTPClassifier struct {
+	endpoint  string
+	apiKey    string
+	threshold float64
+	client    *http.Client
+}
+
+type classifierRequest struct {
+	Text string `json:"text"`
+}
+
+type classifierResponse struct {
+	Flagged    bool `json:"flagged"`
+	Categories []struct {
+		Name  string  `json:"name"`
+		Score float64 `json:"score"`
+	} `json:"categories"`
+}
+
+// NewHTTPClassifier 创建外部分类服务适配器
+func NewHTTPClassifier(endpoint, apiKey string, threshold float64, timeout time.Duration) *HTTPClassifier {
+	return &HTTPClassifier{
+		endpoint:  endpoint,
+		apiKey:    apiKey,
+		threshold: threshold,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *HTTPClassifier) Name() string { return SourceClassifier }
+
+func (c *HTTPClassifier) Check(ctx context.Context, text string) ([]Hit, error) {
+	if text == "" {
+		return nil, nil
+	}
+	body, err := json.Marshal(classifierRequest{Text: text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("classifier returned status %d: %s", resp.StatusCode, data)
+	}
+
+	var result classifierResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid classifier response: %w", err)
+	}
+	var hits []Hit
+	for _, category := range result.Categories {
+		if category.Score >= c.threshold {
+			hits = append(hits, Hit{Source: SourceClassifier, Category: category.Name, Score: category.Score})
+		}
+	}
+	if result.Flagged && len(hits) == 0 {
+		hits = append(hits, Hit{Source: SourceClassifier, Category: "flagged"})
+	}
+	return hits, nil
+}
@@ -0,0 +1,163 @@
+package moderation
+
+import (
+	"context"
+	"unicode"
+)
+
+var _ Moderator = (*Dictionary)(nil)
+
+// Entry 词典中的一个敏感词
+type Entry struct {
+	Word     string
+	Category string
+}
+
+type acNode struct {
+	next map[rune]int32
+	fail int32
+	// out 在此结束的词下标，已合并失败链上的输出
+	out []int32
+}
+
+// Dictionary 基于 Aho–Corasick 自动机的敏感词匹配器，构建后只读，可并发使用。
+// 匹配前统一转为小写、全角转半角，并跳过空白与标点，"敏 感-词" 同样能命中 "敏感词"。
+type Dictionary struct {
+	nodes   []acNode
+	entries []Entry
+	lengths []int // 规范化后每个词的长度
+}
+
+// NewDictionary 构建词典，规范化后为空的词会被忽略
+func NewDictionary(entries []Entry) *Dictionary {
+	d := &Dictionary{nodes: []acNode{{}}}
+	for _, entry := range entries {
+		word := normalizeWord(entry.Word)
+		if len(word) == 0 {
+			continue
+		}
+		cur := int32(0)
+		for _, r := range word {
+			next, ok := d.nodes[cur].next[r]
+			if !ok {
+				if d.nodes[cur].next == nil {
+					d.nodes[cur].next = make(map[rune]int32)
+				}
+				d.nodes = append(d.nodes, acNode{})
+				next = int32(len(d.nodes) - 1)
+				d.nodes[cur].next[r] = next
+			}
+			cur = next
+		}
+		d.nodes[cur].out = append(d.nodes[cur].out, int32(len(d.entries)))
+		d.entries = append(d.entries, entry)
+		d.lengths = append(d.lengths, len(word))
+	}
+	d.buildFailLinks()
+	return d
+}
+
+// buildFailLinks 按层序计算失败指针
+func (d *Dictionary) buildFailLinks() {
+	queue := make([]int32, 0, len(d.nodes))
+	for _, child := range d.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range d.nodes[cur].next {
+			fail := d.nodes[cur].fail
+			for {
+				if next, ok := d.nodes[fail].next[r]; ok && next != child {
+					d.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					d.nodes[child].fail = 0
+					break
+				}
+				fail = d.nodes[fail].fail
+			}
+			d.nodes[child].out = append(d.nodes[child].out, d.nodes[d.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 词典中的词数
+func (d *Dictionary) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.entries)
+}
+
+func (d *Dictionary) Name() string { return SourceDictionary }
+
+func (d *Dictionary) Check(_ context.Context, text string) ([]Hit, error) {
+	return d.Match(text), nil
+}
+
+// Match 返回文本中全部命中（含重叠），位置对应原文
+func (d *Dictionary) Match(text string) []Hit {
+	if d.Len() == 0 || text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	// positions[i] 为规范化序列第 i 个字符在原文中的位置
+	positions := make([]int, 0, len(runes))
+	var hits []Hit
+	state := int32(0)
+	for i, r := range runes {
+		if isIgnorable(r) {
+			continue
+		}
+		r = normalizeRune(r)
+		positions = append(positions, i)
+		for {
+			if next, ok := d.nodes[state].next[r]; ok {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = d.nodes[state].fail
+		}
+		for _, idx := range d.nodes[state].out {
+			start := positions[len(positions)-d.lengths[idx]]
+			hits = append(hits, Hit{
+				Source:   SourceDictionary,
+				Word:     d.entries[idx].Word,
+				Category: d.entries[idx].Category,
+				Start:    start,
+				End:      i + 1,
+			})
+		}
+	}
+	return hits
+}
+
+func normalizeWord(word string) []rune {
+	out := make([]rune, 0, len(word))
+	for _, r := range word {
+		if !isIgnorable(r) {
+			out = append(out, normalizeRune(r))
+		}
+	}
+	return out
+}
+
+// normalizeRune 全角转半角并转为小写
+func normalizeRune(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+// isIgnorable 匹配时跳过的分隔字符
+func isIgnorable(r rune) bool {
+	return r == 0x3000 || unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
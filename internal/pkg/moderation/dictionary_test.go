@@ -0,0 +1,98 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDictionaryMatchOverlapsAndNormalization(t *testing.T) {
+	d := NewDictionary([]Entry{
+		{Word: "he", Category: "a"},
+		{Word: "she", Category: "a"},
+		{Word: "his", Category: "a"},
+		{Word: "hers", Category: "a"},
+		{Word: "敏感词", Category: "b"},
+		{Word: "  ", Category: "ignored"},
+	})
+	if d.Len() != 5 {
+		t.Fatalf("len = %d", d.Len())
+	}
+
+	hits := d.Match("uSHErs")
+	words := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		words = append(words, hit.Word)
+	}
+	slices.Sort(words)
+	if !slices.Equal(words, []string{"he", "hers", "she"}) {
+		t.Fatalf("words = %v", words)
+	}
+
+	text := "这是敏 感-词，还有ＳＨＥ"
+	if got := Mask(text, d.Match(text)); got != "这是* *-*，还有***" {
+		t.Fatalf("masked = %q", got)
+	}
+	if hits := d.Match("敏感"); len(hits) != 0 {
+		t.Fatalf("unexpected hits %v", hits)
+	}
+}
+
+func TestDictionaryMatchPositions(t *testing.T) {
+	d := NewDictionary([]Entry{{Word: "abc"}, {Word: "bc"}})
+	hits := d.Match("xa b c")
+	if len(hits) != 2 {
+		t.Fatalf("hits = %v", hits)
+	}
+	for _, hit := range hits {
+		switch hit.Word {
+		case "abc":
+			if hit.Start != 1 || hit.End != 6 {
+				t.Fatalf("abc at %d-%d", hit.Start, hit.End)
+			}
+		case "bc":
+			if hit.Start != 3 || hit.End != 6 {
+				t.Fatalf("bc at %d-%d", hit.Start, hit.End)
+			}
+		}
+	}
+	if Words(hits)[0] != "abc" {
+		t.Fatalf("words = %v", Words(hits))
+	}
+}
+
+func TestEmptyDictionary(t *testing.T) {
+	var d *Dictionary
+	if d.Len() != 0 || len(NewDictionary(nil).Match("anything")) != 0 {
+		t.Fatal("empty dictionary should not match")
+	}
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"flagged":true,"categories":[{"name":"abuse","score":0.95},{"name":"spam","score":0.2}]}`))
+	}))
+	defer srv.Close()
+
+	hits, err := NewHTTPClassifier(srv.URL, "k", 0.8, time.Second).Check(context.Background(), "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Category != "abuse" || hits[0].Source != SourceClassifier {
+		t.Fatalf("hits = %v", hits)
+	}
+	if got := Mask("text", hits); got != "text" {
+		t.Fatalf("classifier hits should not mask, got %q", got)
+	}
+
+	if _, err := NewHTTPClassifier(srv.URL, "", 0.8, time.Second).Check(context.Background(), "text"); err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+}
@@ -0,0 +1,4 @@
+// Package moderation provides pluggable content moderation: a sensitive-word
+// dictionary backed by an Aho–Corasick automaton and an optional adapter for
+// an external HTTP classifier.
+package moderation
@@ -0,0 +1,96 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	SourceDictionary = "dictionary"
+	SourceClassifier = "classifier"
+)
+
+// Hit 一次命中。词典命中带有原文中的位置（按 rune 计，左闭右开），分类器命中没有位置
+type Hit struct {
+	Source   string  `json:"source"`
+	Word     string  `json:"word,omitempty"`
+	Category string  `json:"category,omitempty"`
+	Start    int     `json:"start,omitempty"`
+	End      int     `json:"end,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+}
+
+// Moderator 内容审核器
+type Moderator interface {
+	// Name 审核器名称，用于日志
+	Name() string
+	// Check 返回文本中的命中，未命中时返回空切片
+	Check(ctx context.Context, text string) ([]Hit, error)
+}
+
+// Mask 将带位置的命中区间替换为 *，没有位置的命中不影响文本
+func Mask(text string, hits []Hit) string {
+	if len(hits) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	masked := false
+	for _, hit := range hits {
+		if hit.End <= hit.Start || hit.Start < 0 || hit.End > len(runes) {
+			continue
+		}
+		for i := hit.Start; i < hit.End; i++ {
+			if !isIgnorable(runes[i]) {
+				runes[i] = '*'
+				masked = true
+			}
+		}
+	}
+	if !masked {
+		return text
+	}
+	return string(runes)
+}
+
+// Words 返回命中的词或分类，去重并保持首次出现的顺序
+func Words(hits []Hit) []string {
+	words := make([]string, 0, len(hits))
+	seen := make(map[string]struct{}, len(hits))
+	for _, hit := range hits {
+		word := hit.Word
+		if word == "" {
+			word = hit.Category
+		}
+		if _, ok := seen[word]; ok || word == "" {
+			continue
+		}
+		seen[word] = struct{}{}
+		words = append(words, word)
+	}
+	return words
+}
+
+// Excerpt 截取命中附近的原文，便于审计时查看上下文
+func Excerpt(text string, hits []Hit, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	start := 0
+	for _, hit := range hits {
+		if hit.End > hit.Start {
+			start = max(hit.Start-maxRunes/4, 0)
+			break
+		}
+	}
+	end := min(start+maxRunes, len(runes))
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
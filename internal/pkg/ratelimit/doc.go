@@ -0,0 +1,24 @@
+// Package ratelimit provides a distributed sliding-window rate limiter.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result 单次限流判定结果
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter 距离窗口内最早一次请求过期（即额度恢复）的时长
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow 在 window 时间窗口内最多允许 limit 次请求，允许时会计入本次请求
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error)
+}
+
+// GlobalLimiter is a global instance of Limiter, nil when Redis is unavailable.
+var GlobalLimiter Limiter
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/redis"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var _ Limiter = (*redisLimiter)(nil)
+
+// slidingWindowScript 基于有序集合的滑动窗口日志算法，整个判定在 Redis 中原子执行。
+// KEYS[1]: 限流 key
+// ARGV[1]: 当前时间（毫秒） ARGV[2]: 窗口长度（毫秒） ARGV[3]: 窗口内最大请求数 ARGV[4]: 本次请求成员
+// 返回 {是否允许, 窗口内请求数, 距离额度恢复的毫秒数}
+var slidingWindowScript = goredis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+type redisLimiter struct {
+	cli redis.RedisClient
+}
+
+// NewRedisLimiter 创建基于Redis的限流器并设置为全局限流器
+func NewRedisLimiter(cli redis.RedisClient) Limiter {
+	limiter := &redisLimiter{cli: cli}
+	GlobalLimiter = limiter
+	return limiter
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	now := time.Now().UnixMilli()
+	values, err := slidingWindowScript.Run(ctx, r.cli.GetRedisCli(), []string{key},
+		now, window.Milliseconds(), limit, fmt.Sprintf("%d-%s", now, uuid.NewString())).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	remaining := limit - int(values[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  remaining,
+		ResetAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
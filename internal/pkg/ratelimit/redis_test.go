@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type testRedisCli struct {
+	cli goredis.UniversalClient
+}
+
+func (t *testRedisCli) GetRedisCli() goredis.UniversalClient { return t.cli }
+
+// newTestLimiter 基于 miniredis 创建限流器，不修改 GlobalLimiter
+func newTestLimiter(t *testing.T) *redisLimiter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cli.Close() })
+	return &redisLimiter{cli: &testRedisCli{cli: cli}}
+}
+
+func TestRedisLimiterSlidingWindow(t *testing.T) {
+	limiter := newTestLimiter(t)
+	ctx := context.Background()
+	window := 300 * time.Millisecond
+
+	for i := range 3 {
+		res, err := limiter.Allow(ctx, "rl:test", 3, window)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !res.Allowed || res.Limit != 3 || res.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i, res)
+		}
+		if res.ResetAfter <= 0 || res.ResetAfter > window {
+			t.Fatalf("request %d: reset after %v", i, res.ResetAfter)
+		}
+	}
+
+	res, err := limiter.Allow(ctx, "rl:test", 3, window)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("request over the limit: %+v", res)
+	}
+
+	// 其他 key 不共享计数
+	if res, err := limiter.Allow(ctx, "rl:other", 3, window); err != nil || !res.Allowed {
+		t.Fatalf("other key: %+v, %v", res, err)
+	}
+
+	// 窗口滑过最早的请求后恢复额度，被拒绝的请求不计入窗口
+	time.Sleep(window + 50*time.Millisecond)
+	res, err = limiter.Allow(ctx, "rl:test", 3, window)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("request after the window: %+v", res)
+	}
+}
@@ -48,7 +48,8 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 	authService := services.NewAuthService(db, cfg, rbacService, ca)
 	pointsService := services.NewPointsService(db)
-	reviewService := services.NewReviewService(db, pointsService)
+	moderationService := services.NewModerationService(db, cfg)
+	reviewService := services.NewReviewService(db, pointsService, moderationService)
 	courseTableService := services.NewCourseTableService(db)
 	failRateService := services.NewFailRateService(db)
 	heroService := services.NewHeroService(db)
@@ -56,19 +57,23 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	ossService := services.NewOSSService(cfg)
 	s3Service := services.NewS3Service(db, cfg)
 	notificationService := services.NewNotificationService(db, rbacService)
-	contributionService := services.NewContributionService(db, pointsService)
+	contributionService := services.NewContributionService(db, pointsService, moderationService)
 	countdownService := services.NewCountdownService(db)
 	studyTaskService := services.NewStudyTaskService(db)
 	featureService := services.NewFeatureService(db)
-	materialService := services.NewMaterialService(db)
+	materialService := services.NewMaterialService(db, moderationService)
 	questionService := services.NewQuestionService(db)
 	gpaBackupService := services.NewGPABackupService(db)
 	pomodoroService := services.NewPomodoroService(db)
 	statService := services.NewStatService(db)
 	dictionaryService := services.NewDictionaryService(db)
-	chatService := services.NewChatService(db, cfg)
+	chatService := services.NewChatService(db, cfg, rbacService, s3Service, materialService, questionService, dictionaryService, moderationService)
 	userActivityService := services.NewUserActivityService(db, rbacService)
 	organizationService := services.NewOrganizationService(db)
+	rateLimitService := services.NewRateLimitService(db, rbacService)
+	llmUsageService := services.NewLLMUsageService(db, rbacService, pointsService)
+	mcpAuditService := services.NewMCPAuditService(db)
+	mcpServerService := services.NewMCPServerService(db, cfg)
 
 	// 初始化处理器
 	rbacHandler := handlers.NewRBACHandler(rbacService)
@@ -92,7 +97,11 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	pomodoroHandler := handlers.NewPomodoroHandler(pomodoroService)
 	statHandler := handlers.NewStatHandler(statService)
 	dictionaryHandler := handlers.NewDictionaryHandler(dictionaryService)
-	chatHandler := handlers.NewChatHandler(chatService)
+	chatHandler := handlers.NewChatHandler(chatService, llmUsageService)
+	llmUsageHandler := handlers.NewLLMUsageHandler(llmUsageService)
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	mcpAuditHandler := handlers.NewMCPAuditHandler(mcpAuditService)
+	mcpServerHandler := handlers.NewMCPServerHandler(mcpServerService)
 	userActivityHandler := handlers.NewUserActivityHandler(userActivityService)
 	organizationHandler := handlers.NewOrganizationHandler(organizationService)
 
@@ -122,10 +131,35 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			failRateService,
 			countdownService,
 			studyTaskService,
+			materialService,
+			questionService,
+			pointsService,
+			gpaBackupService,
+			pomodoroService,
+			dictionaryService,
+			organizationService,
+			contributionService,
+			mcpAuditService,
 		)
 		mcpGroup.Any("", mcpHandler.Handle)
 	}
 
+	// 后台 MCP endpoint，供运营和管理员通过助手处理审核队列
+	adminMCPGroup := api.Group("/admin/mcp")
+	{
+		adminMCPGroup.Use(middleware.AuthMiddleware(cfg, ca))
+		adminMCPGroup.Use(middleware.RequireRole(rbacService, constant.RoleTagOperator, constant.RoleTagAdmin))
+		mcpAdminHandler := handlers.NewMCPAdminHandler(
+			rbacService,
+			reviewService,
+			contributionService,
+			notificationService,
+			statService,
+			mcpAuditService,
+		)
+		adminMCPGroup.Any("", mcpAdminHandler.Handle)
+	}
+
 	v0 := api.Group("/v0")
 	{ // 认证相关路由
 		auth := v0.Group("/auth")
@@ -160,6 +194,12 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			heroes.GET("/", heroHandler.ListAll)
 		}
 
+		// 对话分享（公开查看）
+		shares := v0.Group("/shares")
+		{
+			shares.GET("/:slug", middleware.RateLimit(rateLimitService, constant.RateLimitPolicyChatShareView), chatHandler.GetSharedConversation)
+		}
+
 		// 需要认证的路由
 		authorized := v0.Group("/")
 		authorized.Use(middleware.AuthMiddleware(cfg, ca))
@@ -195,7 +235,7 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			// 评价（需认证）
 			authReviews := authorized.Group("/reviews")
 			{
-				authReviews.POST("/", middleware.RequirePermission(rbacService, constant.PermissionReviewCreate), middleware.IdempotencyRecommended(ca), reviewHandler.CreateReview)
+				authReviews.POST("/", middleware.RequirePermission(rbacService, constant.PermissionReviewCreate), middleware.RateLimit(rateLimitService, constant.RateLimitPolicyReviewCreate), middleware.IdempotencyRecommended(ca), reviewHandler.CreateReview)
 				authReviews.GET("/user", middleware.RequirePermission(rbacService, constant.PermissionReviewGetSelf), reviewHandler.GetUserReviews)
 
 				// 管理员
@@ -296,7 +336,7 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			// 投稿（需认证）
 			contributions := authorized.Group("/contributions")
 			{
-				contributions.POST("/", middleware.RequirePermission(rbacService, constant.PermissionContributionCreate), middleware.IdempotencyRecommended(ca), contributionHandler.CreateContribution)
+				contributions.POST("/", middleware.RequirePermission(rbacService, constant.PermissionContributionCreate), middleware.RateLimit(rateLimitService, constant.RateLimitPolicyContributionCreate), middleware.IdempotencyRecommended(ca), contributionHandler.CreateContribution)
 				contributions.GET("/", middleware.RequirePermission(rbacService, constant.PermissionContributionGet), contributionHandler.GetContributions)
 				contributions.GET("/:id", middleware.RequirePermission(rbacService, constant.PermissionContributionGet), contributionHandler.GetContributionByID)
 				contributions.GET("/stats", middleware.RequirePermission(rbacService, constant.PermissionContributionGet), contributionHandler.GetUserContributionStats)
@@ -334,6 +374,8 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 			// 资料（需认证）
 			materials := authorized.Group("/materials")
+			// 下载资料按用户限流
+			downloads := materials.Group("", middleware.RateLimit(rateLimitService, constant.RateLimitPolicyMaterialDownload))
 			{
 				materials.GET("/", middleware.RequirePermission(rbacService, constant.PermissionMaterialGet), materialHandler.GetMaterialList)                     // 获取资料列表
 				materials.GET("/top", middleware.RequirePermission(rbacService, constant.PermissionMaterialGet), materialHandler.GetTopMaterials)                  // 获取热门资料
@@ -341,7 +383,7 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				materials.GET("/search", middleware.RequirePermission(rbacService, constant.PermissionMaterialGet), materialHandler.SearchMaterials)               // 搜索资料
 				materials.GET("/:md5", middleware.RequirePermission(rbacService, constant.PermissionMaterialGet), materialHandler.GetMaterialDetail)               // 获取资料详情
 				materials.POST("/:md5/rating", middleware.RequirePermission(rbacService, constant.PermissionMaterialRate), materialHandler.RateMaterial)           // 资料评分
-				materials.POST("/:md5/download", middleware.RequirePermission(rbacService, constant.PermissionMaterialDownload), materialHandler.DownloadMaterial) // 下载资料
+				downloads.POST("/:md5/download", middleware.RequirePermission(rbacService, constant.PermissionMaterialDownload), materialHandler.DownloadMaterial) // 下载资料
 			}
 
 			// 资料分类（需认证）
@@ -390,14 +432,32 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 			// 聊天对话相关路由（需认证）
 			chat := authorized.Group("/chat", middleware.RequirePermission(rbacService, constant.PermissionChatStudy))
+			// 调用模型生成回复的接口按对话策略限流
+			chatLLM := chat.Group("", middleware.RateLimit(rateLimitService, constant.RateLimitPolicyChatConversation))
 			{
 				chat.POST("/conversations", middleware.IdempotencyRecommended(ca), chatHandler.CreateConversation) // 创建对话
 				chat.GET("/conversations", chatHandler.ListConversations)                                          // 列出对话
+				chat.GET("/conversations/search", chatHandler.SearchConversations)                                 // 全文检索对话
 				chat.GET("/conversations/:id", chatHandler.ChooseConversation)                                     // 选择对话（返回历史消息）
 				chat.PUT("/conversations/:id", chatHandler.UpdateConversation)                                     // 更新对话
 				chat.DELETE("/conversations/:id", chatHandler.DeleteConversation)                                  // 删除对话
 				chat.GET("/conversations/:id/export", chatHandler.ExportConversation)                              // 导出对话
-				chat.POST("/conversation", chatHandler.StreamConversation)                                         // 流式对话
+				chat.GET("/conversations/:id/stream", chatHandler.ReattachStream)                                  // 断线续传（SSE）
+				chat.PUT("/conversations/:id/model", chatHandler.SetConversationModel)                             // 为对话指定模型
+				chatLLM.POST("/conversations/:id/messages/:message_id/regenerate", chatHandler.RegenerateMessage)  // 从指定消息重新生成回复
+				chat.PUT("/conversations/:id/active-branch", chatHandler.SwitchBranch)                             // 切换当前分支
+				chat.PUT("/conversations/:id/messages/:message_id/feedback", chatHandler.SubmitFeedback)           // 评价助手回答
+				chat.DELETE("/conversations/:id/messages/:message_id/feedback", chatHandler.DeleteFeedback)        // 撤回评价
+				chat.POST("/attachments", chatHandler.UploadAttachment)                                            // 上传对话附件
+				chat.POST("/conversations/:id/shares", chatHandler.CreateShare)                                    // 创建分享链接
+				chat.GET("/shares", chatHandler.ListShares)                                                        // 我的分享
+				chat.DELETE("/shares/:slug", chatHandler.RevokeShare)                                              // 撤销分享
+				chat.GET("/models", chatHandler.ListChatModels)                                                    // 可选模型列表
+				chat.GET("/personas", chatHandler.ListPersonas)                                                    // 可选助手人设
+				chatLLM.POST("/conversation", chatHandler.StreamConversation)                                      // 流式对话
+				chat.GET("/usage", llmUsageHandler.GetMyUsage)                                                     // 当日用量与额度
+				chat.POST("/usage/quota", middleware.RequirePermission(rbacService, constant.PermissionPointSpend),
+					middleware.IdempotencyRecommended(ca), llmUsageHandler.PurchaseQuota) // 使用积分购买额外额度
 			}
 
 			// 通知管理路由（需要运营权限）
@@ -461,6 +521,72 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				adminStats.GET("/studytasks/by-user", statHandler.GetStudyTaskCountsByUser)
 				adminStats.GET("/gpa-backups/by-user", statHandler.GetGPABackupCountsByUser)
 				adminStats.GET("/projects/online", statHandler.GetAllProjectsOnlineCount) // 获取所有启用项目在线人数
+				adminStats.GET("/llm-usage", llmUsageHandler.GetUsageReport)              // AI 对话用量报表
+			}
+
+			// 对话批量导出（管理员，包含全部用户的对话内容）
+			adminChatExport := authorized.Group("/admin/chat/conversations")
+			adminChatExport.Use(middleware.RequirePermission(rbacService, constant.PermissionChatExport))
+			{
+				adminChatExport.GET("/export", chatHandler.ExportConversationsArchive) // 批量导出匿名化对话（ZIP）
+			}
+
+			// 助手人设管理（管理员）
+			adminPersonas := authorized.Group("/admin/chat/personas")
+			adminPersonas.Use(middleware.RequirePermission(rbacService, constant.PermissionConfigManage))
+			{
+				adminPersonas.GET("", chatHandler.AdminListPersonas)
+				adminPersonas.POST("", middleware.IdempotencyRecommended(ca), chatHandler.AdminCreatePersona)
+				adminPersonas.PUT("/:id", chatHandler.AdminUpdatePersona)
+				adminPersonas.DELETE("/:id", chatHandler.AdminDeletePersona)
+				adminPersonas.GET("/:id/versions", chatHandler.AdminListPersonaVersions) // 提示词历史版本
+			}
+
+			// 管理员回答评价复核
+			adminFeedback := authorized.Group("/admin/chat/feedback")
+			adminFeedback.Use(middleware.RequirePermission(rbacService, constant.PermissionStatisticManage))
+			{
+				adminFeedback.GET("", chatHandler.AdminListFeedback)              // 差评复核队列
+				adminFeedback.PUT("/:id/review", chatHandler.AdminReviewFeedback) // 处理评价
+				adminFeedback.GET("/stats", chatHandler.AdminFeedbackStats)       // 按人设/模型/工具统计
+			}
+
+			// 内容审核：敏感词与策略（管理员）
+			adminModeration := authorized.Group("/admin/moderation")
+			adminModeration.Use(middleware.RequirePermission(rbacService, constant.PermissionConfigManage))
+			{
+				adminModeration.GET("/words", moderationHandler.ListSensitiveWords)
+				adminModeration.POST("/words", middleware.IdempotencyRecommended(ca), moderationHandler.CreateSensitiveWords)
+				adminModeration.PUT("/words/:id", moderationHandler.UpdateSensitiveWord)
+				adminModeration.DELETE("/words/:id", moderationHandler.DeleteSensitiveWord)
+				adminModeration.GET("/policy", moderationHandler.GetPolicy) // 当前生效的各场景策略
+				adminModeration.POST("/check", moderationHandler.CheckText) // 试审文本
+			}
+
+			// 内容审核：命中记录与人工复核（管理员）
+			adminModerationRecords := authorized.Group("/admin/moderation/records")
+			adminModerationRecords.Use(middleware.RequirePermission(rbacService, constant.PermissionReviewManage))
+			{
+				adminModerationRecords.GET("", moderationHandler.ListRecords)
+				adminModerationRecords.PUT("/:id/review", moderationHandler.ReviewRecord)
+			}
+
+			// MCP 工具调用审计（管理员）
+			adminMCPToolCalls := authorized.Group("/admin/mcp/tool-calls")
+			adminMCPToolCalls.Use(middleware.RequirePermission(rbacService, constant.PermissionStatisticManage))
+			{
+				adminMCPToolCalls.GET("", mcpAuditHandler.ListToolCalls)
+			}
+
+			// 外部 MCP 服务管理（管理员）
+			adminMCPServers := authorized.Group("/admin/mcp/servers")
+			adminMCPServers.Use(middleware.RequirePermission(rbacService, constant.PermissionConfigManage))
+			{
+				adminMCPServers.GET("", mcpServerHandler.ListServers)
+				adminMCPServers.POST("", middleware.IdempotencyRecommended(ca), mcpServerHandler.CreateServer)
+				adminMCPServers.PUT("/:id", mcpServerHandler.UpdateServer)
+				adminMCPServers.DELETE("/:id", mcpServerHandler.DeleteServer)
+				adminMCPServers.POST("/:id/health", mcpServerHandler.CheckServerHealth) // 立即健康检查
 			}
 
 			// 通知管理（管理员）
@@ -539,6 +665,12 @@ func NewRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				rbacAdmin.POST("/roles/:id/permissions", rbacHandler.UpdateRolePermissions)
 				rbacAdmin.POST("/users/:id/roles", rbacHandler.UpdateUserRoles)
 				rbacAdmin.GET("/users/:id/permissions", rbacHandler.GetUserPermissions)
+				rbacAdmin.POST("/users/:id/role-grants", rbacHandler.GrantUserRole)             // 授予单个角色（支持临时授权）
+				rbacAdmin.DELETE("/users/:id/role-grants/:role_id", rbacHandler.RevokeUserRole) // 撤销单个角色
+				rbacAdmin.GET("/role-grants/expiring", rbacHandler.ListExpiringRoleGrants)      // 即将到期的临时授权
+				rbacAdmin.GET("/policy", rbacHandler.ExportPolicy)                              // 导出 YAML 策略文件
+				rbacAdmin.POST("/policy/diff", rbacHandler.DiffPolicy)                          // 预览策略差异（dry run）
+				rbacAdmin.POST("/policy/apply", rbacHandler.ApplyPolicy)                        // 原子应用策略文件
 			}
 
 			// 资料管理（管理员）
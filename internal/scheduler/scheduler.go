@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/services"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
@@ -18,10 +19,12 @@ type Scheduler struct {
 	db                  *gorm.DB
 	materialService     *services.MaterialService
 	userActivityService *services.UserActivityService
+	rbacService         *services.RBACService
+	mcpServerService    *services.MCPServerService
 }
 
 // NewScheduler 创建新的调度器实例
-func NewScheduler(db *gorm.DB) *Scheduler {
+func NewScheduler(db *gorm.DB, cfg *config.Config) *Scheduler {
 	// 使用中国时区
 	// 使用内置日志
 	c := cron.New()
@@ -32,8 +35,10 @@ func NewScheduler(db *gorm.DB) *Scheduler {
 	return &Scheduler{
 		cron:                c,
 		db:                  db,
-		materialService:     services.NewMaterialService(db),
+		materialService:     services.NewMaterialService(db, nil),
 		userActivityService: userActivityService,
+		rbacService:         rbacService,
+		mcpServerService:    services.NewMCPServerService(db, cfg),
 	}
 }
 
@@ -107,7 +112,60 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
-	logger.Info("定时任务调度器已启动，热度计算任务将在每天凌晨2点执行，活跃用户角色更新任务将在每天凌晨3点执行，在线用户数据清理任务将每小时执行")
+	// 添加每5分钟执行过期临时角色撤销任务
+	// cron表达式: "*/5 * * * *" 表示每5分钟执行一次
+	_, err = s.cron.AddFunc("*/5 * * * *", func() {
+		ctx := context.Background()
+		logger.DebugCtx(ctx, map[string]any{
+			"task":   "expired_role_revocation",
+			"status": "started",
+		})
+		revoked, err := s.rbacService.RevokeExpiredRoles(ctx)
+		if err != nil {
+			logger.ErrorCtx(ctx, map[string]any{
+				"task":   "expired_role_revocation",
+				"status": "failed",
+				"error":  err.Error(),
+			})
+		} else {
+			logger.DebugCtx(ctx, map[string]any{
+				"task":    "expired_role_revocation",
+				"status":  "success",
+				"revoked": revoked,
+			})
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// 添加每5分钟执行外部 MCP 服务健康检查任务，检查失败的服务在对话中不加载
+	_, err = s.cron.AddFunc("*/5 * * * *", func() {
+		ctx := context.Background()
+		logger.DebugCtx(ctx, map[string]any{
+			"task":   "mcp_server_health_check",
+			"status": "started",
+		})
+		if err := s.mcpServerService.CheckAllServersHealth(ctx); err != nil {
+			logger.ErrorCtx(ctx, map[string]any{
+				"task":   "mcp_server_health_check",
+				"status": "failed",
+				"error":  err.Error(),
+			})
+		} else {
+			logger.DebugCtx(ctx, map[string]any{
+				"task":   "mcp_server_health_check",
+				"status": "success",
+			})
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("定时任务调度器已启动，热度计算任务将在每天凌晨2点执行，活跃用户角色更新任务将在每天凌晨3点执行，在线用户数据清理任务将每小时执行，过期临时角色撤销和外部 MCP 服务健康检查任务将每5分钟执行")
 	s.cron.Start()
 	return nil
 }
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/cloudwego/eino/schema"
+)
+
+const harnessUserToken = "user-token"
+
+func TestStreamChatE2EToolFailureBecomesToolResult(t *testing.T) {
+	llm := newStubLLMServer(t,
+		stubLLMTurn{
+			Reasoning: "需要先查课表和成绩",
+			ToolCalls: []stubLLMToolCall{
+				{ID: "call_schedule", Name: "get_schedule", Arguments: `{"semester":"2025-1"}`},
+				{ID: "call_grades", Name: "query_grades", Arguments: `{"semester":"2025-1"}`},
+			},
+			Usage: &schema.TokenUsage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25},
+		},
+		stubLLMTurn{
+			Content: "周一有高数，成绩暂时查不到",
+			Usage:   &schema.TokenUsage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50},
+		},
+	)
+	mcpServer := newFakeMCPServer(t, registerScheduleAndGradeTools)
+	h := newChatHarness(t, llm, mcpServer)
+	conv := h.createConversation(t, 7)
+
+	outputChan, errChan, err := h.service.StreamChat(context.Background(), 7, conv.ID,
+		schema.UserMessage("我周一有什么课，成绩出了吗"), nil, harnessUserToken, "", "")
+	if err != nil {
+		t.Fatalf("StreamChat: %v", err)
+	}
+	events, runErr := streamEvents(t, outputChan, errChan)
+	if runErr != nil {
+		t.Fatalf("unexpected run error: %v", runErr)
+	}
+
+	if got := joinedContent(events, "reasoning"); got != "需要先查课表和成绩" {
+		t.Fatalf("unexpected reasoning: %q", got)
+	}
+	if got := len(eventsOfType(events, "tool_call")); got < 2 {
+		t.Fatalf("expected tool_call events for both tools, got %d", got)
+	}
+	results := map[string]string{}
+	for _, e := range eventsOfType(events, "tool_result") {
+		results[e["tool_call_id"].(string)] = e["content"].(string)
+	}
+	if !strings.Contains(results["call_schedule"], "周一 高等数学") {
+		t.Fatalf("unexpected schedule result: %q", results["call_schedule"])
+	}
+	if !strings.Contains(results["call_grades"], `"success":false`) || !strings.Contains(results["call_grades"], "教务系统维护中") {
+		t.Fatalf("tool failure should be returned as result, got %q", results["call_grades"])
+	}
+	if got := joinedContent(events, "content"); got != "周一有高数，成绩暂时查不到" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	end := eventsOfType(events, "end")
+	if len(end) != 1 {
+		t.Fatalf("expected one end event, got %#v", events)
+	}
+	if usage, _ := end[0]["usage"].(map[string]any); usage["total_tokens"] != float64(50) {
+		t.Fatalf("unexpected end usage: %#v", end[0]["usage"])
+	}
+
+	if calls := mcpServer.Calls(); len(calls) != 2 {
+		t.Fatalf("expected both mcp tools to be called, got %v", calls)
+	}
+	for _, header := range mcpServer.AuthHeaders() {
+		if header != "Bearer "+harnessUserToken {
+			t.Fatalf("mcp request should carry user token, got %q", header)
+		}
+	}
+
+	// 第二次模型调用应带上两个工具结果
+	requests := llm.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 llm requests, got %d", len(requests))
+	}
+	if len(requests[0].Tools) != 2 {
+		t.Fatalf("expected mcp tools to be offered to the model, got %#v", requests[0].Tools)
+	}
+	var toolMessages int
+	for _, m := range requests[1].Messages {
+		if m.Role == "tool" {
+			toolMessages++
+		}
+	}
+	if toolMessages != 2 {
+		t.Fatalf("expected 2 tool messages in follow-up request, got %d", toolMessages)
+	}
+
+	// 用户消息、工具调用、两个工具结果、最终回答依次串成一条分支
+	rows := h.messages(t, conv.ID)
+	wantRoles := []string{"user", "assistant", "tool", "tool", "assistant"}
+	if len(rows) != len(wantRoles) {
+		t.Fatalf("expected %d messages, got %d", len(wantRoles), len(rows))
+	}
+	for i, row := range rows {
+		if row.Role != wantRoles[i] {
+			t.Fatalf("message %d role = %s, want %s", i, row.Role, wantRoles[i])
+		}
+		if i > 0 && row.ParentID != rows[i-1].ID {
+			t.Fatalf("message %d parent = %d, want %d", i, row.ParentID, rows[i-1].ID)
+		}
+		if row.CheckpointID != rows[0].CheckpointID {
+			t.Fatalf("message %d checkpoint = %q, want %q", i, row.CheckpointID, rows[0].CheckpointID)
+		}
+	}
+	if rows[1].ReasoningContent != "需要先查课表和成绩" {
+		t.Fatalf("reasoning should be persisted, got %q", rows[1].ReasoningContent)
+	}
+	if rows[4].TotalTokens != 50 {
+		t.Fatalf("final answer usage should be persisted, got %d", rows[4].TotalTokens)
+	}
+
+	var saved models.Conversation
+	if err := h.db.First(&saved, conv.ID).Error; err != nil {
+		t.Fatalf("load conversation: %v", err)
+	}
+	if saved.ActiveLeafID != rows[4].ID {
+		t.Fatalf("active leaf = %d, want %d", saved.ActiveLeafID, rows[4].ID)
+	}
+
+	var usage models.LLMUsageDaily
+	if err := h.db.Where("user_id = ?", 7).First(&usage).Error; err != nil {
+		t.Fatalf("load usage: %v", err)
+	}
+	if usage.TotalTokens != 75 || usage.RequestCount != 2 {
+		t.Fatalf("unexpected daily usage: %+v", usage)
+	}
+}
+
+func TestStreamChatE2EInterruptAndResume(t *testing.T) {
+	// 删除倒数日默认需要用户确认，确认前中断
+	llm := newStubLLMServer(t,
+		stubLLMTurn{ToolCalls: []stubLLMToolCall{{ID: "call_del", Name: "countdown", Arguments: `{"action":"delete","id":3}`}}},
+		stubLLMTurn{Content: "倒数日已删除"},
+	)
+	mcpServer := newFakeMCPServer(t, registerCountdownTool)
+	h := newChatHarness(t, llm, mcpServer)
+	conv := h.createConversation(t, 8)
+
+	outputChan, errChan, err := h.service.StreamChat(context.Background(), 8, conv.ID,
+		schema.UserMessage("删掉考研倒数日"), nil, harnessUserToken, "", "")
+	if err != nil {
+		t.Fatalf("StreamChat: %v", err)
+	}
+	events, runErr := streamEvents(t, outputChan, errChan)
+	if runErr != nil {
+		t.Fatalf("unexpected run error: %v", runErr)
+	}
+	interrupts := eventsOfType(events, "interrupt")
+	if len(interrupts) != 1 {
+		t.Fatalf("expected interrupt event, got %#v", events)
+	}
+	checkpointID, _ := interrupts[0]["checkpoint_id"].(string)
+	if checkpointID == "" {
+		t.Fatalf("interrupt event should carry checkpoint_id: %#v", interrupts[0])
+	}
+	if len(eventsOfType(events, "end")) != 0 || len(eventsOfType(events, "tool_result")) != 0 {
+		t.Fatalf("interrupted turn should not run the tool or end: %#v", events)
+	}
+
+	// 中断前的工具调用消息已保存，等待恢复
+	rows := h.messages(t, conv.ID)
+	if len(rows) != 2 || rows[0].Role != "user" || rows[1].Role != "assistant" {
+		t.Fatalf("unexpected messages before resume: %+v", rows)
+	}
+	if rows[1].CheckpointID != checkpointID {
+		t.Fatalf("saved checkpoint = %q, want %q", rows[1].CheckpointID, checkpointID)
+	}
+
+	outputChan, errChan, err = h.service.StreamChat(context.Background(), 8, conv.ID, nil, nil, harnessUserToken, checkpointID, constant.ChatToolConfirmApprove)
+	if err != nil {
+		t.Fatalf("resume StreamChat: %v", err)
+	}
+	events, runErr = streamEvents(t, outputChan, errChan)
+	if runErr != nil {
+		t.Fatalf("unexpected resume error: %v", runErr)
+	}
+	if events[0]["type"] != "resume_start" || events[0]["checkpoint_id"] != checkpointID {
+		t.Fatalf("unexpected first resume event: %#v", events[0])
+	}
+	results := eventsOfType(events, "tool_result")
+	if len(results) != 1 || !strings.Contains(results[0]["content"].(string), "deleted") {
+		t.Fatalf("unexpected tool results after resume: %#v", results)
+	}
+	if got := joinedContent(events, "content"); got != "倒数日已删除" {
+		t.Fatalf("unexpected content after resume: %q", got)
+	}
+	if len(eventsOfType(events, "end")) != 1 {
+		t.Fatalf("expected end event after resume: %#v", events)
+	}
+
+	// 恢复后的消息接在中断前的分支末端
+	rows = h.messages(t, conv.ID)
+	wantRoles := []string{"user", "assistant", "tool", "assistant"}
+	if len(rows) != len(wantRoles) {
+		t.Fatalf("expected %d messages after resume, got %d", len(wantRoles), len(rows))
+	}
+	for i, row := range rows {
+		if row.Role != wantRoles[i] {
+			t.Fatalf("message %d role = %s, want %s", i, row.Role, wantRoles[i])
+		}
+		if i > 0 && row.ParentID != rows[i-1].ID {
+			t.Fatalf("message %d parent = %d, want %d", i, row.ParentID, rows[i-1].ID)
+		}
+	}
+	if len(llm.Requests()) != 2 {
+		t.Fatalf("resume should not repeat the first model call, got %d requests", len(llm.Requests()))
+	}
+}
+
+func TestStreamChatE2EModelFailover(t *testing.T) {
+	llm := newStubLLMServer(t,
+		stubLLMTurn{Status: 500},
+		stubLLMTurn{Content: "备用模型回答"},
+	)
+	h := newChatHarness(t, llm, nil, withHarnessProfiles(
+		config.LLMProfile{Name: "primary", Model: "primary-model", Priority: 1},
+		config.LLMProfile{Name: "backup", Model: "backup-model", Priority: 2},
+	))
+	conv := h.createConversation(t, 9)
+
+	outputChan, errChan, err := h.service.StreamChat(context.Background(), 9, conv.ID,
+		schema.UserMessage("你好"), nil, "", "", "")
+	if err != nil {
+		t.Fatalf("StreamChat: %v", err)
+	}
+	events, runErr := streamEvents(t, outputChan, errChan)
+	if runErr != nil {
+		t.Fatalf("unexpected run error: %v", runErr)
+	}
+	if got := joinedContent(events, "content"); got != "备用模型回答" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	requests := llm.Requests()
+	if len(requests) != 2 || requests[0].Model != "primary-model" || requests[1].Model != "backup-model" {
+		t.Fatalf("expected failover from primary to backup, got %+v", requests)
+	}
+	rows := h.messages(t, conv.ID)
+	if len(rows) != 2 || rows[1].ModelProfile != "backup" {
+		t.Fatalf("answer should record the backup profile: %+v", rows)
+	}
+}
+
+func TestStreamChatE2EModelErrorKeepsUserMessage(t *testing.T) {
+	llm := newStubLLMServer(t, stubLLMTurn{Status: 503})
+	h := newChatHarness(t, llm, nil)
+	conv := h.createConversation(t, 10)
+
+	outputChan, errChan, err := h.service.StreamChat(context.Background(), 10, conv.ID,
+		schema.UserMessage("你好"), nil, "", "", "")
+	if err != nil {
+		t.Fatalf("StreamChat: %v", err)
+	}
+	events, runErr := streamEvents(t, outputChan, errChan)
+	if runErr == nil {
+		t.Fatalf("expected run error, got events %#v", events)
+	}
+	if len(eventsOfType(events, "end")) != 0 {
+		t.Fatalf("failed turn should not end normally: %#v", events)
+	}
+
+	rows := h.messages(t, conv.ID)
+	if len(rows) != 1 || rows[0].Role != "user" {
+		t.Fatalf("only the user message should be saved: %+v", rows)
+	}
+
+	// 错误事件写入续传缓冲，重连时可以看到失败原因
+	replay, err := h.service.ReattachChatStream(context.Background(), 10, conv.ID, "")
+	if err != nil {
+		t.Fatalf("ReattachChatStream: %v", err)
+	}
+	var frames []string
+	for frame := range replay {
+		frames = append(frames, frame)
+	}
+	if !strings.Contains(strings.Join(frames, ""), `"type":"error"`) {
+		t.Fatalf("buffered stream should contain error event: %v", frames)
+	}
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/cloudwego/eino/schema"
+	"github.com/glebarez/sqlite"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 端到端对话测试的离线环境：OpenAI 兼容的脚本化模型服务、基于 mcp-go 的假 MCP 服务、
+// SQLite 数据库和内存缓存，不依赖任何外部服务。
+
+// stubLLMTurn 模型服务对一次请求的脚本化回复
+type stubLLMTurn struct {
+	Reasoning string
+	Content   string
+	ToolCalls []stubLLMToolCall
+	// Status 非 0 时直接以该状态码返回错误，不输出流
+	Status int
+	Usage  *schema.TokenUsage
+}
+
+type stubLLMToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// stubLLMRequest 模型服务收到的请求，用于断言发送给模型的上下文
+type stubLLMRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role       string `json:"role"`
+		Content    any    `json:"content"`
+		ToolCallID string `json:"tool_call_id"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// stubLLMServer 按顺序回放脚本的 OpenAI 兼容 /chat/completions 服务
+type stubLLMServer struct {
+	*httptest.Server
+	t        *testing.T
+	mu       sync.Mutex
+	turns    []stubLLMTurn
+	requests []stubLLMRequest
+}
+
+func newStubLLMServer(t *testing.T, turns ...stubLLMTurn) *stubLLMServer {
+	t.Helper()
+	s := &stubLLMServer{t: t, turns: turns}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubLLMServer) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var req stubLLMRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.t.Errorf("decode llm request: %v", err)
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.turns) == 0 {
+		s.mu.Unlock()
+		s.t.Errorf("unexpected llm request #%d", len(s.requests))
+		writeStubLLMError(w, http.StatusInternalServerError, "script exhausted")
+		return
+	}
+	turn := s.turns[0]
+	s.turns = s.turns[1:]
+	s.mu.Unlock()
+
+	if turn.Status != 0 {
+		writeStubLLMError(w, turn.Status, "scripted failure")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	for _, chunk := range turn.chunks(req.Model) {
+		data, _ := json.Marshal(chunk)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+}
+
+// chunks 将一次回复拆成多个增量：推理、逐字内容、工具调用名称与参数分开发送，最后是结束原因和用量
+func (turn stubLLMTurn) chunks(model string) []map[string]any {
+	chunk := func(delta map[string]any, finish any) map[string]any {
+		return map[string]any{
+			"id":      "chatcmpl-stub",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+	}
+
+	chunks := []map[string]any{chunk(map[string]any{"role": "assistant"}, nil)}
+	if turn.Reasoning != "" {
+		chunks = append(chunks, chunk(map[string]any{"reasoning_content": turn.Reasoning}, nil))
+	}
+	for _, r := range turn.Content {
+		chunks = append(chunks, chunk(map[string]any{"content": string(r)}, nil))
+	}
+	for i, tc := range turn.ToolCalls {
+		chunks = append(chunks,
+			chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "id": tc.ID, "type": "function",
+				"function": map[string]any{"name": tc.Name, "arguments": ""},
+			}}}, nil),
+			chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "function": map[string]any{"arguments": tc.Arguments},
+			}}}, nil),
+		)
+	}
+
+	finish := "stop"
+	if len(turn.ToolCalls) > 0 {
+		finish = "tool_calls"
+	}
+	chunks = append(chunks, chunk(map[string]any{}, finish))
+	if turn.Usage != nil {
+		chunks = append(chunks, map[string]any{
+			"id":      "chatcmpl-stub",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{},
+			"usage": map[string]any{
+				"prompt_tokens":     turn.Usage.PromptTokens,
+				"completion_tokens": turn.Usage.CompletionTokens,
+				"total_tokens":      turn.Usage.TotalTokens,
+			},
+		})
+	}
+	return chunks
+}
+
+func writeStubLLMError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": msg, "type": "server_error"},
+	})
+}
+
+func (s *stubLLMServer) Requests() []stubLLMRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stubLLMRequest(nil), s.requests...)
+}
+
+// fakeMCPServer 模拟 yqlx 自身的 /api/mcp 接口
+type fakeMCPServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	authHeaders []string
+	calls       []string
+}
+
+func newFakeMCPServer(t *testing.T, register func(s *server.MCPServer, record func(name string))) *fakeMCPServer {
+	t.Helper()
+	f := &fakeMCPServer{}
+	mcpServer := server.NewMCPServer("yqlx-fake", "1.0.0", server.WithToolCapabilities(false))
+	register(mcpServer, func(name string) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.calls = append(f.calls, name)
+	})
+
+	httpServer := server.NewStreamableHTTPServer(mcpServer,
+		server.WithHTTPContextFunc(func(ctx context.Context, r *http.Request) context.Context {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+			return ctx
+		}))
+	mux := http.NewServeMux()
+	mux.Handle("/api/mcp", httpServer)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeMCPServer) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeMCPServer) AuthHeaders() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.authHeaders...)
+}
+
+// registerScheduleAndGradeTools 注册一个正常返回的课表工具和一个始终失败的成绩工具
+func registerScheduleAndGradeTools(s *server.MCPServer, record func(name string)) {
+	s.AddTool(mcp.NewTool("get_schedule",
+		mcp.WithDescription("查询课表"),
+		mcp.WithString("semester", mcp.Description("学期")),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		record("get_schedule")
+		return mcp.NewToolResultText(fmt.Sprintf("%s 学期周一 高等数学", req.GetString("semester", ""))), nil
+	})
+	s.AddTool(mcp.NewTool("query_grades",
+		mcp.WithDescription("查询成绩"),
+		mcp.WithString("semester", mcp.Description("学期")),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		record("query_grades")
+		return mcp.NewToolResultError("教务系统维护中"), nil
+	})
+}
+
+// newHarnessDB 创建 SQLite 数据库并迁移对话及相关服务用到的模型
+func newHarnessDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "chat.db")), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err := db.Callback().Raw().Before("gorm:raw").Register("harness:sqlite_ddl", adaptMySQLDDLForSQLite); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.SystemConfig{},
+		&models.UserContribution{},
+		&models.PointsTransaction{},
+		&models.Conversation{},
+		&models.ConversationMessage{},
+		&models.LLMUsageDaily{},
+		&models.MCPToolCallAudit{},
+		&models.MCPServer{},
+		&models.S3Data{},
+		&models.ModerationRecord{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// adaptMySQLDDLForSQLite 调整模型上 MySQL 专用的建表语句：
+// 全文索引按普通索引创建；int unsigned 主键改为 INTEGER，SQLite 只对 INTEGER 主键自增。
+func adaptMySQLDDLForSQLite(tx *gorm.DB) {
+	sql := tx.Statement.SQL.String()
+	switch {
+	case strings.HasPrefix(sql, "CREATE FULLTEXT INDEX"):
+		sql = strings.Replace(sql, "CREATE FULLTEXT INDEX", "CREATE INDEX", 1)
+	case strings.HasPrefix(sql, "CREATE TABLE"):
+		sql = strings.Replace(sql, "`id` int unsigned", "`id` integer", 1)
+	default:
+		return
+	}
+	tx.Statement.SQL.Reset()
+	tx.Statement.SQL.WriteString(sql)
+}
+
+// chatHarness 组装好依赖的 ChatService
+type chatHarness struct {
+	service *ChatService
+	db      *gorm.DB
+	llm     *stubLLMServer
+	mcp     *fakeMCPServer
+}
+
+type chatHarnessOption func(cfg *config.Config)
+
+// withHarnessProfiles 使用多模型配置，所有模型都指向同一个脚本化服务
+func withHarnessProfiles(profiles ...config.LLMProfile) chatHarnessOption {
+	return func(cfg *config.Config) {
+		for i := range profiles {
+			profiles[i].BaseURL = cfg.LLM.BaseURL
+			profiles[i].APIKey = cfg.LLM.APIKey
+		}
+		cfg.LLM.Profiles = profiles
+	}
+}
+
+// newChatHarness 替换全局缓存为内存实现，mcpServer 为 nil 时不提供 MCP 工具
+func newChatHarness(t *testing.T, llm *stubLLMServer, mcpServer *fakeMCPServer, opts ...chatHarnessOption) *chatHarness {
+	t.Helper()
+	prevCache := cache.GlobalCache
+	cache.GlobalCache = newMemoryStreamCache()
+	t.Cleanup(func() { cache.GlobalCache = prevCache })
+
+	cfg := &config.Config{}
+	cfg.LLM.Model = "stub-model"
+	cfg.LLM.APIKey = "sk-stub"
+	cfg.LLM.BaseURL = llm.URL
+	cfg.Scheme = "http"
+	cfg.Host = "127.0.0.1:1"
+	if mcpServer != nil {
+		u, err := url.Parse(mcpServer.URL)
+		if err != nil {
+			t.Fatalf("parse mcp url: %v", err)
+		}
+		cfg.Host = u.Host
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	db := newHarnessDB(t)
+	return &chatHarness{
+		service: NewChatService(db, cfg, nil, nil, nil, nil, nil, nil),
+		db:      db,
+		llm:     llm,
+		mcp:     mcpServer,
+	}
+}
+
+func (h *chatHarness) createConversation(t *testing.T, userID uint) *models.Conversation {
+	t.Helper()
+	conv, err := h.service.CreateConversation(context.Background(), userID, "测试对话", 0)
+	if err != nil {
+		t.Fatalf("create conversation: %v", err)
+	}
+	return conv
+}
+
+// messages 按写入顺序返回对话的全部消息
+func (h *chatHarness) messages(t *testing.T, conversationID uint) []models.ConversationMessage {
+	t.Helper()
+	var rows []models.ConversationMessage
+	if err := h.db.Where("conversation_id = ?", conversationID).Order("id ASC").Find(&rows).Error; err != nil {
+		t.Fatalf("load messages: %v", err)
+	}
+	return rows
+}
+
+// streamEvents 读取一轮输出直到结束，返回解析后的 SSE 事件和运行错误
+func streamEvents(t *testing.T, outputChan <-chan string, errChan <-chan error) ([]map[string]any, error) {
+	t.Helper()
+	var events []map[string]any
+	timeout := time.After(30 * time.Second)
+	for outputChan != nil {
+		select {
+		case raw, ok := <-outputChan:
+			if !ok {
+				outputChan = nil
+				continue
+			}
+			for _, line := range strings.Split(raw, "\n") {
+				payload, found := strings.CutPrefix(line, "data: ")
+				if !found || strings.TrimSpace(payload) == "" {
+					continue
+				}
+				var event map[string]any
+				if err := json.Unmarshal([]byte(payload), &event); err != nil {
+					t.Fatalf("unmarshal event %q: %v", payload, err)
+				}
+				events = append(events, event)
+			}
+		case <-timeout:
+			t.Fatalf("stream did not finish, events so far: %#v", events)
+		}
+	}
+	var runErr error
+	for err := range errChan {
+		if err != nil {
+			runErr = err
+		}
+	}
+	return events, runErr
+}
+
+// eventsOfType 过滤指定类型的事件
+func eventsOfType(events []map[string]any, typ string) []map[string]any {
+	var out []map[string]any
+	for _, e := range events {
+		if e["type"] == typ {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// joinedContent 拼接指定类型事件的 content 字段
+func joinedContent(events []map[string]any, typ string) string {
+	var sb strings.Builder
+	for _, e := range eventsOfType(events, typ) {
+		if s, ok := e["content"].(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/cloudwego/eino/schema"
+	"github.com/ledongthuc/pdf"
+	"gorm.io/gorm"
+)
+
+const (
+	chatAttachmentKindImage    = "image"
+	chatAttachmentKindDocument = "document"
+
+	// chatAttachmentsExtraKey 用户消息 Extra 中保存附件引用列表的 key
+	chatAttachmentsExtraKey = "attachments"
+	// chatAttachmentPartIDKey 消息分段 Extra 中保存附件资源ID的 key
+	chatAttachmentPartIDKey = "attachment_id"
+	// chatAttachmentPartNameKey 消息分段 Extra 中保存附件文件名的 key
+	chatAttachmentPartNameKey = "file_name"
+)
+
+// normalizeAttachmentMIME 去掉 MIME 参数并转为小写，如 "text/plain; charset=utf-8" -> "text/plain"
+func normalizeAttachmentMIME(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// chatAttachmentKind 根据 MIME 类型判断附件种类及大小上限
+func chatAttachmentKind(mimeType string) (kind string, maxBytes int64, ok bool) {
+	if _, ok := constant.ChatAttachmentImageMIMETypes[mimeType]; ok {
+		return chatAttachmentKindImage, constant.ChatAttachmentMaxImageBytes, true
+	}
+	if _, ok := constant.ChatAttachmentDocumentMIMETypes[mimeType]; ok {
+		return chatAttachmentKindDocument, constant.ChatAttachmentMaxDocumentBytes, true
+	}
+	return "", 0, false
+}
+
+func chatAttachmentPath(userID uint) string {
+	return fmt.Sprintf("%s/%d", constant.ChatAttachmentPathPrefix, userID)
+}
+
+// UploadChatAttachment 上传对话附件（图片或文档），返回附件引用
+func (s *ChatService) UploadChatAttachment(ctx context.Context, userID uint, data io.ReadCloser, fileName, mimeType string, size int64) (*dto.ChatAttachmentRef, error) {
+	mimeType = normalizeAttachmentMIME(mimeType)
+	kind, maxBytes, ok := chatAttachmentKind(mimeType)
+	if !ok {
+		return nil, apperr.New(constant.ConversationAttachmentTypeUnsupported)
+	}
+	if size > maxBytes {
+		return nil, apperr.New(constant.ConversationAttachmentTooLarge)
+	}
+	data, err := sniffAttachment(data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	path := chatAttachmentPath(userID)
+	resourceID, err := s.s3Service.AddObject(ctx, data, fileName, mimeType, false, &path, map[string]string{
+		"user_id": strconv.FormatUint(uint64(userID), 10),
+		"purpose": "chat_attachment",
+	})
+	if err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":    "upload_chat_attachment",
+			"user_id":   userID,
+			"file_name": fileName,
+			"mime_type": mimeType,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	return &dto.ChatAttachmentRef{
+		ResourceID: resourceID,
+		FileName:   fileName,
+		MimeType:   mimeType,
+		Size:       size,
+		Kind:       kind,
+	}, nil
+}
+
+// sniffAttachment 按文件内容识别类型，与声明的类型不符时拒绝上传；返回的 reader 仍从文件开头读取
+func sniffAttachment(data io.ReadCloser, mimeType string) (io.ReadCloser, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(data, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, apperr.Wrap(constant.StoreFileStreamFailed, err)
+	}
+	head = head[:n]
+
+	sniffed := normalizeAttachmentMIME(http.DetectContentType(head))
+	if !slices.Contains(constant.ChatAttachmentSniffedMIMETypes[sniffed], mimeType) {
+		return nil, apperr.New(constant.ConversationAttachmentTypeUnsupported)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), data), data}, nil
+}
+
+// getOwnedAttachment 获取用户自己上传的对话附件元信息
+func (s *ChatService) getOwnedAttachment(ctx context.Context, userID uint, resourceID string) (*models.S3Data, error) {
+	var data models.S3Data
+	if err := s.db.WithContext(ctx).
+		Where("resource_id = ? AND object_key LIKE ?", resourceID, chatAttachmentPath(userID)+"/%").
+		First(&data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.ConversationAttachmentNotFound)
+		}
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get attachment: %w", err))
+	}
+	return &data, nil
+}
+
+// hasVisionModel 是否配置了支持图片输入的模型
+func (s *ChatService) hasVisionModel() bool {
+	if s.llm != nil {
+		return true
+	}
+	return slices.ContainsFunc(s.profiles, func(p config.LLMProfile) bool { return p.Vision })
+}
+
+// attachToUserMessage 将附件加入用户消息：图片以附件引用的形式保存，发送给模型前再读取内容；
+// 文档在此提取文本并分块，作为文本分段保存。附件引用列表保存在消息 Extra 中。
+func (s *ChatService) attachToUserMessage(ctx context.Context, userID uint, msg *schema.Message, resourceIDs []string) (*schema.Message, error) {
+	// 客户端不能直接引用附件，附件只能通过 resourceIDs 在校验归属后加入
+	msg = stripClientAttachmentRefs(msg)
+	if len(resourceIDs) == 0 {
+		return msg, nil
+	}
+	seen := make(map[string]struct{}, len(resourceIDs))
+	resourceIDs = slices.DeleteFunc(slices.Clone(resourceIDs), func(id string) bool {
+		_, dup := seen[id]
+		seen[id] = struct{}{}
+		return dup
+	})
+	if len(resourceIDs) > constant.ChatAttachmentMaxCount {
+		return nil, apperr.New(constant.ConversationAttachmentTooMany)
+	}
+
+	out := *msg
+	out.UserInputMultiContent = slices.Clone(msg.UserInputMultiContent)
+	refs := make([]dto.ChatAttachmentRef, 0, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		data, err := s.getOwnedAttachment(ctx, userID, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		mimeType := normalizeAttachmentMIME(data.MimeType)
+		kind, maxBytes, ok := chatAttachmentKind(mimeType)
+		if !ok {
+			return nil, apperr.New(constant.ConversationAttachmentTypeUnsupported)
+		}
+		if data.FileSize > maxBytes {
+			return nil, apperr.New(constant.ConversationAttachmentTooLarge)
+		}
+
+		ref := dto.ChatAttachmentRef{
+			ResourceID: resourceID,
+			FileName:   data.FileName,
+			MimeType:   mimeType,
+			Size:       data.FileSize,
+			Kind:       kind,
+		}
+		partExtra := map[string]any{
+			chatAttachmentPartIDKey:   resourceID,
+			chatAttachmentPartNameKey: data.FileName,
+		}
+
+		switch kind {
+		case chatAttachmentKindImage:
+			if !s.hasVisionModel() {
+				return nil, apperr.New(constant.ConversationAttachmentVisionUnavailable)
+			}
+			url := constant.ChatAttachmentURLScheme + resourceID
+			out.UserInputMultiContent = append(out.UserInputMultiContent, schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				Image: &schema.MessageInputImage{
+					MessagePartCommon: schema.MessagePartCommon{URL: &url, MIMEType: mimeType},
+				},
+				Extra: partExtra,
+			})
+		case chatAttachmentKindDocument:
+			content, err := s.readAttachment(ctx, resourceID, maxBytes)
+			if err != nil {
+				return nil, err
+			}
+			text, err := extractAttachmentText(mimeType, content)
+			if err != nil {
+				logger.WarnCtx(ctx, map[string]any{
+					"action":      "chat_attachment_extract",
+					"user_id":     userID,
+					"resource_id": resourceID,
+					"mime_type":   mimeType,
+					"error":       err.Error(),
+				})
+				return nil, apperr.New(constant.ConversationAttachmentExtractFailed)
+			}
+			chunks, truncated := chunkAttachmentText(text, constant.ChatAttachmentMaxDocumentRunes, constant.ChatAttachmentChunkRunes)
+			for i, chunk := range chunks {
+				out.UserInputMultiContent = append(out.UserInputMultiContent, schema.MessageInputPart{
+					Type:  schema.ChatMessagePartTypeText,
+					Text:  fmt.Sprintf("【附件 %s 第 %d/%d 段】\n%s", data.FileName, i+1, len(chunks), chunk),
+					Extra: partExtra,
+				})
+			}
+			ref.Chunks = len(chunks)
+			ref.Truncated = truncated
+		}
+		refs = append(refs, ref)
+	}
+
+	out.Extra = maps.Clone(msg.Extra)
+	if out.Extra == nil {
+		out.Extra = make(map[string]any, 1)
+	}
+	out.Extra[chatAttachmentsExtraKey] = refs
+	return &out, nil
+}
+
+// stripClientAttachmentRefs 移除客户端消息中伪造的附件引用
+func stripClientAttachmentRefs(msg *schema.Message) *schema.Message {
+	isRef := func(part schema.MessageInputPart) bool {
+		return part.Image != nil && part.Image.URL != nil && strings.HasPrefix(*part.Image.URL, constant.ChatAttachmentURLScheme)
+	}
+	_, hasExtra := msg.Extra[chatAttachmentsExtraKey]
+	if !hasExtra && !slices.ContainsFunc(msg.UserInputMultiContent, isRef) {
+		return msg
+	}
+	out := *msg
+	out.UserInputMultiContent = slices.DeleteFunc(slices.Clone(msg.UserInputMultiContent), isRef)
+	if hasExtra {
+		out.Extra = maps.Clone(msg.Extra)
+		delete(out.Extra, chatAttachmentsExtraKey)
+	}
+	return &out
+}
+
+// readAttachment 读取附件内容，超过 maxBytes 视为超出限制
+func (s *ChatService) readAttachment(ctx context.Context, resourceID string, maxBytes int64) ([]byte, error) {
+	obj, _, err := s.s3Service.GetObject(ctx, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	content, err := io.ReadAll(io.LimitReader(obj, maxBytes+1))
+	if err != nil {
+		return nil, apperr.Wrap(constant.StoreFileStreamFailed, err)
+	}
+	if int64(len(content)) > maxBytes {
+		return nil, apperr.New(constant.ConversationAttachmentTooLarge)
+	}
+	return content, nil
+}
+
+// extractAttachmentText 提取文档文本，PDF 解析失败（包括解析库 panic）时返回错误
+func extractAttachmentText(mimeType string, content []byte) (text string, err error) {
+	if mimeType == "application/pdf" {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("parse pdf: %v", r)
+			}
+		}()
+		reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return "", fmt.Errorf("open pdf: %w", err)
+		}
+		plain, err := reader.GetPlainText()
+		if err != nil {
+			return "", fmt.Errorf("extract pdf text: %w", err)
+		}
+		raw, err := io.ReadAll(plain)
+		if err != nil {
+			return "", fmt.Errorf("read pdf text: %w", err)
+		}
+		content = raw
+	}
+
+	text = strings.TrimSpace(strings.ToValidUTF8(string(content), ""))
+	if text == "" {
+		return "", errors.New("no text content")
+	}
+	return text, nil
+}
+
+// chunkAttachmentText 将文本截断到 maxRunes 后按 chunkRunes 分块，尽量在换行处断开
+func chunkAttachmentText(text string, maxRunes, chunkRunes int) (chunks []string, truncated bool) {
+	runes := []rune(text)
+	if len(runes) > maxRunes {
+		runes = runes[:maxRunes]
+		truncated = true
+	}
+	for len(runes) > 0 {
+		end := min(chunkRunes, len(runes))
+		if end < len(runes) {
+			// 在后半段寻找换行作为断点，避免把一行拆开
+			if idx := lastRuneIndex(runes[end/2:end], '\n'); idx >= 0 {
+				end = end/2 + idx + 1
+			}
+		}
+		if chunk := strings.TrimSpace(string(runes[:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		runes = runes[end:]
+	}
+	return chunks, truncated
+}
+
+func lastRuneIndex(runes []rune, target rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// prepareAttachmentsForModel 将历史消息中的附件转换为模型可用的输入：
+// 用户文本移入首个文本分段，最近的图片读取为 base64，更早或读取失败的图片以文字占位。
+func (s *ChatService) prepareAttachmentsForModel(ctx context.Context, messages []*schema.Message) []*schema.Message {
+	imageBudget := constant.ChatAttachmentMaxHistoryImages
+	out := slices.Clone(messages)
+	for i := len(out) - 1; i >= 0; i-- {
+		msg := out[i]
+		if msg == nil || len(msg.UserInputMultiContent) == 0 {
+			continue
+		}
+
+		prepared := *msg
+		parts := make([]schema.MessageInputPart, 0, len(msg.UserInputMultiContent)+1)
+		if msg.Content != "" {
+			parts = append(parts, schema.MessageInputPart{Type: schema.ChatMessagePartTypeText, Text: msg.Content})
+			prepared.Content = ""
+		}
+		for _, part := range msg.UserInputMultiContent {
+			if part.Type != schema.ChatMessagePartTypeImageURL || part.Image == nil || part.Image.URL == nil ||
+				!strings.HasPrefix(*part.Image.URL, constant.ChatAttachmentURLScheme) {
+				parts = append(parts, part)
+				continue
+			}
+
+			resourceID := strings.TrimPrefix(*part.Image.URL, constant.ChatAttachmentURLScheme)
+			fileName, _ := part.Extra[chatAttachmentPartNameKey].(string)
+			placeholder := schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeText,
+				Text: fmt.Sprintf("[图片附件：%s]", fileName),
+			}
+			if imageBudget <= 0 {
+				parts = append(parts, placeholder)
+				continue
+			}
+			content, err := s.readAttachment(ctx, resourceID, constant.ChatAttachmentMaxImageBytes)
+			if err != nil {
+				logger.WarnCtx(ctx, map[string]any{
+					"action":      "chat_attachment_load_image",
+					"resource_id": resourceID,
+					"error":       err.Error(),
+				})
+				parts = append(parts, placeholder)
+				continue
+			}
+			imageBudget--
+			data := base64.StdEncoding.EncodeToString(content)
+			parts = append(parts, schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				Image: &schema.MessageInputImage{
+					MessagePartCommon: schema.MessagePartCommon{Base64Data: &data, MIMEType: part.Image.MIMEType},
+				},
+			})
+		}
+		prepared.UserInputMultiContent = parts
+		out[i] = &prepared
+	}
+	return out
+}
+
+// chatAttachmentRefs 读取用户消息 Extra 中的附件引用列表
+func chatAttachmentRefs(msg *schema.Message) []dto.ChatAttachmentRef {
+	refs, _ := msg.Extra[chatAttachmentsExtraKey].([]dto.ChatAttachmentRef)
+	return refs
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeAttachmentStore 只实现 GetObject 与 AddObject，按资源ID读写内存中的内容
+type fakeAttachmentStore struct {
+	S3ServiceInterface
+	objects map[string][]byte
+}
+
+func (f *fakeAttachmentStore) AddObject(ctx context.Context, data io.ReadCloser, fileName string, mimeType string, isAdmin bool, customPath *string, tags map[string]string) (string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	resourceID := fmt.Sprintf("res-%d", len(f.objects)+1)
+	f.objects[resourceID] = content
+	return resourceID, nil
+}
+
+func (f *fakeAttachmentStore) GetObject(ctx context.Context, resourceID string) (io.ReadCloser, *models.S3Data, error) {
+	content, ok := f.objects[resourceID]
+	if !ok {
+		return nil, nil, errors.New("not found")
+	}
+	return io.NopCloser(strings.NewReader(string(content))), &models.S3Data{ResourceID: resourceID}, nil
+}
+
+func attachmentImagePart(resourceID, fileName string) schema.MessageInputPart {
+	url := constant.ChatAttachmentURLScheme + resourceID
+	return schema.MessageInputPart{
+		Type: schema.ChatMessagePartTypeImageURL,
+		Image: &schema.MessageInputImage{
+			MessagePartCommon: schema.MessagePartCommon{URL: &url, MIMEType: "image/png"},
+		},
+		Extra: map[string]any{chatAttachmentPartIDKey: resourceID, chatAttachmentPartNameKey: fileName},
+	}
+}
+
+func TestChunkAttachmentText(t *testing.T) {
+	text := strings.Repeat("第一行内容\n", 30) + strings.Repeat("长", 100)
+
+	chunks, truncated := chunkAttachmentText(text, 1000, 40)
+	if truncated {
+		t.Fatal("text within limit should not be truncated")
+	}
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if n := utf8.RuneCountInString(chunk); n > 40 {
+			t.Fatalf("chunk exceeds size: %d", n)
+		}
+		if strings.HasPrefix(chunk, "行") || strings.HasPrefix(chunk, "内容") {
+			t.Fatalf("chunk should break at line boundary, got %q", chunk)
+		}
+	}
+	if got := strings.Join(chunks, ""); strings.ReplaceAll(text, "\n", "") != strings.ReplaceAll(got, "\n", "") {
+		t.Fatal("chunks should preserve the text")
+	}
+
+	chunks, truncated = chunkAttachmentText(strings.Repeat("字", 95), 50, 20)
+	if !truncated {
+		t.Fatal("text over limit should be truncated")
+	}
+	if len(chunks) != 3 || utf8.RuneCountInString(strings.Join(chunks, "")) != 50 {
+		t.Fatalf("unexpected truncated chunks %v", chunks)
+	}
+}
+
+func TestExtractAttachmentText(t *testing.T) {
+	text, err := extractAttachmentText("text/plain", []byte("  高数作业\xff第一题  "))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "高数作业第一题" {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	if _, err := extractAttachmentText("text/plain", []byte("   ")); err == nil {
+		t.Fatal("empty document should fail")
+	}
+	if _, err := extractAttachmentText("application/pdf", []byte("not a pdf")); err == nil {
+		t.Fatal("invalid pdf should fail")
+	}
+}
+
+func TestStripClientAttachmentRefs(t *testing.T) {
+	msg := schema.UserMessage("看看这张图")
+	msg.UserInputMultiContent = []schema.MessageInputPart{
+		{Type: schema.ChatMessagePartTypeText, Text: "补充说明"},
+		attachmentImagePart("someone-else", "a.png"),
+	}
+	msg.Extra = map[string]any{chatAttachmentsExtraKey: "forged", "other": 1}
+
+	got := stripClientAttachmentRefs(msg)
+	if len(got.UserInputMultiContent) != 1 || got.UserInputMultiContent[0].Text != "补充说明" {
+		t.Fatalf("forged attachment part should be removed, got %+v", got.UserInputMultiContent)
+	}
+	if _, ok := got.Extra[chatAttachmentsExtraKey]; ok {
+		t.Fatal("forged attachment refs should be removed")
+	}
+	if len(msg.UserInputMultiContent) != 2 {
+		t.Fatal("original message should not be modified")
+	}
+}
+
+func TestPrepareAttachmentsForModel(t *testing.T) {
+	store := &fakeAttachmentStore{objects: map[string][]byte{
+		"img-1": []byte("one"), "img-2": []byte("two"), "img-3": []byte("three"), "img-4": []byte("four"),
+	}}
+	service := &ChatService{s3Service: store}
+
+	older := schema.UserMessage("第一张")
+	older.UserInputMultiContent = []schema.MessageInputPart{attachmentImagePart("img-1", "old.png")}
+	latest := schema.UserMessage("这几道题怎么做")
+	latest.UserInputMultiContent = []schema.MessageInputPart{
+		attachmentImagePart("img-2", "a.png"),
+		attachmentImagePart("img-3", "b.png"),
+		attachmentImagePart("missing", "c.png"),
+		attachmentImagePart("img-4", "d.png"),
+	}
+	input := []*schema.Message{older, schema.AssistantMessage("好的", nil), latest}
+
+	out := service.prepareAttachmentsForModel(context.Background(), input)
+
+	parts := out[2].UserInputMultiContent
+	if out[2].Content != "" || parts[0].Type != schema.ChatMessagePartTypeText || parts[0].Text != "这几道题怎么做" {
+		t.Fatalf("user text should move into the first part, got %+v", out[2])
+	}
+	if parts[1].Image == nil || parts[1].Image.Base64Data == nil || *parts[1].Image.Base64Data != "dHdv" {
+		t.Fatalf("expected img-2 to be inlined, got %+v", parts[1])
+	}
+	if parts[3].Type != schema.ChatMessagePartTypeText || !strings.Contains(parts[3].Text, "c.png") {
+		t.Fatalf("unreadable image should become a placeholder, got %+v", parts[3])
+	}
+	if parts[4].Image == nil || parts[4].Image.Base64Data == nil {
+		t.Fatalf("expected img-4 to be inlined, got %+v", parts[4])
+	}
+
+	// 历史图片超出上限后以文字占位
+	oldParts := out[0].UserInputMultiContent
+	if oldParts[1].Type != schema.ChatMessagePartTypeText || !strings.Contains(oldParts[1].Text, "old.png") {
+		t.Fatalf("older image over budget should become a placeholder, got %+v", oldParts[1])
+	}
+	if latest.Content != "这几道题怎么做" || latest.UserInputMultiContent[0].Image.Base64Data != nil {
+		t.Fatal("input messages should not be modified")
+	}
+}
+
+func TestFailoverChatModelRoutesImagesToVisionModels(t *testing.T) {
+	textOnly := &scriptedStreamModel{chunks: []string{"text"}}
+	vision := &scriptedStreamModel{chunks: []string{"vision"}}
+	model := &failoverChatModel{
+		candidates: []chatModelCandidate{
+			{name: "text", model: textOnly},
+			{name: "vision", model: vision, vision: true},
+		},
+		health: newModelHealthTracker(),
+	}
+
+	msg := schema.UserMessage("")
+	msg.UserInputMultiContent = []schema.MessageInputPart{attachmentImagePart("img", "a.png")}
+	got, err := model.Generate(context.Background(), []*schema.Message{msg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Content != "vision" || textOnly.calls != 0 {
+		t.Fatalf("image input should only go to vision models, got %q (text calls %d)", got.Content, textOnly.calls)
+	}
+
+	model.candidates = model.candidates[:1]
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := model.Stream(ctx, []*schema.Message{msg}); err == nil {
+		t.Fatal("expected error without vision models")
+	}
+}
+
+func TestStreamChatModeratesAttachedDocumentText(t *testing.T) {
+	h := newChatHarness(t, newStubLLMServer(t), nil)
+	moderation := newTestModerationService(ModerationPolicy{
+		constant.ModerationSurfaceChatInput: {Enabled: true, Action: constant.ModerationActionBlock},
+	}, "坏词")
+	moderation.db = h.db
+	h.service.moderationService = moderation
+	h.service.s3Service = &fakeAttachmentStore{objects: map[string][]byte{"doc-1": []byte("笔记里有坏词")}}
+	if err := h.db.Create(&models.S3Data{
+		ResourceID: "doc-1",
+		ObjectKey:  chatAttachmentPath(1) + "/notes.txt",
+		FileName:   "notes.txt",
+		FileSize:   18,
+		MimeType:   "text/plain",
+	}).Error; err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	conv := h.createConversation(t, 1)
+
+	_, _, err := h.service.StreamChat(context.Background(), 1, conv.ID, schema.UserMessage("帮我看看这份笔记"), []string{"doc-1"}, "", "", "")
+	assertAppErrCode(t, err, constant.ModerationContentBlocked)
+
+	if msgs := h.messages(t, conv.ID); len(msgs) != 0 {
+		t.Fatalf("blocked message should not be saved, got %d messages", len(msgs))
+	}
+	var audits int64
+	if err := h.db.Model(&models.ModerationRecord{}).Count(&audits).Error; err != nil || audits != 1 {
+		t.Fatalf("moderation records = %d, %v", audits, err)
+	}
+	if len(h.llm.Requests()) != 0 {
+		t.Fatal("blocked message should not reach the model")
+	}
+}
+
+func TestUploadChatAttachmentSniffsContentType(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 600)...)
+	tests := []struct {
+		name     string
+		content  []byte
+		mimeType string
+		wantErr  bool
+	}{
+		{name: "image matches declared type", content: png, mimeType: "image/png"},
+		{name: "markdown is sniffed as text", content: []byte("# 高数笔记\n极限与连续"), mimeType: "text/markdown; charset=utf-8"},
+		{name: "text disguised as image", content: []byte("<script>alert(1)</script>"), mimeType: "image/png", wantErr: true},
+		{name: "image disguised as document", content: png, mimeType: "text/plain", wantErr: true},
+		{name: "html disguised as text", content: []byte("<html><body>hi</body></html>"), mimeType: "text/plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeAttachmentStore{objects: map[string][]byte{}}
+			service := &ChatService{s3Service: store}
+
+			ref, err := service.UploadChatAttachment(context.Background(), 1, io.NopCloser(bytes.NewReader(tt.content)), "file", tt.mimeType, int64(len(tt.content)))
+			if tt.wantErr {
+				assertAppErrCode(t, err, constant.ConversationAttachmentTypeUnsupported)
+				if len(store.objects) != 0 {
+					t.Fatal("rejected attachment should not be stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadChatAttachment() error = %v", err)
+			}
+			if !bytes.Equal(store.objects[ref.ResourceID], tt.content) {
+				t.Fatal("stored content should include the sniffed header")
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// conversationTree 对话的消息树。
+// 历史对话（active_leaf_id 为 0）尚未建立父子关系，按时间顺序视为一条线性分支。
+type conversationTree struct {
+	rows     map[uint]*models.ConversationMessage
+	parents  map[uint]uint   // 消息ID -> 父消息ID
+	children map[uint][]uint // 父消息ID -> 子消息ID（升序）
+	order    []*models.ConversationMessage
+	leafID   uint
+	linear   bool
+}
+
+// loadConversationTree 加载对话的全部消息并构建消息树
+func loadConversationTree(ctx context.Context, db *gorm.DB, userID, conversationID uint) (*conversationTree, error) {
+	var conv models.Conversation
+	if err := db.WithContext(ctx).
+		Select("id", "active_leaf_id").
+		Where("id = ? AND user_id = ?", conversationID, userID).
+		First(&conv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.ConversationNotFound)
+		}
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get conversation: %w", err))
+	}
+
+	var rows []models.ConversationMessage
+	if err := db.WithContext(ctx).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Order("created_at ASC, id ASC").
+		Find(&rows).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get messages: %w", err))
+	}
+
+	return newConversationTree(rows, conv.ActiveLeafID), nil
+}
+
+func newConversationTree(rows []models.ConversationMessage, leafID uint) *conversationTree {
+	t := &conversationTree{
+		rows:     make(map[uint]*models.ConversationMessage, len(rows)),
+		parents:  make(map[uint]uint, len(rows)),
+		children: make(map[uint][]uint),
+		order:    make([]*models.ConversationMessage, 0, len(rows)),
+		leafID:   leafID,
+		linear:   leafID == 0,
+	}
+	for i := range rows {
+		row := &rows[i]
+		t.rows[row.ID] = row
+		t.order = append(t.order, row)
+	}
+	for i, row := range t.order {
+		parent := row.ParentID
+		if t.linear {
+			parent = 0
+			if i > 0 {
+				parent = t.order[i-1].ID
+			}
+		}
+		t.parents[row.ID] = parent
+		t.children[parent] = append(t.children[parent], row.ID)
+	}
+	for _, ids := range t.children {
+		slices.Sort(ids)
+	}
+	return t
+}
+
+// parentOf 返回消息的父消息ID，历史线性对话以前一条消息作为父消息
+func (t *conversationTree) parentOf(row *models.ConversationMessage) uint {
+	return t.parents[row.ID]
+}
+
+// activePath 返回从根消息到当前分支末端的消息
+func (t *conversationTree) activePath() []*models.ConversationMessage {
+	if t.linear {
+		return t.order
+	}
+	return t.pathTo(t.leafID)
+}
+
+// pathTo 返回从根消息到指定消息的路径，遇到缺失的父消息或环时截断
+func (t *conversationTree) pathTo(id uint) []*models.ConversationMessage {
+	var path []*models.ConversationMessage
+	visited := make(map[uint]struct{})
+	for id != 0 {
+		row, ok := t.rows[id]
+		if !ok {
+			break
+		}
+		if _, seen := visited[id]; seen {
+			break
+		}
+		visited[id] = struct{}{}
+		path = append(path, row)
+		id = t.parentOf(row)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// siblings 返回与消息同一父消息的所有消息ID（含自身）
+func (t *conversationTree) siblings(row *models.ConversationMessage) []uint {
+	return t.children[t.parentOf(row)]
+}
+
+// latestLeaf 从指定消息沿最新的子消息向下，返回所在分支的末端消息ID
+func (t *conversationTree) latestLeaf(id uint) uint {
+	visited := make(map[uint]struct{})
+	for {
+		visited[id] = struct{}{}
+		children := t.children[id]
+		if len(children) == 0 {
+			return id
+		}
+		next := children[len(children)-1]
+		if _, seen := visited[next]; seen {
+			return id
+		}
+		id = next
+	}
+}
+
+// ensureMessageTree 锁定对话并返回当前分支末端；历史线性对话在此补齐父消息指针。
+func ensureMessageTree(ctx context.Context, tx *gorm.DB, userID, conversationID uint) (uint, error) {
+	var conv models.Conversation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "active_leaf_id").
+		Where("id = ? AND user_id = ?", conversationID, userID).
+		First(&conv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, apperr.New(constant.ConversationNotFound)
+		}
+		return 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to lock conversation: %w", err))
+	}
+	if conv.ActiveLeafID != 0 {
+		return conv.ActiveLeafID, nil
+	}
+
+	var rows []models.ConversationMessage
+	if err := tx.Select("id", "parent_id").
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Order("created_at ASC, id ASC").
+		Find(&rows).Error; err != nil {
+		return 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get messages: %w", err))
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	var parent uint
+	for _, row := range rows {
+		if row.ParentID != parent {
+			if err := tx.Model(&models.ConversationMessage{}).
+				Where("id = ?", row.ID).
+				UpdateColumn("parent_id", parent).Error; err != nil {
+				return 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to backfill message parent: %w", err))
+			}
+		}
+		parent = row.ID
+	}
+	if err := tx.Model(&models.Conversation{}).
+		Where("id = ?", conversationID).
+		UpdateColumn("active_leaf_id", parent).Error; err != nil {
+		return 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to set active leaf: %w", err))
+	}
+
+	logger.InfoCtx(ctx, map[string]any{
+		"action":          "conversation_message_tree_backfilled",
+		"user_id":         userID,
+		"conversation_id": conversationID,
+		"messages":        len(rows),
+	})
+	return parent, nil
+}
+
+// setActiveLeaf 在事务内补齐消息树后切换当前分支末端
+func (s *ChatService) setActiveLeaf(ctx context.Context, tx *gorm.DB, userID, conversationID, leafID uint) error {
+	if _, err := ensureMessageTree(ctx, tx, userID, conversationID); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Conversation{}).
+		Where("id = ? AND user_id = ?", conversationID, userID).
+		UpdateColumn("active_leaf_id", leafID).Error; err != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to set active leaf: %w", err))
+	}
+	return nil
+}
+
+// RegenerateChat 从指定消息重新生成回复，原有分支保留。
+//   - 用户消息且提供 content：以编辑后的内容创建同级的新用户消息，并从它开始生成
+//   - 用户消息且未提供 content：在该消息下生成新的回复
+//   - 助手消息：在其所属的用户消息下生成新的回复
+func (s *ChatService) RegenerateChat(ctx context.Context, userID, conversationID, messageID uint, content, userToken string) (<-chan string, <-chan error, error) {
+	conv, err := s.GetConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, ok := tree.rows[messageID]
+	if !ok {
+		return nil, nil, apperr.New(constant.ConversationMessageNotFound)
+	}
+
+	checkpointID := newChatCheckpointID(userID, conversationID)
+	var leafID uint
+	switch target.Role {
+	case string(schema.User):
+		if content != "" {
+			// 编辑问题：新用户消息与原消息同级
+			edited, err := editedUserMessage(target, content)
+			if err != nil {
+				return nil, nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to unmarshal message: %w", err))
+			}
+			if edited, err = s.moderateUserMessage(ctx, userID, conversationID, edited); err != nil {
+				return nil, nil, err
+			}
+			parentID := tree.parentOf(target)
+			leafID, err = s.appendMessages(ctx, userID, conversationID, checkpointID, &parentID, []*schema.Message{edited})
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			leafID = target.ID
+		}
+	case string(schema.Assistant):
+		if content != "" {
+			return nil, nil, apperr.New(constant.ConversationMessageNotRegenerable)
+		}
+		path := tree.pathTo(target.ID)
+		for i := len(path) - 1; i >= 0; i-- {
+			if path[i].Role == string(schema.User) {
+				leafID = path[i].ID
+				break
+			}
+		}
+		if leafID == 0 {
+			return nil, nil, apperr.New(constant.ConversationMessageNotRegenerable)
+		}
+	default:
+		return nil, nil, apperr.New(constant.ConversationMessageNotRegenerable)
+	}
+
+	if content == "" {
+		if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return s.setActiveLeaf(ctx, tx, userID, conversationID, leafID)
+		}); err != nil {
+			return nil, nil, err
+		}
+		s.deleteConversationInfoCache(ctx, userID, conversationID)
+	}
+
+	logger.InfoCtx(ctx, map[string]any{
+		"action":          "regenerate_chat",
+		"user_id":         userID,
+		"conversation_id": conversationID,
+		"message_id":      messageID,
+		"leaf_id":         leafID,
+		"edited":          content != "",
+	})
+	return s.runAgent(ctx, conv, userID, userToken, checkpointID, "", false, leafID)
+}
+
+// editedUserMessage 以新内容替换用户消息的文本，保留原消息的附件
+func editedUserMessage(row *models.ConversationMessage, content string) (*schema.Message, error) {
+	orig, err := conversationMessageToSchemaMessage(row)
+	if err != nil {
+		return nil, err
+	}
+	msg := schema.UserMessage(content)
+	msg.UserInputMultiContent = orig.UserInputMultiContent
+	if len(row.Attachments) > 0 {
+		var refs []dto.ChatAttachmentRef
+		if err := json.Unmarshal(row.Attachments, &refs); err != nil {
+			return nil, err
+		}
+		msg.Extra = map[string]any{chatAttachmentsExtraKey: refs}
+	}
+	return msg, nil
+}
+
+// SwitchBranch 切换到指定消息所在的分支，并返回切换后的当前分支消息
+func (s *ChatService) SwitchBranch(ctx context.Context, userID, conversationID, messageID uint) ([]*schema.Message, error) {
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tree.rows[messageID]; !ok {
+		return nil, apperr.New(constant.ConversationMessageNotFound)
+	}
+
+	if tree.linear {
+		// 补齐父消息指针后再计算分支末端
+		if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			_, err := ensureMessageTree(ctx, tx, userID, conversationID)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if tree, err = loadConversationTree(ctx, s.db, userID, conversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	leafID := tree.latestLeaf(messageID)
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.setActiveLeaf(ctx, tx, userID, conversationID, leafID)
+	}); err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "switch_branch",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"message_id":      messageID,
+			"error":           err.Error(),
+		})
+		return nil, err
+	}
+	s.deleteConversationInfoCache(ctx, userID, conversationID)
+
+	return s.GetMessages(ctx, userID, conversationID)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+)
+
+func branchRow(id, parentID uint, role string) models.ConversationMessage {
+	return models.ConversationMessage{ID: id, ParentID: parentID, Role: role}
+}
+
+func pathIDs(path []*models.ConversationMessage) []uint {
+	ids := make([]uint, 0, len(path))
+	for _, row := range path {
+		ids = append(ids, row.ID)
+	}
+	return ids
+}
+
+func TestConversationTreeActivePath(t *testing.T) {
+	// 1(user) -> 2(assistant)
+	//         -> 3(assistant, 重新生成) -> 4(user) -> 5(assistant)
+	// 6(user, 编辑后的首个问题) -> 7(assistant)
+	rows := []models.ConversationMessage{
+		branchRow(1, 0, "user"),
+		branchRow(2, 1, "assistant"),
+		branchRow(3, 1, "assistant"),
+		branchRow(4, 3, "user"),
+		branchRow(5, 4, "assistant"),
+		branchRow(6, 0, "user"),
+		branchRow(7, 6, "assistant"),
+	}
+
+	tree := newConversationTree(rows, 5)
+	if got := pathIDs(tree.activePath()); !slices.Equal(got, []uint{1, 3, 4, 5}) {
+		t.Fatalf("unexpected active path %v", got)
+	}
+	if got := tree.siblings(tree.rows[3]); !slices.Equal(got, []uint{2, 3}) {
+		t.Fatalf("unexpected siblings %v", got)
+	}
+	if got := tree.siblings(tree.rows[1]); !slices.Equal(got, []uint{1, 6}) {
+		t.Fatalf("unexpected root siblings %v", got)
+	}
+
+	if got := tree.latestLeaf(1); got != 5 {
+		t.Fatalf("expected latest leaf under 1 to be 5, got %d", got)
+	}
+	if got := tree.latestLeaf(2); got != 2 {
+		t.Fatalf("expected leaf message to be its own latest leaf, got %d", got)
+	}
+	if got := pathIDs(newConversationTree(rows, 7).activePath()); !slices.Equal(got, []uint{6, 7}) {
+		t.Fatalf("unexpected switched path %v", got)
+	}
+}
+
+func TestConversationTreeLegacyLinear(t *testing.T) {
+	rows := []models.ConversationMessage{
+		branchRow(10, 0, "user"),
+		branchRow(11, 0, "assistant"),
+		branchRow(12, 0, "user"),
+	}
+
+	tree := newConversationTree(rows, 0)
+	if got := pathIDs(tree.activePath()); !slices.Equal(got, []uint{10, 11, 12}) {
+		t.Fatalf("unexpected legacy path %v", got)
+	}
+	if got := tree.parentOf(tree.rows[12]); got != 11 {
+		t.Fatalf("expected legacy parent 11, got %d", got)
+	}
+	if got := tree.latestLeaf(10); got != 12 {
+		t.Fatalf("expected legacy latest leaf 12, got %d", got)
+	}
+}
+
+func TestConversationTreePathStopsOnCycle(t *testing.T) {
+	rows := []models.ConversationMessage{
+		branchRow(1, 2, "user"),
+		branchRow(2, 1, "assistant"),
+	}
+
+	tree := newConversationTree(rows, 2)
+	if got := pathIDs(tree.activePath()); !slices.Equal(got, []uint{1, 2}) {
+		t.Fatalf("unexpected path with cycle %v", got)
+	}
+}
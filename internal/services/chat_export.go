@@ -0,0 +1,591 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// chatBulkExportBatchSize 批量导出时每批加载的对话数
+const chatBulkExportBatchSize = 100
+
+// ChatExportFile 渲染后的单个导出文件
+type ChatExportFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// chatExportMessage 导出用的消息，附件只保留文件名
+type chatExportMessage struct {
+	*schema.Message
+	Attachments []string
+}
+
+// chatExportDocument 待渲染的对话，消息取自当前分支，不含系统消息
+type chatExportDocument struct {
+	Title     string
+	CreatedAt time.Time
+	Messages  []chatExportMessage
+}
+
+// chatExportBlock 一段渲染内容，Summary 非空时渲染为可折叠区块
+type chatExportBlock struct {
+	Summary string
+	Lang    string
+	Body    string
+}
+
+// chatExportTurn 同一角色连续发言的分组，助手的工具调用与结果归入助手发言
+type chatExportTurn struct {
+	Role   string
+	Label  string
+	Blocks []chatExportBlock
+}
+
+// attachmentFileNames 从消息的附件引用中取出文件名
+func attachmentFileNames(raw datatypes.JSON) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var refs []dto.ChatAttachmentRef
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.FileName)
+	}
+	return names
+}
+
+func newChatExportDocument(title string, createdAt time.Time, path []*models.ConversationMessage) (*chatExportDocument, error) {
+	doc := &chatExportDocument{Title: title, CreatedAt: createdAt, Messages: make([]chatExportMessage, 0, len(path))}
+	for _, row := range path {
+		if row.Role == string(schema.System) {
+			continue
+		}
+		msg, err := conversationMessageToSchemaMessage(row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal message %d: %w", row.ID, err)
+		}
+		doc.Messages = append(doc.Messages, chatExportMessage{Message: msg, Attachments: attachmentFileNames(row.Attachments)})
+	}
+	return doc, nil
+}
+
+// turns 将消息按发言分组：用户发言包含正文与附件，助手发言包含思考过程、正文、工具调用与工具结果
+func (d *chatExportDocument) turns() []chatExportTurn {
+	var turns []chatExportTurn
+	toolNames := make(map[string]string)
+	current := func(role schema.RoleType) *chatExportTurn {
+		if len(turns) == 0 || turns[len(turns)-1].Role != string(role) {
+			label := "助手"
+			if role == schema.User {
+				label = "用户"
+			}
+			turns = append(turns, chatExportTurn{Role: string(role), Label: label})
+		}
+		return &turns[len(turns)-1]
+	}
+
+	for _, msg := range d.Messages {
+		switch msg.Role {
+		case schema.User:
+			turn := current(schema.User)
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				turn.Blocks = append(turn.Blocks, chatExportBlock{Body: content})
+			}
+			if len(msg.Attachments) > 0 {
+				turn.Blocks = append(turn.Blocks, chatExportBlock{Body: "附件：" + strings.Join(msg.Attachments, "、")})
+			}
+		case schema.Assistant:
+			turn := current(schema.Assistant)
+			if reasoning := strings.TrimSpace(msg.ReasoningContent); reasoning != "" {
+				turn.Blocks = append(turn.Blocks, chatExportBlock{Summary: "思考过程", Body: reasoning})
+			}
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				turn.Blocks = append(turn.Blocks, chatExportBlock{Body: content})
+			}
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				turn.Blocks = append(turn.Blocks, chatExportBlock{
+					Summary: "调用工具：" + call.Function.Name,
+					Lang:    "json",
+					Body:    indentJSON(call.Function.Arguments),
+				})
+			}
+		case schema.Tool:
+			name := msg.ToolName
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			turn := current(schema.Assistant)
+			turn.Blocks = append(turn.Blocks, chatExportBlock{Summary: "工具结果：" + name, Body: indentJSON(msg.Content)})
+		}
+	}
+	return turns
+}
+
+// indentJSON 格式化 JSON 文本，非 JSON 时原样返回
+func indentJSON(text string) string {
+	var buf bytes.Buffer
+	if err := stdjson.Indent(&buf, []byte(text), "", "  "); err != nil {
+		return text
+	}
+	return buf.String()
+}
+
+// markdownFence 返回比正文中最长的连续反引号更长的代码块围栏
+func markdownFence(body string) string {
+	longest, run := 0, 0
+	for _, r := range body {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// renderChatMarkdown 渲染为 Markdown，工具调用、工具结果与思考过程使用 <details> 折叠
+func renderChatMarkdown(doc *chatExportDocument) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n> 创建于 %s\n", strings.TrimSpace(doc.Title), doc.CreatedAt.Format(time.DateTime))
+	for _, turn := range doc.turns() {
+		fmt.Fprintf(&b, "\n## %s\n", turn.Label)
+		for _, block := range turn.Blocks {
+			if block.Summary == "" {
+				fmt.Fprintf(&b, "\n%s\n", block.Body)
+				continue
+			}
+			fence := markdownFence(block.Body)
+			fmt.Fprintf(&b, "\n<details>\n<summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n",
+				template.HTMLEscapeString(block.Summary), fence, block.Lang, block.Body, fence)
+		}
+	}
+	return []byte(b.String())
+}
+
+var chatExportHTMLTemplate = template.Must(template.New("chat").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{margin:0;background:#f5f6f8;color:#1f2328;font:15px/1.7 -apple-system,"PingFang SC","Microsoft YaHei",sans-serif}
+main{max-width:820px;margin:0 auto;padding:24px 16px}
+h1{font-size:22px;margin:0 0 4px}
+.meta{color:#6b7280;font-size:13px;margin:0 0 24px}
+section{background:#fff;border-radius:8px;padding:12px 16px;margin:0 0 12px;box-shadow:0 1px 2px rgba(0,0,0,.06)}
+section.user{background:#eef5ff}
+h2{font-size:13px;color:#6b7280;margin:0 0 6px}
+.content{white-space:pre-wrap;word-break:break-word;margin:6px 0}
+details{margin:6px 0;border:1px solid #e5e7eb;border-radius:6px;padding:4px 10px;background:#fafafa}
+summary{cursor:pointer;color:#4b5563;font-size:13px}
+pre{white-space:pre-wrap;word-break:break-word;font:12px/1.5 ui-monospace,Menlo,Consolas,monospace;margin:6px 0}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<p class="meta">创建于 {{.CreatedAt}}</p>
+{{range .Turns}}<section class="{{.Role}}">
+<h2>{{.Label}}</h2>
+{{range .Blocks}}{{if .Summary}}<details><summary>{{.Summary}}</summary><pre>{{.Body}}</pre></details>
+{{else}}<div class="content">{{.Body}}</div>
+{{end}}{{end}}</section>
+{{end}}</main>
+</body>
+</html>
+`))
+
+// renderChatHTML 渲染为不依赖外部资源的独立 HTML 页面
+func renderChatHTML(doc *chatExportDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := chatExportHTMLTemplate.Execute(&buf, map[string]any{
+		"Title":     strings.TrimSpace(doc.Title),
+		"CreatedAt": doc.CreatedAt.Format(time.DateTime),
+		"Turns":     doc.turns(),
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openAIChatMessage OpenAI 微调数据集中的消息
+type openAIChatMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// openAIChatMessages 转换为 OpenAI 微调格式的消息。
+// 没有对应结果的工具调用（如被中断）与找不到调用的工具结果会被丢弃，保证数据集可直接上传；思考过程不导出。
+func openAIChatMessages(doc *chatExportDocument) []openAIChatMessage {
+	answered := make(map[string]bool)
+	for _, msg := range doc.Messages {
+		if msg.Role == schema.Tool {
+			answered[msg.ToolCallID] = true
+		}
+	}
+
+	called := make(map[string]bool)
+	messages := make([]openAIChatMessage, 0, len(doc.Messages))
+	for _, msg := range doc.Messages {
+		switch msg.Role {
+		case schema.User:
+			content := msg.Content
+			if content == "" && len(msg.Attachments) == 0 {
+				continue
+			}
+			if len(msg.Attachments) > 0 {
+				content = strings.TrimSpace(content + "\n（附件：" + strings.Join(msg.Attachments, "、") + "）")
+			}
+			messages = append(messages, openAIChatMessage{Role: string(schema.User), Content: &content})
+		case schema.Assistant:
+			out := openAIChatMessage{Role: string(schema.Assistant)}
+			for _, call := range msg.ToolCalls {
+				if !answered[call.ID] {
+					continue
+				}
+				called[call.ID] = true
+				out.ToolCalls = append(out.ToolCalls, openAIToolCall{
+					ID:       call.ID,
+					Type:     "function",
+					Function: openAIFunctionCall{Name: call.Function.Name, Arguments: call.Function.Arguments},
+				})
+			}
+			if msg.Content != "" {
+				content := msg.Content
+				out.Content = &content
+			}
+			if out.Content == nil && len(out.ToolCalls) == 0 {
+				continue
+			}
+			messages = append(messages, out)
+		case schema.Tool:
+			if !called[msg.ToolCallID] {
+				continue
+			}
+			content := msg.Content
+			messages = append(messages, openAIChatMessage{Role: string(schema.Tool), Content: &content, ToolCallID: msg.ToolCallID})
+		}
+	}
+	return messages
+}
+
+// renderChatJSONL 渲染为一行 OpenAI 微调格式的记录（含换行）
+func renderChatJSONL(doc *chatExportDocument) ([]byte, error) {
+	line, err := json.Marshal(map[string]any{"messages": openAIChatMessages(doc)})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// renderChatExport 按格式渲染，返回内容、Content-Type 与文件扩展名
+func renderChatExport(doc *chatExportDocument, format string) ([]byte, string, string, error) {
+	switch format {
+	case constant.ChatExportFormatMarkdown:
+		return renderChatMarkdown(doc), "text/markdown; charset=utf-8", "md", nil
+	case constant.ChatExportFormatHTML:
+		data, err := renderChatHTML(doc)
+		return data, "text/html; charset=utf-8", "html", err
+	case constant.ChatExportFormatJSONL:
+		data, err := renderChatJSONL(doc)
+		return data, "application/jsonl; charset=utf-8", "jsonl", err
+	default:
+		return nil, "", "", fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ExportConversationFile 按指定格式（markdown、html、jsonl）渲染对话当前分支，供下载
+func (s *ChatService) ExportConversationFile(ctx context.Context, userID, conversationID uint, format string) (*ChatExportFile, error) {
+	conv, err := s.GetConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := newChatExportDocument(conv.Title, conv.CreatedAt, tree.activePath())
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	data, contentType, ext, err := renderChatExport(doc, format)
+	if err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "export_conversation_file",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"format":          format,
+			"error":           err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	return &ChatExportFile{
+		FileName:    fmt.Sprintf("conversation-%d.%s", conversationID, ext),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+var (
+	chatExportEmailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	chatExportIDCardPattern = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	chatExportPhonePattern  = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+)
+
+// chatAnonymizer 脱敏文本：替换用户本人的姓名、学号、手机号、昵称，以及任意邮箱、身份证号与手机号
+type chatAnonymizer struct {
+	replacer *strings.Replacer
+}
+
+func newChatAnonymizer(user *models.User) *chatAnonymizer {
+	var pairs []string
+	if user != nil {
+		for _, field := range []struct{ value, mask string }{
+			{user.StudentID, "[学号]"},
+			{user.Phone, "[手机号]"},
+			{user.RealName, "[姓名]"},
+			{user.Nickname, "[昵称]"},
+		} {
+			// 单字姓名或昵称误伤过多，不做替换
+			if value := strings.TrimSpace(field.value); utf8.RuneCountInString(value) >= 2 {
+				pairs = append(pairs, value, field.mask)
+			}
+		}
+	}
+	return &chatAnonymizer{replacer: strings.NewReplacer(pairs...)}
+}
+
+func (a *chatAnonymizer) apply(text string) string {
+	if text == "" {
+		return text
+	}
+	text = a.replacer.Replace(text)
+	text = chatExportEmailPattern.ReplaceAllString(text, "[邮箱]")
+	text = chatExportIDCardPattern.ReplaceAllString(text, "[身份证号]")
+	return chatExportPhonePattern.ReplaceAllString(text, "[手机号]")
+}
+
+// anonymize 原地脱敏标题、正文、思考过程、工具参数与附件文件名
+func (d *chatExportDocument) anonymize(a *chatAnonymizer) {
+	d.Title = a.apply(d.Title)
+	for i := range d.Messages {
+		msg := *d.Messages[i].Message
+		msg.Content = a.apply(msg.Content)
+		msg.ReasoningContent = a.apply(msg.ReasoningContent)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]schema.ToolCall, len(msg.ToolCalls))
+			copy(calls, msg.ToolCalls)
+			for j := range calls {
+				calls[j].Function.Arguments = a.apply(calls[j].Function.Arguments)
+			}
+			msg.ToolCalls = calls
+		}
+		d.Messages[i].Message = &msg
+
+		names := make([]string, len(d.Messages[i].Attachments))
+		for j, name := range d.Messages[i].Attachments {
+			names[j] = a.apply(name)
+		}
+		d.Messages[i].Attachments = names
+	}
+}
+
+// chatExportManifest 批量导出归档中的 manifest.json
+type chatExportManifest struct {
+	StartDate         string    `json:"start_date"`
+	EndDate           string    `json:"end_date"`
+	Format            string    `json:"format"`
+	ConversationCount int       `json:"conversation_count"`
+	UserCount         int       `json:"user_count"`
+	GeneratedAt       time.Time `json:"generated_at"`
+}
+
+// ExportConversationsArchive 校验导出区间后返回归档文件名与写出 ZIP 的函数，调用方在写出前设置响应头。
+// 归档按对话创建日期筛选，用户以 user-0001 形式按出现顺序编号，内容经过脱敏；jsonl 格式写入单个 conversations.jsonl，
+// 其余格式每个对话一个文件。
+func (s *ChatService) ExportConversationsArchive(ctx context.Context, req *dto.ExportConversationsArchiveRequest) (string, func(io.Writer) error, error) {
+	start, err := time.ParseInLocation(time.DateOnly, req.StartDate, time.Local)
+	if err != nil {
+		return "", nil, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	end, err := time.ParseInLocation(time.DateOnly, req.EndDate, time.Local)
+	if err != nil {
+		return "", nil, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	if end.Before(start) || end.Sub(start) > constant.ChatBulkExportMaxDays*24*time.Hour {
+		appErr := apperr.New(constant.CommonBadRequest)
+		appErr.Message = fmt.Sprintf("导出区间无效，结束日期不能早于开始日期且跨度不超过%d天", constant.ChatBulkExportMaxDays)
+		return "", nil, appErr
+	}
+	format := req.Format
+	if format == "" {
+		format = constant.ChatExportFormatJSONL
+	}
+
+	fileName := fmt.Sprintf("conversations-%s-%s.zip", req.StartDate, req.EndDate)
+	write := func(w io.Writer) error {
+		now := time.Now()
+		zw := zip.NewWriter(w)
+		create := func(name string) (io.Writer, error) {
+			return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		}
+
+		var jsonl io.Writer
+		if format == constant.ChatExportFormatJSONL {
+			f, err := create("conversations.jsonl")
+			if err != nil {
+				return err
+			}
+			jsonl = f
+		}
+
+		aliases := make(map[uint]string)
+		count := 0
+		var batch []models.Conversation
+		result := s.db.WithContext(ctx).
+			Where("created_at >= ? AND created_at < ?", start, end.AddDate(0, 0, 1)).
+			FindInBatches(&batch, chatBulkExportBatchSize, func(tx *gorm.DB, _ int) error {
+				users, err := s.loadExportUsers(ctx, batch)
+				if err != nil {
+					return err
+				}
+				for i := range batch {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					conv := &batch[i]
+					tree, err := loadConversationTree(ctx, s.db, conv.UserID, conv.ID)
+					if err != nil {
+						return err
+					}
+					doc, err := newChatExportDocument(conv.Title, conv.CreatedAt, tree.activePath())
+					if err != nil {
+						logger.WarnCtx(ctx, map[string]any{
+							"action":          "export_conversations_archive_skip",
+							"conversation_id": conv.ID,
+							"error":           err.Error(),
+						})
+						continue
+					}
+					if len(doc.Messages) == 0 {
+						continue
+					}
+					doc.anonymize(newChatAnonymizer(users[conv.UserID]))
+
+					alias, ok := aliases[conv.UserID]
+					if !ok {
+						alias = fmt.Sprintf("user-%04d", len(aliases)+1)
+						aliases[conv.UserID] = alias
+					}
+					count++
+
+					data, _, ext, err := renderChatExport(doc, format)
+					if err != nil {
+						return err
+					}
+					out := jsonl
+					if out == nil {
+						if out, err = create(fmt.Sprintf("%s/conversation-%05d.%s", alias, count, ext)); err != nil {
+							return err
+						}
+					}
+					if _, err := out.Write(data); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		if result.Error != nil {
+			logger.ErrorCtx(ctx, map[string]any{
+				"action":     "export_conversations_archive",
+				"start_date": req.StartDate,
+				"end_date":   req.EndDate,
+				"exported":   count,
+				"error":      result.Error.Error(),
+			})
+			return result.Error
+		}
+
+		manifest, err := json.MarshalIndent(chatExportManifest{
+			StartDate:         req.StartDate,
+			EndDate:           req.EndDate,
+			Format:            format,
+			ConversationCount: count,
+			UserCount:         len(aliases),
+			GeneratedAt:       now,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		out, err := create("manifest.json")
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(manifest); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	return fileName, write, nil
+}
+
+// loadExportUsers 加载一批对话所属用户的身份信息，用于脱敏
+func (s *ChatService) loadExportUsers(ctx context.Context, convs []models.Conversation) (map[uint]*models.User, error) {
+	ids := make([]uint, 0, len(convs))
+	for _, conv := range convs {
+		ids = append(ids, conv.UserID)
+	}
+	var users []models.User
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "nickname", "phone", "student_id", "real_name").
+		Where("id IN ?", ids).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/cloudwego/eino/schema"
+)
+
+func exportTestDocument() *chatExportDocument {
+	call := func(id, name, args string) schema.ToolCall {
+		return schema.ToolCall{ID: id, Type: "function", Function: schema.FunctionCall{Name: name, Arguments: args}}
+	}
+	return &chatExportDocument{
+		Title:     "高数复习",
+		CreatedAt: time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local),
+		Messages: []chatExportMessage{
+			{Message: schema.UserMessage("帮我找一下极限的资料"), Attachments: []string{"笔记.pdf"}},
+			{Message: &schema.Message{Role: schema.Assistant, ReasoningContent: "先检索", ToolCalls: []schema.ToolCall{call("c1", "search", `{"q":"极限"}`)}}},
+			{Message: schema.ToolMessage("含 ``` 的结果", "c1", schema.WithToolName("search"))},
+			{Message: schema.AssistantMessage("找到了以下资料。", nil)},
+			{Message: &schema.Message{Role: schema.Assistant, ToolCalls: []schema.ToolCall{call("c2", "search", `{}`)}}},
+		},
+	}
+}
+
+func TestRenderChatMarkdown(t *testing.T) {
+	out := string(renderChatMarkdown(exportTestDocument()))
+
+	if strings.Count(out, "## 助手") != 1 || strings.Count(out, "## 用户") != 1 {
+		t.Fatalf("consecutive assistant messages should share one heading:\n%s", out)
+	}
+	for _, want := range []string{
+		"# 高数复习",
+		"附件：笔记.pdf",
+		"<summary>思考过程</summary>",
+		"<summary>调用工具：search</summary>\n\n```json\n{\n  \"q\": \"极限\"\n}\n```",
+		"<summary>工具结果：search</summary>\n\n````\n含 ``` 的结果\n````",
+		"\n找到了以下资料。\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderChatHTMLEscapesContent(t *testing.T) {
+	doc := exportTestDocument()
+	doc.Messages[0].Message = schema.UserMessage("<script>alert(1)</script>")
+
+	out, err := renderChatHTML(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(out), "<script>") || !strings.Contains(string(out), "&lt;script&gt;") {
+		t.Fatal("message content should be escaped")
+	}
+	if !strings.Contains(string(out), "<details><summary>调用工具：search</summary>") {
+		t.Fatal("tool calls should be collapsible")
+	}
+}
+
+func TestOpenAIChatMessagesDropsUnansweredToolCalls(t *testing.T) {
+	messages := openAIChatMessages(exportTestDocument())
+
+	if len(messages) != 4 {
+		t.Fatalf("expected user, tool call, tool result and answer, got %+v", messages)
+	}
+	if *messages[0].Content != "帮我找一下极限的资料\n（附件：笔记.pdf）" {
+		t.Fatalf("unexpected user content %q", *messages[0].Content)
+	}
+	if messages[1].Content != nil || len(messages[1].ToolCalls) != 1 || messages[1].ToolCalls[0].Type != "function" {
+		t.Fatalf("unexpected tool call message %+v", messages[1])
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "c1" {
+		t.Fatalf("unexpected tool message %+v", messages[2])
+	}
+	if messages[3].Role != "assistant" || *messages[3].Content != "找到了以下资料。" {
+		t.Fatalf("unexpected final message %+v", messages[3])
+	}
+}
+
+func TestChatAnonymizer(t *testing.T) {
+	a := newChatAnonymizer(&models.User{RealName: "张三", StudentID: "2023123456", Nickname: "王"})
+	got := a.apply("我是张三，学号2023123456，电话13812345678，邮箱zs@example.com，身份证11010519491231002X，王老师")
+	want := "我是[姓名]，学号[学号]，电话[手机号]，邮箱[邮箱]，身份证[身份证号]，王老师"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	doc := exportTestDocument()
+	original := doc.Messages[1].Message
+	doc.Messages[1].Message.ToolCalls[0].Function.Arguments = `{"q":"张三"}`
+	doc.anonymize(a)
+	if doc.Messages[1].ToolCalls[0].Function.Arguments != `{"q":"[姓名]"}` {
+		t.Fatalf("tool arguments should be anonymized, got %s", doc.Messages[1].ToolCalls[0].Function.Arguments)
+	}
+	if original.ToolCalls[0].Function.Arguments != `{"q":"张三"}` {
+		t.Fatal("source message should not be modified")
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// feedbackTurn 截取从本轮用户提问到被评价消息的一段路径，path 以被评价消息结尾
+func feedbackTurn(path []*models.ConversationMessage) []*models.ConversationMessage {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Role == string(schema.User) {
+			return path[i:]
+		}
+	}
+	return path
+}
+
+// messageToolCallNames 返回助手消息发起调用的工具名称
+func messageToolCallNames(row *models.ConversationMessage) []string {
+	if len(row.ToolCalls) == 0 {
+		return nil
+	}
+	var calls []schema.ToolCall
+	if err := json.Unmarshal(row.ToolCalls, &calls); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(calls))
+	for _, call := range calls {
+		if call.Function.Name != "" {
+			names = append(names, call.Function.Name)
+		}
+	}
+	return names
+}
+
+// turnToolNames 汇总一轮对话中调用过的工具名称，去重并保持首次出现的顺序
+func turnToolNames(turn []*models.ConversationMessage) []string {
+	names := make([]string, 0)
+	add := func(name string) {
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, row := range turn {
+		for _, name := range messageToolCallNames(row) {
+			add(name)
+		}
+		if row.Role == string(schema.Tool) {
+			add(row.ToolName)
+		}
+	}
+	return names
+}
+
+// jsonStringList 解析 JSON 字符串数组列，空值或非法内容返回空切片
+func jsonStringList(raw datatypes.JSON) []string {
+	list := make([]string, 0)
+	if len(raw) == 0 {
+		return list
+	}
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+func toMessageFeedbackResponse(fb *models.ChatMessageFeedback) dto.MessageFeedbackResponse {
+	return dto.MessageFeedbackResponse{
+		MessageID: fb.MessageID,
+		Rating:    fb.Rating,
+		Tags:      jsonStringList(fb.Tags),
+		Comment:   fb.Comment,
+		UpdatedAt: fb.UpdatedAt,
+	}
+}
+
+// SubmitMessageFeedback 评价助手回答，重复提交覆盖之前的评价并重新进入复核队列
+func (s *ChatService) SubmitMessageFeedback(ctx context.Context, userID, conversationID, messageID uint, req *dto.SubmitMessageFeedbackRequest) (*dto.MessageFeedbackResponse, error) {
+	conv, err := s.getOwnedConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	row, ok := tree.rows[messageID]
+	if !ok {
+		return nil, apperr.New(constant.ConversationMessageNotFound)
+	}
+	if row.Role != string(schema.Assistant) {
+		return nil, apperr.New(constant.ConversationFeedbackNotAllowed)
+	}
+
+	tags, err := json.Marshal(append(make([]string, 0, len(req.Tags)), req.Tags...))
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to marshal feedback tags: %w", err))
+	}
+	tools, err := json.Marshal(turnToolNames(feedbackTurn(tree.pathTo(messageID))))
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to marshal feedback tools: %w", err))
+	}
+
+	now := time.Now()
+	feedback := &models.ChatMessageFeedback{
+		MessageID:      messageID,
+		ConversationID: conversationID,
+		UserID:         userID,
+		Rating:         req.Rating,
+		Tags:           datatypes.JSON(tags),
+		Comment:        req.Comment,
+		PersonaID:      conv.PersonaID,
+		ModelProfile:   row.ModelProfile,
+		ToolNames:      datatypes.JSON(tools),
+		UpdatedAt:      now,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"rating":        feedback.Rating,
+			"tags":          feedback.Tags,
+			"comment":       feedback.Comment,
+			"persona_id":    feedback.PersonaID,
+			"model_profile": feedback.ModelProfile,
+			"tool_names":    feedback.ToolNames,
+			"review_status": constant.ChatFeedbackReviewPending,
+			"review_note":   "",
+			"reviewed_by":   0,
+			"reviewed_at":   nil,
+			"updated_at":    now,
+		}),
+	}).Create(feedback).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "submit_message_feedback",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"message_id":      messageID,
+			"error":           err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to save feedback: %w", err))
+	}
+
+	resp := toMessageFeedbackResponse(feedback)
+	return &resp, nil
+}
+
+// DeleteMessageFeedback 撤回对助手回答的评价，评价不存在时视为成功
+func (s *ChatService) DeleteMessageFeedback(ctx context.Context, userID, conversationID, messageID uint) error {
+	if _, err := s.getOwnedConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).
+		Where("message_id = ? AND conversation_id = ? AND user_id = ?", messageID, conversationID, userID).
+		Delete(&models.ChatMessageFeedback{}).Error; err != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to delete feedback: %w", err))
+	}
+	return nil
+}
+
+// conversationFeedback 返回用户在对话中的评价，按消息ID索引
+func (s *ChatService) conversationFeedback(ctx context.Context, userID, conversationID uint) (map[uint]dto.MessageFeedbackResponse, error) {
+	var rows []models.ChatMessageFeedback
+	if err := s.db.WithContext(ctx).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]dto.MessageFeedbackResponse, len(rows))
+	for i := range rows {
+		result[rows[i].MessageID] = toMessageFeedbackResponse(&rows[i])
+	}
+	return result, nil
+}
+
+// ListFeedbackReview 管理员复核队列，默认列出待复核的差评，每条附带被评价回答所在的一轮对话
+func (s *ChatService) ListFeedbackReview(ctx context.Context, req *dto.ListFeedbackReviewRequest) ([]dto.FeedbackReviewItem, int64, error) {
+	rating := constant.ChatFeedbackRatingDown
+	if req.Rating != nil {
+		rating = *req.Rating
+	}
+	status := constant.ChatFeedbackReviewPending
+	if req.ReviewStatus != nil {
+		status = *req.ReviewStatus
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.ChatMessageFeedback{}).
+		Where("rating = ? AND review_status = ?", rating, status)
+	if req.Tag != "" {
+		query = query.Where("JSON_CONTAINS(tags, JSON_QUOTE(?))", req.Tag)
+	}
+	if req.PersonaID != nil {
+		query = query.Where("persona_id = ?", *req.PersonaID)
+	}
+	if req.ModelProfile != "" {
+		query = query.Where("model_profile = ?", req.ModelProfile)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to count feedback: %w", err))
+	}
+
+	pagination := utils.GetPagination(req.Page, req.PageSize)
+	var rows []models.ChatMessageFeedback
+	if err := query.Order("created_at DESC, id DESC").
+		Offset(pagination.Offset).
+		Limit(pagination.Size).
+		Find(&rows).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action": "list_feedback_review",
+			"error":  err.Error(),
+		})
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to list feedback: %w", err))
+	}
+
+	// 同一对话只加载一次消息树
+	trees := make(map[uint]*conversationTree)
+	items := make([]dto.FeedbackReviewItem, 0, len(rows))
+	for i := range rows {
+		fb := &rows[i]
+		tree, ok := trees[fb.ConversationID]
+		if !ok {
+			var err error
+			tree, err = loadConversationTree(ctx, s.db, fb.UserID, fb.ConversationID)
+			if err != nil {
+				// 用户已删除对话时不再展示上下文
+				if appErr, ok := apperr.As(err); !ok || appErr.Code != constant.ConversationNotFound {
+					return nil, 0, err
+				}
+				tree = newConversationTree(nil, 0)
+			}
+			trees[fb.ConversationID] = tree
+		}
+
+		item := dto.FeedbackReviewItem{
+			ID:             fb.ID,
+			MessageID:      fb.MessageID,
+			ConversationID: fb.ConversationID,
+			UserID:         fb.UserID,
+			Rating:         fb.Rating,
+			Tags:           jsonStringList(fb.Tags),
+			Comment:        fb.Comment,
+			PersonaID:      fb.PersonaID,
+			ModelProfile:   fb.ModelProfile,
+			ToolNames:      jsonStringList(fb.ToolNames),
+			ReviewStatus:   fb.ReviewStatus,
+			ReviewNote:     fb.ReviewNote,
+			ReviewedBy:     fb.ReviewedBy,
+			ReviewedAt:     fb.ReviewedAt,
+			CreatedAt:      fb.CreatedAt,
+			Context:        make([]dto.FeedbackContextMessage, 0),
+		}
+		if _, ok := tree.rows[fb.MessageID]; ok {
+			for _, row := range feedbackTurn(tree.pathTo(fb.MessageID)) {
+				item.Context = append(item.Context, dto.FeedbackContextMessage{
+					MessageID: row.ID,
+					Role:      row.Role,
+					Content:   row.Content,
+					ToolName:  row.ToolName,
+					ToolCalls: messageToolCallNames(row),
+				})
+			}
+		}
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// ReviewFeedback 处理复核队列中的评价
+func (s *ChatService) ReviewFeedback(ctx context.Context, operatorID, feedbackID uint, req *dto.ReviewFeedbackRequest) error {
+	var feedback models.ChatMessageFeedback
+	if err := s.db.WithContext(ctx).Select("id").First(&feedback, feedbackID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New(constant.ConversationFeedbackNotFound)
+		}
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get feedback: %w", err))
+	}
+	if err := s.db.WithContext(ctx).Model(&models.ChatMessageFeedback{}).
+		Where("id = ?", feedbackID).
+		Updates(map[string]any{
+			"review_status": req.ReviewStatus,
+			"review_note":   req.ReviewNote,
+			"reviewed_by":   operatorID,
+			"reviewed_at":   time.Now(),
+		}).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":      "review_feedback",
+			"operator_id": operatorID,
+			"feedback_id": feedbackID,
+			"error":       err.Error(),
+		})
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to review feedback: %w", err))
+	}
+	return nil
+}
+
+// feedbackStatsRow 统计所需的评价字段
+type feedbackStatsRow struct {
+	ID           uint
+	Rating       int8
+	Tags         datatypes.JSON
+	PersonaID    uint
+	ModelProfile string
+	ToolNames    datatypes.JSON
+}
+
+// feedbackStatsAggregator 按人设、模型、工具三个维度累加评价
+type feedbackStatsAggregator struct {
+	total, up, down int64
+	byPersona       map[string]*dto.FeedbackStatsItem
+	byModel         map[string]*dto.FeedbackStatsItem
+	byTool          map[string]*dto.FeedbackStatsItem
+}
+
+func newFeedbackStatsAggregator() *feedbackStatsAggregator {
+	return &feedbackStatsAggregator{
+		byPersona: make(map[string]*dto.FeedbackStatsItem),
+		byModel:   make(map[string]*dto.FeedbackStatsItem),
+		byTool:    make(map[string]*dto.FeedbackStatsItem),
+	}
+}
+
+func (a *feedbackStatsAggregator) add(row *feedbackStatsRow) {
+	tags := jsonStringList(row.Tags)
+	a.total++
+	if row.Rating > 0 {
+		a.up++
+	} else {
+		a.down++
+	}
+	count := func(group map[string]*dto.FeedbackStatsItem, key string) {
+		item, ok := group[key]
+		if !ok {
+			item = &dto.FeedbackStatsItem{Key: key, TagCounts: make(map[string]int64)}
+			group[key] = item
+		}
+		item.Total++
+		if row.Rating > 0 {
+			item.Up++
+		} else {
+			item.Down++
+		}
+		for _, tag := range tags {
+			item.TagCounts[tag]++
+		}
+	}
+	count(a.byPersona, strconv.FormatUint(uint64(row.PersonaID), 10))
+	count(a.byModel, row.ModelProfile)
+	for _, tool := range jsonStringList(row.ToolNames) {
+		count(a.byTool, tool)
+	}
+}
+
+// feedbackStatsItems 计算差评率并按评价数量降序排列
+func feedbackStatsItems(group map[string]*dto.FeedbackStatsItem) []dto.FeedbackStatsItem {
+	items := make([]dto.FeedbackStatsItem, 0, len(group))
+	for _, item := range group {
+		if item.Total > 0 {
+			item.DownRate = float64(item.Down) / float64(item.Total)
+		}
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Total != items[j].Total {
+			return items[i].Total > items[j].Total
+		}
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+func (a *feedbackStatsAggregator) result() *dto.FeedbackStatsResponse {
+	return &dto.FeedbackStatsResponse{
+		Total:     a.total,
+		Up:        a.up,
+		Down:      a.down,
+		ByPersona: feedbackStatsItems(a.byPersona),
+		ByModel:   feedbackStatsItems(a.byModel),
+		ByTool:    feedbackStatsItems(a.byTool),
+	}
+}
+
+// FeedbackStats 按人设、模型、工具汇总统计区间内的评价
+func (s *ChatService) FeedbackStats(ctx context.Context, req *dto.FeedbackStatsRequest) (*dto.FeedbackStatsResponse, error) {
+	start, err := time.ParseInLocation(time.DateOnly, req.StartDate, time.Local)
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	end, err := time.ParseInLocation(time.DateOnly, req.EndDate, time.Local)
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	if end.Before(start) || end.Sub(start) > constant.ChatFeedbackStatsMaxDays*24*time.Hour {
+		appErr := apperr.New(constant.CommonBadRequest)
+		appErr.Message = fmt.Sprintf("统计区间无效，结束日期不能早于开始日期且跨度不超过%d天", constant.ChatFeedbackStatsMaxDays)
+		return nil, appErr
+	}
+
+	agg := newFeedbackStatsAggregator()
+	var batch []feedbackStatsRow
+	if err := s.db.WithContext(ctx).Model(&models.ChatMessageFeedback{}).
+		Select("id, rating, tags, persona_id, model_profile, tool_names").
+		Where("created_at >= ? AND created_at < ?", start, end.AddDate(0, 0, 1)).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				agg.add(&batch[i])
+			}
+			return nil
+		}).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action": "feedback_stats",
+			"error":  err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to aggregate feedback: %w", err))
+	}
+	return agg.result(), nil
+}
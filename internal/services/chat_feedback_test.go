@@ -0,0 +1,54 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/datatypes"
+)
+
+func TestFeedbackTurnAndToolNames(t *testing.T) {
+	path := []*models.ConversationMessage{
+		{ID: 1, Role: string(schema.User), Content: "上一轮"},
+		{ID: 2, Role: string(schema.Assistant), ToolCalls: datatypes.JSON(`[{"id":"c0","function":{"name":"old_tool"}}]`)},
+		{ID: 3, Role: string(schema.User), Content: "本轮"},
+		{ID: 4, Role: string(schema.Assistant), ToolCalls: datatypes.JSON(`[{"id":"c1","function":{"name":"search_materials"}},{"id":"c2","function":{"name":"lookup_word"}}]`)},
+		{ID: 5, Role: string(schema.Tool), ToolName: "search_materials"},
+		{ID: 6, Role: string(schema.Tool), ToolName: "lookup_word"},
+		{ID: 7, Role: string(schema.Assistant), Content: "回答"},
+	}
+
+	turn := feedbackTurn(path)
+	if len(turn) != 5 || turn[0].ID != 3 || turn[len(turn)-1].ID != 7 {
+		t.Fatalf("unexpected turn: first=%d len=%d", turn[0].ID, len(turn))
+	}
+	if got := turnToolNames(turn); !slices.Equal(got, []string{"search_materials", "lookup_word"}) {
+		t.Fatalf("tool names = %v", got)
+	}
+	if got := turnToolNames(nil); got == nil || len(got) != 0 {
+		t.Fatalf("expected empty non-nil slice, got %#v", got)
+	}
+}
+
+func TestFeedbackStatsAggregator(t *testing.T) {
+	agg := newFeedbackStatsAggregator()
+	agg.add(&feedbackStatsRow{Rating: 1, PersonaID: 1, ModelProfile: "primary", Tags: datatypes.JSON(`["helpful"]`)})
+	agg.add(&feedbackStatsRow{Rating: -1, PersonaID: 1, ModelProfile: "backup", Tags: datatypes.JSON(`["wrong","tool_error"]`), ToolNames: datatypes.JSON(`["search_materials"]`)})
+	agg.add(&feedbackStatsRow{Rating: -1, PersonaID: 2, ModelProfile: "backup", Tags: datatypes.JSON(`["wrong"]`), ToolNames: datatypes.JSON(`["search_materials","lookup_word"]`)})
+
+	res := agg.result()
+	if res.Total != 3 || res.Up != 1 || res.Down != 2 {
+		t.Fatalf("totals = %d/%d/%d", res.Total, res.Up, res.Down)
+	}
+	if len(res.ByPersona) != 2 || res.ByPersona[0].Key != "1" || res.ByPersona[0].DownRate != 0.5 {
+		t.Fatalf("by persona = %+v", res.ByPersona)
+	}
+	if res.ByModel[0].Key != "backup" || res.ByModel[0].Down != 2 || res.ByModel[0].TagCounts["wrong"] != 2 {
+		t.Fatalf("by model = %+v", res.ByModel)
+	}
+	if len(res.ByTool) != 2 || res.ByTool[0].Key != "search_materials" || res.ByTool[0].Total != 2 || res.ByTool[1].Key != "lookup_word" {
+		t.Fatalf("by tool = %+v", res.ByTool)
+	}
+}
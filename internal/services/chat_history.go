@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/cloudwego/eino/adk"
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatMessageTokenOverhead 每条消息的角色、分隔符等固定开销
+	chatMessageTokenOverhead = 4
+	// chatSummaryToolResultMaxRunes 生成摘要时单条工具结果保留的最大字符数
+	chatSummaryToolResultMaxRunes = 1000
+)
+
+// chatHistoryMessage 带消息ID的历史消息，ID 用于记录摘要覆盖范围
+type chatHistoryMessage struct {
+	ID      uint
+	Message *schema.Message
+}
+
+// chatHistoryUnit 历史消息的最小保留/摘要单位。
+// 带 ToolCalls 的 assistant 消息与其后的 tool 结果属于同一单位，保证不会被拆开。
+type chatHistoryUnit struct {
+	messages []chatHistoryMessage
+	tokens   int
+}
+
+func (u *chatHistoryUnit) lastID() uint {
+	return u.messages[len(u.messages)-1].ID
+}
+
+// chatHistoryResult 历史构建结果
+type chatHistoryResult struct {
+	Messages []*schema.Message
+	// Summary 当前生效的摘要
+	Summary string
+	// SummaryMessageID 摘要已覆盖的最后一条消息ID
+	SummaryMessageID uint
+	// Summarized 本次是否生成了新的摘要，需要持久化
+	Summarized bool
+	// SummaryUsage 本次摘要调用消耗的 token，未调用模型时为 nil
+	SummaryUsage *schema.TokenUsage
+}
+
+// chatHistoryBuilder 按 token 预算构建发送给模型的历史消息：
+// 系统提示词与最近的对话原样保留，更早的对话交给模型滚动压缩为摘要。
+type chatHistoryBuilder struct {
+	model einomodel.BaseChatModel
+	// budget 历史消息的 token 预算，<=0 表示不限制
+	budget int
+	// reserved 预留给不在历史中的内容（如 Agent Instruction）的 token 数
+	reserved int
+}
+
+func newChatHistoryBuilder(model einomodel.BaseChatModel, budget, reserved int) *chatHistoryBuilder {
+	return &chatHistoryBuilder{model: model, budget: budget, reserved: reserved}
+}
+
+// Build 构建历史消息。summary 为已存储的摘要，history 为摘要之后的消息（按时间升序）。
+// 超出预算时，最近的对话保留到预算的一半以内，其余部分与旧摘要合并为新摘要，
+// 避免此后每一轮对话都触发摘要；摘要失败时仅丢弃早期消息，不影响本轮对话。
+func (b *chatHistoryBuilder) Build(ctx context.Context, summary string, summaryMessageID uint, history []chatHistoryMessage) *chatHistoryResult {
+	var pinned []*schema.Message
+	var rest []chatHistoryMessage
+	for _, item := range history {
+		if item.Message == nil {
+			continue
+		}
+		if item.Message.Role == schema.System {
+			pinned = append(pinned, item.Message)
+			continue
+		}
+		rest = append(rest, item)
+	}
+
+	result := &chatHistoryResult{Summary: summary, SummaryMessageID: summaryMessageID}
+	units := groupChatHistoryUnits(rest)
+
+	available := b.budget - b.reserved
+	for _, msg := range pinned {
+		available -= estimateMessageTokens(msg)
+	}
+	if summary != "" {
+		available -= estimateMessageTokens(chatSummaryMessage(summary))
+	}
+
+	total := 0
+	for i := range units {
+		total += units[i].tokens
+	}
+
+	split := 0
+	if b.budget > 0 && total > available {
+		split = splitChatHistoryUnits(units, available/2)
+	}
+
+	if split > 0 {
+		newSummary, usage, err := b.summarize(ctx, summary, units[:split])
+		result.SummaryUsage = usage
+		if err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":  "chat_history_summarize",
+				"message": "早期消息摘要失败，本轮仅丢弃早期消息",
+				"units":   split,
+				"error":   err.Error(),
+			})
+		} else {
+			result.Summary = newSummary
+			result.SummaryMessageID = units[split-1].lastID()
+			result.Summarized = true
+		}
+	}
+
+	messages := make([]*schema.Message, 0, len(pinned)+len(rest)+1)
+	messages = append(messages, pinned...)
+	if result.Summary != "" {
+		messages = append(messages, chatSummaryMessage(result.Summary))
+	}
+	for _, unit := range units[split:] {
+		for _, item := range unit.messages {
+			messages = append(messages, item.Message)
+		}
+	}
+	result.Messages = messages
+	return result
+}
+
+// summarize 将旧摘要与早期对话合并为新摘要，同时返回模型调用的 token 用量
+func (b *chatHistoryBuilder) summarize(ctx context.Context, summary string, units []chatHistoryUnit) (string, *schema.TokenUsage, error) {
+	if b.model == nil {
+		return "", nil, errors.New("chat model is nil")
+	}
+
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString("已有摘要：\n")
+		sb.WriteString(summary)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("新增对话：\n")
+	for _, unit := range units {
+		for _, item := range unit.messages {
+			writeChatTranscriptLine(&sb, item.Message)
+		}
+	}
+
+	resp, err := b.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(constant.ChatHistorySummaryPrompt),
+		schema.UserMessage(sb.String()),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	var usage *schema.TokenUsage
+	if resp != nil && resp.ResponseMeta != nil {
+		usage = resp.ResponseMeta.Usage
+	}
+	if resp == nil || strings.TrimSpace(resp.Content) == "" {
+		return "", usage, errors.New("empty summary")
+	}
+	return strings.TrimSpace(resp.Content), usage, nil
+}
+
+// groupChatHistoryUnits 将消息划分为不可拆分的单位：tool 消息总是并入前一个单位
+func groupChatHistoryUnits(history []chatHistoryMessage) []chatHistoryUnit {
+	units := make([]chatHistoryUnit, 0, len(history))
+	for _, item := range history {
+		tokens := estimateMessageTokens(item.Message)
+		if item.Message.Role == schema.Tool && len(units) > 0 {
+			last := &units[len(units)-1]
+			last.messages = append(last.messages, item)
+			last.tokens += tokens
+			continue
+		}
+		units = append(units, chatHistoryUnit{messages: []chatHistoryMessage{item}, tokens: tokens})
+	}
+	return units
+}
+
+// splitChatHistoryUnits 返回需要摘要的单位数：从最新的单位向前保留，直到超出 keepTokens。
+// 最新的一个单位总是保留；保留部分不能以孤立的 tool 消息开头。
+func splitChatHistoryUnits(units []chatHistoryUnit, keepTokens int) int {
+	if len(units) == 0 {
+		return 0
+	}
+	split := len(units) - 1
+	used := units[split].tokens
+	for split > 0 && used+units[split-1].tokens <= keepTokens {
+		split--
+		used += units[split].tokens
+	}
+	for split < len(units)-1 && units[split].messages[0].Message.Role == schema.Tool {
+		split++
+	}
+	return split
+}
+
+// chatSummaryMessage 将摘要包装为注入上下文的系统消息
+func chatSummaryMessage(summary string) *schema.Message {
+	return schema.SystemMessage(constant.ChatHistorySummaryPrefix + summary)
+}
+
+func writeChatTranscriptLine(sb *strings.Builder, msg *schema.Message) {
+	switch msg.Role {
+	case schema.User:
+		sb.WriteString("[用户] ")
+		sb.WriteString(chatMessageText(msg))
+	case schema.Assistant:
+		sb.WriteString("[助手] ")
+		sb.WriteString(chatMessageText(msg))
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(sb, " (调用工具 %s: %s)", call.Function.Name, call.Function.Arguments)
+		}
+	case schema.Tool:
+		fmt.Fprintf(sb, "[工具 %s] ", msg.ToolName)
+		sb.WriteString(truncateRunes(chatMessageText(msg), chatSummaryToolResultMaxRunes))
+	default:
+		return
+	}
+	sb.WriteString("\n")
+}
+
+// chatMessageText 提取消息中的文本内容
+func chatMessageText(msg *schema.Message) string {
+	if len(msg.MultiContent) == 0 && len(msg.UserInputMultiContent) == 0 {
+		return msg.Content
+	}
+	parts := []string{msg.Content}
+	for _, part := range msg.MultiContent {
+		if part.Type == schema.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	for _, part := range msg.UserInputMultiContent {
+		if part.Type == schema.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+func truncateRunes(text string, maxRunes int) string {
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	return string([]rune(text)[:maxRunes]) + "..."
+}
+
+// estimateTokens 粗略估算文本 token 数：非 ASCII 字符（中文等）按 1 个 token，ASCII 字符按 4 个折合 1 个 token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
+
+// estimateMessageTokens 估算单条消息的 token 数
+func estimateMessageTokens(msg *schema.Message) int {
+	tokens := chatMessageTokenOverhead + estimateTokens(chatMessageText(msg))
+	for _, call := range msg.ToolCalls {
+		tokens += estimateTokens(call.Function.Name) + estimateTokens(call.Function.Arguments)
+	}
+	for _, part := range msg.UserInputMultiContent {
+		if part.Type == schema.ChatMessagePartTypeImageURL {
+			tokens += constant.ChatAttachmentImageTokenEstimate
+		}
+	}
+	return tokens
+}
+
+// buildAgentHistory 构建发送给 Agent 的历史消息，超出预算时滚动摘要早期消息并持久化摘要；instruction 为系统提示词，计入预算
+func (s *ChatService) buildAgentHistory(ctx context.Context, userID, conversationID uint, instruction string) ([]adk.Message, error) {
+	var conv models.Conversation
+	if err := s.db.WithContext(ctx).
+		Select("id", "summary", "summary_message_id").
+		Where("id = ? AND user_id = ?", conversationID, userID).
+		First(&conv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.ConversationNotFound)
+		}
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "build_agent_history_get_conversation",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get conversation: %w", err))
+	}
+
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "build_agent_history_get_messages",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+		return nil, err
+	}
+
+	// 只取当前分支；摘要位置不在当前分支上时（切换或重新生成了更早的消息），摘要作废
+	path := tree.activePath()
+	storedSummaryID := conv.SummaryMessageID
+	start := slices.IndexFunc(path, func(row *models.ConversationMessage) bool {
+		return row.ID == conv.SummaryMessageID
+	})
+	if start < 0 {
+		conv.Summary, conv.SummaryMessageID = "", 0
+	}
+
+	// 系统提示词由 Agent 的 Instruction 提供，历史中的系统消息不再重复发送
+	history := make([]chatHistoryMessage, 0, len(path)-start-1)
+	for _, row := range path[start+1:] {
+		if row.Role == string(schema.System) {
+			continue
+		}
+		msg, err := conversationMessageToSchemaMessage(row)
+		if err != nil {
+			return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to unmarshal message: %w", err))
+		}
+		history = append(history, chatHistoryMessage{ID: row.ID, Message: msg})
+	}
+
+	// 摘要使用默认模型顺序，不占用对话指定的模型
+	chatModel, err := s.createChatModel(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	builder := newChatHistoryBuilder(chatModel, s.cfg.LLM.HistoryTokenBudget, estimateTokens(instruction))
+	result := builder.Build(ctx, conv.Summary, conv.SummaryMessageID, history)
+
+	// 摘要调用同样计入用户当日用量
+	if err := recordLLMCallUsage(s.db.WithContext(ctx), userID, result.SummaryUsage); err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "build_agent_history_record_usage",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+	}
+
+	if result.Summarized {
+		// 以旧的摘要位置作为条件，避免并发请求用较旧的摘要覆盖较新的摘要
+		if err := s.db.WithContext(ctx).Model(&models.Conversation{}).
+			Where("id = ? AND summary_message_id = ?", conversationID, storedSummaryID).
+			UpdateColumns(map[string]any{
+				"summary":            result.Summary,
+				"summary_message_id": result.SummaryMessageID,
+			}).Error; err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":          "build_agent_history_save_summary",
+				"user_id":         userID,
+				"conversation_id": conversationID,
+				"error":           err.Error(),
+			})
+		} else {
+			logger.InfoCtx(ctx, map[string]any{
+				"action":             "build_agent_history_summarized",
+				"user_id":            userID,
+				"conversation_id":    conversationID,
+				"summary_message_id": result.SummaryMessageID,
+			})
+		}
+	}
+
+	return result.Messages, nil
+}
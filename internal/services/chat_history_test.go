@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+type fakeToolCallingChatModel struct {
+	reply string
+	usage *schema.TokenUsage
+	err   error
+	calls [][]*schema.Message
+}
+
+func (f *fakeToolCallingChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	f.calls = append(f.calls, input)
+	if f.err != nil {
+		return nil, f.err
+	}
+	msg := schema.AssistantMessage(f.reply, nil)
+	if f.usage != nil {
+		msg.ResponseMeta = &schema.ResponseMeta{Usage: f.usage}
+	}
+	return msg, nil
+}
+
+func (f *fakeToolCallingChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := f.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (f *fakeToolCallingChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	return f, nil
+}
+
+// buildTestHistory 构造一段包含工具调用的对话：
+// 1 system, 2 user, 3 assistant(tool call), 4 tool, 5 assistant, 6 user, 7 assistant
+func buildTestHistory(turnText string) []chatHistoryMessage {
+	toolCall := schema.ToolCall{ID: "call_1", Function: schema.FunctionCall{Name: "search", Arguments: `{"q":"高数"}`}}
+	return []chatHistoryMessage{
+		{ID: 1, Message: schema.SystemMessage("你是学习助手")},
+		{ID: 2, Message: schema.UserMessage(turnText)},
+		{ID: 3, Message: schema.AssistantMessage("", []schema.ToolCall{toolCall})},
+		{ID: 4, Message: schema.ToolMessage(turnText, "call_1", schema.WithToolName("search"))},
+		{ID: 5, Message: schema.AssistantMessage(turnText, nil)},
+		{ID: 6, Message: schema.UserMessage(turnText)},
+		{ID: 7, Message: schema.AssistantMessage("最新回答", nil)},
+	}
+}
+
+func TestChatHistoryBuilderKeepsAllWithinBudget(t *testing.T) {
+	model := &fakeToolCallingChatModel{reply: "摘要"}
+	history := buildTestHistory("短消息")
+
+	result := newChatHistoryBuilder(model, 10000, 0).Build(context.Background(), "", 0, history)
+
+	if result.Summarized || len(model.calls) != 0 {
+		t.Fatalf("expected no summarization, got summarized=%v calls=%d", result.Summarized, len(model.calls))
+	}
+	if len(result.Messages) != len(history) {
+		t.Fatalf("expected %d messages, got %d", len(history), len(result.Messages))
+	}
+	for i := range history {
+		if result.Messages[i] != history[i].Message {
+			t.Fatalf("message %d changed", i)
+		}
+	}
+}
+
+func TestChatHistoryBuilderSummarizesOlderTurns(t *testing.T) {
+	model := &fakeToolCallingChatModel{reply: "用户在复习高数", usage: &schema.TokenUsage{TotalTokens: 42}}
+	history := buildTestHistory(strings.Repeat("微积分", 50))
+
+	result := newChatHistoryBuilder(model, 400, 0).Build(context.Background(), "旧摘要", 0, history)
+
+	if !result.Summarized {
+		t.Fatal("expected summarization")
+	}
+	if len(model.calls) != 1 {
+		t.Fatalf("expected one summarize call, got %d", len(model.calls))
+	}
+	prompt := model.calls[0][1].Content
+	if !strings.Contains(prompt, "旧摘要") || !strings.Contains(prompt, "[工具 search]") {
+		t.Fatalf("summarize prompt missing previous summary or tool result: %q", prompt)
+	}
+	if result.Summary != "用户在复习高数" {
+		t.Fatalf("unexpected summary %q", result.Summary)
+	}
+	if result.SummaryUsage == nil || result.SummaryUsage.TotalTokens != 42 {
+		t.Fatalf("summarize usage should be returned for metering, got %+v", result.SummaryUsage)
+	}
+
+	msgs := result.Messages
+	if msgs[0] != history[0].Message {
+		t.Fatalf("system prompt should be kept first, got %+v", msgs[0])
+	}
+	if msgs[1].Role != schema.System || msgs[1].Content != constant.ChatHistorySummaryPrefix+"用户在复习高数" {
+		t.Fatalf("expected summary message, got %+v", msgs[1])
+	}
+	if last := msgs[len(msgs)-1]; last != history[len(history)-1].Message {
+		t.Fatalf("latest message should be kept verbatim, got %+v", last)
+	}
+	for _, msg := range msgs {
+		if msg == history[1].Message {
+			t.Fatal("oldest user turn should have been summarized")
+		}
+	}
+	if result.SummaryMessageID == 0 || result.SummaryMessageID >= 7 {
+		t.Fatalf("unexpected summary message id %d", result.SummaryMessageID)
+	}
+}
+
+func TestChatHistoryBuilderNeverSplitsToolPairs(t *testing.T) {
+	model := &fakeToolCallingChatModel{reply: "摘要"}
+	history := buildTestHistory(strings.Repeat("线性代数", 40))
+
+	for budget := 50; budget <= 1000; budget += 10 {
+		result := newChatHistoryBuilder(model, budget, 0).Build(context.Background(), "", 0, history)
+
+		var hasCall, hasResult bool
+		for _, msg := range result.Messages {
+			hasCall = hasCall || len(msg.ToolCalls) > 0
+			hasResult = hasResult || msg.Role == schema.Tool
+		}
+		if hasCall != hasResult {
+			t.Fatalf("budget %d: tool call and result split (call=%v result=%v)", budget, hasCall, hasResult)
+		}
+		if result.Summarized && result.SummaryMessageID == 3 {
+			t.Fatalf("budget %d: summary boundary between tool call and tool result", budget)
+		}
+	}
+}
+
+func TestChatHistoryBuilderKeepsLatestUnitOverBudget(t *testing.T) {
+	model := &fakeToolCallingChatModel{reply: "摘要"}
+	history := []chatHistoryMessage{
+		{ID: 1, Message: schema.UserMessage("旧问题")},
+		{ID: 2, Message: schema.UserMessage(strings.Repeat("超长的问题", 100))},
+	}
+
+	result := newChatHistoryBuilder(model, 20, 0).Build(context.Background(), "", 0, history)
+
+	if result.SummaryMessageID != 1 {
+		t.Fatalf("expected summary to cover message 1, got %d", result.SummaryMessageID)
+	}
+	if last := result.Messages[len(result.Messages)-1]; last != history[1].Message {
+		t.Fatalf("latest message should always be kept, got %+v", last)
+	}
+}
+
+func TestChatHistoryBuilderSummaryFailureDropsOlderTurns(t *testing.T) {
+	model := &fakeToolCallingChatModel{err: errors.New("llm unavailable")}
+	history := buildTestHistory(strings.Repeat("概率论", 50))
+
+	result := newChatHistoryBuilder(model, 400, 0).Build(context.Background(), "旧摘要", 5, history)
+
+	if result.Summarized {
+		t.Fatal("summary should not be persisted on failure")
+	}
+	if result.Summary != "旧摘要" || result.SummaryMessageID != 5 {
+		t.Fatalf("previous summary should be kept, got %q/%d", result.Summary, result.SummaryMessageID)
+	}
+	if last := result.Messages[len(result.Messages)-1]; last != history[len(history)-1].Message {
+		t.Fatalf("latest message should be kept, got %+v", last)
+	}
+}
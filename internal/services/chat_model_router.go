@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// defaultFirstTokenTimeout 默认等待首个 token 的超时时间
+	defaultFirstTokenTimeout = 30 * time.Second
+	// modelFailureThreshold 连续失败多少次后将模型标记为不健康
+	modelFailureThreshold = 3
+	// modelUnhealthyCooldown 不健康模型的冷却时间，冷却结束后重新参与优先级排序
+	modelUnhealthyCooldown = time.Minute
+	// chatModelProfileExtraKey 消息 Extra 中记录实际生成该消息的模型配置名称
+	chatModelProfileExtraKey = "model_profile"
+)
+
+var _ einomodel.ToolCallingChatModel = (*failoverChatModel)(nil)
+
+// modelHealthState 单个模型的健康状态
+type modelHealthState struct {
+	failures       int
+	unhealthyUntil time.Time
+	lastError      string
+}
+
+// modelHealthTracker 进程内的模型健康状态，连续失败达到阈值后在冷却期内降低优先级
+type modelHealthTracker struct {
+	mu     sync.Mutex
+	states map[string]*modelHealthState
+	now    func() time.Time
+}
+
+func newModelHealthTracker() *modelHealthTracker {
+	return &modelHealthTracker{
+		states: make(map[string]*modelHealthState),
+		now:    time.Now,
+	}
+}
+
+func (t *modelHealthTracker) healthy(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[name]
+	return !ok || !t.now().Before(state.unhealthyUntil)
+}
+
+func (t *modelHealthTracker) recordSuccess(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.states, name)
+}
+
+func (t *modelHealthTracker) recordFailure(name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[name]
+	if !ok {
+		state = &modelHealthState{}
+		t.states[name] = state
+	}
+	state.failures++
+	state.lastError = err.Error()
+	if state.failures >= modelFailureThreshold {
+		state.unhealthyUntil = t.now().Add(modelUnhealthyCooldown)
+	}
+}
+
+// chatModelCandidate 参与故障转移的模型
+type chatModelCandidate struct {
+	name              string
+	model             einomodel.ToolCallingChatModel
+	firstTokenTimeout time.Duration
+	vision            bool // 是否支持图片输入
+}
+
+// failoverChatModel 按顺序尝试多个模型：在收到首个 token 之前出错或超时，自动切换到下一个模型。
+// 一旦开始输出，后续错误直接返回给调用方，避免重复输出。
+type failoverChatModel struct {
+	candidates []chatModelCandidate
+	health     *modelHealthTracker
+}
+
+// ordered 健康的模型优先，不健康的模型按原顺序排在最后兜底；输入包含图片时只使用支持图片的模型
+func (f *failoverChatModel) ordered(input []*schema.Message) ([]chatModelCandidate, error) {
+	needVision := messagesHaveImages(input)
+	healthy := make([]chatModelCandidate, 0, len(f.candidates))
+	var unhealthy []chatModelCandidate
+	for _, c := range f.candidates {
+		if needVision && !c.vision {
+			continue
+		}
+		if f.health.healthy(c.name) {
+			healthy = append(healthy, c)
+		} else {
+			unhealthy = append(unhealthy, c)
+		}
+	}
+	ordered := append(healthy, unhealthy...)
+	if len(ordered) == 0 {
+		return nil, errors.New("no vision-capable chat model available")
+	}
+	return ordered, nil
+}
+
+// messagesHaveImages 判断输入是否包含图片
+func messagesHaveImages(input []*schema.Message) bool {
+	for _, msg := range input {
+		for _, part := range msg.UserInputMultiContent {
+			if part.Type == schema.ChatMessagePartTypeImageURL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *failoverChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	candidates, err := f.ordered(input)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, c := range candidates {
+		msg, err := c.model.Generate(ctx, input, opts...)
+		if err == nil {
+			f.health.recordSuccess(c.name)
+			return withModelProfile(msg, c.name), nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		f.fail(ctx, c.name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+	}
+	return nil, fmt.Errorf("all chat models failed: %w", errors.Join(errs...))
+}
+
+func (f *failoverChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	candidates, err := f.ordered(input)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, c := range candidates {
+		// 每次尝试使用独立的 context，放弃该模型时取消请求，解除阻塞在 Recv 上的读取
+		attemptCtx, cancel := context.WithCancel(ctx)
+		sr, err := c.model.Stream(attemptCtx, input, opts...)
+		if err == nil {
+			var first *schema.Message
+			first, err = recvFirstChunk(ctx, sr, c.firstTokenTimeout)
+			if err == nil {
+				f.health.recordSuccess(c.name)
+				return prependStreamChunk(withModelProfile(first, c.name), sr, cancel), nil
+			}
+			sr.Close()
+		}
+		cancel()
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		f.fail(ctx, c.name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+	}
+	return nil, fmt.Errorf("all chat models failed: %w", errors.Join(errs...))
+}
+
+func (f *failoverChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	candidates := make([]chatModelCandidate, 0, len(f.candidates))
+	var errs []error
+	for _, c := range f.candidates {
+		m, err := c.model.WithTools(tools)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		c.model = m
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no chat model supports tools: %w", errors.Join(errs...))
+	}
+	return &failoverChatModel{candidates: candidates, health: f.health}, nil
+}
+
+func (f *failoverChatModel) fail(ctx context.Context, name string, err error) {
+	f.health.recordFailure(name, err)
+	logger.WarnCtx(ctx, map[string]any{
+		"action":  "chat_model_failover",
+		"message": "模型调用失败，尝试切换到下一个模型",
+		"model":   name,
+		"error":   err.Error(),
+	})
+}
+
+// withModelProfile 在消息（流式时为首个分片）上标记模型配置名称，拼接分片后保留在完整消息中
+func withModelProfile(msg *schema.Message, profile string) *schema.Message {
+	if msg == nil {
+		return nil
+	}
+	out := *msg
+	out.Extra = maps.Clone(msg.Extra)
+	if out.Extra == nil {
+		out.Extra = make(map[string]any, 1)
+	}
+	out.Extra[chatModelProfileExtraKey] = profile
+	return &out
+}
+
+// recvFirstChunk 在超时时间内读取首个 token，空流也视为失败
+func recvFirstChunk(ctx context.Context, sr *schema.StreamReader[*schema.Message], timeout time.Duration) (*schema.Message, error) {
+	if timeout <= 0 {
+		timeout = defaultFirstTokenTimeout
+	}
+	type recvResult struct {
+		msg *schema.Message
+		err error
+	}
+	ch := make(chan recvResult, 1)
+	go func() {
+		msg, err := sr.Recv()
+		ch <- recvResult{msg: msg, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		if errors.Is(r.err, io.EOF) {
+			return nil, errors.New("empty stream")
+		}
+		return r.msg, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("first token timeout after %s", timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// prependStreamChunk 将已读取的首个 token 放回流的开头，流结束后调用 cancel 释放本次请求
+func prependStreamChunk(first *schema.Message, sr *schema.StreamReader[*schema.Message], cancel context.CancelFunc) *schema.StreamReader[*schema.Message] {
+	out, w := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer w.Close()
+		defer sr.Close()
+		defer cancel()
+		if closed := w.Send(first, nil); closed {
+			return
+		}
+		for {
+			msg, err := sr.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if closed := w.Send(msg, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
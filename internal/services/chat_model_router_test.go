@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// scriptedStreamModel 按脚本返回流：streamErr 直接失败，hang 不返回首个 token 直到请求被取消，否则依次输出 chunks
+type scriptedStreamModel struct {
+	streamErr error
+	hang      bool
+	chunks    []string
+	calls     int
+	// ctx 最近一次 Stream 调用的请求 context
+	ctx context.Context
+}
+
+func (m *scriptedStreamModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	m.calls++
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+	return schema.AssistantMessage(m.chunks[0], nil), nil
+}
+
+func (m *scriptedStreamModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	m.ctx = ctx
+	if m.streamErr != nil {
+		return nil, m.streamErr
+	}
+	sr, w := schema.Pipe[*schema.Message](len(m.chunks))
+	if m.hang {
+		// 与 HTTP 流一致：请求被取消后读取返回错误
+		go func() {
+			<-ctx.Done()
+			w.Send(nil, ctx.Err())
+			w.Close()
+		}()
+		return sr, nil
+	}
+	for _, chunk := range m.chunks {
+		w.Send(schema.AssistantMessage(chunk, nil), nil)
+	}
+	w.Close()
+	return sr, nil
+}
+
+func (m *scriptedStreamModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func readStreamContent(t *testing.T, sr *schema.StreamReader[*schema.Message]) string {
+	t.Helper()
+	defer sr.Close()
+	var content string
+	for {
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			return content
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		content += msg.Content
+	}
+}
+
+func TestFailoverChatModelStreamFailsOverBeforeFirstToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary *scriptedStreamModel
+	}{
+		{name: "stream error", primary: &scriptedStreamModel{streamErr: errors.New("503")}},
+		{name: "first token timeout", primary: &scriptedStreamModel{hang: true}},
+		{name: "empty stream", primary: &scriptedStreamModel{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup := &scriptedStreamModel{chunks: []string{"你好", "，同学"}}
+			health := newModelHealthTracker()
+			model := &failoverChatModel{
+				candidates: []chatModelCandidate{
+					{name: "primary", model: tt.primary, firstTokenTimeout: 20 * time.Millisecond},
+					{name: "backup", model: backup},
+				},
+				health: health,
+			}
+
+			sr, err := model.Stream(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := readStreamContent(t, sr); got != "你好，同学" {
+				t.Fatalf("unexpected content %q", got)
+			}
+			if health.states["primary"] == nil || health.states["primary"].failures != 1 {
+				t.Fatalf("expected primary failure to be recorded, got %+v", health.states["primary"])
+			}
+			if tt.primary.ctx != nil && tt.primary.ctx.Err() == nil {
+				t.Fatal("abandoned attempt should have its request cancelled")
+			}
+			if backup.ctx.Err() == nil {
+				t.Fatal("request should be released once the stream is fully read")
+			}
+		})
+	}
+}
+
+func TestFailoverChatModelAllCandidatesFail(t *testing.T) {
+	model := &failoverChatModel{
+		candidates: []chatModelCandidate{
+			{name: "a", model: &scriptedStreamModel{streamErr: errors.New("a down")}},
+			{name: "b", model: &scriptedStreamModel{streamErr: errors.New("b down")}},
+		},
+		health: newModelHealthTracker(),
+	}
+
+	if _, err := model.Stream(context.Background(), nil); err == nil {
+		t.Fatal("expected error when all candidates fail")
+	}
+	if _, err := model.Generate(context.Background(), nil); err == nil {
+		t.Fatal("expected error when all candidates fail")
+	}
+}
+
+func TestFailoverChatModelSkipsUnhealthyProfile(t *testing.T) {
+	now := time.Now()
+	health := newModelHealthTracker()
+	health.now = func() time.Time { return now }
+
+	primary := &scriptedStreamModel{streamErr: errors.New("timeout")}
+	backup := &scriptedStreamModel{chunks: []string{"ok"}}
+	model := &failoverChatModel{
+		candidates: []chatModelCandidate{
+			{name: "primary", model: primary},
+			{name: "backup", model: backup},
+		},
+		health: health,
+	}
+
+	for range modelFailureThreshold {
+		if _, err := model.Generate(context.Background(), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if health.healthy("primary") {
+		t.Fatal("primary should be unhealthy after consecutive failures")
+	}
+
+	primary.calls = 0
+	if _, err := model.Generate(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary.calls != 0 {
+		t.Fatalf("unhealthy primary should be tried after healthy candidates, got %d calls", primary.calls)
+	}
+
+	now = now.Add(modelUnhealthyCooldown)
+	if !health.healthy("primary") {
+		t.Fatal("primary should be healthy again after cooldown")
+	}
+}
+
+func TestWithModelProfileDoesNotMutateInput(t *testing.T) {
+	in := &schema.Message{Role: schema.Assistant, Content: "hi", Extra: map[string]any{"k": "v"}}
+	out := withModelProfile(in, "backup")
+	if out.Extra[chatModelProfileExtraKey] != "backup" || out.Extra["k"] != "v" {
+		t.Fatalf("unexpected extra: %v", out.Extra)
+	}
+	if _, ok := in.Extra[chatModelProfileExtraKey]; ok {
+		t.Fatal("input message extra was mutated")
+	}
+	if withModelProfile(nil, "x") != nil {
+		t.Fatal("nil message should stay nil")
+	}
+}
+
+func TestCreateChatModelRetriesFailedProfile(t *testing.T) {
+	ctx := context.Background()
+	attempts := map[string]int{}
+	s := &ChatService{
+		modelHealth: newModelHealthTracker(),
+		profiles:    []config.LLMProfile{{Name: "primary"}, {Name: "backup"}},
+		newProfileModel: func(ctx context.Context, profile config.LLMProfile) (einomodel.ToolCallingChatModel, error) {
+			attempts[profile.Name]++
+			if profile.Name == "primary" && attempts[profile.Name] == 1 {
+				return nil, errors.New("invalid config")
+			}
+			return &scriptedStreamModel{}, nil
+		},
+	}
+
+	candidateNames := func() []string {
+		t.Helper()
+		chatModel, err := s.createChatModel(ctx, 1, "")
+		if err != nil {
+			t.Fatalf("createChatModel() error = %v", err)
+		}
+		var names []string
+		for _, candidate := range chatModel.(*failoverChatModel).candidates {
+			names = append(names, candidate.name)
+		}
+		return names
+	}
+
+	// 首次创建失败的模型暂不可用，下次调用时重新创建
+	if got := candidateNames(); len(got) != 1 || got[0] != "backup" {
+		t.Fatalf("first candidates = %v", got)
+	}
+	if got := candidateNames(); len(got) != 2 || got[0] != "primary" {
+		t.Fatalf("second candidates = %v", got)
+	}
+	// 创建成功后复用已缓存的实例
+	candidateNames()
+	if attempts["primary"] != 2 || attempts["backup"] != 1 {
+		t.Fatalf("attempts = %v", attempts)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/schema"
+)
+
+// moderateUserMessage 审核用户消息中的文本（正文与多模态文本片段），打码时返回替换后的副本。
+// 任一段被拦截即拒绝本轮对话；命中记录关联到对话ID。
+func (s *ChatService) moderateUserMessage(ctx context.Context, userID, conversationID uint, msg *schema.Message) (*schema.Message, error) {
+	if s.moderationService == nil || msg == nil {
+		return msg, nil
+	}
+	targetID := moderationTargetID(conversationID)
+	out := *msg
+
+	decision, err := s.moderationService.Moderate(ctx, constant.ModerationSurfaceChatInput, userID, msg.Content)
+	if err != nil {
+		return nil, err
+	}
+	out.Content = decision.Text
+	s.moderationService.Audit(ctx, decision, targetID)
+
+	if len(msg.UserInputMultiContent) > 0 {
+		out.UserInputMultiContent = slices.Clone(msg.UserInputMultiContent)
+		for i, part := range out.UserInputMultiContent {
+			if part.Type != schema.ChatMessagePartTypeText {
+				continue
+			}
+			decision, err := s.moderationService.Moderate(ctx, constant.ModerationSurfaceChatInput, userID, part.Text)
+			if err != nil {
+				return nil, err
+			}
+			out.UserInputMultiContent[i].Text = decision.Text
+			s.moderationService.Audit(ctx, decision, targetID)
+		}
+	}
+	return &out, nil
+}
+
+// moderateAssistantMessage 审核助手回答。内容已经流式发给客户端，命中打码或拦截时
+// 保存处理后的内容，并发送 moderation 事件让客户端替换刚才的回答。
+func (p *streamEventProcessor) moderateAssistantMessage(msg *schema.Message) *schema.Message {
+	if p.service == nil || p.service.moderationService == nil || msg == nil || msg.Role != schema.Assistant || msg.Content == "" {
+		return msg
+	}
+	decision, err := p.service.moderationService.Moderate(p.ctx, constant.ModerationSurfaceChatOutput, p.userID, msg.Content)
+	if err == nil {
+		p.service.moderationService.Audit(p.ctx, decision, moderationTargetID(p.conversationID))
+	}
+	if decision.Text == msg.Content && decision.Action != constant.ModerationActionBlock {
+		return msg
+	}
+
+	out := *msg
+	out.Content = decision.Text
+	if decision.Action == constant.ModerationActionBlock {
+		out.Content = constant.ChatModerationBlockedReply
+	}
+	logger.InfoCtx(p.ctx, map[string]any{
+		"action":          "chat_output_moderated",
+		"user_id":         p.userID,
+		"conversation_id": p.conversationID,
+		"checkpoint_id":   p.checkpointID,
+		"moderation":      decision.Action,
+	})
+
+	event := map[string]interface{}{
+		"type":    "moderation",
+		"action":  decision.Action,
+		"content": out.Content,
+	}
+	if data, err := json.Marshal(event); err == nil {
+		p.outputChan <- p.stream.frame(p.ctx, data)
+	}
+	return &out
+}
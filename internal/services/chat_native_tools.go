@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	einotool "github.com/cloudwego/eino/components/tool"
+	einotoolutils "github.com/cloudwego/eino/components/tool/utils"
+)
+
+const (
+	nativeToolSource          = "native"
+	nativeToolMaterialMaxSize = 10
+)
+
+// SearchMaterialsToolInput search_materials 工具参数
+type SearchMaterialsToolInput struct {
+	Keywords string `json:"keywords" jsonschema_description:"搜索关键词，例如课程名、资料名或标签"`
+	PageSize int    `json:"page_size,omitempty" jsonschema_description:"返回数量，默认5，最大10"`
+}
+
+// QuizMeToolInput quiz_me 工具参数
+type QuizMeToolInput struct {
+	ProjectID uint `json:"project_id,omitempty" jsonschema_description:"题库项目ID；不传时返回可选的题库项目列表"`
+}
+
+// GradeQuizAnswerToolInput grade_quiz_answer 工具参数
+type GradeQuizAnswerToolInput struct {
+	QuestionID uint   `json:"question_id" jsonschema_description:"quiz_me 返回的题目ID（分组题传子题ID）"`
+	Answer     string `json:"answer" jsonschema_description:"用户的作答内容，选择题可以是选项字母或选项原文"`
+}
+
+// LookupWordToolInput lookup_word 工具参数
+type LookupWordToolInput struct {
+	Word string `json:"word" jsonschema_description:"要查询的英文单词"`
+}
+
+// quizQuestion 出题时返回给模型的题目，不包含答案
+type quizQuestion struct {
+	QuestionID   uint           `json:"question_id"`
+	ProjectID    uint           `json:"project_id"`
+	Type         string         `json:"type"`
+	Title        string         `json:"title"`
+	Options      []string       `json:"options,omitempty"`
+	SubQuestions []quizQuestion `json:"sub_questions,omitempty"`
+}
+
+type quizMeResult struct {
+	Projects []response.QuestionProjectResponse `json:"projects,omitempty"`
+	Question *quizQuestion                      `json:"question,omitempty"`
+	Hint     string                             `json:"hint"`
+}
+
+type gradeQuizAnswerResult struct {
+	QuestionID      uint   `json:"question_id"`
+	Type            string `json:"type"`
+	Correct         *bool  `json:"correct,omitempty"` // 简答题由模型对照参考答案判分，不给出结论
+	ReferenceAnswer string `json:"reference_answer"`
+	Hint            string `json:"hint"`
+}
+
+type lookupWordResult struct {
+	Word       string `json:"word"`
+	PhoneticUK string `json:"phonetic_uk,omitempty"`
+	PhoneticUS string `json:"phonetic_us,omitempty"`
+	Trans      any    `json:"trans,omitempty"`
+	Sentences  any    `json:"sentences,omitempty"`
+	Phrases    any    `json:"phrases,omitempty"`
+	Synos      any    `json:"synos,omitempty"`
+	RelWords   any    `json:"rel_words,omitempty"`
+}
+
+// nativeAgentTool 内置工具及其所需权限
+type nativeAgentTool struct {
+	permission string
+	build      func() (einotool.InvokableTool, error)
+}
+
+// loadNativeTools 按用户权限构建直接调用业务服务的内置工具，工具错误同样转换为工具结果
+func (s *ChatService) loadNativeTools(ctx context.Context, userID, conversationID uint) []einotool.BaseTool {
+	if s.rbacService == nil {
+		return nil
+	}
+	snap, err := s.rbacService.GetUserPermissionSnapshot(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "stream_chat_load_native_tools",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"msg":             "Failed to get permission snapshot, skipping native tools",
+			"error":           err.Error(),
+		})
+		return nil
+	}
+
+	tools := make([]einotool.BaseTool, 0)
+	for _, spec := range s.nativeAgentTools(userID) {
+		if !snap.HasPermission(spec.permission) {
+			continue
+		}
+		t, err := spec.build()
+		if err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":          "stream_chat_build_native_tool",
+				"user_id":         userID,
+				"conversation_id": conversationID,
+				"error":           err.Error(),
+			})
+			continue
+		}
+		tools = append(tools, t)
+	}
+	return wrapAgentToolsWithFailureResults(ctx, tools, nativeToolSource, userID, conversationID)
+}
+
+// nativeAgentTools 列出所有内置工具，服务未注入时跳过对应工具
+func (s *ChatService) nativeAgentTools(userID uint) []nativeAgentTool {
+	specs := make([]nativeAgentTool, 0, 4)
+	if s.materialService != nil {
+		specs = append(specs, nativeAgentTool{
+			permission: constant.PermissionMaterialGet,
+			build: func() (einotool.InvokableTool, error) {
+				return einotoolutils.InferTool("search_materials",
+					"搜索学习资料库，按关键词返回匹配的资料文件（文件名、标签、热度、下载次数）",
+					func(ctx context.Context, in SearchMaterialsToolInput) (*response.MaterialSearchResponse, error) {
+						return s.searchMaterialsTool(ctx, in)
+					})
+			},
+		})
+	}
+	if s.questionService != nil {
+		specs = append(specs,
+			nativeAgentTool{
+				permission: constant.PermissionQuestion,
+				build: func() (einotool.InvokableTool, error) {
+					return einotoolutils.InferTool("quiz_me",
+						"刷题模式出题：从指定题库随机抽取一道题（不含答案）让用户作答；不传 project_id 时返回题库列表",
+						func(ctx context.Context, in QuizMeToolInput) (*quizMeResult, error) {
+							return s.quizMeTool(ctx, userID, in)
+						})
+				},
+			},
+			nativeAgentTool{
+				permission: constant.PermissionQuestion,
+				build: func() (einotool.InvokableTool, error) {
+					return einotoolutils.InferTool("grade_quiz_answer",
+						"批改用户对 quiz_me 题目的作答并记录做题次数：选择题直接判定对错，简答题返回参考答案供对照评分",
+						func(ctx context.Context, in GradeQuizAnswerToolInput) (*gradeQuizAnswerResult, error) {
+							return s.gradeQuizAnswerTool(ctx, userID, in)
+						})
+				},
+			},
+		)
+	}
+	if s.dictionaryService != nil {
+		specs = append(specs, nativeAgentTool{
+			permission: constant.PermissionDictionary,
+			build: func() (einotool.InvokableTool, error) {
+				return einotoolutils.InferTool("lookup_word",
+					"查询英语词典，返回音标、释义、例句、短语、同义词和派生词",
+					func(ctx context.Context, in LookupWordToolInput) (*lookupWordResult, error) {
+						return s.lookupWordTool(ctx, in)
+					})
+			},
+		})
+	}
+	return specs
+}
+
+func (s *ChatService) searchMaterialsTool(ctx context.Context, in SearchMaterialsToolInput) (*response.MaterialSearchResponse, error) {
+	keywords := strings.TrimSpace(in.Keywords)
+	if keywords == "" {
+		return nil, fmt.Errorf("keywords is required")
+	}
+	pageSize := in.PageSize
+	if pageSize <= 0 {
+		pageSize = 5
+	}
+	if pageSize > nativeToolMaterialMaxSize {
+		pageSize = nativeToolMaterialMaxSize
+	}
+	return s.materialService.SearchMaterials(ctx, &request.MaterialSearchRequest{
+		Keywords: keywords,
+		Page:     1,
+		PageSize: pageSize,
+	})
+}
+
+func (s *ChatService) quizMeTool(ctx context.Context, userID uint, in QuizMeToolInput) (*quizMeResult, error) {
+	if in.ProjectID == 0 {
+		projects, err := s.questionService.GetProjects(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &quizMeResult{Projects: projects, Hint: "请让用户选择一个题库项目后再次调用 quiz_me 并传入 project_id"}, nil
+	}
+
+	list, err := s.questionService.GetQuestions(ctx, userID, &request.GetQuestionRequest{ProjectID: in.ProjectID, Random: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.QuestionIDs) == 0 {
+		return nil, fmt.Errorf("题库 %d 中没有可用题目", in.ProjectID)
+	}
+	question, err := s.questionService.GetQuestionByID(ctx, userID, list.QuestionIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	quiz := toQuizQuestion(question)
+	return &quizMeResult{Question: &quiz, Hint: "请把题目展示给用户，不要透露答案；用户作答后调用 grade_quiz_answer 批改"}, nil
+}
+
+func (s *ChatService) gradeQuizAnswerTool(ctx context.Context, userID uint, in GradeQuizAnswerToolInput) (*gradeQuizAnswerResult, error) {
+	if in.QuestionID == 0 {
+		return nil, fmt.Errorf("question_id is required")
+	}
+	question, err := s.questionService.GetQuestionByID(ctx, userID, in.QuestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &gradeQuizAnswerResult{
+		QuestionID:      question.ID,
+		Type:            quizQuestionType(question.Type),
+		ReferenceAnswer: question.Answer,
+	}
+	if question.Type == int8(models.QuestionTypeChoice) {
+		correct := gradeChoiceAnswer(question.Answer, in.Answer, question.Options)
+		result.Correct = &correct
+		result.Hint = "请告知用户判定结果并讲解正确答案"
+	} else {
+		result.Hint = "请对照参考答案评估用户作答的要点覆盖情况并给出讲解"
+	}
+
+	// 做题次数只是统计，记录失败不影响批改
+	if err := s.questionService.SubmitPractice(ctx, userID, &request.SubmitPracticeRequest{QuestionID: question.ID, ProjectID: question.ProjectID}); err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":      "native_tool_submit_practice",
+			"user_id":     userID,
+			"question_id": question.ID,
+			"error":       err.Error(),
+		})
+	}
+	return result, nil
+}
+
+func (s *ChatService) lookupWordTool(ctx context.Context, in LookupWordToolInput) (*lookupWordResult, error) {
+	entry, err := s.dictionaryService.LookupWord(ctx, in.Word)
+	if err != nil {
+		return nil, err
+	}
+	return &lookupWordResult{
+		Word:       entry.Word,
+		PhoneticUK: entry.PhoneticUK,
+		PhoneticUS: entry.PhoneticUS,
+		Trans:      rawJSONValue(entry.Trans),
+		Sentences:  rawJSONValue(entry.Sentences),
+		Phrases:    rawJSONValue(entry.Phrases),
+		Synos:      rawJSONValue(entry.Synos),
+		RelWords:   rawJSONValue(entry.RelWords),
+	}, nil
+}
+
+// rawJSONValue 把 JSON 列解码为通用值，空值或非法 JSON 返回 nil
+func rawJSONValue(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func quizQuestionType(t int8) string {
+	if t == int8(models.QuestionTypeChoice) {
+		return "choice"
+	}
+	return "short_answer"
+}
+
+// toQuizQuestion 去掉答案与统计信息，仅保留出题所需字段
+func toQuizQuestion(q *response.QuestionResponse) quizQuestion {
+	quiz := quizQuestion{
+		QuestionID: q.ID,
+		ProjectID:  q.ProjectID,
+		Type:       quizQuestionType(q.Type),
+		Title:      q.Title,
+		Options:    q.Options,
+	}
+	for i := range q.SubQuestions {
+		quiz.SubQuestions = append(quiz.SubQuestions, toQuizQuestion(&q.SubQuestions[i]))
+	}
+	return quiz
+}
+
+// gradeChoiceAnswer 判定选择题作答：答案为选项字母时按字母集合比较（忽略顺序、大小写和分隔符），
+// 否则按选项原文比较；作答为选项原文时先换算成对应字母
+func gradeChoiceAnswer(expected, given string, options []string) bool {
+	expectedLetters, expectedIsLetters := choiceLetters(expected, len(options))
+	givenLetters, givenIsLetters := choiceLetters(given, len(options))
+	if !givenIsLetters {
+		givenLetters, givenIsLetters = optionLetters(given, options)
+	}
+	if expectedIsLetters {
+		return givenIsLetters && givenLetters == expectedLetters
+	}
+	if givenIsLetters {
+		// 参考答案为选项原文，作答为字母
+		if exp, ok := optionLetters(expected, options); ok {
+			return exp == givenLetters
+		}
+	}
+	return normalizeChoiceText(expected) != "" && normalizeChoiceText(expected) == normalizeChoiceText(given)
+}
+
+// choiceLetters 解析形如 "A"、"a,c"、"B D" 的字母答案，返回排序去重后的字母串
+func choiceLetters(answer string, optionCount int) (string, bool) {
+	letters := make([]rune, 0, 4)
+	for _, r := range strings.ToUpper(answer) {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			if optionCount > 0 && int(r-'A') >= optionCount {
+				return "", false
+			}
+			if !slices.Contains(letters, r) {
+				letters = append(letters, r)
+			}
+		case unicode.IsSpace(r) || strings.ContainsRune(",，、;；", r):
+		default:
+			return "", false
+		}
+	}
+	if len(letters) == 0 {
+		return "", false
+	}
+	slices.Sort(letters)
+	return string(letters), true
+}
+
+// optionLetters 将选项原文换算为字母
+func optionLetters(answer string, options []string) (string, bool) {
+	target := normalizeChoiceText(answer)
+	if target == "" {
+		return "", false
+	}
+	for i, option := range options {
+		if normalizeChoiceText(option) == target || normalizeChoiceText(stripOptionLabel(option, i)) == target {
+			return string(rune('A' + i)), true
+		}
+	}
+	return "", false
+}
+
+func normalizeChoiceText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// stripOptionLabel 去掉选项自带的 "A." "A、" 等序号前缀
+func stripOptionLabel(option string, index int) string {
+	trimmed := strings.TrimSpace(option)
+	label := string(rune('A' + index))
+	if !strings.HasPrefix(strings.ToUpper(trimmed), label) {
+		return option
+	}
+	rest := strings.TrimSpace(trimmed[1:])
+	for _, sep := range []string{".", "．", "、", ")", "）", ":", "："} {
+		if strings.HasPrefix(rest, sep) {
+			return strings.TrimPrefix(rest, sep)
+		}
+	}
+	return option
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+
+	json "github.com/bytedance/sonic"
+)
+
+func TestGradeChoiceAnswer(t *testing.T) {
+	options := []string{"A. 牛顿", "B. 爱因斯坦", "C. 伽利略", "D. 开普勒"}
+	cases := []struct {
+		name     string
+		expected string
+		given    string
+		want     bool
+	}{
+		{"single letter", "B", "b", true},
+		{"multi letter order and separators", "AC", "c, a", true},
+		{"multi letter partial", "AC", "A", false},
+		{"option text", "B", "爱因斯坦", true},
+		{"option text with label", "B", "B. 爱因斯坦", true},
+		{"wrong option", "B", "D", false},
+		{"letter outside options", "B", "F", false},
+		{"text reference with letter answer", "伽利略", "c", true},
+		{"text reference with text answer", "伽利略", " 伽利略 ", true},
+		{"empty answer", "A", "", false},
+	}
+	for _, tc := range cases {
+		if got := gradeChoiceAnswer(tc.expected, tc.given, options); got != tc.want {
+			t.Errorf("%s: gradeChoiceAnswer(%q, %q) = %v, want %v", tc.name, tc.expected, tc.given, got, tc.want)
+		}
+	}
+}
+
+func TestToQuizQuestionOmitsAnswer(t *testing.T) {
+	q := &response.QuestionResponse{
+		ID:        1,
+		ProjectID: 2,
+		Type:      int8(models.QuestionTypeEssay),
+		Title:     "材料题",
+		Answer:    "secret-parent",
+		SubQuestions: []response.QuestionResponse{
+			{ID: 3, ProjectID: 2, Type: int8(models.QuestionTypeChoice), Title: "子题", Options: []string{"x", "y"}, Answer: "secret-child"},
+		},
+	}
+	data, err := json.Marshal(toQuizQuestion(q))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("quiz question leaks the answer: %s", data)
+	}
+	if decoded["type"] != "short_answer" {
+		t.Fatalf("unexpected type: %v", decoded["type"])
+	}
+	subs, _ := decoded["sub_questions"].([]any)
+	if len(subs) != 1 {
+		t.Fatalf("expected one sub question, got %v", decoded["sub_questions"])
+	}
+}
+
+func TestNativeAgentToolsRespectPermissions(t *testing.T) {
+	s := &ChatService{
+		materialService:   &MaterialService{},
+		questionService:   &QuestionService{},
+		dictionaryService: &DictionaryService{},
+	}
+	snap := &UserPermissionSnapshot{PermissionTags: []string{constant.PermissionDictionary, constant.PermissionQuestion}}
+
+	ctx := context.Background()
+	var names []string
+	for _, spec := range s.nativeAgentTools(1) {
+		if !snap.HasPermission(spec.permission) {
+			continue
+		}
+		tool, err := spec.build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := tool.Info(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, info.Name)
+	}
+	want := []string{"quiz_me", "grade_quiz_answer", "lookup_word"}
+	if len(names) != len(want) {
+		t.Fatalf("tools = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("tools = %v, want %v", names, want)
+		}
+	}
+
+	admin := &UserPermissionSnapshot{IsAdmin: true}
+	if !admin.HasPermission(constant.PermissionMaterialGet) {
+		t.Fatal("admin should have every permission")
+	}
+}
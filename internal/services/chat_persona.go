@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	einotool "github.com/cloudwego/eino/components/tool"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// chatPersonaRuntime 运行对话时生效的人设配置
+type chatPersonaRuntime struct {
+	instruction  string
+	allowedTools []string // 为空表示不限制
+	modelProfile string
+	temperature  *float32
+}
+
+// defaultChatPersona 未选择人设或人设无法加载时使用的默认助手
+func defaultChatPersona() *chatPersonaRuntime {
+	return &chatPersonaRuntime{instruction: constant.ChatSystemPrompt}
+}
+
+// resolveChatPersona 加载对话的人设。提示词使用创建对话时的版本；工具、模型与温度使用人设的当前配置。
+// 人设被停用或删除后，已有对话继续使用原人设，加载失败时回退到默认助手。
+func (s *ChatService) resolveChatPersona(ctx context.Context, conv *models.Conversation) *chatPersonaRuntime {
+	if conv.PersonaID == 0 {
+		return defaultChatPersona()
+	}
+
+	var persona models.ChatPersona
+	if err := s.db.WithContext(ctx).Unscoped().First(&persona, conv.PersonaID).Error; err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "resolve_chat_persona",
+			"conversation_id": conv.ID,
+			"persona_id":      conv.PersonaID,
+			"error":           err.Error(),
+		})
+		return defaultChatPersona()
+	}
+
+	runtime := &chatPersonaRuntime{
+		instruction:  persona.SystemPrompt,
+		modelProfile: persona.ModelProfile,
+		temperature:  persona.Temperature,
+	}
+	if len(persona.AllowedTools) > 0 {
+		if err := json.Unmarshal(persona.AllowedTools, &runtime.allowedTools); err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":     "resolve_chat_persona_tools",
+				"persona_id": persona.ID,
+				"error":      err.Error(),
+			})
+		}
+	}
+
+	if conv.PersonaVersion > 0 && conv.PersonaVersion != persona.PromptVersion {
+		var version models.ChatPersonaPromptVersion
+		if err := s.db.WithContext(ctx).
+			Where("persona_id = ? AND version = ?", persona.ID, conv.PersonaVersion).
+			First(&version).Error; err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":     "resolve_chat_persona_version",
+				"persona_id": persona.ID,
+				"version":    conv.PersonaVersion,
+				"error":      err.Error(),
+			})
+		} else {
+			runtime.instruction = version.SystemPrompt
+		}
+	}
+	return runtime
+}
+
+// filterAgentTools 只保留人设允许的工具，allowed 为空时不过滤
+func filterAgentTools(ctx context.Context, tools []einotool.BaseTool, allowed []string) []einotool.BaseTool {
+	if len(allowed) == 0 {
+		return tools
+	}
+	filtered := make([]einotool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil || info == nil {
+			continue
+		}
+		if slices.Contains(allowed, info.Name) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// getSelectablePersona 获取可供新对话选择的人设
+func (s *ChatService) getSelectablePersona(ctx context.Context, personaID uint) (*models.ChatPersona, error) {
+	var persona models.ChatPersona
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND is_enabled = ?", personaID, true).
+		First(&persona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.ConversationPersonaNotFound)
+		}
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get persona: %w", err))
+	}
+	return &persona, nil
+}
+
+// ListChatPersonas 列出用户可选的人设
+func (s *ChatService) ListChatPersonas(ctx context.Context) ([]dto.ChatPersonaResponse, error) {
+	var personas []models.ChatPersona
+	if err := s.db.WithContext(ctx).
+		Select("id", "name", "description").
+		Where("is_enabled = ?", true).
+		Order("sort ASC, id ASC").
+		Find(&personas).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to list personas: %w", err))
+	}
+
+	items := make([]dto.ChatPersonaResponse, 0, len(personas))
+	for _, persona := range personas {
+		items = append(items, dto.ChatPersonaResponse{ID: persona.ID, Name: persona.Name, Description: persona.Description})
+	}
+	return items, nil
+}
+
+// AdminListChatPersonas 列出全部人设（含已停用）
+func (s *ChatService) AdminListChatPersonas(ctx context.Context) ([]models.ChatPersona, error) {
+	var personas []models.ChatPersona
+	if err := s.db.WithContext(ctx).Order("sort ASC, id ASC").Find(&personas).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to list personas: %w", err))
+	}
+	return personas, nil
+}
+
+// validatePersonaModelProfile 人设指定的模型必须是已配置的模型
+func (s *ChatService) validatePersonaModelProfile(profileName string) error {
+	if profileName == "" {
+		return nil
+	}
+	for _, profile := range s.profiles {
+		if profile.Name == profileName {
+			return nil
+		}
+	}
+	return apperr.New(constant.ConversationModelNotAllowed)
+}
+
+// personaNameTaken 名称唯一索引包含已删除的人设
+func (s *ChatService) personaNameTaken(tx *gorm.DB, name string, excludeID uint) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&models.ChatPersona{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateChatPersona 创建人设，同时记录第 1 版提示词
+func (s *ChatService) CreateChatPersona(ctx context.Context, operatorID uint, req *dto.CreateChatPersonaRequest) (*models.ChatPersona, error) {
+	if err := s.validatePersonaModelProfile(req.ModelProfile); err != nil {
+		return nil, err
+	}
+	var allowedTools datatypes.JSON
+	if len(req.AllowedTools) > 0 {
+		raw, err := json.Marshal(req.AllowedTools)
+		if err != nil {
+			return nil, apperr.Wrap(constant.CommonBadRequest, err)
+		}
+		allowedTools = raw
+	}
+
+	persona := &models.ChatPersona{
+		Name:          req.Name,
+		Description:   req.Description,
+		SystemPrompt:  req.SystemPrompt,
+		PromptVersion: 1,
+		AllowedTools:  allowedTools,
+		ModelProfile:  req.ModelProfile,
+		Temperature:   req.Temperature,
+		Sort:          req.Sort,
+		IsEnabled:     req.IsEnabled == nil || *req.IsEnabled,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		taken, err := s.personaNameTaken(tx, req.Name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return apperr.New(constant.ConversationPersonaNameExists)
+		}
+		if err := tx.Create(persona).Error; err != nil {
+			return err
+		}
+		// is_enabled 的零值会被数据库默认值覆盖，需单独写入
+		if !persona.IsEnabled {
+			if err := tx.Model(persona).Update("is_enabled", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.ChatPersonaPromptVersion{
+			PersonaID:    persona.ID,
+			Version:      1,
+			SystemPrompt: persona.SystemPrompt,
+			CreatedBy:    operatorID,
+		}).Error
+	})
+	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			return nil, err
+		}
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":      "create_chat_persona",
+			"operator_id": operatorID,
+			"name":        req.Name,
+			"error":       err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to create persona: %w", err))
+	}
+	return persona, nil
+}
+
+// UpdateChatPersona 更新人设；提示词变化时版本号加一并记录新版本，已有对话继续使用创建时的版本
+func (s *ChatService) UpdateChatPersona(ctx context.Context, operatorID, personaID uint, req *dto.UpdateChatPersonaRequest) (*models.ChatPersona, error) {
+	if req.ModelProfile != nil {
+		if err := s.validatePersonaModelProfile(*req.ModelProfile); err != nil {
+			return nil, err
+		}
+	}
+
+	var persona models.ChatPersona
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&persona, personaID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.New(constant.ConversationPersonaNotFound)
+			}
+			return err
+		}
+
+		updates := map[string]any{}
+		if req.Name != nil && *req.Name != persona.Name {
+			taken, err := s.personaNameTaken(tx, *req.Name, persona.ID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return apperr.New(constant.ConversationPersonaNameExists)
+			}
+			updates["name"] = *req.Name
+		}
+		if req.Description != nil {
+			updates["description"] = *req.Description
+		}
+		if req.AllowedTools != nil {
+			var allowedTools datatypes.JSON
+			if len(*req.AllowedTools) > 0 {
+				raw, err := json.Marshal(*req.AllowedTools)
+				if err != nil {
+					return apperr.Wrap(constant.CommonBadRequest, err)
+				}
+				allowedTools = raw
+			}
+			updates["allowed_tools"] = allowedTools
+		}
+		if req.ModelProfile != nil {
+			updates["model_profile"] = *req.ModelProfile
+		}
+		if req.ClearTemperature {
+			updates["temperature"] = nil
+		} else if req.Temperature != nil {
+			updates["temperature"] = *req.Temperature
+		}
+		if req.Sort != nil {
+			updates["sort"] = *req.Sort
+		}
+		if req.IsEnabled != nil {
+			updates["is_enabled"] = *req.IsEnabled
+		}
+		if req.SystemPrompt != nil && *req.SystemPrompt != persona.SystemPrompt {
+			version := persona.PromptVersion + 1
+			if err := tx.Create(&models.ChatPersonaPromptVersion{
+				PersonaID:    persona.ID,
+				Version:      version,
+				SystemPrompt: *req.SystemPrompt,
+				CreatedBy:    operatorID,
+			}).Error; err != nil {
+				return err
+			}
+			updates["system_prompt"] = *req.SystemPrompt
+			updates["prompt_version"] = version
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&persona).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&persona, persona.ID).Error
+	})
+	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			return nil, err
+		}
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":      "update_chat_persona",
+			"operator_id": operatorID,
+			"persona_id":  personaID,
+			"error":       err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to update persona: %w", err))
+	}
+	return &persona, nil
+}
+
+// DeleteChatPersona 删除人设，已有对话继续使用该人设
+func (s *ChatService) DeleteChatPersona(ctx context.Context, personaID uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.ChatPersona{}, personaID)
+	if result.Error != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to delete persona: %w", result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return apperr.New(constant.ConversationPersonaNotFound)
+	}
+	return nil
+}
+
+// ListChatPersonaVersions 列出人设的提示词历史版本，新版本在前
+func (s *ChatService) ListChatPersonaVersions(ctx context.Context, personaID uint) ([]models.ChatPersonaPromptVersion, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.ChatPersona{}).Where("id = ?", personaID).Count(&count).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get persona: %w", err))
+	}
+	if count == 0 {
+		return nil, apperr.New(constant.ConversationPersonaNotFound)
+	}
+
+	var versions []models.ChatPersonaPromptVersion
+	if err := s.db.WithContext(ctx).
+		Where("persona_id = ?", personaID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to list persona versions: %w", err))
+	}
+	return versions, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	json "github.com/bytedance/sonic"
+	einotool "github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+type namedAgentTool string
+
+func (n namedAgentTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: string(n)}, nil
+}
+
+func TestFilterAgentTools(t *testing.T) {
+	ctx := context.Background()
+	tools := []einotool.BaseTool{namedAgentTool("search_materials"), namedAgentTool("ragflow_retrieval"), namedAgentTool("get_points")}
+
+	if got := filterAgentTools(ctx, tools, nil); len(got) != 3 {
+		t.Fatalf("empty allow list should keep all tools, got %d", len(got))
+	}
+
+	got := filterAgentTools(ctx, tools, []string{"ragflow_retrieval", "search_materials", "not_loaded"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 tools, got %d", len(got))
+	}
+	for _, tool := range got {
+		if info, _ := tool.Info(ctx); info.Name == "get_points" {
+			t.Fatal("tool outside the allow list should be removed")
+		}
+	}
+}
+
+func TestResolveModelProfileChecksPersonaProfileRoles(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemoryStreamCache()
+	for userID, snap := range map[uint]UserPermissionSnapshot{
+		1: {RoleTags: []string{constant.RoleTagUserBasic}},
+		2: {RoleTags: []string{constant.RoleTagUserBasic, constant.RoleTagUserVerified}},
+	} {
+		data, _ := json.Marshal(snap)
+		mem.values[fmt.Sprintf("rbac:user:%d:permissions", userID)] = string(data)
+	}
+	s := &ChatService{
+		rbacService: &RBACService{cache: mem},
+		profiles: []config.LLMProfile{
+			{Name: "fast", Selectable: true},
+			{Name: "pro", Selectable: true, AllowedRoles: []string{constant.RoleTagUserVerified}},
+			{Name: "internal", AllowedRoles: []string{constant.RoleTagUserVerified}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		userID       uint
+		convProfile  string
+		personaModel string
+		want         string
+	}{
+		{name: "conversation choice wins", userID: 2, convProfile: "pro", personaModel: "fast", want: "pro"},
+		{name: "disallowed choice falls back to persona", userID: 1, convProfile: "pro", personaModel: "fast", want: "fast"},
+		{name: "persona profile outside user roles", userID: 1, personaModel: "pro", want: ""},
+		{name: "persona profile outside user roles after fallback", userID: 1, convProfile: "pro", personaModel: "internal", want: ""},
+		{name: "persona profile need not be selectable", userID: 2, personaModel: "internal", want: "internal"},
+		{name: "removed persona profile", userID: 2, personaModel: "gone", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := &models.Conversation{UserID: tt.userID, ModelProfile: tt.convProfile}
+			got := s.resolveModelProfile(ctx, tt.userID, conv, &chatPersonaRuntime{modelProfile: tt.personaModel})
+			if got != tt.want {
+				t.Fatalf("resolveModelProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateChatModelSkipsProfilesOutsideUserRoles(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemoryStreamCache()
+	for userID, snap := range map[uint]UserPermissionSnapshot{
+		1: {RoleTags: []string{constant.RoleTagUserBasic}},
+		2: {RoleTags: []string{constant.RoleTagUserBasic, constant.RoleTagUserVerified}},
+	} {
+		data, _ := json.Marshal(snap)
+		mem.values[fmt.Sprintf("rbac:user:%d:permissions", userID)] = string(data)
+	}
+	s := &ChatService{
+		rbacService: &RBACService{cache: mem},
+		modelHealth: newModelHealthTracker(),
+		profiles: []config.LLMProfile{
+			{Name: "pro", Model: "pro", APIKey: "k", BaseURL: "http://127.0.0.1", AllowedRoles: []string{constant.RoleTagUserVerified}},
+			{Name: "fast", Model: "fast", APIKey: "k", BaseURL: "http://127.0.0.1", Selectable: true},
+			{Name: "internal", Model: "internal", APIKey: "k", BaseURL: "http://127.0.0.1", AllowedRoles: []string{constant.RoleTagUserVerified}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		userID    uint
+		preferred string
+		want      []string
+	}{
+		{name: "default order skips restricted profiles", userID: 1, want: []string{"fast"}},
+		{name: "restricted preferred profile is ignored", userID: 1, preferred: "internal", want: []string{"fast"}},
+		{name: "allowed user keeps every profile", userID: 2, preferred: "fast", want: []string{"fast", "pro", "internal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel, err := s.createChatModel(ctx, tt.userID, tt.preferred)
+			if err != nil {
+				t.Fatalf("createChatModel() error = %v", err)
+			}
+			var got []string
+			for _, candidate := range chatModel.(*failoverChatModel).candidates {
+				got = append(got, candidate.name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// chatSearchSnippetRunes 片段在首个命中词之前保留的字符数，之后保留其 2 倍
+	chatSearchSnippetRunes = 40
+	// chatSearchTitleBoost 标题命中的相关度加权，使标题命中排在同等相关的消息之前
+	chatSearchTitleBoost = 2
+)
+
+// chatSearchRow 全文检索的原始结果
+type chatSearchRow struct {
+	ConversationID uint
+	MessageID      uint
+	Title          string
+	Role           string
+	Content        string
+	CreatedAt      time.Time
+	Score          float64
+}
+
+// chatSearchQuery 去掉 BOOLEAN MODE 运算符后，将关键词拆分为必须同时出现的短语，如 `+"拉普拉斯" +"变换"`
+func chatSearchQuery(keyword string) (query string, terms []string) {
+	clean := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, keyword)
+
+	var b strings.Builder
+	for _, term := range strings.Fields(clean) {
+		terms = append(terms, term)
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(`+"`)
+		b.WriteString(term)
+		b.WriteByte('"')
+	}
+	return b.String(), terms
+}
+
+// SearchConversations 在用户的对话标题与消息内容中全文检索（MySQL FULLTEXT + ngram 分词），按相关度排序
+func (s *ChatService) SearchConversations(ctx context.Context, userID uint, req *dto.SearchConversationsRequest) ([]dto.ConversationSearchHit, int64, error) {
+	query, terms := chatSearchQuery(req.Keyword)
+	if query == "" {
+		return []dto.ConversationSearchHit{}, 0, nil
+	}
+
+	const titleWhere = `FROM conversations c
+		WHERE c.user_id = ? AND c.deleted_at IS NULL AND MATCH(c.title) AGAINST(? IN BOOLEAN MODE)`
+	const messageWhere = `FROM conversation_messages m
+		JOIN conversations c ON c.id = m.conversation_id AND c.deleted_at IS NULL
+		WHERE m.user_id = ? AND m.role IN (?, ?) AND MATCH(m.content) AGAINST(? IN BOOLEAN MODE)`
+	roles := []any{string(schema.User), string(schema.Assistant)}
+
+	var counts struct {
+		Titles   int64
+		Messages int64
+	}
+	if err := s.db.WithContext(ctx).Raw(
+		"SELECT (SELECT COUNT(*) "+titleWhere+") AS titles, (SELECT COUNT(*) "+messageWhere+") AS messages",
+		userID, query, userID, roles[0], roles[1], query,
+	).Scan(&counts).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "search_conversations_count",
+			"user_id": userID,
+			"keyword": req.Keyword,
+			"error":   err.Error(),
+		})
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to count search results: %w", err))
+	}
+	total := counts.Titles + counts.Messages
+	if total == 0 {
+		return []dto.ConversationSearchHit{}, 0, nil
+	}
+
+	var rows []chatSearchRow
+	if err := s.db.WithContext(ctx).Raw(
+		`SELECT c.id AS conversation_id, 0 AS message_id, c.title, '' AS role, c.title AS content, c.updated_at AS created_at,
+			MATCH(c.title) AGAINST(? IN BOOLEAN MODE) * ? AS score `+titleWhere+`
+		UNION ALL
+		SELECT m.conversation_id, m.id AS message_id, c.title, m.role, m.content, m.created_at,
+			MATCH(m.content) AGAINST(? IN BOOLEAN MODE) AS score `+messageWhere+`
+		ORDER BY score DESC, created_at DESC
+		LIMIT ? OFFSET ?`,
+		query, chatSearchTitleBoost, userID, query,
+		query, userID, roles[0], roles[1], query,
+		req.PageSize, (req.Page-1)*req.PageSize,
+	).Scan(&rows).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "search_conversations",
+			"user_id": userID,
+			"keyword": req.Keyword,
+			"error":   err.Error(),
+		})
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to search conversations: %w", err))
+	}
+
+	hits := make([]dto.ConversationSearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, dto.ConversationSearchHit{
+			ConversationID: row.ConversationID,
+			MessageID:      row.MessageID,
+			Title:          row.Title,
+			Role:           row.Role,
+			Snippet:        highlightSnippet(row.Content, terms, chatSearchSnippetRunes),
+			Score:          row.Score,
+			ActiveBranch:   true,
+			CreatedAt:      row.CreatedAt,
+		})
+	}
+	if err := s.markInactiveBranchHits(ctx, userID, hits); err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "search_conversations_branches",
+			"user_id": userID,
+			"keyword": req.Keyword,
+			"error":   err.Error(),
+		})
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to load conversation branches: %w", err))
+	}
+	return hits, total, nil
+}
+
+// markInactiveBranchHits 标记位于非当前分支（重新生成或编辑前的旧分支）的消息命中，只加载构建消息树所需的列
+func (s *ChatService) markInactiveBranchHits(ctx context.Context, userID uint, hits []dto.ConversationSearchHit) error {
+	var conversationIDs []uint
+	for _, hit := range hits {
+		if hit.MessageID != 0 && !slices.Contains(conversationIDs, hit.ConversationID) {
+			conversationIDs = append(conversationIDs, hit.ConversationID)
+		}
+	}
+	if len(conversationIDs) == 0 {
+		return nil
+	}
+
+	var convs []models.Conversation
+	if err := s.db.WithContext(ctx).
+		Select("id", "active_leaf_id").
+		Where("id IN ? AND user_id = ?", conversationIDs, userID).
+		Find(&convs).Error; err != nil {
+		return err
+	}
+	var rows []models.ConversationMessage
+	if err := s.db.WithContext(ctx).
+		Select("id", "conversation_id", "parent_id", "created_at").
+		Where("conversation_id IN ? AND user_id = ?", conversationIDs, userID).
+		Order("created_at ASC, id ASC").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	rowsByConversation := make(map[uint][]models.ConversationMessage, len(convs))
+	for _, row := range rows {
+		rowsByConversation[row.ConversationID] = append(rowsByConversation[row.ConversationID], row)
+	}
+
+	active := make(map[uint]struct{}, len(rows))
+	for _, conv := range convs {
+		for _, row := range newConversationTree(rowsByConversation[conv.ID], conv.ActiveLeafID).activePath() {
+			active[row.ID] = struct{}{}
+		}
+	}
+	for i := range hits {
+		if hits[i].MessageID == 0 {
+			continue
+		}
+		_, ok := active[hits[i].MessageID]
+		hits[i].ActiveBranch = ok
+	}
+	return nil
+}
+
+// highlightSnippet 截取首个命中词附近的片段，转义 HTML 后用 <em> 标记所有命中词（不区分大小写）
+func highlightSnippet(content string, terms []string, contextRunes int) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	if len(lower) != len(runes) {
+		// 极少数字符大小写转换后长度变化，退化为区分大小写匹配
+		lower = runes
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if slices.Equal(lower[i:i+len(t)], t) {
+				spans = append(spans, span{i, i + len(t)})
+				i += len(t) - 1
+			}
+		}
+	}
+
+	first := span{len(runes), len(runes)}
+	for _, sp := range spans {
+		if sp.start < first.start {
+			first = sp
+		}
+	}
+	if len(spans) == 0 {
+		first = span{0, 0}
+	}
+	start := max(0, first.start-contextRunes)
+	end := min(len(runes), first.end+contextRunes*2)
+
+	marks := make([]int, end-start+1) // 1 表示在该位置开始高亮，-1 表示结束
+	covered := make([]bool, end-start)
+	for _, sp := range spans {
+		for i := max(sp.start, start); i < min(sp.end, end); i++ {
+			covered[i-start] = true
+		}
+	}
+	for i := range covered {
+		if covered[i] && (i == 0 || !covered[i-1]) {
+			marks[i] = 1
+		}
+		if covered[i] && (i == len(covered)-1 || !covered[i+1]) {
+			marks[i+1] = -1
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		if marks[i-start] == 1 {
+			b.WriteString("<em>")
+		}
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			r = ' '
+		}
+		b.WriteString(html.EscapeString(string(r)))
+		if marks[i-start+1] == -1 {
+			b.WriteString("</em>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
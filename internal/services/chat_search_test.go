@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+)
+
+func TestChatSearchQuery(t *testing.T) {
+	query, terms := chatSearchQuery(` 拉普拉斯  变换+"(x)" `)
+	if query != `+"拉普拉斯" +"变换" +"x"` {
+		t.Fatalf("unexpected query %q", query)
+	}
+	if !slices.Equal(terms, []string{"拉普拉斯", "变换", "x"}) {
+		t.Fatalf("unexpected terms %v", terms)
+	}
+
+	if query, _ := chatSearchQuery(`+-*"`); query != "" {
+		t.Fatalf("operators only should produce empty query, got %q", query)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		context int
+		want    string
+	}{
+		{
+			name:    "highlights all terms and escapes html",
+			content: "拉普拉斯变换<b>把微分方程\n变成代数方程",
+			terms:   []string{"拉普拉斯变换", "方程"},
+			context: 40,
+			want:    "<em>拉普拉斯变换</em>&lt;b&gt;把微分<em>方程</em> 变成代数<em>方程</em>",
+		},
+		{
+			name:    "case insensitive and trims around first hit",
+			content: "前面很长的一段内容 Laplace transform 后面也很长",
+			terms:   []string{"laplace"},
+			context: 3,
+			want:    "...内容 <em>Laplace</em> trans...",
+		},
+		{
+			name:    "merges overlapping terms",
+			content: "傅里叶变换",
+			terms:   []string{"傅里叶", "里叶变换"},
+			context: 10,
+			want:    "<em>傅里叶变换</em>",
+		},
+		{
+			name:    "no hit keeps beginning",
+			content: "abcdef",
+			terms:   []string{"xyz"},
+			context: 1,
+			want:    "ab...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.content, tt.terms, tt.context); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkInactiveBranchHits(t *testing.T) {
+	db := newHarnessDB(t)
+	s := &ChatService{db: db}
+	ctx := context.Background()
+
+	// 对话 1：助手回答重新生成过，当前分支为新回答
+	branched := models.Conversation{ID: 1, UserID: 7, Title: "分支对话"}
+	linear := models.Conversation{ID: 2, UserID: 7, Title: "历史对话"}
+	for _, conv := range []*models.Conversation{&branched, &linear} {
+		if err := db.Create(conv).Error; err != nil {
+			t.Fatalf("create conversation: %v", err)
+		}
+	}
+	for _, msg := range []models.ConversationMessage{
+		{ID: 1, ConversationID: 1, UserID: 7, Role: "user", Content: "拉普拉斯变换"},
+		{ID: 2, ConversationID: 1, ParentID: 1, UserID: 7, Role: "assistant", Content: "旧回答"},
+		{ID: 3, ConversationID: 1, ParentID: 1, UserID: 7, Role: "assistant", Content: "新回答"},
+		{ID: 4, ConversationID: 2, UserID: 7, Role: "user", Content: "线性对话"},
+	} {
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+	}
+	if err := db.Model(&branched).Update("active_leaf_id", 3).Error; err != nil {
+		t.Fatalf("set active leaf: %v", err)
+	}
+
+	hits := []dto.ConversationSearchHit{
+		{ConversationID: 1, ActiveBranch: true},
+		{ConversationID: 1, MessageID: 1, ActiveBranch: true},
+		{ConversationID: 1, MessageID: 2, ActiveBranch: true},
+		{ConversationID: 1, MessageID: 3, ActiveBranch: true},
+		{ConversationID: 2, MessageID: 4, ActiveBranch: true},
+	}
+	if err := s.markInactiveBranchHits(ctx, 7, hits); err != nil {
+		t.Fatalf("markInactiveBranchHits() error = %v", err)
+	}
+	want := []bool{true, true, false, true, true}
+	for i, hit := range hits {
+		if hit.ActiveBranch != want[i] {
+			t.Fatalf("hit %d (message %d) active_branch = %v, want %v", i, hit.MessageID, hit.ActiveBranch, want[i])
+		}
+	}
+}
@@ -2,14 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
@@ -85,16 +89,56 @@ type ChatService struct {
 	httpClient      *http.Client // 复用的 HTTP 客户端,用于 MCP 等外部请求,减少GC压力和连接创建开销
 	db              *gorm.DB
 	cfg             *config.Config
+	rbacService     *RBACService
+	s3Service       S3ServiceInterface
 	llm             einomodel.ToolCallingChatModel
 	checkPointStore compose.CheckPointStore
+
+	// 内置工具直接调用的业务服务，为 nil 时不提供对应工具
+	materialService   *MaterialService
+	questionService   *QuestionService
+	dictionaryService *DictionaryService
+
+	// 对话输入与回答的内容审核，为 nil 时不审核
+	moderationService *ModerationService
+
+	// 管理员接入的外部 MCP 服务，为 nil 时只加载内置 MCP 服务
+	mcpServers *MCPServerService
+
+	// 工具执行确认策略，可通过 SystemConfig 覆盖
+	confirmPolicy *configPolicy[ToolConfirmationPolicy]
+
+	// 多模型配置与健康状态，模型实例在首次使用时创建
+	profiles      []config.LLMProfile
+	modelHealth   *modelHealthTracker
+	modelsMu      sync.Mutex
+	profileModels map[string]einomodel.ToolCallingChatModel
+	// newProfileModel 创建模型实例，为 nil 时使用 OpenAI 兼容接口
+	newProfileModel func(ctx context.Context, profile config.LLMProfile) (einomodel.ToolCallingChatModel, error)
 }
 
-func NewChatService(db *gorm.DB, cfg *config.Config) *ChatService {
+func NewChatService(db *gorm.DB, cfg *config.Config, rbacService *RBACService, s3Service S3ServiceInterface, materialService *MaterialService, questionService *QuestionService, dictionaryService *DictionaryService, moderationService *ModerationService) *ChatService {
+	profiles, err := cfg.LLM.ModelProfiles()
+	if err != nil {
+		logger.Warnf("LLM 多模型配置无效，使用单模型配置: %v", err)
+		profiles, _ = config.LLM{Model: cfg.LLM.Model, APIKey: cfg.LLM.APIKey, BaseURL: cfg.LLM.BaseURL, Vision: cfg.LLM.Vision}.ModelProfiles()
+	}
 	return &ChatService{
-		db:              db,
-		cfg:             cfg,
+		db:                db,
+		cfg:               cfg,
+		rbacService:       rbacService,
+		s3Service:         s3Service,
+		materialService:   materialService,
+		questionService:   questionService,
+		dictionaryService: dictionaryService,
+		moderationService: moderationService,
+		mcpServers:        NewMCPServerService(db, cfg),
+		confirmPolicy: newConfigPolicy(db, constant.ChatToolConfirmationConfigKey, constant.ChatToolConfirmationReloadInterval,
+			defaultToolConfirmationPolicyCopy, mergeToolConfirmationPolicy),
 		httpClient:      &http.Client{Timeout: 30 * time.Second}, // todo: 全局 http client 可以考虑放到更上层统一管理
 		checkPointStore: newRedisCheckPointStore(),
+		profiles:        profiles,
+		modelHealth:     newModelHealthTracker(),
 	}
 }
 
@@ -185,11 +229,19 @@ func normalizeBearerToken(apiKey string) (string, string) {
 }
 
 // CreateConversation 创建新对话
-func (s *ChatService) CreateConversation(ctx context.Context, userID uint, title string) (*models.Conversation, error) {
+func (s *ChatService) CreateConversation(ctx context.Context, userID uint, title string, personaID uint) (*models.Conversation, error) {
 	conv := &models.Conversation{
 		UserID: userID,
 		Title:  title,
 	}
+	if personaID != 0 {
+		persona, err := s.getSelectablePersona(ctx, personaID)
+		if err != nil {
+			return nil, err
+		}
+		conv.PersonaID = persona.ID
+		conv.PersonaVersion = persona.PromptVersion
+	}
 
 	if err := s.db.WithContext(ctx).Create(conv).Error; err != nil {
 		logger.ErrorCtx(ctx, map[string]any{
@@ -309,21 +361,34 @@ func (s *ChatService) GetConversation(ctx context.Context, userID, conversationI
 
 // DeleteConversation 删除对话
 func (s *ChatService) DeleteConversation(ctx context.Context, userID, conversationID uint) error {
-	result := s.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", conversationID, userID).
-		Delete(&models.Conversation{})
+	var rowsAffected int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", conversationID, userID).
+			Delete(&models.Conversation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 {
+			return nil
+		}
+		// 对话删除后其分享链接一并撤销
+		return tx.Model(&models.ConversationShare{}).
+			Where("conversation_id = ? AND user_id = ? AND revoked_at IS NULL", conversationID, userID).
+			Update("revoked_at", time.Now()).Error
+	})
 
-	if result.Error != nil {
+	if err != nil {
 		logger.ErrorCtx(ctx, map[string]any{
 			"action":          "delete_conversation",
 			"user_id":         userID,
 			"conversation_id": conversationID,
-			"error":           result.Error.Error(),
+			"error":           err.Error(),
 		})
-		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to delete conversation: %w", result.Error))
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to delete conversation: %w", err))
 	}
 
-	if result.RowsAffected == 0 {
+	if rowsAffected == 0 {
 
 		logger.WarnCtx(ctx, map[string]any{
 			"action":          "delete_conversation_not_found",
@@ -382,7 +447,7 @@ func schemaMessageToConversationMessage(userID, conversationID uint, checkpointI
 		}
 	}
 
-	return &models.ConversationMessage{
+	record := &models.ConversationMessage{
 		ConversationID:   conversationID,
 		UserID:           userID,
 		CheckpointID:     checkpointID,
@@ -393,7 +458,24 @@ func schemaMessageToConversationMessage(userID, conversationID uint, checkpointI
 		ToolName:         msg.ToolName,
 		ToolCalls:        datatypes.JSON(toolCalls),
 		RawMessage:       datatypes.JSON(rawMessage),
-	}, nil
+	}
+	if refs := chatAttachmentRefs(msg); len(refs) > 0 {
+		attachments, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		record.Attachments = datatypes.JSON(attachments)
+	}
+	// 模型返回的 token 用量，用于按用户按日统计
+	if msg.ResponseMeta != nil && msg.ResponseMeta.Usage != nil {
+		record.PromptTokens = msg.ResponseMeta.Usage.PromptTokens
+		record.CompletionTokens = msg.ResponseMeta.Usage.CompletionTokens
+		record.TotalTokens = msg.ResponseMeta.Usage.TotalTokens
+	}
+	if profile, ok := msg.Extra[chatModelProfileExtraKey].(string); ok {
+		record.ModelProfile = profile
+	}
+	return record, nil
 }
 
 func conversationMessageToSchemaMessage(row *models.ConversationMessage) (*schema.Message, error) {
@@ -419,48 +501,77 @@ func conversationMessageToSchemaMessage(row *models.ConversationMessage) (*schem
 	return msg, nil
 }
 
-// GetMessages 获取对话的所有消息
+// GetMessages 获取对话当前分支上的消息。
+// 每条消息的 Extra 中附带 message_id、parent_id，存在其他分支时附带 sibling_ids，供前端重新生成与切换分支；
+// 用户评价过的助手消息附带 feedback。
 func (s *ChatService) GetMessages(ctx context.Context, userID, conversationID uint) ([]*schema.Message, error) {
 	// 验证对话属于用户（带缓存）
 	if _, err := s.getOwnedConversation(ctx, userID, conversationID); err != nil {
 		return nil, err
 	}
 
-	var rows []models.ConversationMessage
-	if err := s.db.WithContext(ctx).
-		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
-		Order("created_at ASC, id ASC").
-		Find(&rows).Error; err != nil {
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
 		logger.ErrorCtx(ctx, map[string]any{
 			"action":          "get_messages",
 			"user_id":         userID,
 			"conversation_id": conversationID,
 			"error":           err.Error(),
 		})
-		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get messages: %w", err))
+		return nil, err
 	}
 
-	messages := make([]*schema.Message, 0, len(rows))
-	for i := range rows {
-		msg, err := conversationMessageToSchemaMessage(&rows[i])
+	// 评价只是附加信息，读取失败不影响返回消息
+	feedback, err := s.conversationFeedback(ctx, userID, conversationID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "get_messages_feedback",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+	}
+
+	path := tree.activePath()
+	messages := make([]*schema.Message, 0, len(path))
+	for _, row := range path {
+		msg, err := conversationMessageToSchemaMessage(row)
 		if err != nil {
 			logger.ErrorCtx(ctx, map[string]any{
 				"action":          "get_messages_unmarshal",
 				"user_id":         userID,
 				"conversation_id": conversationID,
-				"message_id":      rows[i].ID,
+				"message_id":      row.ID,
 				"error":           err.Error(),
 			})
 			return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to unmarshal message: %w", err))
 		}
+		if msg.Extra == nil {
+			msg.Extra = make(map[string]any, 3)
+		}
+		msg.Extra["message_id"] = row.ID
+		msg.Extra["parent_id"] = tree.parentOf(row)
+		if siblings := tree.siblings(row); len(siblings) > 1 {
+			msg.Extra["sibling_ids"] = siblings
+		}
+		if fb, ok := feedback[row.ID]; ok {
+			msg.Extra["feedback"] = fb
+		}
 		messages = append(messages, msg)
 	}
 
 	return messages, nil
 }
 
-// AppendMessages 追加消息到对话消息表
+// AppendMessages 追加消息到对话当前分支的末端
 func (s *ChatService) AppendMessages(ctx context.Context, userID, conversationID uint, checkpointID string, messages []*schema.Message) error {
+	_, err := s.appendMessages(ctx, userID, conversationID, checkpointID, nil, messages)
+	return err
+}
+
+// appendMessages 将消息依次串接到 parentID 之下（nil 表示当前分支末端），并将最后一条消息设为分支末端。
+// 返回最后一条消息的ID，没有写入消息时返回 0。
+func (s *ChatService) appendMessages(ctx context.Context, userID, conversationID uint, checkpointID string, parentID *uint, messages []*schema.Message) (uint, error) {
 	records := make([]models.ConversationMessage, 0, len(messages))
 	for _, msg := range messages {
 		if msg == nil {
@@ -474,45 +585,56 @@ func (s *ChatService) AppendMessages(ctx context.Context, userID, conversationID
 				"conversation_id": conversationID,
 				"error":           err.Error(),
 			})
-			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to marshal message: %w", err))
+			return 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to marshal message: %w", err))
 		}
 		records = append(records, *record)
 	}
 	if len(records) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	now := time.Now()
+	var lastID uint
 	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var conv models.Conversation
-		if err := tx.Select("id").
-			Where("id = ? AND user_id = ?", conversationID, userID).
-			First(&conv).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return apperr.New(constant.ConversationNotFound)
+		leafID, err := ensureMessageTree(ctx, tx, userID, conversationID)
+		if err != nil {
+			return err
+		}
+		parent := leafID
+		if parentID != nil {
+			parent = *parentID
+		}
+
+		// 逐条写入以便串接父子关系
+		for i := range records {
+			records[i].ParentID = parent
+			if err := tx.Create(&records[i]).Error; err != nil {
+				logger.ErrorCtx(ctx, map[string]any{
+					"action":          "append_messages_create",
+					"user_id":         userID,
+					"conversation_id": conversationID,
+					"error":           err.Error(),
+				})
+				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to append messages: %w", err))
 			}
-			logger.ErrorCtx(ctx, map[string]any{
-				"action":          "append_messages_get_conversation",
-				"user_id":         userID,
-				"conversation_id": conversationID,
-				"error":           err.Error(),
-			})
-			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get conversation: %w", err))
+			parent = records[i].ID
 		}
+		lastID = parent
 
-		if err := tx.Create(&records).Error; err != nil {
+		if err := recordLLMUsage(tx, userID, records); err != nil {
 			logger.ErrorCtx(ctx, map[string]any{
-				"action":          "append_messages_create",
+				"action":          "append_messages_record_usage",
 				"user_id":         userID,
 				"conversation_id": conversationID,
 				"error":           err.Error(),
 			})
-			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to append messages: %w", err))
+			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to record llm usage: %w", err))
 		}
 
 		result := tx.Model(&models.Conversation{}).
 			Where("id = ? AND user_id = ?", conversationID, userID).
 			Updates(map[string]interface{}{
+				"active_leaf_id":  lastID,
 				"last_message_at": now,
 				"updated_at":      now,
 			})
@@ -531,11 +653,11 @@ func (s *ChatService) AppendMessages(ctx context.Context, userID, conversationID
 		return nil
 	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	s.deleteConversationInfoCache(ctx, userID, conversationID)
-	return nil
+	return lastID, nil
 }
 
 func (s *ChatService) initYQLXMCP(ctx context.Context, userID uint, userToken string) (*client.Client, error) {
@@ -586,8 +708,14 @@ func (s *ChatService) initYQLXMCP(ctx context.Context, userID uint, userToken st
 	return yqlxMcpClient, nil
 }
 
-// todo: mcp server 除了系统内置的 mcp client 之外，还可以支持用户自定义的 mcp client
-func (s *ChatService) prepareUserMcpClient(ctx context.Context, userID, conversationID uint, userToken string) mcpClient {
+// mcpToolFilter 外部 MCP 服务配置的工具允许/禁止列表
+type mcpToolFilter struct {
+	allowed []string // 为空表示不限制
+	denied  []string
+}
+
+// prepareUserMcpClient 创建内置 MCP 客户端和用户可用的外部 MCP 客户端，返回的过滤规则只包含外部服务
+func (s *ChatService) prepareUserMcpClient(ctx context.Context, userID, conversationID uint, userToken string) (mcpClient, map[string]mcpToolFilter) {
 	clients := make(mcpClient)
 
 	yqlxMcpClient, err := s.initYQLXMCP(ctx, userID, userToken)
@@ -614,29 +742,105 @@ func (s *ChatService) prepareUserMcpClient(ctx context.Context, userID, conversa
 		clients["ragflow"] = ragMcpClient
 	}
 
-	return clients
+	return clients, s.initExternalMCPClients(ctx, userID, clients)
+}
+
+// initExternalMCPClients 连接用户角色可用的外部 MCP 服务并加入 clients，连接失败的服务被跳过
+func (s *ChatService) initExternalMCPClients(ctx context.Context, userID uint, clients mcpClient) map[string]mcpToolFilter {
+	filters := make(map[string]mcpToolFilter)
+	if s.mcpServers == nil {
+		return filters
+	}
+
+	// 角色获取失败时只加载未限制角色的服务
+	roleTags, err := s.userRoleTags(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "prepare_user_mcp_client",
+			"stage":   "load_user_roles",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+	servers, err := s.mcpServers.serversForRoles(ctx, roleTags)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "prepare_user_mcp_client",
+			"stage":   "load_external_mcp_servers",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return filters
+	}
+
+	// 并发连接，全部服务共用一个连接超时，慢服务不会逐个累加首字延迟
+	connectCtx, cancel := context.WithTimeout(ctx, constant.MCPServerConnectTimeout)
+	defer cancel()
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for i := range servers {
+		server := &servers[i]
+		wg.Go(func() {
+			cli, err := s.mcpServers.connect(connectCtx, server)
+			if err != nil {
+				logger.WarnCtx(ctx, map[string]any{
+					"action":    "prepare_user_mcp_client",
+					"stage":     "init_external_mcp_client",
+					"user_id":   userID,
+					"server_id": server.ID,
+					"name":      server.Name,
+					"error":     err.Error(),
+				})
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			clients[server.Name] = cli
+			filters[server.Name] = mcpToolFilter{
+				allowed: jsonStringList(server.AllowedTools),
+				denied:  jsonStringList(server.DeniedTools),
+			}
+		})
+	}
+	wg.Wait()
+	return filters
 }
 
+// loadMCPTools 加载全部 MCP 工具。内置服务先加载，外部服务按允许/禁止列表过滤，且与已加载工具同名的工具被跳过
 func (s *ChatService) loadMCPTools(ctx context.Context, userID, conversationID uint, userToken string) ([]einotool.BaseTool, mcpClient) {
-	clients := s.prepareUserMcpClient(ctx, userID, conversationID, userToken)
+	clients, filters := s.prepareUserMcpClient(ctx, userID, conversationID, userToken)
 	allTools := make([]einotool.BaseTool, 0)
-	for name, cli := range clients {
-		if cli == nil {
-			continue
-		}
-		tools, err := einomcp.GetTools(ctx, &einomcp.Config{Cli: cli})
-		if err != nil {
-			logger.WarnCtx(ctx, map[string]any{
-				"action":  "stream_chat_get_mcp_tools",
-				"user_id": userID,
-				"name":    name,
-				"msg":     "Failed to load MCP tools, skipping",
-				"error":   err.Error(),
-			})
-			continue
+	loaded := make(map[string]struct{})
+	for _, external := range []bool{false, true} {
+		for name, cli := range clients {
+			filter, isExternal := filters[name]
+			if cli == nil || isExternal != external {
+				continue
+			}
+			tools, err := einomcp.GetTools(ctx, &einomcp.Config{Cli: cli, ToolNameList: filter.allowed})
+			if err != nil {
+				logger.WarnCtx(ctx, map[string]any{
+					"action":  "stream_chat_get_mcp_tools",
+					"user_id": userID,
+					"name":    name,
+					"msg":     "Failed to load MCP tools, skipping",
+					"error":   err.Error(),
+				})
+				continue
+			}
+			if isExternal {
+				tools = excludeExternalMCPTools(ctx, tools, filter.denied, loaded)
+			}
+			for _, t := range tools {
+				if info, err := t.Info(ctx); err == nil && info != nil {
+					loaded[info.Name] = struct{}{}
+				}
+			}
+			tools = wrapAgentToolsWithFailureResults(ctx, tools, name, userID, conversationID)
+			allTools = append(allTools, tools...)
 		}
-		tools = wrapAgentToolsWithFailureResults(ctx, tools, name, userID, conversationID)
-		allTools = append(allTools, tools...)
 	}
 
 	logger.InfoCtx(ctx, map[string]any{
@@ -650,6 +854,22 @@ func (s *ChatService) loadMCPTools(ctx context.Context, userID, conversationID u
 	return allTools, clients
 }
 
+// excludeExternalMCPTools 去掉禁止列表中的工具以及与已加载工具同名的工具
+func excludeExternalMCPTools(ctx context.Context, tools []einotool.BaseTool, denied []string, loaded map[string]struct{}) []einotool.BaseTool {
+	kept := make([]einotool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil || info == nil {
+			continue
+		}
+		if _, dup := loaded[info.Name]; dup || slices.Contains(denied, info.Name) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
 func wrapAgentToolsWithFailureResults(ctx context.Context, tools []einotool.BaseTool, source string, userID, conversationID uint) []einotool.BaseTool {
 	if len(tools) == 0 {
 		return tools
@@ -726,12 +946,21 @@ type streamEventProcessor struct {
 	outputChan     chan string
 	errChan        chan error
 	startEventType string // "start" 或 "resume_start"
+	leafID         uint   // 本轮消息挂载的父消息ID，0 表示当前分支末端
+	stream         *chatTurnStream
+	cancel         context.CancelFunc // 本轮运行结束时释放与请求解耦的上下文
 }
 
 func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent]) {
 	defer close(p.outputChan)
 	defer close(p.errChan)
 	defer p.mcpClients.Close(p.ctx)
+	defer func() {
+		p.stream.finish(p.ctx)
+		if p.cancel != nil {
+			p.cancel()
+		}
+	}()
 
 	var usage *schema.TokenUsage
 	var turnMessages []*schema.Message
@@ -742,7 +971,7 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 		"checkpoint_id": p.checkpointID,
 	}
 	if data, err := json.Marshal(startEvent); err == nil {
-		p.outputChan <- "data: " + string(data) + "\n\n"
+		p.outputChan <- p.stream.frame(p.ctx, data)
 	}
 
 	for {
@@ -760,12 +989,13 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 				"error":           event.Err.Error(),
 			})
 			p.saveMessages(turnMessages)
-			p.errChan <- event.Err
+			p.sendError(event.Err)
 			return
 		}
 
 		if event.Action != nil && event.Action.Interrupted != nil {
 			p.saveMessages(turnMessages)
+			p.emitToolConfirmations(event.Action.Interrupted)
 
 			interruptEvent := map[string]interface{}{
 				"type":          "interrupt",
@@ -773,7 +1003,7 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 				"data":          event.Action.Interrupted.Data,
 			}
 			if data, err := json.Marshal(interruptEvent); err == nil {
-				p.outputChan <- "data: " + string(data) + "\n\n"
+				p.outputChan <- p.stream.frame(p.ctx, data)
 			}
 			logger.InfoCtx(p.ctx, map[string]any{
 				"action":          "agent_interrupted",
@@ -808,7 +1038,7 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 						"error":           err.Error(),
 					})
 					p.saveMessages(turnMessages)
-					p.errChan <- err
+					p.sendError(err)
 					return
 				}
 
@@ -841,7 +1071,7 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 					if msg.Role == schema.Tool {
 						p.emitToolResult(msg)
 					}
-					turnMessages = appendAgentMessage(turnMessages, msg)
+					turnMessages = appendAgentMessage(turnMessages, p.moderateAssistantMessage(msg))
 				}
 			}
 			continue
@@ -867,7 +1097,7 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 		if msg.Role == schema.Tool {
 			p.emitToolResult(msg)
 		}
-		turnMessages = appendAgentMessage(turnMessages, msg)
+		turnMessages = appendAgentMessage(turnMessages, p.moderateAssistantMessage(msg))
 	}
 
 	logger.InfoCtx(p.ctx, map[string]any{
@@ -894,10 +1124,22 @@ func (p *streamEventProcessor) process(iter *adk.AsyncIterator[*adk.AgentEvent])
 		}
 	}
 	if data, err := json.Marshal(endEvent); err == nil {
-		p.outputChan <- "data: " + string(data) + "\n\n"
+		p.outputChan <- p.stream.frame(p.ctx, data)
 	}
 }
 
+// sendError 错误事件与其他事件一样分配序号并写入缓冲，当前连接与续传都能按序收到；errChan 仅供调用方获知本轮失败
+func (p *streamEventProcessor) sendError(err error) {
+	errorEvent := map[string]interface{}{
+		"type":  "error",
+		"error": err.Error(),
+	}
+	if data, jsonErr := json.Marshal(errorEvent); jsonErr == nil {
+		p.outputChan <- p.stream.frame(p.ctx, data)
+	}
+	p.errChan <- err
+}
+
 func (p *streamEventProcessor) emitStreamingChunk(recv *schema.Message) {
 	if recv.Content != "" {
 		contentEvent := map[string]interface{}{
@@ -905,7 +1147,7 @@ func (p *streamEventProcessor) emitStreamingChunk(recv *schema.Message) {
 			"content": recv.Content,
 		}
 		if data, err := json.Marshal(contentEvent); err == nil {
-			p.outputChan <- "data: " + string(data) + "\n\n"
+			p.outputChan <- p.stream.frame(p.ctx, data)
 		}
 	}
 
@@ -915,7 +1157,7 @@ func (p *streamEventProcessor) emitStreamingChunk(recv *schema.Message) {
 			"content": recv.ReasoningContent,
 		}
 		if data, err := json.Marshal(reasoningEvent); err == nil {
-			p.outputChan <- "data: " + string(data) + "\n\n"
+			p.outputChan <- p.stream.frame(p.ctx, data)
 		}
 	}
 
@@ -927,7 +1169,7 @@ func (p *streamEventProcessor) emitStreamingChunk(recv *schema.Message) {
 			"arguments": tc.Function.Arguments,
 		}
 		if data, err := json.Marshal(toolCallEvent); err == nil {
-			p.outputChan <- "data: " + string(data) + "\n\n"
+			p.outputChan <- p.stream.frame(p.ctx, data)
 		}
 	}
 }
@@ -940,7 +1182,7 @@ func (p *streamEventProcessor) emitToolResult(msg *schema.Message) {
 		"content":      msg.Content,
 	}
 	if data, err := json.Marshal(toolResultEvent); err == nil {
-		p.outputChan <- "data: " + string(data) + "\n\n"
+		p.outputChan <- p.stream.frame(p.ctx, data)
 	}
 }
 
@@ -958,7 +1200,12 @@ func (p *streamEventProcessor) saveMessages(messages []*schema.Message) {
 	if len(messages) == 0 || p.service == nil || p.conv == nil {
 		return
 	}
-	if err := p.service.AppendMessages(context.WithoutCancel(p.ctx), p.userID, p.conv.ID, p.checkpointID, messages); err != nil {
+	var parentID *uint
+	if p.leafID != 0 {
+		parentID = &p.leafID
+	}
+	lastID, err := p.service.appendMessages(context.WithoutCancel(p.ctx), p.userID, p.conv.ID, p.checkpointID, parentID, messages)
+	if err != nil {
 		logger.ErrorCtx(p.ctx, map[string]any{
 			"action":          "agent_save_messages",
 			"user_id":         p.userID,
@@ -966,36 +1213,217 @@ func (p *streamEventProcessor) saveMessages(messages []*schema.Message) {
 			"checkpoint_id":   p.checkpointID,
 			"error":           err.Error(),
 		})
+		return
+	}
+	if lastID != 0 {
+		p.leafID = lastID
 	}
 }
 
-// createChatModel 创建或获取 ChatModel
-func (s *ChatService) createChatModel(ctx context.Context) (einomodel.ToolCallingChatModel, error) {
+// createChatModel 创建或获取 ChatModel。
+// preferredProfile 为对话指定的模型，排在最前面；其余模型按优先级依次作为故障转移候选。
+// 候选只包含用户角色可使用的模型，故障转移也不会切换到受限模型。
+func (s *ChatService) createChatModel(ctx context.Context, userID uint, preferredProfile string) (einomodel.ToolCallingChatModel, error) {
 	if s.llm != nil {
 		chatModel := s.llm
 		return chatModel, nil
 	}
 
-	// 如果没有全局 LLM，为这个请求创建临时实例
-	model, err := einoopenai.NewChatModel(ctx, &einoopenai.ChatModelConfig{
+	// 角色获取失败时只使用未限制角色的模型
+	roleTags, err := s.userRoleTags(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "create_chat_model_role_tags",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		roleTags = nil
+	}
+
+	candidates := make([]chatModelCandidate, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		if !rolesAllowed(profile.AllowedRoles, roleTags) {
+			continue
+		}
+		model, ok := s.profileModel(ctx, profile)
+		if !ok {
+			continue
+		}
+		candidate := chatModelCandidate{
+			name:              profile.Name,
+			model:             model,
+			firstTokenTimeout: time.Duration(profile.FirstTokenTimeoutSeconds) * time.Second,
+			vision:            profile.Vision,
+		}
+		if profile.Name == preferredProfile {
+			candidates = append([]chatModelCandidate{candidate}, candidates...)
+		} else {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, apperr.Wrap(constant.CommonInternal, errors.New("failed to create chat model: no available llm profile"))
+	}
+	return &failoverChatModel{candidates: candidates, health: s.modelHealth}, nil
+}
+
+// profileModel 获取模型实例，首次使用时创建；创建失败不缓存，下次调用时重试
+func (s *ChatService) profileModel(ctx context.Context, profile config.LLMProfile) (einomodel.ToolCallingChatModel, bool) {
+	s.modelsMu.Lock()
+	defer s.modelsMu.Unlock()
+	if model, ok := s.profileModels[profile.Name]; ok {
+		return model, true
+	}
+
+	newModel := s.newProfileModel
+	if newModel == nil {
+		newModel = s.newOpenAIChatModel
+	}
+	model, err := newModel(ctx, profile)
+	if err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "create_chat_model",
+			"profile": profile.Name,
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+	if s.profileModels == nil {
+		s.profileModels = make(map[string]einomodel.ToolCallingChatModel, len(s.profiles))
+	}
+	s.profileModels[profile.Name] = model
+	return model, true
+}
+
+func (s *ChatService) newOpenAIChatModel(ctx context.Context, profile config.LLMProfile) (einomodel.ToolCallingChatModel, error) {
+	return einoopenai.NewChatModel(ctx, &einoopenai.ChatModelConfig{
 		HTTPClient: s.httpClient,
-		Model:      s.cfg.LLM.Model,
-		APIKey:     s.cfg.LLM.APIKey,
-		BaseURL:    s.cfg.LLM.BaseURL,
+		Model:      profile.Model,
+		APIKey:     profile.APIKey,
+		BaseURL:    profile.BaseURL,
 	})
+}
+
+// ListChatModels 列出用户可为对话选择的模型
+func (s *ChatService) ListChatModels(ctx context.Context, userID uint) ([]dto.ChatModelResponse, error) {
+	roleTags, err := s.userRoleTags(ctx, userID)
 	if err != nil {
+		return nil, err
+	}
+
+	models := make([]dto.ChatModelResponse, 0, len(s.profiles))
+	for i, profile := range s.profiles {
+		if !profileAllowed(profile, roleTags) {
+			continue
+		}
+		models = append(models, dto.ChatModelResponse{
+			Name:        profile.Name,
+			DisplayName: profile.DisplayName,
+			Description: profile.Description,
+			IsDefault:   i == 0,
+			Healthy:     s.modelHealth.healthy(profile.Name),
+		})
+	}
+	return models, nil
+}
+
+// SetConversationModel 为对话指定模型，空字符串表示恢复默认
+func (s *ChatService) SetConversationModel(ctx context.Context, userID, conversationID uint, profileName string) error {
+	if _, err := s.getOwnedConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	if profileName != "" {
+		allowed, err := s.allowedModelProfile(ctx, userID, profileName)
+		if err != nil {
+			return err
+		}
+		if allowed == "" {
+			return apperr.New(constant.ConversationModelNotAllowed)
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Conversation{}).
+		Where("id = ? AND user_id = ?", conversationID, userID).
+		Update("model_profile", profileName).Error; err != nil {
 		logger.ErrorCtx(ctx, map[string]any{
-			"action": "create_chat_model",
-			"error":  err.Error(),
+			"action":          "set_conversation_model",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to update conversation model: %w", err))
+	}
+
+	s.deleteConversationInfoCache(ctx, userID, conversationID)
+	return nil
+}
+
+// allowedModelProfile 校验用户当前是否仍可使用该模型，不可用时返回空字符串
+func (s *ChatService) allowedModelProfile(ctx context.Context, userID uint, profileName string) (string, error) {
+	if profileName == "" {
+		return "", nil
+	}
+	roleTags, err := s.userRoleTags(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, profile := range s.profiles {
+		if profile.Name == profileName && profileAllowed(profile, roleTags) {
+			return profileName, nil
+		}
+	}
+	return "", nil
+}
+
+// resolveModelProfile 对话指定的模型在用户失去权限或配置移除后回退到人设的模型，再回退到默认顺序。
+// 人设的模型同样受 AllowedRoles 限制，公开人设不能让用户用上其角色无权使用的模型。
+func (s *ChatService) resolveModelProfile(ctx context.Context, userID uint, conv *models.Conversation, persona *chatPersonaRuntime) string {
+	if conv.ModelProfile == "" && persona.modelProfile == "" {
+		return ""
+	}
+	roleTags, err := s.userRoleTags(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "stream_chat_resolve_model",
+			"user_id":         userID,
+			"conversation_id": conv.ID,
+			"model_profile":   conv.ModelProfile,
+			"error":           err.Error(),
 		})
-		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to create chat model: %w", err))
+		return ""
+	}
+	for _, profile := range s.profiles {
+		if profile.Name == conv.ModelProfile && profileAllowed(profile, roleTags) {
+			return profile.Name
+		}
+	}
+	for _, profile := range s.profiles {
+		if profile.Name == persona.modelProfile && rolesAllowed(profile.AllowedRoles, roleTags) {
+			return profile.Name
+		}
 	}
-	return model, nil
+	return ""
+}
+
+func (s *ChatService) userRoleTags(ctx context.Context, userID uint) ([]string, error) {
+	if s.rbacService == nil {
+		return nil, nil
+	}
+	snap, err := s.rbacService.GetUserPermissionSnapshot(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return snap.RoleTags, nil
+}
+
+// profileAllowed 模型需允许手动选择，且未限制角色或用户拥有其中之一
+func profileAllowed(profile config.LLMProfile, roleTags []string) bool {
+	return profile.Selectable && rolesAllowed(profile.AllowedRoles, roleTags)
 }
 
 // createAgent 创建 ChatModelAgent
-func (s *ChatService) createAgent(ctx context.Context, tools []einotool.BaseTool) (adk.Agent, error) {
-	chatModel, err := s.createChatModel(ctx)
+func (s *ChatService) createAgent(ctx context.Context, userID uint, tools []einotool.BaseTool, modelProfile, instruction string) (adk.Agent, error) {
+	chatModel, err := s.createChatModel(ctx, userID, modelProfile)
 	if err != nil {
 		return nil, err
 	}
@@ -1003,7 +1431,7 @@ func (s *ChatService) createAgent(ctx context.Context, tools []einotool.BaseTool
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
 		Name:        "StudyAssistant",
 		Description: "A helpful assistant for students at JXUST.",
-		Instruction: constant.ChatSystemPrompt,
+		Instruction: instruction,
 		Model:       chatModel,
 		ToolsConfig: adk.ToolsConfig{
 			ToolsNodeConfig: compose.ToolsNodeConfig{
@@ -1026,7 +1454,7 @@ func (s *ChatService) createAgent(ctx context.Context, tools []einotool.BaseTool
 // StreamChat 流式聊天 (返回一个通道用于 SSE)
 // 使用 NewChatModelAgent 实现，支持中断恢复和流式传输
 // 当 checkpointID 不为空时，执行恢复操作；否则执行新对话
-func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uint, newMessage *schema.Message, userToken string, checkpointID string, resumeInput string) (<-chan string, <-chan error, error) {
+func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uint, newMessage *schema.Message, attachments []string, userToken string, checkpointID string, resumeInput string) (<-chan string, <-chan error, error) {
 	// 验证对话属于用户
 	conv, err := s.GetConversation(ctx, userID, conversationID)
 	if err != nil {
@@ -1039,27 +1467,24 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 		return nil, nil, err
 	}
 
-	// 获取完整的会话消息
-	messages, err := s.GetMessages(ctx, userID, conversationID)
-	if err != nil {
-		logger.ErrorCtx(ctx, map[string]any{
-			"action":          "stream_chat_get_messages",
-			"user_id":         userID,
-			"conversation_id": conversationID,
-			"error":           err.Error(),
-		})
-		return nil, nil, err
-	}
-
 	isResume := checkpointID != ""
 
-	// 新对话：合并新消息
+	// 新对话：合并新消息，追加到当前分支末端
+	var leafID uint
 	if !isResume {
 		if newMessage == nil {
 			return nil, nil, apperr.New(constant.ConversationMessageRequired)
 		}
-		checkpointID = fmt.Sprintf("%d:%d:%d", userID, conversationID, time.Now().UnixNano())
-		if err := s.AppendMessages(ctx, userID, conversationID, checkpointID, []*schema.Message{newMessage}); err != nil {
+		// 先加入附件再审核，文档提取出的文本与用户输入一起审核
+		if newMessage, err = s.attachToUserMessage(ctx, userID, newMessage, attachments); err != nil {
+			return nil, nil, err
+		}
+		if newMessage, err = s.moderateUserMessage(ctx, userID, conversationID, newMessage); err != nil {
+			return nil, nil, err
+		}
+		checkpointID = newChatCheckpointID(userID, conversationID)
+		leafID, err = s.appendMessages(ctx, userID, conversationID, checkpointID, nil, []*schema.Message{newMessage})
+		if err != nil {
 			logger.ErrorCtx(ctx, map[string]any{
 				"action":          "stream_chat_append_user_message",
 				"user_id":         userID,
@@ -1069,16 +1494,39 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 			})
 			return nil, nil, err
 		}
-		messages = append(messages, newMessage)
 	}
 
+	return s.runAgent(ctx, conv, userID, userToken, checkpointID, resumeInput, isResume, leafID)
+}
+
+func newChatCheckpointID(userID, conversationID uint) string {
+	return fmt.Sprintf("%d:%d:%d", userID, conversationID, time.Now().UnixNano())
+}
+
+// runAgent 创建 Agent 并流式运行（或从 checkpoint 恢复）。
+// leafID 为本轮回复挂载的父消息，0 表示挂载到保存时的当前分支末端。
+func (s *ChatService) runAgent(ctx context.Context, conv *models.Conversation, userID uint, userToken, checkpointID, resumeInput string, isResume bool, leafID uint) (<-chan string, <-chan error, error) {
+	conversationID := conv.ID
+
+	// 本轮运行与 HTTP 请求解耦：客户端断开后继续生成并保存，事件写入缓冲供重连续传
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constant.ChatTurnTimeout)
+
+	persona := s.resolveChatPersona(ctx, conv)
+
 	// MCP 工具为增强能力，不应阻塞基础聊天能力。resume 也需要加载工具以恢复中断点。
 	allTools, mcpClients := s.loadMCPTools(ctx, userID, conversationID, userToken)
+	allTools = append(allTools, s.loadNativeTools(ctx, userID, conversationID)...)
+	allTools = filterAgentTools(ctx, allTools, persona.allowedTools)
+	confirmPolicy := s.confirmPolicy.get(ctx)
+	allTools = wrapToolsWithConfirmation(ctx, allTools, &confirmPolicy)
+
+	modelProfile := s.resolveModelProfile(ctx, userID, conv, persona)
 
 	// 创建 Agent
-	agent, err := s.createAgent(ctx, allTools)
+	agent, err := s.createAgent(ctx, userID, allTools, modelProfile, persona.instruction)
 	if err != nil {
 		mcpClients.Close(ctx)
+		cancel()
 		logger.ErrorCtx(ctx, map[string]any{
 			"action":          "stream_chat_create_agent",
 			"user_id":         userID,
@@ -1095,6 +1543,12 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 		CheckPointStore: s.checkPointStore,
 	})
 
+	// 人设指定的温度作为每次模型调用的选项
+	var runOpts []adk.AgentRunOption
+	if persona.temperature != nil {
+		runOpts = append(runOpts, adk.WithChatModelOptions([]einomodel.Option{einomodel.WithTemperature(*persona.temperature)}))
+	}
+
 	var iter *adk.AsyncIterator[*adk.AgentEvent]
 	var startEventType string
 
@@ -1104,9 +1558,10 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 		if resumeInput != "" {
 			toolOpts = append(toolOpts, WithResumeInput(resumeInput))
 		}
-		iter, err = runner.Resume(ctx, checkpointID, adk.WithToolOptions(toolOpts))
+		iter, err = runner.Resume(ctx, checkpointID, append(runOpts, adk.WithToolOptions(toolOpts))...)
 		if err != nil {
 			mcpClients.Close(ctx)
+			cancel()
 			logger.ErrorCtx(ctx, map[string]any{
 				"action":          "stream_chat_resume_agent",
 				"user_id":         userID,
@@ -1124,16 +1579,25 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 			"checkpoint_id":   checkpointID,
 		})
 	} else {
-		// 构建 Agent 输入消息（不包含系统提示词，由 Agent 的 Instruction 处理）
-		var inputMessages []adk.Message
-		for _, msg := range messages {
-			if msg.Role != schema.System {
-				inputMessages = append(inputMessages, msg)
-			}
+		// 构建 Agent 输入消息（系统提示词由 Agent 的 Instruction 处理，早期消息按 token 预算滚动摘要）
+		inputMessages, err := s.buildAgentHistory(ctx, userID, conversationID, persona.instruction)
+		if err != nil {
+			mcpClients.Close(ctx)
+			cancel()
+			logger.ErrorCtx(ctx, map[string]any{
+				"action":          "stream_chat_build_history",
+				"user_id":         userID,
+				"conversation_id": conversationID,
+				"error":           err.Error(),
+			})
+			return nil, nil, err
 		}
 
+		// 附件引用替换为模型可用的输入
+		inputMessages = s.prepareAttachmentsForModel(ctx, inputMessages)
+
 		// 运行 Agent
-		iter = runner.Run(ctx, inputMessages, adk.WithCheckPointID(checkpointID))
+		iter = runner.Run(ctx, inputMessages, append(runOpts, adk.WithCheckPointID(checkpointID))...)
 		startEventType = "start"
 	}
 
@@ -1153,6 +1617,9 @@ func (s *ChatService) StreamChat(ctx context.Context, userID, conversationID uin
 		outputChan:     outputChan,
 		errChan:        errChan,
 		startEventType: startEventType,
+		leafID:         leafID,
+		stream:         newChatTurnStream(conversationID),
+		cancel:         cancel,
 	}
 
 	go processor.process(iter)
@@ -120,3 +120,48 @@ func TestWrapAgentToolsWithFailureResultsConvertsErrorToResult(t *testing.T) {
 		t.Fatalf("unexpected error: %#v", payload["error"])
 	}
 }
+
+func TestStreamEventProcessorErrorEventHasEventID(t *testing.T) {
+	t.Parallel()
+
+	iter, gen := adk.NewAsyncIteratorPair[*adk.AgentEvent]()
+	outputChan := make(chan string, 10)
+	errChan := make(chan error, 1)
+	mem := newMemoryStreamCache()
+
+	processor := &streamEventProcessor{
+		ctx:            context.Background(),
+		conv:           &models.Conversation{},
+		outputChan:     outputChan,
+		errChan:        errChan,
+		mcpClients:     mcpClient{},
+		startEventType: "start",
+		stream:         &chatTurnStream{cache: mem, conversationID: 7, turnID: "abc"},
+	}
+
+	go processor.process(iter)
+	gen.Send(&adk.AgentEvent{Err: errors.New("model unavailable")})
+	gen.Close()
+
+	var frames []string
+	for frame := range outputChan {
+		frames = append(frames, frame)
+	}
+	if len(frames) != 2 || frames[1] != "id: abc:2\ndata: {\"error\":\"model unavailable\",\"type\":\"error\"}\n\n" {
+		t.Fatalf("unexpected frames %q", frames)
+	}
+	if err := <-errChan; err == nil || err.Error() != "model unavailable" {
+		t.Fatalf("errChan = %v", err)
+	}
+
+	// 续传时同样重放错误事件
+	replay := make(chan string, 10)
+	go (&chatTurnStream{cache: mem, conversationID: 7, turnID: "abc"}).tail(context.Background(), 1, replay)
+	var replayed []string
+	for frame := range replay {
+		replayed = append(replayed, frame)
+	}
+	if len(replayed) != 1 || replayed[0] != frames[1] {
+		t.Fatalf("unexpected replay %q", replayed)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// newShareSlug 生成 128 位随机、URL 安全的分享标识
+func newShareSlug() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// buildShareSnapshot 从当前分支构建分享快照：只保留用户与助手的正文，去掉系统提示、工具调用与结果、推理过程及附件内容
+func buildShareSnapshot(path []*models.ConversationMessage) []dto.SharedMessage {
+	snapshot := make([]dto.SharedMessage, 0, len(path))
+	for _, row := range path {
+		if row.Role != string(schema.User) && row.Role != string(schema.Assistant) {
+			continue
+		}
+		content := strings.TrimSpace(row.Content)
+		if names := attachmentFileNames(row.Attachments); row.Role == string(schema.User) && len(names) > 0 {
+			content = strings.TrimSpace(content + "\n（附件：" + strings.Join(names, "、") + "）")
+		}
+		if content == "" {
+			// 仅包含工具调用的助手消息
+			continue
+		}
+		snapshot = append(snapshot, dto.SharedMessage{Role: row.Role, Content: content})
+	}
+	return snapshot
+}
+
+func toConversationShareResponse(share *models.ConversationShare) dto.ConversationShareResponse {
+	return dto.ConversationShareResponse{
+		Slug:           share.Slug,
+		ConversationID: share.ConversationID,
+		Title:          share.Title,
+		MessageCount:   share.MessageCount,
+		ViewCount:      share.ViewCount,
+		ExpiresAt:      share.ExpiresAt,
+		RevokedAt:      share.RevokedAt,
+		CreatedAt:      share.CreatedAt,
+	}
+}
+
+// CreateConversationShare 为对话当前分支创建不可变的公开分享快照
+func (s *ChatService) CreateConversationShare(ctx context.Context, userID, conversationID uint, req *dto.CreateConversationShareRequest) (*dto.ConversationShareResponse, error) {
+	conv, err := s.getOwnedConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := loadConversationTree(ctx, s.db, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := buildShareSnapshot(tree.activePath())
+	if len(snapshot) == 0 {
+		return nil, apperr.New(constant.ConversationMessageRequired)
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to marshal share snapshot: %w", err))
+	}
+	slug, err := newShareSlug()
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to generate share slug: %w", err))
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = conv.Title
+	}
+	share := &models.ConversationShare{
+		Slug:           slug,
+		UserID:         userID,
+		ConversationID: conversationID,
+		Title:          title,
+		Snapshot:       datatypes.JSON(raw),
+		MessageCount:   len(snapshot),
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+	if err := s.db.WithContext(ctx).Create(share).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":          "create_conversation_share",
+			"user_id":         userID,
+			"conversation_id": conversationID,
+			"error":           err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to create share: %w", err))
+	}
+
+	resp := toConversationShareResponse(share)
+	return &resp, nil
+}
+
+// ListConversationShares 列出用户创建的分享
+func (s *ChatService) ListConversationShares(ctx context.Context, userID uint, page, pageSize int) ([]dto.ConversationShareResponse, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.ConversationShare{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to count shares: %w", err))
+	}
+
+	var shares []models.ConversationShare
+	if err := query.Omit("snapshot").
+		Order("created_at DESC, id DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&shares).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "list_conversation_shares",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to list shares: %w", err))
+	}
+
+	items := make([]dto.ConversationShareResponse, 0, len(shares))
+	for i := range shares {
+		items = append(items, toConversationShareResponse(&shares[i]))
+	}
+	return items, total, nil
+}
+
+// RevokeConversationShare 撤销分享，重复撤销视为成功
+func (s *ChatService) RevokeConversationShare(ctx context.Context, userID uint, slug string) error {
+	var share models.ConversationShare
+	if err := s.db.WithContext(ctx).
+		Select("id", "revoked_at").
+		Where("slug = ? AND user_id = ?", slug, userID).
+		First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New(constant.ConversationShareNotFound)
+		}
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get share: %w", err))
+	}
+	if share.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.ConversationShare{}).
+		Where("id = ? AND revoked_at IS NULL", share.ID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":  "revoke_conversation_share",
+			"user_id": userID,
+			"slug":    slug,
+			"error":   err.Error(),
+		})
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to revoke share: %w", err))
+	}
+	return nil
+}
+
+// GetSharedConversation 公开读取分享快照并累加浏览次数
+func (s *ChatService) GetSharedConversation(ctx context.Context, slug string) (*dto.SharedConversationResponse, error) {
+	var share models.ConversationShare
+	if err := s.db.WithContext(ctx).Where("slug = ?", slug).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.ConversationShareNotFound)
+		}
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to get share: %w", err))
+	}
+	if share.RevokedAt != nil {
+		return nil, apperr.New(constant.ConversationShareNotFound)
+	}
+	if share.ExpiresAt != nil && !time.Now().Before(*share.ExpiresAt) {
+		return nil, apperr.New(constant.ConversationShareExpired)
+	}
+
+	var messages []dto.SharedMessage
+	if err := json.Unmarshal(share.Snapshot, &messages); err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("failed to unmarshal share snapshot: %w", err))
+	}
+
+	// 浏览计数失败不影响读取
+	if err := s.db.WithContext(ctx).Model(&models.ConversationShare{}).
+		Where("id = ?", share.ID).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action": "conversation_share_view_count",
+			"slug":   slug,
+			"error":  err.Error(),
+		})
+	} else {
+		share.ViewCount++
+	}
+
+	return &dto.SharedConversationResponse{
+		Title:     share.Title,
+		Messages:  messages,
+		ViewCount: share.ViewCount,
+		ExpiresAt: share.ExpiresAt,
+		CreatedAt: share.CreatedAt,
+	}, nil
+}
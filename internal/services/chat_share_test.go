@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/datatypes"
+)
+
+func TestBuildShareSnapshot(t *testing.T) {
+	path := []*models.ConversationMessage{
+		{Role: string(schema.System), Content: "系统提示"},
+		{Role: string(schema.User), Content: "这题怎么做", Attachments: datatypes.JSON(`[{"resource_id":"r1","file_name":"题目.png"}]`)},
+		{Role: string(schema.Assistant), Content: "", ToolCalls: datatypes.JSON(`[{"id":"call-1"}]`)},
+		{Role: string(schema.Tool), Content: `{"result":"内部检索结果"}`},
+		{Role: string(schema.Assistant), Content: "  先求导，再代入。  ", ReasoningContent: "内部推理"},
+	}
+
+	got := buildShareSnapshot(path)
+	if len(got) != 2 {
+		t.Fatalf("expected user and final answer only, got %+v", got)
+	}
+	if got[0].Role != string(schema.User) || got[0].Content != "这题怎么做\n（附件：题目.png）" {
+		t.Fatalf("unexpected user message %+v", got[0])
+	}
+	if got[1].Role != string(schema.Assistant) || got[1].Content != "先求导，再代入。" {
+		t.Fatalf("unexpected assistant message %+v", got[1])
+	}
+}
+
+func TestNewShareSlug(t *testing.T) {
+	a, err := newShareSlug()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := newShareSlug()
+	if len(a) != 22 || a == b {
+		t.Fatalf("unexpected slugs %q %q", a, b)
+	}
+}
+
+func TestDeleteConversationRevokesShares(t *testing.T) {
+	db := newHarnessDB(t)
+	if err := db.AutoMigrate(&models.ConversationShare{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	prevCache := cache.GlobalCache
+	cache.GlobalCache = newMemoryStreamCache()
+	t.Cleanup(func() { cache.GlobalCache = prevCache })
+	s := &ChatService{db: db}
+	ctx := context.Background()
+
+	for _, conv := range []models.Conversation{{ID: 1, UserID: 7, Title: "待删除"}, {ID: 2, UserID: 7, Title: "保留"}} {
+		if err := db.Create(&conv).Error; err != nil {
+			t.Fatalf("create conversation: %v", err)
+		}
+		msg := models.ConversationMessage{ConversationID: conv.ID, UserID: 7, Role: string(schema.User), Content: "这题怎么做"}
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+	}
+	deleted, err := s.CreateConversationShare(ctx, 7, 1, &dto.CreateConversationShareRequest{})
+	if err != nil {
+		t.Fatalf("CreateConversationShare() error = %v", err)
+	}
+	kept, err := s.CreateConversationShare(ctx, 7, 2, &dto.CreateConversationShareRequest{})
+	if err != nil {
+		t.Fatalf("CreateConversationShare() error = %v", err)
+	}
+	if _, err := s.GetSharedConversation(ctx, deleted.Slug); err != nil {
+		t.Fatalf("GetSharedConversation() before delete error = %v", err)
+	}
+
+	if err := s.DeleteConversation(ctx, 7, 1); err != nil {
+		t.Fatalf("DeleteConversation() error = %v", err)
+	}
+	_, err = s.GetSharedConversation(ctx, deleted.Slug)
+	assertAppErrCode(t, err, constant.ConversationShareNotFound)
+	if _, err := s.GetSharedConversation(ctx, kept.Slug); err != nil {
+		t.Fatalf("share of another conversation should stay readable: %v", err)
+	}
+
+	var share models.ConversationShare
+	if err := db.Where("slug = ?", deleted.Slug).First(&share).Error; err != nil {
+		t.Fatalf("load share: %v", err)
+	}
+	if share.RevokedAt == nil {
+		t.Fatal("share should be marked revoked")
+	}
+}
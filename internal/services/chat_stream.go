@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+)
+
+// chatTurnStream 为一轮对话的 SSE 事件分配序号并缓冲到 Redis，客户端断线后可凭 Last-Event-ID 续传。
+// 事件 ID 形如 "<turnID>:<seq>"，seq 从 1 开始连续递增；nil 时只输出不带 ID 的事件且不缓冲。
+type chatTurnStream struct {
+	cache          cache.Cache
+	conversationID uint
+	turnID         string
+	seq            int64
+}
+
+func newChatTurnStream(conversationID uint) *chatTurnStream {
+	return &chatTurnStream{
+		cache:          cache.GlobalCache,
+		conversationID: conversationID,
+		turnID:         strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+func (t *chatTurnStream) eventsKey() string {
+	return fmt.Sprintf(constant.CacheKeyChatStreamEvents, t.conversationID, t.turnID)
+}
+
+func (t *chatTurnStream) doneKey() string {
+	return fmt.Sprintf(constant.CacheKeyChatStreamDone, t.conversationID, t.turnID)
+}
+
+// frame 为事件分配序号、写入缓冲并返回 SSE 文本
+func (t *chatTurnStream) frame(ctx context.Context, payload []byte) string {
+	if t == nil {
+		return "data: " + string(payload) + "\n\n"
+	}
+	t.seq++
+	frame := fmt.Sprintf("id: %s:%d\ndata: %s\n\n", t.turnID, t.seq, payload)
+	t.buffer(ctx, frame)
+	return frame
+}
+
+// buffer 缓冲写入失败只影响续传，不影响当前连接
+func (t *chatTurnStream) buffer(ctx context.Context, frame string) {
+	if t.cache == nil {
+		return
+	}
+	key := t.eventsKey()
+	if err := t.cache.ZAdd(ctx, key, float64(t.seq), frame); err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":          "chat_stream_buffer_event",
+			"conversation_id": t.conversationID,
+			"turn_id":         t.turnID,
+			"seq":             t.seq,
+			"error":           err.Error(),
+		})
+		return
+	}
+	if t.seq == 1 {
+		ttl := constant.ChatStreamBufferTTL
+		_ = t.cache.Expire(ctx, key, ttl)
+		_ = t.cache.Set(ctx, fmt.Sprintf(constant.CacheKeyChatStreamLatest, t.conversationID), t.turnID, &ttl)
+	}
+}
+
+// finish 标记本轮结束，缓冲保留一小段时间供断线客户端补齐
+func (t *chatTurnStream) finish(ctx context.Context) {
+	if t == nil || t.cache == nil {
+		return
+	}
+	ttl := constant.ChatStreamFinishedTTL
+	_ = t.cache.Set(ctx, t.doneKey(), "1", &ttl)
+	_ = t.cache.Expire(ctx, t.eventsKey(), ttl)
+}
+
+// parseChatStreamEventID 解析 "<turnID>:<seq>" 形式的事件 ID
+func parseChatStreamEventID(id string) (string, int64, bool) {
+	turnID, seqText, ok := strings.Cut(strings.TrimSpace(id), ":")
+	if !ok || turnID == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseInt(seqText, 10, 64)
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return turnID, seq, true
+}
+
+// chatStreamFrameSeq 从缓冲的 SSE 文本中取出序号
+func chatStreamFrameSeq(frame string) (int64, bool) {
+	idLine, _, ok := strings.Cut(frame, "\n")
+	if !ok || !strings.HasPrefix(idLine, "id: ") {
+		return 0, false
+	}
+	_, seq, ok := parseChatStreamEventID(strings.TrimPrefix(idLine, "id: "))
+	return seq, ok
+}
+
+// ReattachChatStream 续传进行中或刚结束的一轮对话事件。
+// lastEventID 为客户端收到的最后一个事件 ID，为空时从对话最近一轮的第一个事件开始重放。
+func (s *ChatService) ReattachChatStream(ctx context.Context, userID, conversationID uint, lastEventID string) (<-chan string, error) {
+	if _, err := s.getOwnedConversation(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	if cache.GlobalCache == nil {
+		return nil, apperr.New(constant.ConversationStreamNotFound)
+	}
+
+	stream := &chatTurnStream{cache: cache.GlobalCache, conversationID: conversationID}
+	var after int64
+	if lastEventID != "" {
+		turnID, seq, ok := parseChatStreamEventID(lastEventID)
+		if !ok {
+			return nil, apperr.Wrap(constant.CommonBadRequest, fmt.Errorf("invalid Last-Event-ID: %q", lastEventID))
+		}
+		stream.turnID, after = turnID, seq
+	} else {
+		turnID, err := stream.cache.Get(ctx, fmt.Sprintf(constant.CacheKeyChatStreamLatest, conversationID))
+		if err != nil || turnID == "" {
+			return nil, apperr.New(constant.ConversationStreamNotFound)
+		}
+		stream.turnID = turnID
+	}
+	if exists, err := stream.cache.Exists(ctx, stream.eventsKey()); err != nil || !exists {
+		return nil, apperr.New(constant.ConversationStreamNotFound)
+	}
+
+	outputChan := make(chan string, 50)
+	go stream.tail(ctx, after, outputChan)
+	return outputChan, nil
+}
+
+// tail 依次输出序号大于 after 的事件，直到本轮结束且事件已全部送出、缓冲过期或客户端断开
+func (t *chatTurnStream) tail(ctx context.Context, after int64, outputChan chan<- string) {
+	defer close(outputChan)
+
+	ticker := time.NewTicker(constant.ChatStreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 先读结束标记再读事件，保证结束前写入的事件都能读到
+		done, _ := t.cache.Exists(ctx, t.doneKey())
+		frames, err := t.cache.ZRangeByScore(ctx, t.eventsKey(), float64(after+1), math.Inf(1))
+		if err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":          "chat_stream_tail",
+				"conversation_id": t.conversationID,
+				"turn_id":         t.turnID,
+				"error":           err.Error(),
+			})
+			return
+		}
+		for _, frame := range frames {
+			if seq, ok := chatStreamFrameSeq(frame); ok {
+				after = seq
+			}
+			select {
+			case outputChan <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(frames) == 0 {
+			if done {
+				return
+			}
+			if exists, err := t.cache.Exists(ctx, t.eventsKey()); err != nil || !exists {
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
+)
+
+// memoryStreamCache 只实现续传缓冲、对话缓存和 checkpoint 用到的方法
+type memoryStreamCache struct {
+	cache.Cache
+	mu     sync.Mutex
+	values map[string]string
+	zsets  map[string]map[string]float64
+}
+
+func newMemoryStreamCache() *memoryStreamCache {
+	return &memoryStreamCache{values: map[string]string{}, zsets: map[string]map[string]float64{}}
+}
+
+func (m *memoryStreamCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *memoryStreamCache) Set(ctx context.Context, key, value string, expiration *time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryStreamCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	delete(m.zsets, key)
+	return nil
+}
+
+func (m *memoryStreamCache) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.values[key]
+	_, zok := m.zsets[key]
+	return ok || zok, nil
+}
+
+func (m *memoryStreamCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return nil
+}
+
+func (m *memoryStreamCache) ZAdd(ctx context.Context, key string, score float64, member interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.zsets[key] == nil {
+		m.zsets[key] = map[string]float64{}
+	}
+	m.zsets[key][member.(string)] = score
+	return nil
+}
+
+func (m *memoryStreamCache) ZRangeByScore(ctx context.Context, key string, min, max float64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var members []string
+	for member, score := range m.zsets[key] {
+		if score >= min && score <= max {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return m.zsets[key][members[i]] < m.zsets[key][members[j]] })
+	return members, nil
+}
+
+func TestChatTurnStreamTailReplaysAfterLastEventID(t *testing.T) {
+	mem := newMemoryStreamCache()
+	stream := &chatTurnStream{cache: mem, conversationID: 7, turnID: "abc"}
+	ctx := context.Background()
+
+	first := stream.frame(ctx, []byte(`{"type":"start"}`))
+	if first != "id: abc:1\ndata: {\"type\":\"start\"}\n\n" {
+		t.Fatalf("unexpected frame %q", first)
+	}
+	stream.frame(ctx, []byte(`{"type":"content","content":"hi"}`))
+	stream.frame(ctx, []byte(`{"type":"end"}`))
+	stream.finish(ctx)
+
+	if latest, _ := mem.Get(ctx, "chat:stream:latest:7"); latest != "abc" {
+		t.Fatalf("latest turn = %q", latest)
+	}
+
+	out := make(chan string, 10)
+	reader := &chatTurnStream{cache: mem, conversationID: 7, turnID: "abc"}
+	go reader.tail(ctx, 1, out)
+
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		select {
+		case frame, ok := <-out:
+			if !ok {
+				done = true
+				continue
+			}
+			got = append(got, frame)
+		case <-timeout:
+			t.Fatal("tail did not stop after the turn finished")
+		}
+	}
+	if len(got) != 2 || !strings.HasPrefix(got[0], "id: abc:2\n") || !strings.HasPrefix(got[1], "id: abc:3\n") {
+		t.Fatalf("unexpected replay: %q", got)
+	}
+}
+
+func TestNilChatTurnStreamFrame(t *testing.T) {
+	var stream *chatTurnStream
+	if got := stream.frame(context.Background(), []byte(`{}`)); got != "data: {}\n\n" {
+		t.Fatalf("unexpected frame %q", got)
+	}
+	stream.finish(context.Background())
+}
+
+func TestParseChatStreamEventID(t *testing.T) {
+	if turn, seq, ok := parseChatStreamEventID(" k3x9:12 "); !ok || turn != "k3x9" || seq != 12 {
+		t.Fatalf("got %q %d %v", turn, seq, ok)
+	}
+	for _, id := range []string{"", "abc", ":3", "abc:x", "abc:-1"} {
+		if _, _, ok := parseChatStreamEventID(id); ok {
+			t.Fatalf("%q should be rejected", id)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/eino/adk"
+	einotool "github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+func init() {
+	// 中断信息随 checkpoint 序列化保存，需要注册类型
+	schema.RegisterName[*ToolConfirmation]("yqlx_tool_confirmation")
+}
+
+// ToolConfirmationPolicy Agent 工具执行确认策略
+type ToolConfirmationPolicy struct {
+	// Tools 需要用户确认的工具，值为需要确认的 action 参数取值，为空表示该工具的每次调用都需要确认
+	Tools map[string][]string `json:"tools"`
+}
+
+// defaultToolConfirmationPolicy 内置的默认策略：修改课表、删除倒数日、发表教师评价、修改个人信息和删除 GPA 备份前需要确认。
+// 可通过 SystemConfig(chat.tool_confirmation) 覆盖，配置了 tools 时完整替换默认列表。
+var defaultToolConfirmationPolicy = ToolConfirmationPolicy{
+	Tools: map[string][]string{
+		"editCourseCell": nil,
+		"countdown":      {"delete"},
+		"teacherReview":  {"create"},
+		"userProfile":    {"update"},
+		"gpaBackup":      {"delete"},
+	},
+}
+
+// requiresConfirmation 判断该工具的这次调用是否需要确认
+func (p *ToolConfirmationPolicy) requiresConfirmation(toolName, action string) bool {
+	actions, ok := p.Tools[toolName]
+	if !ok {
+		return false
+	}
+	return len(actions) == 0 || slices.Contains(actions, action)
+}
+
+// ToolConfirmation 工具执行确认中断携带的信息，通过 confirmation 事件下发给客户端
+type ToolConfirmation struct {
+	ToolCallID string             `json:"tool_call_id"`
+	ToolName   string             `json:"tool_name"`
+	Action     string             `json:"action,omitempty"`
+	Arguments  stdjson.RawMessage `json:"arguments"`
+}
+
+// defaultToolConfirmationPolicyCopy 返回默认策略的副本
+func defaultToolConfirmationPolicyCopy() ToolConfirmationPolicy {
+	return ToolConfirmationPolicy{Tools: maps.Clone(defaultToolConfirmationPolicy.Tools)}
+}
+
+// mergeToolConfirmationPolicy 配置了 tools 时完整替换默认列表
+func mergeToolConfirmationPolicy(policy *ToolConfirmationPolicy, raw string) error {
+	var override ToolConfirmationPolicy
+	if err := json.Unmarshal([]byte(raw), &override); err != nil {
+		return err
+	}
+	if override.Tools != nil {
+		*policy = override
+	}
+	return nil
+}
+
+// wrapToolsWithConfirmation 为策略中列出的工具加上执行前确认
+func wrapToolsWithConfirmation(ctx context.Context, tools []einotool.BaseTool, policy *ToolConfirmationPolicy) []einotool.BaseTool {
+	if policy == nil || len(policy.Tools) == 0 {
+		return tools
+	}
+	wrapped := make([]einotool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		invokable, ok := t.(einotool.InvokableTool)
+		if err != nil || info == nil || !ok {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		if _, listed := policy.Tools[info.Name]; !listed {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		wrapped = append(wrapped, &confirmationTool{InvokableTool: invokable, name: info.Name, policy: policy})
+	}
+	return wrapped
+}
+
+// confirmationTool 需要确认的调用先中断，等待用户通过 resume_input 同意后再执行
+type confirmationTool struct {
+	einotool.InvokableTool
+	name   string
+	policy *ToolConfirmationPolicy
+}
+
+func (t *confirmationTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...einotool.Option) (string, error) {
+	action := toolCallAction(argumentsInJSON)
+	if !t.policy.requiresConfirmation(t.name, action) {
+		return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	}
+
+	// 恢复运行时同一工具调用会重新执行，此时根据用户输入决定是否继续
+	if wasInterrupted, _, _ := einotool.GetInterruptState[any](ctx); !wasInterrupted {
+		return "", einotool.Interrupt(ctx, &ToolConfirmation{
+			ToolCallID: compose.GetToolCallID(ctx),
+			ToolName:   t.name,
+			Action:     action,
+			Arguments:  toolCallArguments(argumentsInJSON),
+		})
+	}
+
+	opt := einotool.GetImplSpecificOptions(&resumeInputOption{}, opts...)
+	if strings.TrimSpace(opt.input) != constant.ChatToolConfirmApprove {
+		logger.InfoCtx(ctx, map[string]any{
+			"action":       "agent_tool_confirmation_rejected",
+			"tool_name":    t.name,
+			"tool_action":  action,
+			"resume_input": opt.input,
+		})
+		return agentToolFailureResult(t.name, "用户拒绝执行该操作"), nil
+	}
+	return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// toolCallAction 读取工具参数中的 action 字段，没有时返回空字符串
+func toolCallAction(argumentsInJSON string) string {
+	var args struct {
+		Action string `json:"action"`
+	}
+	_ = json.Unmarshal([]byte(argumentsInJSON), &args)
+	return args.Action
+}
+
+// toolCallArguments 原样返回合法的 JSON 参数，否则作为字符串返回
+func toolCallArguments(argumentsInJSON string) stdjson.RawMessage {
+	if stdjson.Valid([]byte(argumentsInJSON)) {
+		return stdjson.RawMessage(argumentsInJSON)
+	}
+	data, _ := json.Marshal(argumentsInJSON)
+	return data
+}
+
+// emitToolConfirmations 中断由工具执行确认引起时，先下发 confirmation 事件列出待确认的调用
+func (p *streamEventProcessor) emitToolConfirmations(interrupted *adk.InterruptInfo) {
+	var confirmations []*ToolConfirmation
+	for _, ic := range interrupted.InterruptContexts {
+		if c, ok := ic.Info.(*ToolConfirmation); ok && ic.IsRootCause {
+			confirmations = append(confirmations, c)
+		}
+	}
+	if len(confirmations) == 0 {
+		return
+	}
+
+	confirmationEvent := map[string]interface{}{
+		"type":          "confirmation",
+		"checkpoint_id": p.checkpointID,
+		"confirmations": confirmations,
+	}
+	if data, err := json.Marshal(confirmationEvent); err == nil {
+		p.outputChan <- p.stream.frame(p.ctx, data)
+	}
+}
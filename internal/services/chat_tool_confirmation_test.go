@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestToolConfirmationPolicyRequiresConfirmation(t *testing.T) {
+	t.Parallel()
+
+	policy := &defaultToolConfirmationPolicy
+	cases := []struct {
+		tool, action string
+		want         bool
+	}{
+		{"editCourseCell", "", true},
+		{"countdown", "delete", true},
+		{"countdown", "list", false},
+		{"teacherReview", "create", true},
+		{"teacherReview", "get", false},
+		{"userProfile", "update", true},
+		{"gpaBackup", "delete", true},
+		{"gpaBackup", "list", false},
+		{"getCourseTable", "", false},
+	}
+	for _, c := range cases {
+		if got := policy.requiresConfirmation(c.tool, c.action); got != c.want {
+			t.Fatalf("requiresConfirmation(%q, %q) = %v, want %v", c.tool, c.action, got, c.want)
+		}
+	}
+}
+
+func TestToolCallArgumentsKeepsInvalidJSONAsString(t *testing.T) {
+	t.Parallel()
+
+	if got := string(toolCallArguments(`{"id":3}`)); got != `{"id":3}` {
+		t.Fatalf("valid arguments should be kept as is, got %s", got)
+	}
+	if got := string(toolCallArguments(`{"id":`)); got != `"{\"id\":"` {
+		t.Fatalf("invalid arguments should be quoted, got %s", got)
+	}
+}
+
+func TestToolConfirmationPolicyOverride(t *testing.T) {
+	h := newChatHarness(t, newStubLLMServer(t), nil)
+	if err := h.db.Create(&models.SystemConfig{
+		Key:       constant.ChatToolConfirmationConfigKey,
+		Value:     `{"tools":{"studyTask":["delete"]}}`,
+		ValueType: "json",
+	}).Error; err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	policy := h.service.confirmPolicy.get(context.Background())
+	if !policy.requiresConfirmation("studyTask", "delete") {
+		t.Fatalf("override should require confirmation for studyTask delete")
+	}
+	if policy.requiresConfirmation("editCourseCell", "") {
+		t.Fatalf("override should replace the default tool list")
+	}
+}
+
+// registerCountdownTool 注册倒数日工具，记录实际执行的 action
+func registerCountdownTool(s *server.MCPServer, record func(name string)) {
+	s.AddTool(mcp.NewTool("countdown",
+		mcp.WithDescription("倒数日管理"),
+		mcp.WithString("action", mcp.Required()),
+		mcp.WithNumber("id"),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		action := req.GetString("action", "")
+		record("countdown:" + action)
+		return mcp.NewToolResultText(`{"deleted":true}`), nil
+	})
+}
+
+func TestStreamChatE2EToolConfirmation(t *testing.T) {
+	cases := []struct {
+		name        string
+		resumeInput string
+		wantCalls   []string
+		wantResult  string
+	}{
+		{name: "approve", resumeInput: constant.ChatToolConfirmApprove, wantCalls: []string{"countdown:delete"}, wantResult: "deleted"},
+		{name: "reject", resumeInput: "算了", wantResult: "用户拒绝执行该操作"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			llm := newStubLLMServer(t,
+				stubLLMTurn{ToolCalls: []stubLLMToolCall{{ID: "call_del", Name: "countdown", Arguments: `{"action":"delete","id":3}`}}},
+				stubLLMTurn{Content: "好的"},
+			)
+			mcpServer := newFakeMCPServer(t, registerCountdownTool)
+			h := newChatHarness(t, llm, mcpServer)
+			conv := h.createConversation(t, 11)
+
+			outputChan, errChan, err := h.service.StreamChat(context.Background(), 11, conv.ID,
+				schema.UserMessage("删掉考研倒数日"), nil, harnessUserToken, "", "")
+			if err != nil {
+				t.Fatalf("StreamChat: %v", err)
+			}
+			events, runErr := streamEvents(t, outputChan, errChan)
+			if runErr != nil {
+				t.Fatalf("unexpected run error: %v", runErr)
+			}
+
+			confirmations := eventsOfType(events, "confirmation")
+			if len(confirmations) != 1 {
+				t.Fatalf("expected confirmation event, got %#v", events)
+			}
+			items, _ := confirmations[0]["confirmations"].([]any)
+			if len(items) != 1 {
+				t.Fatalf("expected one pending confirmation, got %#v", confirmations[0])
+			}
+			item := items[0].(map[string]any)
+			args, _ := item["arguments"].(map[string]any)
+			if item["tool_name"] != "countdown" || item["action"] != "delete" || item["tool_call_id"] != "call_del" || args["id"] != float64(3) {
+				t.Fatalf("unexpected confirmation: %#v", item)
+			}
+			interrupts := eventsOfType(events, "interrupt")
+			if len(interrupts) != 1 || interrupts[0]["checkpoint_id"] != confirmations[0]["checkpoint_id"] {
+				t.Fatalf("confirmation should be followed by interrupt with the same checkpoint: %#v", events)
+			}
+			if calls := mcpServer.Calls(); len(calls) != 0 {
+				t.Fatalf("tool must not run before confirmation, got %v", calls)
+			}
+
+			checkpointID := confirmations[0]["checkpoint_id"].(string)
+			outputChan, errChan, err = h.service.StreamChat(context.Background(), 11, conv.ID, nil, nil, harnessUserToken, checkpointID, c.resumeInput)
+			if err != nil {
+				t.Fatalf("resume StreamChat: %v", err)
+			}
+			events, runErr = streamEvents(t, outputChan, errChan)
+			if runErr != nil {
+				t.Fatalf("unexpected resume error: %v", runErr)
+			}
+
+			if calls := mcpServer.Calls(); strings.Join(calls, ",") != strings.Join(c.wantCalls, ",") {
+				t.Fatalf("mcp calls = %v, want %v", calls, c.wantCalls)
+			}
+			results := eventsOfType(events, "tool_result")
+			if len(results) != 1 || !strings.Contains(results[0]["content"].(string), c.wantResult) {
+				t.Fatalf("unexpected tool result: %#v", results)
+			}
+			if len(eventsOfType(events, "end")) != 1 {
+				t.Fatalf("expected end event after resume: %#v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	json "github.com/bytedance/sonic"
+	"gorm.io/gorm"
+)
+
+// configPolicy 可通过 SystemConfig 覆盖的内置策略。
+// 在默认策略基础上合并覆盖配置并缓存在本地，超过刷新间隔后重新加载；配置缺失或无效时沿用默认值。
+type configPolicy[T any] struct {
+	configService *ConfigService
+	key           string
+	interval      time.Duration
+	// defaults 返回默认策略的副本，merge 在副本上合并覆盖配置
+	defaults func() T
+	merge    func(policy *T, raw string) error
+
+	mu       sync.RWMutex
+	value    T
+	loadedAt time.Time
+}
+
+func newConfigPolicy[T any](db *gorm.DB, key string, interval time.Duration, defaults func() T, merge func(policy *T, raw string) error) *configPolicy[T] {
+	return &configPolicy[T]{
+		configService: NewConfigService(db),
+		key:           key,
+		interval:      interval,
+		defaults:      defaults,
+		merge:         merge,
+	}
+}
+
+// get 获取当前生效的策略，返回值由多个请求共享，调用方不应修改
+func (p *configPolicy[T]) get(ctx context.Context) T {
+	p.mu.RLock()
+	value, loadedAt := p.value, p.loadedAt
+	p.mu.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) <= p.interval {
+		return value
+	}
+
+	value = p.defaults()
+	cfg, err := p.configService.GetByKey(ctx, p.key)
+	switch {
+	case err == nil:
+		if err := p.merge(&value, cfg.Value); err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":  "config_policy_load",
+				"message": "策略配置解析失败，无效部分使用默认值",
+				"key":     p.key,
+				"error":   err.Error(),
+			})
+		}
+	case !isConfigKeyNotFound(err):
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "config_policy_load",
+			"message": "读取策略配置失败，使用默认策略",
+			"key":     p.key,
+			"error":   err.Error(),
+		})
+	}
+
+	p.store(value)
+	return value
+}
+
+// store 替换本地缓存的策略
+func (p *configPolicy[T]) store(value T) {
+	p.mu.Lock()
+	p.value = value
+	p.loadedAt = time.Now()
+	p.mu.Unlock()
+}
+
+// roleOverride 按用户角色覆盖策略中的限制值，<=0 表示不限制。
+// 用户拥有多个角色时取最宽松的值；没有匹配的角色或获取角色失败时返回 base。
+func roleOverride[N int | int64](ctx context.Context, rbacService *RBACService, userID uint, base N, overrides map[string]N) N {
+	if len(overrides) == 0 || userID == 0 || rbacService == nil {
+		return base
+	}
+	snap, err := rbacService.GetUserPermissionSnapshot(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "role_override_lookup",
+			"message": "获取用户角色失败，使用默认限制",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return base
+	}
+
+	value, matched := base, false
+	for _, roleTag := range snap.RoleTags {
+		roleValue, ok := overrides[roleTag]
+		if !ok {
+			continue
+		}
+		if roleValue <= 0 {
+			return 0
+		}
+		if !matched || roleValue > value {
+			value, matched = roleValue, true
+		}
+	}
+	return value
+}
+
+// overrideJSON 将 JSON 配置覆盖到 policy 上，未填写的字段沿用原值；解析失败时 policy 保持不变
+func overrideJSON[T any](policy *T, raw string) error {
+	override := *policy
+	if err := json.Unmarshal([]byte(raw), &override); err != nil {
+		return err
+	}
+	*policy = override
+	return nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+)
+
+type testConfigPolicy struct {
+	Limit int    `json:"limit"`
+	Mode  string `json:"mode"`
+}
+
+func TestConfigPolicyMergesAndReloads(t *testing.T) {
+	db := newHarnessDB(t)
+	ctx := context.Background()
+	p := newConfigPolicy(db, "test.policy", time.Hour,
+		func() testConfigPolicy { return testConfigPolicy{Limit: 10, Mode: "default"} }, overrideJSON[testConfigPolicy])
+
+	if got := p.get(ctx); got != (testConfigPolicy{Limit: 10, Mode: "default"}) {
+		t.Fatalf("missing config should use defaults, got %+v", got)
+	}
+
+	cfg := models.SystemConfig{Key: "test.policy", Value: `{"limit":3}`}
+	if err := db.Create(&cfg).Error; err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+	if got := p.get(ctx); got.Limit != 10 {
+		t.Fatalf("policy should be cached until the reload interval, got %+v", got)
+	}
+
+	p.loadedAt = time.Time{}
+	if got := p.get(ctx); got != (testConfigPolicy{Limit: 3, Mode: "default"}) {
+		t.Fatalf("override should be merged onto defaults, got %+v", got)
+	}
+
+	if err := db.Model(&cfg).Update("value", `{"limit":`).Error; err != nil {
+		t.Fatalf("update config: %v", err)
+	}
+	p.loadedAt = time.Time{}
+	if got := p.get(ctx); got != (testConfigPolicy{Limit: 10, Mode: "default"}) {
+		t.Fatalf("invalid config should fall back to defaults, got %+v", got)
+	}
+}
@@ -2,21 +2,25 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+	json "github.com/bytedance/sonic"
 	"gorm.io/gorm"
 )
 
 type ConfigService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache cache.Cache
 }
 
 func NewConfigService(db *gorm.DB) *ConfigService {
-	return &ConfigService{db: db}
+	return &ConfigService{db: db, cache: cache.GlobalTieredCache}
 }
 
 // Create 创建配置项（key唯一）
@@ -67,6 +71,7 @@ func (s *ConfigService) Update(ctx context.Context, key, value, valueType string
 	if tx.RowsAffected == 0 {
 		return apperr.New(constant.ConfigKeyNotFound)
 	}
+	s.clearConfigCache(ctx, key)
 	return nil
 }
 
@@ -79,11 +84,22 @@ func (s *ConfigService) Delete(ctx context.Context, key string) error {
 	if tx.RowsAffected == 0 {
 		return apperr.New(constant.ConfigKeyNotFound)
 	}
+	s.clearConfigCache(ctx, key)
 	return nil
 }
 
 // GetByKey 通过key获取配置项
 func (s *ConfigService) GetByKey(ctx context.Context, key string) (*models.SystemConfig, error) {
+	cacheKey := fmt.Sprintf(constant.CacheKeySystemConfig, key)
+	if s.cache != nil {
+		if cached, err := s.cache.Get(ctx, cacheKey); err == nil && cached != "" {
+			var m models.SystemConfig
+			if err := json.Unmarshal([]byte(cached), &m); err == nil {
+				return &m, nil
+			}
+		}
+	}
+
 	var m models.SystemConfig
 	if err := s.db.WithContext(ctx).Where("`key` = ?", key).First(&m).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -91,9 +107,29 @@ func (s *ConfigService) GetByKey(ctx context.Context, key string) (*models.Syste
 		}
 		return nil, apperr.Wrap(constant.CommonInternal, err)
 	}
+
+	if s.cache != nil {
+		if data, err := json.Marshal(m); err == nil {
+			ttl := constant.SystemConfigCacheTTL
+			_ = s.cache.Set(ctx, cacheKey, string(data), &ttl)
+		}
+	}
 	return &m, nil
 }
 
+// isConfigKeyNotFound 判断 GetByKey 的错误是否为配置项不存在
+func isConfigKeyNotFound(err error) bool {
+	appErr, ok := apperr.As(err)
+	return ok && appErr.Code == constant.ConfigKeyNotFound
+}
+
+// clearConfigCache 清除配置项缓存
+func (s *ConfigService) clearConfigCache(ctx context.Context, key string) {
+	if s.cache != nil {
+		_ = s.cache.Delete(ctx, fmt.Sprintf(constant.CacheKeySystemConfig, key))
+	}
+}
+
 // SearchConfigs 搜索配置项，支持按key搜索，空query返回全部（分页版本）
 func (s *ConfigService) SearchConfigs(ctx context.Context, query string, page, size int) ([]models.SystemConfig, int64, error) {
 	var list []models.SystemConfig
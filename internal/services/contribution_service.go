@@ -17,19 +17,30 @@ import (
 )
 
 type ContributionService struct {
-	db            *gorm.DB
-	pointsService *PointsService
+	db                *gorm.DB
+	pointsService     *PointsService
+	moderationService *ModerationService
 }
 
-func NewContributionService(db *gorm.DB, pointsService *PointsService) *ContributionService {
+func NewContributionService(db *gorm.DB, pointsService *PointsService, moderationService *ModerationService) *ContributionService {
 	return &ContributionService{
-		db:            db,
-		pointsService: pointsService,
+		db:                db,
+		pointsService:     pointsService,
+		moderationService: moderationService,
 	}
 }
 
 // CreateContribution 创建投稿
 func (s *ContributionService) CreateContribution(ctx context.Context, userID uint, req *request.CreateContributionRequest) error {
+	// 内容审核：标题与正文分别审核，任一被拦截即拒绝投稿
+	titleDecision, err := s.moderationService.Moderate(ctx, constant.ModerationSurfaceContribution, userID, req.Title)
+	if err != nil {
+		return err
+	}
+	contentDecision, err := s.moderationService.Moderate(ctx, constant.ModerationSurfaceContribution, userID, req.Content)
+	if err != nil {
+		return err
+	}
 
 	// 序列化分类
 	categoriesJSON, err := json.Marshal(req.Categories)
@@ -40,8 +51,8 @@ func (s *ContributionService) CreateContribution(ctx context.Context, userID uin
 	// 创建投稿
 	contribution := models.UserContribution{
 		UserID:     userID,
-		Title:      req.Title,
-		Content:    req.Content,
+		Title:      titleDecision.Text,
+		Content:    contentDecision.Text,
 		Categories: categoriesJSON,
 		Status:     models.UserContributionStatusPending,
 	}
@@ -49,6 +60,8 @@ func (s *ContributionService) CreateContribution(ctx context.Context, userID uin
 	if err := s.db.WithContext(ctx).Create(&contribution).Error; err != nil {
 		return apperr.Wrap(constant.CommonInternal, err)
 	}
+	s.moderationService.Audit(ctx, titleDecision, moderationTargetID(contribution.ID))
+	s.moderationService.Audit(ctx, contentDecision, moderationTargetID(contribution.ID))
 
 	return nil
 }
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+
+	"gorm.io/datatypes"
+)
+
+// TestContributionServiceScopesByInjectedRoles 非 gin 请求（后台 MCP）通过 WithUserRBAC 注入角色后可查看所有用户的投稿
+func TestContributionServiceScopesByInjectedRoles(t *testing.T) {
+	ctx := context.Background()
+	db := newHarnessDB(t)
+	service := NewContributionService(db, nil, nil)
+
+	for _, user := range []models.User{{ID: 1, OpenID: "o1", Nickname: "alice"}, {ID: 2, OpenID: "o2", Nickname: "bob"}} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+	contributions := []models.UserContribution{
+		{UserID: 1, Title: "停水通知", Status: models.UserContributionStatusPending},
+		{UserID: 2, Title: "讲座通知", Status: models.UserContributionStatusPending},
+		{UserID: 2, Title: "社团招新", Status: models.UserContributionStatusApproved},
+	}
+	for i := range contributions {
+		contributions[i].Categories = datatypes.JSON("[]")
+		if err := db.Create(&contributions[i]).Error; err != nil {
+			t.Fatalf("create contribution: %v", err)
+		}
+	}
+
+	page, err := service.GetContributions(ctx, 1, &request.GetContributionsRequest{Page: 1, Size: 10})
+	if err != nil {
+		t.Fatalf("GetContributions() error = %v", err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("student total = %d, want 1", page.Total)
+	}
+	_, err = service.GetContributionByID(ctx, contributions[1].ID, 1)
+	assertAppErrCode(t, err, constant.ContributionForbidden)
+
+	operatorCtx := utils.WithUserRBAC(ctx, []string{constant.RoleTagOperator}, []string{constant.PermissionContributionManage}, false)
+	pending := uint8(models.UserContributionStatusPending)
+	page, err = service.GetContributions(operatorCtx, 1, &request.GetContributionsRequest{Page: 1, Size: 10, Status: &pending})
+	if err != nil {
+		t.Fatalf("GetContributions(operator) error = %v", err)
+	}
+	if page.Total != 2 {
+		t.Fatalf("operator pending total = %d, want 2", page.Total)
+	}
+	got, err := service.GetContributionByID(operatorCtx, contributions[1].ID, 1)
+	if err != nil {
+		t.Fatalf("GetContributionByID(operator) error = %v", err)
+	}
+	if got.User == nil || got.User.Nickname != "bob" {
+		t.Fatalf("contribution user = %+v, want bob", got.User)
+	}
+}
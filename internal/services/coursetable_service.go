@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
@@ -357,3 +359,124 @@ func (s *CourseTableService) ResetUserBindCountToOne(ctx context.Context, target
 		return nil
 	})
 }
+
+// GetUserCourseTableForWeek 获取用户课程表中指定教学周的课程
+func (s *CourseTableService) GetUserCourseTableForWeek(ctx context.Context, userID uint, semester string, week int) (*response.CourseTableResponse, error) {
+	result, err := s.GetUserCourseTable(ctx, userID, semester)
+	if err != nil {
+		return nil, err
+	}
+	filtered, err := filterCourseDataByWeek(result.CourseData, week)
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("解析课程表失败: %w", err))
+	}
+	result.CourseData = filtered
+	return result, nil
+}
+
+// filterCourseDataByWeek 按教学周过滤课程数据。
+// 格子可以是单门课程对象或课程数组，课程通过 weeks/week 字段标注上课周次（整数数组或 "1-8,10-16周"、"1-16(单)" 形式的字符串），
+// 没有周次信息的课程视为每周都上，予以保留；过滤后为空的格子置为 null。
+func filterCourseDataByWeek(data datatypes.JSON, week int) (datatypes.JSON, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	var cells map[string]any
+	if err := json.Unmarshal(data, &cells); err != nil {
+		return nil, err
+	}
+
+	for index, cell := range cells {
+		switch v := cell.(type) {
+		case map[string]any:
+			if !courseEntryInWeek(v, week) {
+				cells[index] = nil
+			}
+		case []any:
+			kept := make([]any, 0, len(v))
+			for _, entry := range v {
+				if m, ok := entry.(map[string]any); !ok || courseEntryInWeek(m, week) {
+					kept = append(kept, entry)
+				}
+			}
+			if len(kept) == 0 {
+				cells[index] = nil
+			} else {
+				cells[index] = kept
+			}
+		}
+	}
+	return json.Marshal(cells)
+}
+
+// courseEntryInWeek 判断课程在指定周是否上课，无法识别周次信息时返回 true
+func courseEntryInWeek(entry map[string]any, week int) bool {
+	raw, ok := entry["weeks"]
+	if !ok {
+		raw, ok = entry["week"]
+	}
+	if !ok {
+		return true
+	}
+
+	switch v := raw.(type) {
+	case []any:
+		if len(v) == 0 {
+			return true
+		}
+		for _, w := range v {
+			if n, ok := w.(float64); ok && int(n) == week {
+				return true
+			}
+		}
+		return false
+	case float64:
+		return int(v) == week
+	case string:
+		weeks, ok := parseWeekRanges(v)
+		return !ok || weeks[week]
+	default:
+		return true
+	}
+}
+
+// parseWeekRanges 解析 "1-8,10-16周"、"1-16(单)"、"2-16双周" 形式的周次描述
+func parseWeekRanges(text string) (map[int]bool, bool) {
+	weeks := make(map[int]bool)
+	for _, segment := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+		parity := 0
+		switch {
+		case strings.Contains(segment, "单"):
+			parity = 1
+		case strings.Contains(segment, "双"):
+			parity = 2
+		}
+		segment = strings.Map(func(r rune) rune {
+			if (r >= '0' && r <= '9') || r == '-' {
+				return r
+			}
+			return -1
+		}, segment)
+		if segment == "" {
+			continue
+		}
+
+		start, end, found := strings.Cut(segment, "-")
+		from, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, false
+		}
+		to := from
+		if found {
+			if to, err = strconv.Atoi(end); err != nil {
+				return nil, false
+			}
+		}
+		for w := from; w <= to; w++ {
+			if parity == 0 || (parity == 1 && w%2 == 1) || (parity == 2 && w%2 == 0) {
+				weeks[w] = true
+			}
+		}
+	}
+	return weeks, len(weeks) > 0
+}
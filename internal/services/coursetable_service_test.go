@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	json "github.com/bytedance/sonic"
+	"gorm.io/datatypes"
+)
+
+func TestParseWeekRanges(t *testing.T) {
+	tests := []struct {
+		text   string
+		week   int
+		want   bool
+		wantOK bool
+	}{
+		{text: "1-8,10-16周", week: 9, want: false, wantOK: true},
+		{text: "1-8,10-16周", week: 10, want: true, wantOK: true},
+		{text: "1-16(单)", week: 3, want: true, wantOK: true},
+		{text: "1-16(单)", week: 4, want: false, wantOK: true},
+		{text: "2-16双周", week: 4, want: true, wantOK: true},
+		{text: "3，5，7周", week: 5, want: true, wantOK: true},
+		{text: "全周", week: 5, want: false, wantOK: false},
+	}
+	for _, tt := range tests {
+		weeks, ok := parseWeekRanges(tt.text)
+		if ok != tt.wantOK || weeks[tt.week] != tt.want {
+			t.Errorf("parseWeekRanges(%q)[%d] = %v, ok=%v; want %v, ok=%v", tt.text, tt.week, weeks[tt.week], ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFilterCourseDataByWeek(t *testing.T) {
+	data := datatypes.JSON(`{
+		"1": {"name": "高等数学", "weeks": "1-8周"},
+		"2": [{"name": "大学英语", "weeks": [9, 10]}, {"name": "体育", "weeks": "9-16"}],
+		"3": {"name": "形势与政策"},
+		"4": null
+	}`)
+
+	filtered, err := filterCourseDataByWeek(data, 9)
+	if err != nil {
+		t.Fatalf("filterCourseDataByWeek() error = %v", err)
+	}
+	var cells map[string]any
+	if err := json.Unmarshal(filtered, &cells); err != nil {
+		t.Fatalf("unmarshal filtered data: %v", err)
+	}
+
+	if cells["1"] != nil {
+		t.Errorf("cell 1 = %v, want nil", cells["1"])
+	}
+	if entries, ok := cells["2"].([]any); !ok || len(entries) != 2 {
+		t.Errorf("cell 2 = %v, want both courses", cells["2"])
+	}
+	if cells["3"] == nil {
+		t.Error("cell 3 without week info should be kept")
+	}
+
+	filtered, err = filterCourseDataByWeek(data, 12)
+	if err != nil {
+		t.Fatalf("filterCourseDataByWeek() error = %v", err)
+	}
+	cells = nil
+	if err := json.Unmarshal(filtered, &cells); err != nil {
+		t.Fatalf("unmarshal filtered data: %v", err)
+	}
+	if entries, ok := cells["2"].([]any); !ok || len(entries) != 1 {
+		t.Errorf("cell 2 = %v, want only the course in week 12", cells["2"])
+	}
+}
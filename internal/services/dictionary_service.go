@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
@@ -46,3 +47,27 @@ func (s *DictionaryService) GetRandomWord(ctx context.Context) (*models.Dictiona
 
 	return &word, nil
 }
+
+// LookupWord 按单词精确查询，未命中时回退到前缀匹配的第一个词条
+func (s *DictionaryService) LookupWord(ctx context.Context, word string) (*models.Dictionary, error) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return nil, apperr.New(constant.DictionaryWordNotFound)
+	}
+
+	var entry models.Dictionary
+	err := s.db.WithContext(ctx).Where("word = ?", word).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.db.WithContext(ctx).
+			Where("word LIKE ?", word+"%").
+			Order("CHAR_LENGTH(word) ASC, id ASC").
+			First(&entry).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.DictionaryWordNotFound)
+		}
+		return nil, apperr.Wrap(constant.DictionaryLookupFailed, err)
+	}
+	return &entry, nil
+}
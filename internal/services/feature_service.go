@@ -22,7 +22,7 @@ type FeatureService struct {
 func NewFeatureService(db *gorm.DB) *FeatureService {
 	return &FeatureService{
 		db:    db,
-		cache: cache.GlobalCache,
+		cache: cache.GlobalTieredCache,
 	}
 }
 
@@ -6,18 +6,21 @@ import (
 
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
 	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
 
+	json "github.com/bytedance/sonic"
 	"gorm.io/gorm"
 )
 
 type HeroService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache cache.Cache
 }
 
 func NewHeroService(db *gorm.DB) *HeroService {
-	return &HeroService{db: db}
+	return &HeroService{db: db, cache: cache.GlobalTieredCache}
 }
 
 func (s *HeroService) Create(ctx context.Context, name string, sort int, isShow bool) (*models.Hero, error) {
@@ -40,6 +43,7 @@ func (s *HeroService) Create(ctx context.Context, name string, sort int, isShow
 	if err := s.db.WithContext(ctx).Create(hero).Error; err != nil {
 		return nil, apperr.Wrap(constant.CommonInternal, err)
 	}
+	s.clearListAllCache(ctx)
 	return hero, nil
 }
 
@@ -65,6 +69,7 @@ func (s *HeroService) Update(ctx context.Context, id uint, name string, sort *in
 	if err := s.db.WithContext(ctx).Model(&models.Hero{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		return apperr.Wrap(constant.CommonInternal, err)
 	}
+	s.clearListAllCache(ctx)
 	return nil
 }
 
@@ -72,6 +77,7 @@ func (s *HeroService) Delete(ctx context.Context, id uint) error {
 	if err := s.db.WithContext(ctx).Unscoped().Delete(&models.Hero{}, id).Error; err != nil {
 		return apperr.Wrap(constant.CommonInternal, err)
 	}
+	s.clearListAllCache(ctx)
 	return nil
 }
 
@@ -88,6 +94,15 @@ func (s *HeroService) Get(ctx context.Context, id uint) (*models.Hero, error) {
 
 // ListAll 返回仅名称的字符串数组，按 sort 升序（只返回is_show=true的）
 func (s *HeroService) ListAll(ctx context.Context) ([]string, error) {
+	if s.cache != nil {
+		if cached, err := s.cache.Get(ctx, constant.CacheKeyHeroListAll); err == nil && cached != "" {
+			var names []string
+			if err := json.Unmarshal([]byte(cached), &names); err == nil {
+				return names, nil
+			}
+		}
+	}
+
 	var list []models.Hero
 	if err := s.db.WithContext(ctx).Model(&models.Hero{}).Where("is_show = ?", true).Order("sort ASC").Find(&list).Error; err != nil {
 		return nil, apperr.Wrap(constant.CommonInternal, err)
@@ -96,9 +111,23 @@ func (s *HeroService) ListAll(ctx context.Context) ([]string, error) {
 	for _, it := range list {
 		names = append(names, it.Name)
 	}
+
+	if s.cache != nil {
+		if data, err := json.Marshal(names); err == nil {
+			ttl := constant.HeroListAllCacheTTL
+			_ = s.cache.Set(ctx, constant.CacheKeyHeroListAll, string(data), &ttl)
+		}
+	}
 	return names, nil
 }
 
+// clearListAllCache 清除英雄榜展示列表缓存
+func (s *HeroService) clearListAllCache(ctx context.Context) {
+	if s.cache != nil {
+		_ = s.cache.Delete(ctx, constant.CacheKeyHeroListAll)
+	}
+}
+
 // SearchHeroes 搜索英雄，支持按名称搜索和是否显示过滤，空query返回全部（分页版本）
 func (s *HeroService) SearchHeroes(ctx context.Context, query string, isShow *bool, page, size int) ([]models.Hero, int64, error) {
 	var list []models.Hero
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// llmUsageReportMaxDays 用量报表允许查询的最大天数
+const llmUsageReportMaxDays = 366
+
+// LLMQuotaPolicy AI 对话额度策略
+type LLMQuotaPolicy struct {
+	// DailyTokens 每日默认 token 额度，<=0 表示不限制
+	DailyTokens int64 `json:"daily_tokens"`
+	// RoleDailyTokens 按角色覆盖每日额度，用户拥有多个角色时取最宽松的值，<=0 表示该角色不受限制
+	RoleDailyTokens map[string]int64 `json:"role_daily_tokens,omitempty"`
+	// PackTokens 每个额度包包含的 token 数，<=0 表示不开放购买
+	PackTokens int64 `json:"pack_tokens"`
+	// PackPoints 每个额度包消耗的积分
+	PackPoints uint `json:"pack_points"`
+	// MaxPacksPerDay 每日最多可购买的额度包数量，<=0 表示不限制
+	MaxPacksPerDay int `json:"max_packs_per_day"`
+}
+
+// defaultLLMQuotaPolicy 内置的默认额度策略，可通过 SystemConfig(llm.quota) 覆盖
+var defaultLLMQuotaPolicy = LLMQuotaPolicy{
+	DailyTokens:     200000,
+	RoleDailyTokens: map[string]int64{constant.RoleTagAdmin: 0},
+	PackTokens:      50000,
+	PackPoints:      10,
+	MaxPacksPerDay:  10,
+}
+
+// LLMUsageService AI 对话 token 用量统计与额度控制
+type LLMUsageService struct {
+	db            *gorm.DB
+	rbacService   *RBACService
+	pointsService *PointsService
+	policy        *configPolicy[LLMQuotaPolicy]
+}
+
+// NewLLMUsageService 创建 AI 对话用量服务
+func NewLLMUsageService(db *gorm.DB, rbacService *RBACService, pointsService *PointsService) *LLMUsageService {
+	return &LLMUsageService{
+		db:            db,
+		rbacService:   rbacService,
+		pointsService: pointsService,
+		policy: newConfigPolicy(db, constant.LLMQuotaConfigKey, constant.LLMQuotaPolicyReloadInterval,
+			defaultLLMQuotaPolicyCopy, overrideJSON[LLMQuotaPolicy]),
+	}
+}
+
+// defaultLLMQuotaPolicyCopy 返回默认额度策略的副本
+func defaultLLMQuotaPolicyCopy() LLMQuotaPolicy {
+	policy := defaultLLMQuotaPolicy
+	policy.RoleDailyTokens = maps.Clone(policy.RoleDailyTokens)
+	return policy
+}
+
+// CheckQuota 检查用户当日额度是否已用完。统计失败时放行，不影响对话。
+func (s *LLMUsageService) CheckQuota(ctx context.Context, userID uint) error {
+	usage, err := s.GetUserUsage(ctx, userID)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "llm_quota_check",
+			"message": "获取AI对话用量失败，跳过额度检查",
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+	if !usage.Unlimited && usage.RemainingTokens <= 0 {
+		return apperr.New(constant.LLMQuotaExceeded)
+	}
+	return nil
+}
+
+// GetUserUsage 获取用户当日用量与额度
+func (s *LLMUsageService) GetUserUsage(ctx context.Context, userID uint) (*dto.LLMUsageResponse, error) {
+	today := llmUsageToday()
+
+	var row models.LLMUsageDaily
+	err := s.db.WithContext(ctx).Where("user_id = ? AND date = ?", userID, today).First(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询AI对话用量失败：%w", err))
+	}
+
+	policy := s.policy.get(ctx)
+	dailyQuota := roleOverride(ctx, s.rbacService, userID, policy.DailyTokens, policy.RoleDailyTokens)
+
+	resp := &dto.LLMUsageResponse{
+		Date:             today.Format(time.DateOnly),
+		PromptTokens:     row.PromptTokens,
+		CompletionTokens: row.CompletionTokens,
+		TotalTokens:      row.TotalTokens,
+		RequestCount:     row.RequestCount,
+		Unlimited:        dailyQuota <= 0,
+		ExtraQuota:       row.ExtraQuota,
+		PackTokens:       policy.PackTokens,
+		PackPoints:       policy.PackPoints,
+		MaxPacksPerDay:   policy.MaxPacksPerDay,
+	}
+	if !resp.Unlimited {
+		resp.DailyQuota = dailyQuota
+		resp.RemainingTokens = max(dailyQuota+row.ExtraQuota-row.TotalTokens, 0)
+	}
+	return resp, nil
+}
+
+// PurchaseQuota 使用积分购买当日额外额度，积分扣减与额度增加在同一事务中完成
+func (s *LLMUsageService) PurchaseQuota(ctx context.Context, userID uint, packs int) (*dto.LLMUsageResponse, error) {
+	policy := s.policy.get(ctx)
+	if policy.PackTokens <= 0 || policy.PackPoints == 0 {
+		err := apperr.New(constant.LLMQuotaPurchaseLimitExceeded)
+		err.Message = "当前未开放额度购买"
+		return nil, err
+	}
+
+	date := llmUsageToday()
+	extra := policy.PackTokens * int64(packs)
+
+	err := s.pointsService.SpendPoints(ctx, userID, &request.SpendPointsRequest{
+		Points:      policy.PackPoints * uint(packs),
+		Description: fmt.Sprintf("购买AI对话额度 %d 个（%d tokens）", packs, extra),
+	}, func(tx *gorm.DB) error {
+		if err := upsertLLMUsageDaily(tx, &models.LLMUsageDaily{UserID: userID, Date: date, ExtraQuota: extra}); err != nil {
+			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("增加AI对话额度失败：%w", err))
+		}
+		if policy.MaxPacksPerDay <= 0 {
+			return nil
+		}
+		var row models.LLMUsageDaily
+		if err := tx.Select("extra_quota").Where("user_id = ? AND date = ?", userID, date).First(&row).Error; err != nil {
+			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询AI对话额度失败：%w", err))
+		}
+		if row.ExtraQuota > policy.PackTokens*int64(policy.MaxPacksPerDay) {
+			return apperr.New(constant.LLMQuotaPurchaseLimitExceeded)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoCtx(ctx, map[string]any{
+		"action":      "llm_quota_purchase",
+		"user_id":     userID,
+		"packs":       packs,
+		"extra_quota": extra,
+	})
+	return s.GetUserUsage(ctx, userID)
+}
+
+// GetUsageReport 按用户汇总统计区间内的 AI 对话用量，按总 token 数降序
+func (s *LLMUsageService) GetUsageReport(ctx context.Context, req *dto.LLMUsageReportRequest) ([]dto.LLMUsageReportItem, int64, error) {
+	start, err := time.Parse(time.DateOnly, req.StartDate)
+	if err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	end, err := time.Parse(time.DateOnly, req.EndDate)
+	if err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	if end.Before(start) || end.Sub(start) > llmUsageReportMaxDays*24*time.Hour {
+		appErr := apperr.New(constant.CommonBadRequest)
+		appErr.Message = fmt.Sprintf("统计区间无效，结束日期不能早于开始日期且跨度不超过%d天", llmUsageReportMaxDays)
+		return nil, 0, appErr
+	}
+
+	base := func() *gorm.DB {
+		q := s.db.WithContext(ctx).Model(&models.LLMUsageDaily{}).
+			Where("date BETWEEN ? AND ?", req.StartDate, req.EndDate)
+		if req.UserID != 0 {
+			q = q.Where("user_id = ?", req.UserID)
+		}
+		return q
+	}
+
+	var total int64
+	if err := base().Distinct("user_id").Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("统计AI对话用量用户数失败：%w", err))
+	}
+
+	pagination := utils.GetPagination(req.Page, req.PageSize)
+	items := make([]dto.LLMUsageReportItem, 0)
+	if err := base().
+		Select("user_id, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, " +
+			"SUM(total_tokens) AS total_tokens, SUM(request_count) AS request_count, SUM(extra_quota) AS extra_quota, " +
+			"SUM(CASE WHEN request_count > 0 THEN 1 ELSE 0 END) AS active_days").
+		Group("user_id").
+		Order("total_tokens DESC, user_id ASC").
+		Offset(pagination.Offset).
+		Limit(pagination.Size).
+		Scan(&items).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("统计AI对话用量失败：%w", err))
+	}
+
+	return items, total, nil
+}
+
+// recordLLMUsage 在消息写入事务中累加用户当日用量
+func recordLLMUsage(tx *gorm.DB, userID uint, records []models.ConversationMessage) error {
+	usage := models.LLMUsageDaily{UserID: userID}
+	for i := range records {
+		if records[i].TotalTokens == 0 && records[i].PromptTokens == 0 && records[i].CompletionTokens == 0 {
+			continue
+		}
+		usage.PromptTokens += int64(records[i].PromptTokens)
+		usage.CompletionTokens += int64(records[i].CompletionTokens)
+		usage.TotalTokens += int64(records[i].TotalTokens)
+		usage.RequestCount++
+	}
+	if usage.RequestCount == 0 {
+		return nil
+	}
+	usage.Date = llmUsageToday()
+	return upsertLLMUsageDaily(tx, &usage)
+}
+
+// recordLLMCallUsage 累加不产生对话消息的模型调用（如历史摘要）的用量
+func recordLLMCallUsage(tx *gorm.DB, userID uint, usage *schema.TokenUsage) error {
+	if usage == nil || (usage.TotalTokens == 0 && usage.PromptTokens == 0 && usage.CompletionTokens == 0) {
+		return nil
+	}
+	delta := models.LLMUsageDaily{
+		UserID:           userID,
+		Date:             llmUsageToday(),
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+		TotalTokens:      int64(usage.TotalTokens),
+		RequestCount:     1,
+	}
+	return upsertLLMUsageDaily(tx, &delta)
+}
+
+// llmUsageToday 当日用量记录的日期，查询与写入使用同一个值
+func llmUsageToday() time.Time {
+	today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+	return today
+}
+
+// upsertLLMUsageDaily 原子累加用户当日用量记录，记录不存在时创建
+func upsertLLMUsageDaily(tx *gorm.DB, delta *models.LLMUsageDaily) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", delta.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", delta.CompletionTokens),
+			"total_tokens":      gorm.Expr("total_tokens + ?", delta.TotalTokens),
+			"request_count":     gorm.Expr("request_count + ?", delta.RequestCount),
+			"extra_quota":       gorm.Expr("extra_quota + ?", delta.ExtraQuota),
+			"updated_at":        time.Now(),
+		}),
+	}).Create(delta).Error
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/cloudwego/eino/schema"
+	"gorm.io/gorm"
+)
+
+// newTestLLMUsageService 每日 1000 tokens，每包 500 tokens / 10 积分，每日最多 2 包；用户 1 有 100 积分
+func newTestLLMUsageService(t *testing.T) (*LLMUsageService, *gorm.DB) {
+	t.Helper()
+	db := newHarnessDB(t)
+	if err := db.Create(&models.SystemConfig{
+		Key:   constant.LLMQuotaConfigKey,
+		Value: `{"daily_tokens":1000,"pack_tokens":500,"pack_points":10,"max_packs_per_day":2}`,
+	}).Error; err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+	if err := db.Create(&models.User{ID: 1, OpenID: "o1", Points: 100}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	rbac := newTestRBACService(t, map[uint]UserPermissionSnapshot{
+		1: {RoleTags: []string{constant.RoleTagUserBasic}},
+		2: {RoleTags: []string{constant.RoleTagAdmin}},
+	})
+	return NewLLMUsageService(db, rbac, NewPointsService(db)), db
+}
+
+// recordTestUsage 以一次模型调用的形式累加用户当日用量
+func recordTestUsage(t *testing.T, db *gorm.DB, userID uint, tokens int) {
+	t.Helper()
+	if err := recordLLMCallUsage(db, userID, &schema.TokenUsage{PromptTokens: tokens, TotalTokens: tokens}); err != nil {
+		t.Fatalf("record usage: %v", err)
+	}
+}
+
+func TestLLMUsageServiceQuotaLimit(t *testing.T) {
+	s, db := newTestLLMUsageService(t)
+	ctx := context.Background()
+
+	recordTestUsage(t, db, 1, 600)
+	if err := s.CheckQuota(ctx, 1); err != nil {
+		t.Fatalf("CheckQuota() under the quota = %v", err)
+	}
+	usage, err := s.GetUserUsage(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUserUsage() error = %v", err)
+	}
+	if usage.DailyQuota != 1000 || usage.RemainingTokens != 400 || usage.RequestCount != 1 || usage.Unlimited {
+		t.Fatalf("usage = %+v", usage)
+	}
+
+	recordTestUsage(t, db, 1, 400)
+	assertAppErrCode(t, s.CheckQuota(ctx, 1), constant.LLMQuotaExceeded)
+
+	// 管理员默认不限额
+	recordTestUsage(t, db, 2, 5000)
+	if err := s.CheckQuota(ctx, 2); err != nil {
+		t.Fatalf("admin CheckQuota() = %v", err)
+	}
+}
+
+func TestLLMUsageServicePurchaseQuota(t *testing.T) {
+	s, db := newTestLLMUsageService(t)
+	ctx := context.Background()
+
+	recordTestUsage(t, db, 1, 1000)
+	assertAppErrCode(t, s.CheckQuota(ctx, 1), constant.LLMQuotaExceeded)
+
+	usage, err := s.PurchaseQuota(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("PurchaseQuota() error = %v", err)
+	}
+	if usage.ExtraQuota != 500 || usage.RemainingTokens != 500 {
+		t.Fatalf("usage after purchase = %+v", usage)
+	}
+	if err := s.CheckQuota(ctx, 1); err != nil {
+		t.Fatalf("CheckQuota() after purchase = %v", err)
+	}
+
+	// 超过每日上限时积分扣减与额度增加一起回滚
+	_, err = s.PurchaseQuota(ctx, 1, 2)
+	assertAppErrCode(t, err, constant.LLMQuotaPurchaseLimitExceeded)
+	var user models.User
+	if err := db.First(&user, 1).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if user.Points != 90 {
+		t.Fatalf("points = %d, want 90", user.Points)
+	}
+	var spent int64
+	if err := db.Model(&models.PointsTransaction{}).Where("user_id = ?", 1).Count(&spent).Error; err != nil || spent != 1 {
+		t.Fatalf("points transactions = %d, %v", spent, err)
+	}
+	if usage, _ := s.GetUserUsage(ctx, 1); usage.ExtraQuota != 500 {
+		t.Fatalf("extra quota after a rejected purchase = %d", usage.ExtraQuota)
+	}
+
+	if err := db.Model(&user).Update("points", 5).Error; err != nil {
+		t.Fatalf("update points: %v", err)
+	}
+	_, err = s.PurchaseQuota(ctx, 1, 1)
+	assertAppErrCode(t, err, constant.PointsInsufficient)
+}
+
+func TestLLMUsageServiceDailyRollover(t *testing.T) {
+	s, db := newTestLLMUsageService(t)
+	ctx := context.Background()
+
+	// 昨天用完额度并买满额度包，不影响今天
+	yesterday := llmUsageToday().AddDate(0, 0, -1)
+	if err := db.Create(&models.LLMUsageDaily{UserID: 1, Date: yesterday, TotalTokens: 5000, RequestCount: 9, ExtraQuota: 1000}).Error; err != nil {
+		t.Fatalf("create usage: %v", err)
+	}
+
+	usage, err := s.GetUserUsage(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUserUsage() error = %v", err)
+	}
+	if usage.Date != llmUsageToday().Format(time.DateOnly) || usage.TotalTokens != 0 || usage.ExtraQuota != 0 || usage.RemainingTokens != 1000 {
+		t.Fatalf("usage on a new day = %+v", usage)
+	}
+	if _, err := s.PurchaseQuota(ctx, 1, 2); err != nil {
+		t.Fatalf("packs bought yesterday should not count toward today's limit: %v", err)
+	}
+
+	recordTestUsage(t, db, 1, 300)
+	var rows []models.LLMUsageDaily
+	if err := db.Where("user_id = ?", 1).Order("date").Find(&rows).Error; err != nil {
+		t.Fatalf("load usage rows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].TotalTokens != 5000 || rows[1].TotalTokens != 300 || rows[1].ExtraQuota != 1000 {
+		t.Fatalf("usage rows = %+v", rows)
+	}
+}
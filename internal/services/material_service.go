@@ -16,11 +16,12 @@ import (
 )
 
 type MaterialService struct {
-	db *gorm.DB
+	db                *gorm.DB
+	moderationService *ModerationService
 }
 
-func NewMaterialService(db *gorm.DB) *MaterialService {
-	return &MaterialService{db: db}
+func NewMaterialService(db *gorm.DB, moderationService *ModerationService) *MaterialService {
+	return &MaterialService{db: db, moderationService: moderationService}
 }
 
 // ==================== 资料管理 ====================
@@ -274,7 +275,26 @@ func (s *MaterialService) GetTopMaterials(ctx context.Context, req *request.TopM
 // ==================== 资料描述管理 ====================
 
 // UpdateMaterialDesc 更新资料描述（管理员，不存在时自动创建）
-func (s *MaterialService) UpdateMaterialDesc(ctx context.Context, md5 string, req *request.MaterialDescUpdateRequest) error {
+func (s *MaterialService) UpdateMaterialDesc(ctx context.Context, userID uint, md5 string, req *request.MaterialDescUpdateRequest) error {
+	// 内容审核：拦截时直接返回，打码时保存打码后的描述
+	var decision *ModerationDecision
+	if req.Description != nil {
+		var err error
+		if decision, err = s.moderationService.Moderate(ctx, constant.ModerationSurfaceMaterialDesc, userID, *req.Description); err != nil {
+			return err
+		}
+		req.Description = &decision.Text
+	}
+
+	if err := s.saveMaterialDesc(ctx, md5, req); err != nil {
+		return err
+	}
+	s.moderationService.Audit(ctx, decision, md5)
+	return nil
+}
+
+// saveMaterialDesc 保存资料描述，不存在时创建
+func (s *MaterialService) saveMaterialDesc(ctx context.Context, md5 string, req *request.MaterialDescUpdateRequest) error {
 	// 检查记录是否存在
 	var existing models.MaterialDesc
 	err := s.db.WithContext(ctx).Model(&models.MaterialDesc{}).Where("md5 = ?", md5).First(&existing).Error
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// MCPAuditService MCP 工具调用审计
+type MCPAuditService struct {
+	db *gorm.DB
+}
+
+func NewMCPAuditService(db *gorm.DB) *MCPAuditService {
+	return &MCPAuditService{db: db}
+}
+
+// RecordToolCall 写入一条工具调用审计记录
+func (s *MCPAuditService) RecordToolCall(ctx context.Context, record *models.MCPToolCallAudit) error {
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("写入MCP工具调用审计记录失败: %w", err))
+	}
+	return nil
+}
+
+// ListToolCalls 分页查询工具调用审计记录，按时间倒序
+func (s *MCPAuditService) ListToolCalls(ctx context.Context, req *request.ListMCPToolCallsRequest) ([]models.MCPToolCallAudit, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.MCPToolCallAudit{})
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.ToolName != "" {
+		query = query.Where("tool_name = ?", req.ToolName)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.StartDate != "" {
+		start, err := time.ParseInLocation(time.DateOnly, req.StartDate, time.Local)
+		if err != nil {
+			return nil, 0, apperr.Wrap(constant.CommonBadRequest, err)
+		}
+		query = query.Where("created_at >= ?", start)
+	}
+	if req.EndDate != "" {
+		end, err := time.ParseInLocation(time.DateOnly, req.EndDate, time.Local)
+		if err != nil {
+			return nil, 0, apperr.Wrap(constant.CommonBadRequest, err)
+		}
+		query = query.Where("created_at < ?", end.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("统计MCP工具调用审计记录失败: %w", err))
+	}
+	pagination := utils.GetPagination(req.Page, req.PageSize)
+	records := make([]models.MCPToolCallAudit, 0)
+	if err := query.Order("id DESC").Offset(pagination.Offset).Limit(pagination.Size).Find(&records).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询MCP工具调用审计记录失败: %w", err))
+	}
+	return records, total, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+)
+
+func TestMCPAuditServiceListToolCalls(t *testing.T) {
+	ctx := context.Background()
+	service := NewMCPAuditService(newHarnessDB(t))
+
+	now := time.Now()
+	records := []models.MCPToolCallAudit{
+		{UserID: 1, ToolName: "countdown", Status: constant.MCPToolCallStatusSuccess, CreatedAt: now.AddDate(0, 0, -3)},
+		{UserID: 1, ToolName: "points", Status: constant.MCPToolCallStatusError, ErrorMessage: "权限不足: point.get", CreatedAt: now},
+		{UserID: 2, ToolName: "countdown", Status: constant.MCPToolCallStatusToolError, CreatedAt: now},
+	}
+	for i := range records {
+		if err := service.RecordToolCall(ctx, &records[i]); err != nil {
+			t.Fatalf("RecordToolCall() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     request.ListMCPToolCallsRequest
+		wantIDs []uint
+	}{
+		{name: "all newest first", req: request.ListMCPToolCallsRequest{}, wantIDs: []uint{3, 2, 1}},
+		{name: "by user", req: request.ListMCPToolCallsRequest{UserID: 1}, wantIDs: []uint{2, 1}},
+		{name: "by tool and status", req: request.ListMCPToolCallsRequest{ToolName: "countdown", Status: constant.MCPToolCallStatusToolError}, wantIDs: []uint{3}},
+		{name: "by date", req: request.ListMCPToolCallsRequest{StartDate: now.Format(time.DateOnly), EndDate: now.Format(time.DateOnly)}, wantIDs: []uint{3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Page, tt.req.PageSize = 1, 20
+			got, total, err := service.ListToolCalls(ctx, &tt.req)
+			if err != nil {
+				t.Fatalf("ListToolCalls() error = %v", err)
+			}
+			if total != int64(len(tt.wantIDs)) || len(got) != len(tt.wantIDs) {
+				t.Fatalf("ListToolCalls() total = %d, len = %d, want %d", total, len(got), len(tt.wantIDs))
+			}
+			for i, record := range got {
+				if record.ID != tt.wantIDs[i] {
+					t.Fatalf("ListToolCalls()[%d].ID = %d, want %d", i, record.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+
+	if _, _, err := service.ListToolCalls(ctx, &request.ListMCPToolCallsRequest{StartDate: "2024/01/01", Page: 1, PageSize: 20}); err == nil {
+		t.Fatal("ListToolCalls() with invalid start_date should fail")
+	}
+}
@@ -0,0 +1,475 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+
+	json "github.com/bytedance/sonic"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reservedMCPServerNames 内置 MCP 客户端使用的名称，外部服务不能占用
+var reservedMCPServerNames = []string{"yqlx", "ragflow"}
+
+// MCPServerService 外部 MCP 服务管理：维护接入配置、定期健康检查，并为对话提供用户可用的服务
+type MCPServerService struct {
+	db         *gorm.DB
+	secret     string // 认证请求头的加密密钥
+	httpClient *http.Client
+}
+
+func NewMCPServerService(db *gorm.DB, cfg *config.Config) *MCPServerService {
+	return &MCPServerService{
+		db:         db,
+		secret:     cfg.LLM.MCPSecretKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ListServers 列出全部外部 MCP 服务
+func (s *MCPServerService) ListServers(ctx context.Context) ([]response.MCPServerResponse, error) {
+	var servers []models.MCPServer
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&servers).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询 MCP 服务失败: %w", err))
+	}
+	items := make([]response.MCPServerResponse, 0, len(servers))
+	for _, server := range servers {
+		items = append(items, s.toResponse(ctx, server))
+	}
+	return items, nil
+}
+
+// CreateServer 接入外部 MCP 服务，认证请求头加密保存
+func (s *MCPServerService) CreateServer(ctx context.Context, operatorID uint, req *request.CreateMCPServerRequest) (*response.MCPServerResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if slices.Contains(reservedMCPServerNames, strings.ToLower(name)) {
+		return nil, apperr.New(constant.MCPServerNameExists)
+	}
+	if err := validateMCPServerURL(req.URL); err != nil {
+		return nil, err
+	}
+	headers, err := s.encryptHeaders(req.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &models.MCPServer{
+		Name:         name,
+		Description:  req.Description,
+		URL:          req.URL,
+		Headers:      headers,
+		IsEnabled:    req.IsEnabled == nil || *req.IsEnabled,
+		HealthStatus: constant.MCPServerHealthUnknown,
+		CreatedBy:    operatorID,
+	}
+	if server.AllowedRoles, err = optionalStringListJSON(req.AllowedRoles); err != nil {
+		return nil, err
+	}
+	if server.AllowedTools, err = optionalStringListJSON(req.AllowedTools); err != nil {
+		return nil, err
+	}
+	if server.DeniedTools, err = optionalStringListJSON(req.DeniedTools); err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		taken, err := mcpServerNameTaken(tx, name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return apperr.New(constant.MCPServerNameExists)
+		}
+		if err := tx.Create(server).Error; err != nil {
+			return err
+		}
+		// is_enabled 的零值会被数据库默认值覆盖，需单独写入
+		if !server.IsEnabled {
+			return tx.Model(server).Update("is_enabled", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			return nil, err
+		}
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":      "create_mcp_server",
+			"operator_id": operatorID,
+			"name":        name,
+			"error":       err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("创建 MCP 服务失败: %w", err))
+	}
+
+	resp := s.toResponse(ctx, *server)
+	return &resp, nil
+}
+
+// UpdateServer 更新外部 MCP 服务；地址或请求头变化后健康状态重置为未检查
+func (s *MCPServerService) UpdateServer(ctx context.Context, id uint, req *request.UpdateMCPServerRequest) (*response.MCPServerResponse, error) {
+	updates := map[string]any{}
+	if req.URL != nil {
+		if err := validateMCPServerURL(*req.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Headers != nil {
+		headers, err := s.encryptHeaders(*req.Headers)
+		if err != nil {
+			return nil, err
+		}
+		updates["headers"] = headers
+	}
+	if len(updates) > 0 {
+		updates["health_status"] = constant.MCPServerHealthUnknown
+		updates["health_error"] = ""
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsEnabled != nil {
+		updates["is_enabled"] = *req.IsEnabled
+	}
+	for column, list := range map[string]*[]string{
+		"allowed_roles": req.AllowedRoles,
+		"allowed_tools": req.AllowedTools,
+		"denied_tools":  req.DeniedTools,
+	} {
+		if list == nil {
+			continue
+		}
+		raw, err := optionalStringListJSON(*list)
+		if err != nil {
+			return nil, err
+		}
+		updates[column] = raw
+	}
+
+	var server models.MCPServer
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&server, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.New(constant.MCPServerNotFound)
+			}
+			return err
+		}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if name != server.Name {
+				if slices.Contains(reservedMCPServerNames, strings.ToLower(name)) {
+					return apperr.New(constant.MCPServerNameExists)
+				}
+				taken, err := mcpServerNameTaken(tx, name, server.ID)
+				if err != nil {
+					return err
+				}
+				if taken {
+					return apperr.New(constant.MCPServerNameExists)
+				}
+				updates["name"] = name
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&server).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&server, server.ID).Error
+	})
+	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			return nil, err
+		}
+		logger.ErrorCtx(ctx, map[string]any{
+			"action":    "update_mcp_server",
+			"server_id": id,
+			"error":     err.Error(),
+		})
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新 MCP 服务失败: %w", err))
+	}
+
+	resp := s.toResponse(ctx, server)
+	return &resp, nil
+}
+
+// DeleteServer 删除外部 MCP 服务，之后的对话不再加载其工具
+func (s *MCPServerService) DeleteServer(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.MCPServer{}, id)
+	if result.Error != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("删除 MCP 服务失败: %w", result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return apperr.New(constant.MCPServerNotFound)
+	}
+	return nil
+}
+
+// CheckServerHealth 立即检查指定服务并返回检查后的状态
+func (s *MCPServerService) CheckServerHealth(ctx context.Context, id uint) (*response.MCPServerResponse, error) {
+	var server models.MCPServer
+	if err := s.db.WithContext(ctx).First(&server, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New(constant.MCPServerNotFound)
+		}
+		return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询 MCP 服务失败: %w", err))
+	}
+	if err := s.checkHealth(ctx, &server); err != nil {
+		return nil, err
+	}
+	resp := s.toResponse(ctx, server)
+	return &resp, nil
+}
+
+// CheckAllServersHealth 检查全部已启用的服务（定时任务调用），单个服务不可用不影响其他服务
+func (s *MCPServerService) CheckAllServersHealth(ctx context.Context) error {
+	var servers []models.MCPServer
+	if err := s.db.WithContext(ctx).Where("is_enabled = ?", true).Find(&servers).Error; err != nil {
+		return fmt.Errorf("查询 MCP 服务失败: %w", err)
+	}
+	for i := range servers {
+		if err := s.checkHealth(ctx, &servers[i]); err != nil {
+			return err
+		}
+		if servers[i].HealthStatus == constant.MCPServerHealthUnhealthy {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":    "mcp_server_health_check",
+				"server_id": servers[i].ID,
+				"name":      servers[i].Name,
+				"error":     servers[i].HealthError,
+			})
+		}
+	}
+	return nil
+}
+
+// checkHealth 连接服务并列出工具，将结果写回数据库；只有写库失败才返回错误
+func (s *MCPServerService) checkHealth(ctx context.Context, server *models.MCPServer) error {
+	checkCtx, cancel := context.WithTimeout(ctx, constant.MCPServerHealthCheckTimeout)
+	defer cancel()
+
+	now := time.Now()
+	server.LastCheckedAt = &now
+	server.HealthStatus = constant.MCPServerHealthHealthy
+	server.HealthError = ""
+
+	tools, err := s.listTools(checkCtx, server)
+	if err != nil {
+		server.HealthStatus = constant.MCPServerHealthUnhealthy
+		server.HealthError = truncateRunes(err.Error(), 500)
+	} else {
+		names := make([]string, 0, len(tools))
+		for _, t := range tools {
+			names = append(names, t.Name)
+		}
+		raw, _ := json.Marshal(names)
+		server.DiscoveredTools = raw
+	}
+
+	updates := map[string]any{
+		"health_status":   server.HealthStatus,
+		"health_error":    server.HealthError,
+		"last_checked_at": now,
+	}
+	if err == nil {
+		updates["discovered_tools"] = server.DiscoveredTools
+	}
+	if err := s.db.WithContext(ctx).Model(&models.MCPServer{}).Where("id = ?", server.ID).Updates(updates).Error; err != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("保存 MCP 服务健康状态失败: %w", err))
+	}
+	return nil
+}
+
+func (s *MCPServerService) listTools(ctx context.Context, server *models.MCPServer) ([]mcp.Tool, error) {
+	cli, err := s.connect(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cli.Close() }()
+
+	result, err := cli.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("list tools: %w", err)
+	}
+	return result.Tools, nil
+}
+
+// connect 创建并初始化外部服务的 MCP 客户端，调用方负责关闭
+func (s *MCPServerService) connect(ctx context.Context, server *models.MCPServer) (*client.Client, error) {
+	headers, err := s.decryptHeaders(server.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt headers: %w", err)
+	}
+	cli, err := client.NewStreamableHttpClient(server.URL,
+		transport.WithHTTPHeaders(headers),
+		transport.WithHTTPTimeout(30*time.Second),
+		transport.WithHTTPLogger(logger.L()),
+		transport.WithHTTPBasicClient(s.httpClient),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	if err := cli.Start(ctx); err != nil {
+		_ = cli.Close()
+		return nil, fmt.Errorf("start client: %w", err)
+	}
+	if _, err := cli.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		_ = cli.Close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return cli, nil
+}
+
+// serversForRoles 返回启用、最近一次检查未失败且允许这些角色使用的服务
+func (s *MCPServerService) serversForRoles(ctx context.Context, roleTags []string) ([]models.MCPServer, error) {
+	var servers []models.MCPServer
+	if err := s.db.WithContext(ctx).
+		Where("is_enabled = ? AND health_status <> ?", true, constant.MCPServerHealthUnhealthy).
+		Order("id ASC").
+		Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	allowed := make([]models.MCPServer, 0, len(servers))
+	for _, server := range servers {
+		if rolesAllowed(jsonStringList(server.AllowedRoles), roleTags) {
+			allowed = append(allowed, server)
+		}
+	}
+	return allowed, nil
+}
+
+// CheckHeaderSecret 已有服务保存了认证请求头但未配置 LLM_MCP_SECRET_KEY 时返回错误，启动时调用
+func (s *MCPServerService) CheckHeaderSecret(ctx context.Context) error {
+	if s.secret != "" {
+		return nil
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.MCPServer{}).Where("headers IS NOT NULL AND headers <> ''").Count(&count).Error; err != nil {
+		return fmt.Errorf("查询 MCP 服务失败: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%d 个外部 MCP 服务保存了认证请求头，但未配置 LLM_MCP_SECRET_KEY", count)
+	}
+	return nil
+}
+
+func (s *MCPServerService) encryptHeaders(headers map[string]string) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	if s.secret == "" {
+		return "", apperr.New(constant.MCPServerSecretMissing)
+	}
+	raw, err := json.Marshal(headers)
+	if err != nil {
+		return "", apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	encrypted, err := utils.EncryptString(s.secret, string(raw))
+	if err != nil {
+		return "", apperr.Wrap(constant.CommonInternal, fmt.Errorf("加密 MCP 服务请求头失败: %w", err))
+	}
+	return encrypted, nil
+}
+
+func (s *MCPServerService) decryptHeaders(encrypted string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if encrypted == "" {
+		return headers, nil
+	}
+	plaintext, err := utils.DecryptString(s.secret, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(plaintext), &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// toResponse 请求头只返回名称；无法解密（密钥已更换）时返回空列表
+func (s *MCPServerService) toResponse(ctx context.Context, server models.MCPServer) response.MCPServerResponse {
+	names := make([]string, 0)
+	headers, err := s.decryptHeaders(server.Headers)
+	if err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":    "decrypt_mcp_server_headers",
+			"server_id": server.ID,
+			"error":     err.Error(),
+		})
+	}
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return response.MCPServerResponse{MCPServer: server, HeaderNames: names}
+}
+
+// mcpServerNameTaken 检查名称是否已被其他服务使用
+func mcpServerNameTaken(tx *gorm.DB, name string, excludeID uint) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.MCPServer{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// validateMCPServerURL 只支持 http/https 的 streamable HTTP 地址
+func validateMCPServerURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apperr.Wrap(constant.CommonBadRequest, fmt.Errorf("MCP 服务地址仅支持 http/https: %s", rawURL))
+	}
+	return nil
+}
+
+// optionalStringListJSON 空列表保存为 NULL
+func optionalStringListJSON(list []string) (datatypes.JSON, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return nil, apperr.Wrap(constant.CommonBadRequest, err)
+	}
+	return raw, nil
+}
+
+// rolesAllowed 未限制角色或用户拥有其中之一
+func rolesAllowed(allowedRoles, roleTags []string) bool {
+	if len(allowedRoles) == 0 {
+		return true
+	}
+	for _, role := range roleTags {
+		if slices.Contains(allowedRoles, role) {
+			return true
+		}
+	}
+	return false
+}
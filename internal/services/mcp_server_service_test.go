@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerLibraryTools 模拟图书馆检索服务，get_schedule 与内置服务同名
+func registerLibraryTools(s *server.MCPServer, record func(name string)) {
+	for _, name := range []string{"search_books", "reserve_seat", "get_schedule"} {
+		s.AddTool(mcp.NewTool(name), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			record(name)
+			return mcp.NewToolResultText("ok"), nil
+		})
+	}
+}
+
+func assertAppErrCode(t *testing.T, err error, want constant.ResCode) {
+	t.Helper()
+	appErr, ok := apperr.As(err)
+	if !ok || appErr.Code != want {
+		t.Fatalf("error = %v, want code %d", err, want)
+	}
+}
+
+func TestMCPServerServiceCRUD(t *testing.T) {
+	ctx := context.Background()
+	db := newHarnessDB(t)
+	service := NewMCPServerService(db, &config.Config{LLM: config.LLM{MCPSecretKey: "test-secret"}})
+
+	created, err := service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+		Name:        "library",
+		URL:         "https://library.example.com/mcp",
+		Headers:     map[string]string{"Authorization": "Bearer lib-token", "X-Tenant": "jxust"},
+		DeniedTools: []string{"reserve_seat"},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	if !created.IsEnabled || created.HealthStatus != constant.MCPServerHealthUnknown {
+		t.Fatalf("CreateServer() = %+v, want enabled with unknown health", created.MCPServer)
+	}
+	if !slices.Equal(created.HeaderNames, []string{"Authorization", "X-Tenant"}) {
+		t.Fatalf("HeaderNames = %v", created.HeaderNames)
+	}
+
+	var stored models.MCPServer
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("load server: %v", err)
+	}
+	if stored.Headers == "" || strings.Contains(stored.Headers, "lib-token") {
+		t.Fatalf("headers stored in plaintext: %q", stored.Headers)
+	}
+	headers, err := service.decryptHeaders(stored.Headers)
+	if err != nil || headers["Authorization"] != "Bearer lib-token" {
+		t.Fatalf("decryptHeaders() = %v, %v", headers, err)
+	}
+
+	_, err = service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{Name: "yqlx", URL: "https://a.example.com/mcp"})
+	assertAppErrCode(t, err, constant.MCPServerNameExists)
+	_, err = service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{Name: "library", URL: "https://a.example.com/mcp"})
+	assertAppErrCode(t, err, constant.MCPServerNameExists)
+	_, err = service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{Name: "ftp", URL: "ftp://a.example.com/mcp"})
+	assertAppErrCode(t, err, constant.CommonBadRequest)
+
+	// 更换请求头后需要重新检查
+	db.Model(&models.MCPServer{}).Where("id = ?", created.ID).Update("health_status", constant.MCPServerHealthHealthy)
+	disabled := false
+	updated, err := service.UpdateServer(ctx, created.ID, &request.UpdateMCPServerRequest{
+		Headers:   &map[string]string{},
+		IsEnabled: &disabled,
+	})
+	if err != nil {
+		t.Fatalf("UpdateServer() error = %v", err)
+	}
+	if updated.IsEnabled || updated.HealthStatus != constant.MCPServerHealthUnknown || len(updated.HeaderNames) != 0 {
+		t.Fatalf("UpdateServer() = %+v, headers %v", updated.MCPServer, updated.HeaderNames)
+	}
+
+	if err := service.DeleteServer(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteServer() error = %v", err)
+	}
+	assertAppErrCode(t, service.DeleteServer(ctx, created.ID), constant.MCPServerNotFound)
+}
+
+func TestMCPServerServiceRequiresSecretForHeaders(t *testing.T) {
+	ctx := context.Background()
+	db := newHarnessDB(t)
+	// 未配置 LLM_MCP_SECRET_KEY 时不回退到 JWT 密钥
+	unkeyed := NewMCPServerService(db, &config.Config{JWTSecret: "jwt-secret"})
+
+	if _, err := unkeyed.CreateServer(ctx, 1, &request.CreateMCPServerRequest{Name: "open", URL: "https://open.example.com/mcp"}); err != nil {
+		t.Fatalf("CreateServer() without headers error = %v", err)
+	}
+	if err := unkeyed.CheckHeaderSecret(ctx); err != nil {
+		t.Fatalf("CheckHeaderSecret() without stored headers error = %v", err)
+	}
+	_, err := unkeyed.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+		Name:    "library",
+		URL:     "https://library.example.com/mcp",
+		Headers: map[string]string{"Authorization": "Bearer lib-token"},
+	})
+	assertAppErrCode(t, err, constant.MCPServerSecretMissing)
+
+	keyed := NewMCPServerService(db, &config.Config{LLM: config.LLM{MCPSecretKey: "test-secret"}})
+	if _, err := keyed.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+		Name:    "library",
+		URL:     "https://library.example.com/mcp",
+		Headers: map[string]string{"Authorization": "Bearer lib-token"},
+	}); err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	if err := keyed.CheckHeaderSecret(ctx); err != nil {
+		t.Fatalf("CheckHeaderSecret() with secret error = %v", err)
+	}
+	if err := unkeyed.CheckHeaderSecret(ctx); err == nil {
+		t.Fatal("CheckHeaderSecret() should fail when stored headers exist without a secret")
+	}
+}
+
+func TestStreamChatLoadsExternalMCPTools(t *testing.T) {
+	ctx := context.Background()
+	yqlx := newFakeMCPServer(t, registerScheduleAndGradeTools)
+	library := newFakeMCPServer(t, registerLibraryTools)
+	h := newChatHarness(t, newStubLLMServer(t), yqlx, func(cfg *config.Config) { cfg.LLM.MCPSecretKey = "test-secret" })
+
+	service := h.service.mcpServers
+	libraryServer, err := service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+		Name:        "library",
+		URL:         library.URL + "/api/mcp",
+		Headers:     map[string]string{"Authorization": "Bearer lib-token"},
+		DeniedTools: []string{"reserve_seat"},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	// 限制角色的服务对没有该角色的用户不可见，检查失败的服务不加载
+	if _, err := service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+		Name:         "admin-only",
+		URL:          library.URL + "/api/mcp",
+		AllowedRoles: []string{"admin"},
+	}); err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	broken, err := service.CreateServer(ctx, 1, &request.CreateMCPServerRequest{Name: "broken", URL: "http://127.0.0.1:1/mcp"})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+
+	checked, err := service.CheckServerHealth(ctx, libraryServer.ID)
+	if err != nil {
+		t.Fatalf("CheckServerHealth() error = %v", err)
+	}
+	if checked.HealthStatus != constant.MCPServerHealthHealthy || len(jsonStringList(checked.DiscoveredTools)) != 3 {
+		t.Fatalf("CheckServerHealth() = %+v", checked.MCPServer)
+	}
+	if checked, err = service.CheckServerHealth(ctx, broken.ID); err != nil || checked.HealthStatus != constant.MCPServerHealthUnhealthy || checked.HealthError == "" {
+		t.Fatalf("CheckServerHealth(broken) = %+v, %v", checked, err)
+	}
+
+	conv := h.createConversation(t, 7)
+	tools, clients := h.service.loadMCPTools(ctx, 7, conv.ID, "user-token")
+	defer clients.Close(ctx)
+
+	var names []string
+	for _, tool := range tools {
+		info, err := tool.Info(ctx)
+		if err != nil {
+			t.Fatalf("tool info: %v", err)
+		}
+		names = append(names, info.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"get_schedule", "query_grades", "search_books"}; !slices.Equal(names, want) {
+		t.Fatalf("loaded tools = %v, want %v", names, want)
+	}
+	if _, ok := clients["admin-only"]; ok {
+		t.Fatal("role-restricted server should not be loaded")
+	}
+	if _, ok := clients["broken"]; ok {
+		t.Fatal("unhealthy server should not be loaded")
+	}
+	if !slices.Contains(library.AuthHeaders(), "Bearer lib-token") {
+		t.Fatalf("library auth headers = %v", library.AuthHeaders())
+	}
+}
+
+func TestInitExternalMCPClientsConnectsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	library := newFakeMCPServer(t, registerLibraryTools)
+	// 每个请求延迟 500 毫秒，连接需要 initialize 与 initialized 两次请求，逐个连接 4 个服务至少需要 4 秒
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		library.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(slow.Close)
+	h := newChatHarness(t, newStubLLMServer(t), nil)
+
+	for i := range 4 {
+		if _, err := h.service.mcpServers.CreateServer(ctx, 1, &request.CreateMCPServerRequest{
+			Name: fmt.Sprintf("slow-%d", i),
+			URL:  slow.URL + "/api/mcp",
+		}); err != nil {
+			t.Fatalf("CreateServer() error = %v", err)
+		}
+	}
+
+	clients := mcpClient{}
+	defer clients.Close(ctx)
+	start := time.Now()
+	filters := h.service.initExternalMCPClients(ctx, 7, clients)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("connecting took %v", elapsed)
+	}
+	if len(filters) != 4 || len(clients) != 4 {
+		t.Fatalf("connected %d servers, want 4", len(clients))
+	}
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/config"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/request"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/moderation"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/utils"
+	json "github.com/bytedance/sonic"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModerationSurfacePolicy 单个场景的审核策略
+type ModerationSurfacePolicy struct {
+	// Enabled 是否审核该场景
+	Enabled bool `json:"enabled"`
+	// Action 命中后的处理：block/mask/review
+	Action string `json:"action"`
+	// UseClassifier 是否同时调用外部分类服务，未配置分类服务时不生效
+	UseClassifier bool `json:"use_classifier"`
+}
+
+// ModerationPolicy 各场景的审核策略，key 为场景
+type ModerationPolicy map[string]ModerationSurfacePolicy
+
+// defaultModerationPolicy 内置的默认审核策略，可通过 SystemConfig(moderation.policy) 按场景覆盖
+var defaultModerationPolicy = ModerationPolicy{
+	constant.ModerationSurfaceChatInput:     {Enabled: true, Action: constant.ModerationActionMask, UseClassifier: true},
+	constant.ModerationSurfaceChatOutput:    {Enabled: true, Action: constant.ModerationActionMask},
+	constant.ModerationSurfaceTeacherReview: {Enabled: true, Action: constant.ModerationActionReview, UseClassifier: true},
+	constant.ModerationSurfaceContribution:  {Enabled: true, Action: constant.ModerationActionReview, UseClassifier: true},
+	constant.ModerationSurfaceMaterialDesc:  {Enabled: true, Action: constant.ModerationActionBlock},
+}
+
+// ModerationDecision 一次审核的结果
+type ModerationDecision struct {
+	Surface string
+	UserID  uint
+	// Action 实际执行的处理，未命中时为空
+	Action string
+	// Text 处理后的文本，打码时为替换后的内容，其余情况与原文相同
+	Text string
+	Hits []moderation.Hit
+
+	original string
+}
+
+// Flagged 是否命中
+func (d *ModerationDecision) Flagged() bool {
+	return d != nil && len(d.Hits) > 0
+}
+
+// ModerationService 内容审核：管理员维护的敏感词词典，加上可选的外部分类服务
+type ModerationService struct {
+	db         *gorm.DB
+	classifier moderation.Moderator
+
+	mu               sync.RWMutex
+	dictionary       *moderation.Dictionary
+	dictionaryLoaded time.Time
+	policy           *configPolicy[ModerationPolicy]
+}
+
+// NewModerationService 创建内容审核服务，配置了分类服务地址时启用外部分类
+func NewModerationService(db *gorm.DB, cfg *config.Config) *ModerationService {
+	s := &ModerationService{
+		db: db,
+		policy: newConfigPolicy(db, constant.ModerationPolicyConfigKey, constant.ModerationReloadInterval,
+			func() ModerationPolicy { return maps.Clone(defaultModerationPolicy) },
+			func(policy *ModerationPolicy, raw string) error { return mergeModerationPolicy(*policy, raw) }),
+	}
+	if cfg != nil && cfg.Moderation.ClassifierURL != "" {
+		s.classifier = moderation.NewHTTPClassifier(cfg.Moderation.ClassifierURL, cfg.Moderation.ClassifierAPIKey,
+			cfg.Moderation.ClassifierThreshold, cfg.Moderation.ClassifierTimeout)
+	}
+	return s
+}
+
+// Moderate 按场景策略审核文本。
+// 拦截时直接写入审核记录并返回 ModerationContentBlocked（decision 仍然返回）；
+// 打码与人工复核由调用方在内容保存后调用 Audit 记录，以便关联内容ID。
+// s 为 nil 时不审核。
+func (s *ModerationService) Moderate(ctx context.Context, surface string, userID uint, text string) (*ModerationDecision, error) {
+	decision := s.evaluate(ctx, surface, text)
+	decision.UserID = userID
+	if decision.Action == constant.ModerationActionBlock {
+		s.Audit(ctx, decision, "")
+		return decision, apperr.New(constant.ModerationContentBlocked)
+	}
+	return decision, nil
+}
+
+// evaluate 计算审核结果，不写入审核记录
+func (s *ModerationService) evaluate(ctx context.Context, surface, text string) *ModerationDecision {
+	decision := &ModerationDecision{Surface: surface, Text: text, original: text}
+	if s == nil || strings.TrimSpace(text) == "" {
+		return decision
+	}
+	policy, ok := s.policy.get(ctx)[surface]
+	if !ok || !policy.Enabled {
+		return decision
+	}
+
+	hits := s.loadDictionary(ctx).Match(text)
+	if policy.UseClassifier && s.classifier != nil {
+		// 分类服务不可用时只使用词典结果
+		classified, err := s.classifier.Check(ctx, text)
+		if err != nil {
+			logger.WarnCtx(ctx, map[string]any{
+				"action":  "moderation_classifier",
+				"surface": surface,
+				"error":   err.Error(),
+			})
+		}
+		hits = append(hits, classified...)
+	}
+	if len(hits) == 0 {
+		return decision
+	}
+
+	decision.Hits = hits
+	decision.Action = policy.Action
+	if policy.Action == constant.ModerationActionMask {
+		decision.Text = moderation.Mask(text, hits)
+		// 只有分类器命中时没有可以打码的位置，转为人工复核
+		if decision.Text == text {
+			decision.Action = constant.ModerationActionReview
+		}
+	}
+	return decision
+}
+
+// Audit 记录命中的审核结果，未命中时不记录；记录失败只打日志，不影响内容提交
+func (s *ModerationService) Audit(ctx context.Context, decision *ModerationDecision, targetID string) {
+	if s == nil || !decision.Flagged() {
+		return
+	}
+	words, _ := json.Marshal(moderation.Words(decision.Hits))
+	hits, _ := json.Marshal(decision.Hits)
+	status := constant.ModerationReviewNone
+	if decision.Action == constant.ModerationActionReview {
+		status = constant.ModerationReviewPending
+	}
+	record := &models.ModerationRecord{
+		Surface:      decision.Surface,
+		Action:       decision.Action,
+		UserID:       decision.UserID,
+		TargetID:     targetID,
+		Words:        words,
+		Hits:         hits,
+		Excerpt:      moderation.Excerpt(decision.original, decision.Hits, constant.ModerationExcerptMaxRunes),
+		ReviewStatus: status,
+	}
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Create(record).Error; err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":    "moderation_audit",
+			"surface":   decision.Surface,
+			"user_id":   decision.UserID,
+			"target_id": targetID,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// loadDictionary 获取敏感词词典，本地缓存超过刷新间隔后从数据库重建
+func (s *ModerationService) loadDictionary(ctx context.Context) *moderation.Dictionary {
+	s.mu.RLock()
+	dictionary, loadedAt := s.dictionary, s.dictionaryLoaded
+	s.mu.RUnlock()
+	if dictionary != nil && time.Since(loadedAt) <= constant.ModerationReloadInterval {
+		return dictionary
+	}
+
+	var words []models.SensitiveWord
+	if err := s.db.WithContext(ctx).Select("word", "category").Where("is_enabled = ?", true).Find(&words).Error; err != nil {
+		logger.WarnCtx(ctx, map[string]any{
+			"action":  "moderation_dictionary_load",
+			"message": "读取敏感词失败，继续使用已加载的词典",
+			"error":   err.Error(),
+		})
+		if dictionary != nil {
+			return dictionary
+		}
+		return moderation.NewDictionary(nil)
+	}
+	entries := make([]moderation.Entry, 0, len(words))
+	for _, word := range words {
+		entries = append(entries, moderation.Entry{Word: word.Word, Category: word.Category})
+	}
+	dictionary = moderation.NewDictionary(entries)
+
+	s.mu.Lock()
+	s.dictionary = dictionary
+	s.dictionaryLoaded = time.Now()
+	s.mu.Unlock()
+	return dictionary
+}
+
+// invalidateDictionary 敏感词变更后让本实例立即重建词典，其他实例在刷新间隔内生效
+func (s *ModerationService) invalidateDictionary() {
+	s.mu.Lock()
+	s.dictionaryLoaded = time.Time{}
+	s.mu.Unlock()
+}
+
+// mergeModerationPolicy 将 JSON 配置按场景覆盖到 policy 上，未填写的字段沿用默认值，未知场景与无效的处理方式被忽略
+func mergeModerationPolicy(policy ModerationPolicy, raw string) error {
+	var overrides map[string]stdjson.RawMessage
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return err
+	}
+	var invalid []string
+	for surface, data := range overrides {
+		current, ok := policy[surface]
+		if !ok {
+			invalid = append(invalid, surface)
+			continue
+		}
+		override := current
+		if err := json.Unmarshal(data, &override); err != nil {
+			invalid = append(invalid, surface)
+			continue
+		}
+		switch override.Action {
+		case constant.ModerationActionBlock, constant.ModerationActionMask, constant.ModerationActionReview:
+			policy[surface] = override
+		default:
+			invalid = append(invalid, surface)
+		}
+	}
+	if len(invalid) > 0 {
+		slices.Sort(invalid)
+		return fmt.Errorf("ignored invalid moderation policy for %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// GetPolicy 获取当前生效的审核策略
+func (s *ModerationService) GetPolicy(ctx context.Context) ModerationPolicy {
+	return s.policy.get(ctx)
+}
+
+// CheckText 按场景策略试审文本，不写入审核记录
+func (s *ModerationService) CheckText(ctx context.Context, req *request.CheckModerationRequest) *response.ModerationCheckResponse {
+	decision := s.evaluate(ctx, req.Surface, req.Text)
+	hits := decision.Hits
+	if hits == nil {
+		hits = []moderation.Hit{}
+	}
+	return &response.ModerationCheckResponse{Action: decision.Action, Text: decision.Text, Hits: hits}
+}
+
+// ==================== 敏感词管理 ====================
+
+// ListSensitiveWords 分页查询敏感词
+func (s *ModerationService) ListSensitiveWords(ctx context.Context, req *request.ListSensitiveWordsRequest) ([]models.SensitiveWord, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.SensitiveWord{})
+	if req.Keyword != "" {
+		query = query.Where("word LIKE ?", "%"+req.Keyword+"%")
+	}
+	if req.Category != "" {
+		query = query.Where("category = ?", req.Category)
+	}
+	if req.IsEnabled != nil {
+		query = query.Where("is_enabled = ?", *req.IsEnabled)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("统计敏感词失败: %w", err))
+	}
+	pagination := utils.GetPagination(req.Page, req.PageSize)
+	words := make([]models.SensitiveWord, 0)
+	if err := query.Order("id DESC").Offset(pagination.Offset).Limit(pagination.Size).Find(&words).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询敏感词失败: %w", err))
+	}
+	return words, total, nil
+}
+
+// CreateSensitiveWords 批量添加敏感词，已存在的词被跳过
+func (s *ModerationService) CreateSensitiveWords(ctx context.Context, operatorID uint, req *request.CreateSensitiveWordsRequest) (*response.CreateSensitiveWordsResponse, error) {
+	words := make([]models.SensitiveWord, 0, len(req.Words))
+	seen := make(map[string]struct{}, len(req.Words))
+	for _, word := range req.Words {
+		word = strings.TrimSpace(word)
+		key := strings.ToLower(word)
+		if _, dup := seen[key]; dup || word == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		words = append(words, models.SensitiveWord{
+			Word:      word,
+			Category:  strings.TrimSpace(req.Category),
+			IsEnabled: true,
+			CreatedBy: operatorID,
+		})
+	}
+
+	var created int64
+	if len(words) > 0 {
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&words, 200)
+		if result.Error != nil {
+			return nil, apperr.Wrap(constant.CommonInternal, fmt.Errorf("添加敏感词失败: %w", result.Error))
+		}
+		created = result.RowsAffected
+	}
+	s.invalidateDictionary()
+	return &response.CreateSensitiveWordsResponse{Created: int(created), Skipped: len(req.Words) - int(created)}, nil
+}
+
+// UpdateSensitiveWord 更新敏感词分类或启用状态
+func (s *ModerationService) UpdateSensitiveWord(ctx context.Context, id uint, req *request.UpdateSensitiveWordRequest) error {
+	updates := make(map[string]any)
+	if req.Category != nil {
+		updates["category"] = strings.TrimSpace(*req.Category)
+	}
+	if req.IsEnabled != nil {
+		updates["is_enabled"] = *req.IsEnabled
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["updated_at"] = time.Now()
+
+	result := s.db.WithContext(ctx).Model(&models.SensitiveWord{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新敏感词失败: %w", result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return apperr.New(constant.ModerationWordNotFound)
+	}
+	s.invalidateDictionary()
+	return nil
+}
+
+// DeleteSensitiveWord 删除敏感词
+func (s *ModerationService) DeleteSensitiveWord(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.SensitiveWord{}, id)
+	if result.Error != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("删除敏感词失败: %w", result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return apperr.New(constant.ModerationWordNotFound)
+	}
+	s.invalidateDictionary()
+	return nil
+}
+
+// ==================== 审核记录 ====================
+
+// ListRecords 分页查询审核记录，新记录在前
+func (s *ModerationService) ListRecords(ctx context.Context, req *request.ListModerationRecordsRequest) ([]models.ModerationRecord, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.ModerationRecord{})
+	if req.Surface != "" {
+		query = query.Where("surface = ?", req.Surface)
+	}
+	if req.Action != "" {
+		query = query.Where("action = ?", req.Action)
+	}
+	if req.ReviewStatus != nil {
+		query = query.Where("review_status = ?", *req.ReviewStatus)
+	}
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("统计审核记录失败: %w", err))
+	}
+	pagination := utils.GetPagination(req.Page, req.PageSize)
+	records := make([]models.ModerationRecord, 0)
+	if err := query.Order("id DESC").Offset(pagination.Offset).Limit(pagination.Size).Find(&records).Error; err != nil {
+		return nil, 0, apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询审核记录失败: %w", err))
+	}
+	return records, total, nil
+}
+
+// ReviewRecord 人工复核审核记录
+func (s *ModerationService) ReviewRecord(ctx context.Context, operatorID, id uint, req *request.ReviewModerationRecordRequest) error {
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&models.ModerationRecord{}).Where("id = ?", id).Updates(map[string]any{
+		"review_status": req.ReviewStatus,
+		"review_note":   req.ReviewNote,
+		"reviewed_by":   operatorID,
+		"reviewed_at":   &now,
+	})
+	if result.Error != nil {
+		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("复核审核记录失败: %w", result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return apperr.New(constant.ModerationRecordNotFound)
+	}
+	return nil
+}
+
+// moderationTargetID 将数字ID转为审核记录中的内容ID
+func moderationTargetID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
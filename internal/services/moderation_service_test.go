@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/moderation"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+)
+
+type stubClassifier struct {
+	hits []moderation.Hit
+}
+
+func (c stubClassifier) Name() string { return moderation.SourceClassifier }
+
+func (c stubClassifier) Check(context.Context, string) ([]moderation.Hit, error) {
+	return c.hits, nil
+}
+
+func newTestModerationService(policy ModerationPolicy, words ...string) *ModerationService {
+	entries := make([]moderation.Entry, 0, len(words))
+	for _, word := range words {
+		entries = append(entries, moderation.Entry{Word: word})
+	}
+	s := &ModerationService{
+		dictionary:       moderation.NewDictionary(entries),
+		dictionaryLoaded: time.Now(),
+		policy:           newConfigPolicy[ModerationPolicy](nil, constant.ModerationPolicyConfigKey, constant.ModerationReloadInterval, nil, nil),
+	}
+	s.policy.store(policy)
+	return s
+}
+
+func TestModerationEvaluateActions(t *testing.T) {
+	s := newTestModerationService(ModerationPolicy{
+		constant.ModerationSurfaceChatInput:     {Enabled: true, Action: constant.ModerationActionMask},
+		constant.ModerationSurfaceMaterialDesc:  {Enabled: true, Action: constant.ModerationActionBlock},
+		constant.ModerationSurfaceTeacherReview: {Enabled: false, Action: constant.ModerationActionBlock},
+	}, "坏词")
+	ctx := context.Background()
+
+	masked := s.evaluate(ctx, constant.ModerationSurfaceChatInput, "这是坏词")
+	if masked.Action != constant.ModerationActionMask || masked.Text != "这是**" || !masked.Flagged() {
+		t.Fatalf("mask decision = %+v", masked)
+	}
+
+	blocked := s.evaluate(ctx, constant.ModerationSurfaceMaterialDesc, "坏 词")
+	if blocked.Action != constant.ModerationActionBlock || blocked.Text != "坏 词" {
+		t.Fatalf("block decision = %+v", blocked)
+	}
+
+	if d := s.evaluate(ctx, constant.ModerationSurfaceTeacherReview, "坏词"); d.Flagged() {
+		t.Fatalf("disabled surface should pass, got %+v", d)
+	}
+	if d := s.evaluate(ctx, constant.ModerationSurfaceChatInput, "正常内容"); d.Flagged() || d.Text != "正常内容" {
+		t.Fatalf("clean text should pass, got %+v", d)
+	}
+
+	var nilService *ModerationService
+	if d, err := nilService.Moderate(ctx, constant.ModerationSurfaceChatInput, 1, "坏词"); err != nil || d.Text != "坏词" {
+		t.Fatalf("nil service should pass through, got %+v %v", d, err)
+	}
+}
+
+func TestModerationClassifierOnlyHitFallsBackToReview(t *testing.T) {
+	s := newTestModerationService(ModerationPolicy{
+		constant.ModerationSurfaceChatInput: {Enabled: true, Action: constant.ModerationActionMask, UseClassifier: true},
+	})
+	s.classifier = stubClassifier{hits: []moderation.Hit{{Source: moderation.SourceClassifier, Category: "abuse", Score: 0.9}}}
+
+	d := s.evaluate(context.Background(), constant.ModerationSurfaceChatInput, "看起来正常")
+	if d.Action != constant.ModerationActionReview || d.Text != "看起来正常" || len(d.Hits) != 1 {
+		t.Fatalf("decision = %+v", d)
+	}
+}
+
+func TestMergeModerationPolicy(t *testing.T) {
+	policy := ModerationPolicy{
+		constant.ModerationSurfaceChatInput:    {Enabled: true, Action: constant.ModerationActionMask, UseClassifier: true},
+		constant.ModerationSurfaceContribution: {Enabled: true, Action: constant.ModerationActionReview},
+	}
+	err := mergeModerationPolicy(policy, `{
+		"chat_input": {"action": "block"},
+		"contribution": {"action": "delete"},
+		"unknown": {"action": "block"}
+	}`)
+	if err == nil {
+		t.Fatal("expected error for invalid entries")
+	}
+	if got := policy[constant.ModerationSurfaceChatInput]; got.Action != constant.ModerationActionBlock || !got.Enabled || !got.UseClassifier {
+		t.Fatalf("chat_input = %+v", got)
+	}
+	if got := policy[constant.ModerationSurfaceContribution]; got.Action != constant.ModerationActionReview {
+		t.Fatalf("invalid override should be ignored, got %+v", got)
+	}
+	if _, ok := policy["unknown"]; ok {
+		t.Fatal("unknown surface should not be added")
+	}
+}
@@ -141,7 +141,8 @@ func (s *PointsService) GetPointsTransactions(ctx context.Context, userID uint,
 }
 
 // SpendPoints 消费积分（原RedeemPoints）
-func (s *PointsService) SpendPoints(ctx context.Context, userID uint, req *request.SpendPointsRequest) error {
+// onSpent 在扣减积分的同一事务中执行，用于写入与消费关联的业务数据（如购买 AI 对话额度），任一失败整体回滚
+func (s *PointsService) SpendPoints(ctx context.Context, userID uint, req *request.SpendPointsRequest, onSpent ...func(tx *gorm.DB) error) error {
 	// 获取用户信息
 	var user models.User
 	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
@@ -176,6 +177,12 @@ func (s *PointsService) SpendPoints(ctx context.Context, userID uint, req *reque
 			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("创建积分交易记录失败：%w", err))
 		}
 
+		for _, fn := range onSpent {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
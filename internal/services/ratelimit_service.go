@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/ratelimit"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	json "github.com/bytedance/sonic"
+	"gorm.io/gorm"
+)
+
+// RateLimitRule 单个限流策略
+type RateLimitRule struct {
+	// KeyBy 计数维度：user | ip | route
+	KeyBy string `json:"key_by"`
+	// Limit 窗口内允许的最大请求数，<=0 表示不限制
+	Limit int `json:"limit"`
+	// WindowSeconds 滑动窗口长度（秒）
+	WindowSeconds int `json:"window_seconds"`
+	// RoleLimits 按角色覆盖 Limit，用户拥有多个角色时取最宽松的值，<=0 表示该角色不受限制
+	RoleLimits map[string]int `json:"role_limits,omitempty"`
+}
+
+// Window 返回滑动窗口长度
+func (r RateLimitRule) Window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// defaultRateLimitRules 内置的默认限流策略，可通过 SystemConfig(rate_limit.policies) 覆盖
+var defaultRateLimitRules = map[string]RateLimitRule{
+	constant.RateLimitPolicyChatConversation: {
+		KeyBy: constant.RateLimitKeyByUser, Limit: 20, WindowSeconds: 60,
+		RoleLimits: map[string]int{constant.RoleTagAdmin: 0},
+	},
+	constant.RateLimitPolicyMaterialDownload: {
+		KeyBy: constant.RateLimitKeyByUser, Limit: 30, WindowSeconds: 60,
+		RoleLimits: map[string]int{constant.RoleTagAdmin: 0},
+	},
+	constant.RateLimitPolicyReviewCreate: {
+		KeyBy: constant.RateLimitKeyByUser, Limit: 5, WindowSeconds: 60,
+	},
+	constant.RateLimitPolicyContributionCreate: {
+		KeyBy: constant.RateLimitKeyByUser, Limit: 5, WindowSeconds: 60,
+	},
+	constant.RateLimitPolicyChatShareView: {
+		KeyBy: constant.RateLimitKeyByIP, Limit: 60, WindowSeconds: 60,
+	},
+}
+
+// RateLimitDecision 限流判定结果
+type RateLimitDecision struct {
+	Rule   RateLimitRule
+	Result *ratelimit.Result
+}
+
+// RateLimitService 根据策略对请求进行限流判定
+type RateLimitService struct {
+	rbacService *RBACService
+	rules       *configPolicy[map[string]RateLimitRule]
+}
+
+// NewRateLimitService 创建限流服务
+func NewRateLimitService(db *gorm.DB, rbacService *RBACService) *RateLimitService {
+	return &RateLimitService{
+		rbacService: rbacService,
+		rules: newConfigPolicy(db, constant.RateLimitConfigKey, constant.RateLimitPolicyReloadInterval,
+			defaultRateLimitRuleSet, mergeRateLimitRules),
+	}
+}
+
+// Check 对一次请求进行限流判定。
+// 返回 nil 表示该请求不受限流约束（策略未配置、不限制或限流器不可用）。
+func (s *RateLimitService) Check(ctx context.Context, policy string, userID uint, clientIP string) (*RateLimitDecision, error) {
+	limiter := ratelimit.GlobalLimiter
+	if limiter == nil {
+		return nil, nil
+	}
+
+	rule, ok := s.rules.get(ctx)[policy]
+	if !ok {
+		return nil, nil
+	}
+	limit := roleOverride(ctx, s.rbacService, userID, rule.Limit, rule.RoleLimits)
+	if limit <= 0 || rule.WindowSeconds <= 0 {
+		return nil, nil
+	}
+
+	var subject string
+	switch {
+	case rule.KeyBy == constant.RateLimitKeyByRoute:
+		subject = "route"
+	case rule.KeyBy == constant.RateLimitKeyByUser && userID != 0:
+		subject = fmt.Sprintf("user:%d", userID)
+	default:
+		subject = "ip:" + clientIP
+	}
+
+	result, err := limiter.Allow(ctx, fmt.Sprintf("%s%s:%s", constant.RateLimitCachePrefix, policy, subject), limit, rule.Window())
+	if err != nil {
+		return nil, err
+	}
+	rule.Limit = limit
+	return &RateLimitDecision{Rule: rule, Result: result}, nil
+}
+
+// defaultRateLimitRuleSet 返回默认策略的副本
+func defaultRateLimitRuleSet() map[string]RateLimitRule {
+	rules := make(map[string]RateLimitRule, len(defaultRateLimitRules))
+	for name, rule := range defaultRateLimitRules {
+		rule.RoleLimits = maps.Clone(rule.RoleLimits)
+		rules[name] = rule
+	}
+	return rules
+}
+
+// mergeRateLimitRules 将 JSON 配置按策略名称覆盖到 rules 上，未填写的字段沿用默认策略，无法解析的策略被忽略
+func mergeRateLimitRules(rules *map[string]RateLimitRule, raw string) error {
+	var overrides map[string]json.NoCopyRawMessage
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return err
+	}
+	var invalid []string
+	for name, data := range overrides {
+		rule := (*rules)[name]
+		if err := overrideJSON(&rule, string(data)); err != nil {
+			invalid = append(invalid, name)
+			continue
+		}
+		(*rules)[name] = rule
+	}
+	if len(invalid) > 0 {
+		slices.Sort(invalid)
+		return fmt.Errorf("ignored invalid rate limit policy for %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/ratelimit"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/alicebob/miniredis/v2"
+	json "github.com/bytedance/sonic"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type testRedisCli struct {
+	cli goredis.UniversalClient
+}
+
+func (t *testRedisCli) GetRedisCli() goredis.UniversalClient { return t.cli }
+
+// useTestLimiter 将全局限流器替换为基于 miniredis 的实例
+func useTestLimiter(t *testing.T) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	prev := ratelimit.GlobalLimiter
+	ratelimit.NewRedisLimiter(&testRedisCli{cli: cli})
+	t.Cleanup(func() {
+		ratelimit.GlobalLimiter = prev
+		_ = cli.Close()
+	})
+}
+
+// newTestRBACService 以缓存中的权限快照构造 RBACService，未写入快照的用户会回落到没有 RBAC 表的数据库并报错
+func newTestRBACService(t *testing.T, snapshots map[uint]UserPermissionSnapshot) *RBACService {
+	t.Helper()
+	mem := newMemoryStreamCache()
+	for userID, snap := range snapshots {
+		data, _ := json.Marshal(snap)
+		mem.values[fmt.Sprintf("rbac:user:%d:permissions", userID)] = string(data)
+	}
+	return &RBACService{db: newHarnessDB(t), cache: mem}
+}
+
+func TestRateLimitServiceRoleOverrides(t *testing.T) {
+	useTestLimiter(t)
+	db := newHarnessDB(t)
+	if err := db.Create(&models.SystemConfig{
+		Key:   constant.RateLimitConfigKey,
+		Value: `{"chat_conversation":{"limit":2,"role_limits":{"user_verified":5,"operator":3}}}`,
+	}).Error; err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+	s := NewRateLimitService(db, newTestRBACService(t, map[uint]UserPermissionSnapshot{
+		1: {RoleTags: []string{constant.RoleTagUserBasic}},
+		2: {RoleTags: []string{constant.RoleTagUserBasic, constant.RoleTagOperator, constant.RoleTagUserVerified}},
+		3: {RoleTags: []string{constant.RoleTagAdmin}},
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		userID    uint
+		wantLimit int
+	}{
+		{name: "override applies to users without a role limit", userID: 1, wantLimit: 2},
+		{name: "most permissive role limit wins", userID: 2, wantLimit: 5},
+		{name: "role snapshot unavailable", userID: 4, wantLimit: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := s.Check(ctx, constant.RateLimitPolicyChatConversation, tt.userID, "10.0.0.1")
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if decision == nil || decision.Rule.Limit != tt.wantLimit || decision.Result.Limit != tt.wantLimit {
+				t.Fatalf("decision = %+v, want limit %d", decision, tt.wantLimit)
+			}
+			if decision.Rule.WindowSeconds != 60 {
+				t.Fatalf("unset fields should keep the default rule: %+v", decision.Rule)
+			}
+		})
+	}
+
+	// 默认策略中的管理员不限流在覆盖配置后仍然有效
+	decision, err := s.Check(ctx, constant.RateLimitPolicyChatConversation, 3, "10.0.0.1")
+	if err != nil || decision != nil {
+		t.Fatalf("admin should be unlimited, got %+v, %v", decision, err)
+	}
+}
+
+func TestRateLimitServiceCountsPerSubject(t *testing.T) {
+	useTestLimiter(t)
+	s := NewRateLimitService(newHarnessDB(t), nil)
+	ctx := context.Background()
+
+	// review_create 默认每用户每分钟 5 次
+	for range 5 {
+		if d, err := s.Check(ctx, constant.RateLimitPolicyReviewCreate, 1, "10.0.0.1"); err != nil || !d.Result.Allowed {
+			t.Fatalf("Check() = %+v, %v", d, err)
+		}
+	}
+	if d, _ := s.Check(ctx, constant.RateLimitPolicyReviewCreate, 1, "10.0.0.1"); d.Result.Allowed {
+		t.Fatal("sixth request should be limited")
+	}
+	if d, _ := s.Check(ctx, constant.RateLimitPolicyReviewCreate, 2, "10.0.0.1"); !d.Result.Allowed {
+		t.Fatal("another user from the same IP should not share the counter")
+	}
+
+	// 未登录用户按 IP 计数
+	for range 5 {
+		_, _ = s.Check(ctx, constant.RateLimitPolicyReviewCreate, 0, "10.0.0.2")
+	}
+	if d, _ := s.Check(ctx, constant.RateLimitPolicyReviewCreate, 0, "10.0.0.2"); d.Result.Allowed {
+		t.Fatal("anonymous requests should be limited by IP")
+	}
+	if d, _ := s.Check(ctx, constant.RateLimitPolicyReviewCreate, 0, "10.0.0.3"); !d.Result.Allowed {
+		t.Fatal("another IP should not share the counter")
+	}
+
+	if d, err := s.Check(ctx, "unknown_policy", 1, "10.0.0.1"); d != nil || err != nil {
+		t.Fatalf("unknown policy should not be limited, got %+v, %v", d, err)
+	}
+}
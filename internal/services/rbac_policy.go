@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/logger"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// RBACPolicyVersion 当前支持的策略文件版本
+const RBACPolicyVersion = 1
+
+//go:embed rbac_default_policy.yaml
+var defaultRBACPolicyYAML []byte
+
+// DefaultRBACPolicy 解析内置的预置策略
+func DefaultRBACPolicy() (*RBACPolicy, error) {
+	return ParseRBACPolicy(defaultRBACPolicyYAML)
+}
+
+// RBACPolicy 声明式 RBAC 策略文件，统一描述角色、权限及其绑定关系
+type RBACPolicy struct {
+	Version     int                    `yaml:"version" json:"version"`
+	Permissions []RBACPolicyPermission `yaml:"permissions" json:"permissions"`
+	Roles       []RBACPolicyRole       `yaml:"roles" json:"roles"`
+}
+
+// RBACPolicyPermission 策略文件中的权限定义
+type RBACPolicyPermission struct {
+	Tag         string `yaml:"tag" json:"tag"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// RBACPolicyRole 策略文件中的角色定义及其拥有的权限标识
+type RBACPolicyRole struct {
+	Tag         string   `yaml:"tag" json:"tag"`
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+// ParseRBACPolicy 解析并校验 YAML 格式的策略文件
+func ParseRBACPolicy(data []byte) (*RBACPolicy, error) {
+	var policy RBACPolicy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, newRBACPolicyInvalid(fmt.Sprintf("策略文件解析失败: %v", err))
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ToYAML 将策略序列化为 YAML
+func (p *RBACPolicy) ToYAML() ([]byte, error) {
+	return yaml.Marshal(p)
+}
+
+// Validate 校验策略文件：版本、标识唯一性以及角色引用的权限必须已声明
+func (p *RBACPolicy) Validate() error {
+	if p.Version != RBACPolicyVersion {
+		return newRBACPolicyInvalid(fmt.Sprintf("不支持的策略文件版本: %d", p.Version))
+	}
+
+	permTags := make(map[string]struct{}, len(p.Permissions))
+	for _, perm := range p.Permissions {
+		if strings.TrimSpace(perm.Tag) == "" || strings.TrimSpace(perm.Name) == "" {
+			return newRBACPolicyInvalid("权限的 tag 和 name 不能为空")
+		}
+		if _, ok := permTags[perm.Tag]; ok {
+			return newRBACPolicyInvalid(fmt.Sprintf("权限重复定义: %s", perm.Tag))
+		}
+		permTags[perm.Tag] = struct{}{}
+	}
+
+	roleTags := make(map[string]struct{}, len(p.Roles))
+	for _, role := range p.Roles {
+		if strings.TrimSpace(role.Tag) == "" || strings.TrimSpace(role.Name) == "" {
+			return newRBACPolicyInvalid("角色的 tag 和 name 不能为空")
+		}
+		if _, ok := roleTags[role.Tag]; ok {
+			return newRBACPolicyInvalid(fmt.Sprintf("角色重复定义: %s", role.Tag))
+		}
+		roleTags[role.Tag] = struct{}{}
+
+		bound := make(map[string]struct{}, len(role.Permissions))
+		for _, tag := range role.Permissions {
+			if _, ok := permTags[tag]; !ok {
+				return newRBACPolicyInvalid(fmt.Sprintf("角色 %s 引用了未声明的权限: %s", role.Tag, tag))
+			}
+			if _, ok := bound[tag]; ok {
+				return newRBACPolicyInvalid(fmt.Sprintf("角色 %s 重复绑定权限: %s", role.Tag, tag))
+			}
+			bound[tag] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func newRBACPolicyInvalid(message string) error {
+	err := apperr.New(constant.RBACPolicyInvalid)
+	err.Message = message
+	return err
+}
+
+// rbacState 数据库中当前的角色、权限与绑定关系
+type rbacState struct {
+	roles       map[string]models.Role
+	permissions map[string]models.Permission
+	// bindings 角色标识 -> 权限标识集合
+	bindings map[string]map[string]struct{}
+}
+
+// loadRBACState 读取数据库中当前的 RBAC 配置
+func loadRBACState(db *gorm.DB) (*rbacState, error) {
+	var roles []models.Role
+	if err := db.Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	var perms []models.Permission
+	if err := db.Order("id ASC").Find(&perms).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	var rolePerms []models.RolePermission
+	if err := db.Find(&rolePerms).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	state := &rbacState{
+		roles:       make(map[string]models.Role, len(roles)),
+		permissions: make(map[string]models.Permission, len(perms)),
+		bindings:    make(map[string]map[string]struct{}, len(roles)),
+	}
+	roleTagByID := make(map[uint]string, len(roles))
+	for _, role := range roles {
+		state.roles[role.RoleTag] = role
+		state.bindings[role.RoleTag] = make(map[string]struct{})
+		roleTagByID[role.ID] = role.RoleTag
+	}
+	permTagByID := make(map[uint]string, len(perms))
+	for _, perm := range perms {
+		state.permissions[perm.PermissionTag] = perm
+		permTagByID[perm.ID] = perm.PermissionTag
+	}
+	for _, rp := range rolePerms {
+		roleTag, ok := roleTagByID[rp.RoleID]
+		if !ok {
+			continue
+		}
+		permTag, ok := permTagByID[rp.PermissionID]
+		if !ok {
+			continue
+		}
+		state.bindings[roleTag][permTag] = struct{}{}
+	}
+	return state, nil
+}
+
+// ExportPolicy 将数据库中当前的 RBAC 配置导出为策略
+func (s *RBACService) ExportPolicy(ctx context.Context) (*RBACPolicy, error) {
+	var roles []models.Role
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	var perms []models.Permission
+	if err := s.db.WithContext(ctx).Order("id ASC").Find(&perms).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	var rolePerms []models.RolePermission
+	if err := s.db.WithContext(ctx).Order("permission_id ASC").Find(&rolePerms).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	policy := &RBACPolicy{
+		Version:     RBACPolicyVersion,
+		Permissions: make([]RBACPolicyPermission, 0, len(perms)),
+		Roles:       make([]RBACPolicyRole, 0, len(roles)),
+	}
+	permTagByID := make(map[uint]string, len(perms))
+	for _, perm := range perms {
+		permTagByID[perm.ID] = perm.PermissionTag
+		policy.Permissions = append(policy.Permissions, RBACPolicyPermission{
+			Tag:         perm.PermissionTag,
+			Name:        perm.Name,
+			Description: perm.Description,
+		})
+	}
+	rolePermTags := make(map[uint][]string, len(roles))
+	for _, rp := range rolePerms {
+		if tag, ok := permTagByID[rp.PermissionID]; ok && !slices.Contains(rolePermTags[rp.RoleID], tag) {
+			rolePermTags[rp.RoleID] = append(rolePermTags[rp.RoleID], tag)
+		}
+	}
+	for _, role := range roles {
+		tags := rolePermTags[role.ID]
+		if tags == nil {
+			tags = []string{}
+		}
+		policy.Roles = append(policy.Roles, RBACPolicyRole{
+			Tag:         role.RoleTag,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: tags,
+		})
+	}
+	return policy, nil
+}
+
+// DiffPolicy 对比策略与数据库当前配置（只读，不做任何修改）
+func (s *RBACService) DiffPolicy(ctx context.Context, policy *RBACPolicy) (*response.RBACPolicyDiffResponse, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	state, err := loadRBACState(s.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	diff := diffRBACPolicy(policy, state)
+	diff.DryRun = true
+	return diff, nil
+}
+
+// ApplyPolicy 在单个事务中将策略应用到数据库：
+// 创建/更新策略中的角色与权限，并将策略中角色的权限绑定重置为声明值。
+// 数据库中存在但策略未声明的角色与权限保持不变，仅在差异中列出。
+func (s *RBACService) ApplyPolicy(ctx context.Context, policy *RBACPolicy) (*response.RBACPolicyDiffResponse, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		diff          *response.RBACPolicyDiffResponse
+		affectedUsers []uint
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := loadRBACState(tx)
+		if err != nil {
+			return err
+		}
+		diff = diffRBACPolicy(policy, state)
+		if !diff.HasChanges {
+			return nil
+		}
+
+		for _, perm := range policy.Permissions {
+			existing, ok := state.permissions[perm.Tag]
+			if !ok {
+				created := models.Permission{PermissionTag: perm.Tag, Name: perm.Name, Description: perm.Description}
+				if err := tx.Create(&created).Error; err != nil {
+					return apperr.Wrap(constant.CommonInternal, fmt.Errorf("创建权限失败[%s]: %w", perm.Tag, err))
+				}
+				state.permissions[perm.Tag] = created
+				continue
+			}
+			if existing.Name != perm.Name || existing.Description != perm.Description {
+				if err := tx.Model(&existing).Updates(map[string]any{
+					"name":        perm.Name,
+					"description": perm.Description,
+				}).Error; err != nil {
+					return apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新权限失败[%s]: %w", perm.Tag, err))
+				}
+			}
+		}
+
+		for _, role := range policy.Roles {
+			existing, ok := state.roles[role.Tag]
+			if !ok {
+				created := models.Role{RoleTag: role.Tag, Name: role.Name, Description: role.Description}
+				if err := tx.Create(&created).Error; err != nil {
+					return apperr.Wrap(constant.CommonInternal, fmt.Errorf("创建角色失败[%s]: %w", role.Tag, err))
+				}
+				state.roles[role.Tag] = created
+				continue
+			}
+			if existing.Name != role.Name || existing.Description != role.Description {
+				if err := tx.Model(&existing).Updates(map[string]any{
+					"name":        role.Name,
+					"description": role.Description,
+				}).Error; err != nil {
+					return apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新角色失败[%s]: %w", role.Tag, err))
+				}
+			}
+		}
+
+		changedRoleIDs := make([]uint, 0)
+		for _, change := range diff.BindingsRemoved {
+			role := state.roles[change.RoleTag]
+			permIDs := make([]uint, 0, len(change.PermissionTags))
+			for _, tag := range change.PermissionTags {
+				permIDs = append(permIDs, state.permissions[tag].ID)
+			}
+			if err := tx.Where("role_id = ? AND permission_id IN ?", role.ID, permIDs).
+				Delete(&models.RolePermission{}).Error; err != nil {
+				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("解绑角色权限失败[%s]: %w", change.RoleTag, err))
+			}
+			changedRoleIDs = append(changedRoleIDs, role.ID)
+		}
+		for _, change := range diff.BindingsAdded {
+			role := state.roles[change.RoleTag]
+			for _, tag := range change.PermissionTags {
+				rp := models.RolePermission{RoleID: role.ID, PermissionID: state.permissions[tag].ID}
+				if err := tx.Create(&rp).Error; err != nil {
+					return apperr.Wrap(constant.CommonInternal, fmt.Errorf("绑定角色权限失败[%s]: %w", change.RoleTag, err))
+				}
+			}
+			if !slices.Contains(changedRoleIDs, role.ID) {
+				changedRoleIDs = append(changedRoleIDs, role.ID)
+			}
+		}
+
+		if len(changedRoleIDs) > 0 {
+			if err := tx.Model(&models.UserRole{}).
+				Distinct("user_id").
+				Where("role_id IN ?", changedRoleIDs).
+				Pluck("user_id", &affectedUsers).Error; err != nil {
+				return apperr.Wrap(constant.CommonInternal, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再清理缓存，避免并发请求读到旧数据后重新写入缓存
+	for _, uid := range affectedUsers {
+		s.invalidateUserCache(uid)
+	}
+	if diff.HasChanges {
+		logger.InfoCtx(ctx, map[string]any{
+			"action":              "rbac_policy_apply",
+			"message":             "RBAC 策略已应用",
+			"roles_created":       diff.RolesCreated,
+			"roles_updated":       diff.RolesUpdated,
+			"permissions_created": diff.PermissionsCreated,
+			"permissions_updated": diff.PermissionsUpdated,
+			"affected_users":      len(affectedUsers),
+		})
+	}
+	return diff, nil
+}
+
+// diffRBACPolicy 计算策略相对数据库当前配置的差异
+func diffRBACPolicy(policy *RBACPolicy, state *rbacState) *response.RBACPolicyDiffResponse {
+	diff := &response.RBACPolicyDiffResponse{
+		PermissionsCreated:   []string{},
+		PermissionsUpdated:   []string{},
+		RolesCreated:         []string{},
+		RolesUpdated:         []string{},
+		BindingsAdded:        []response.RBACPolicyBindingChange{},
+		BindingsRemoved:      []response.RBACPolicyBindingChange{},
+		UnmanagedRoles:       []string{},
+		UnmanagedPermissions: []string{},
+	}
+
+	declaredPerms := make(map[string]struct{}, len(policy.Permissions))
+	for _, perm := range policy.Permissions {
+		declaredPerms[perm.Tag] = struct{}{}
+		existing, ok := state.permissions[perm.Tag]
+		switch {
+		case !ok:
+			diff.PermissionsCreated = append(diff.PermissionsCreated, perm.Tag)
+		case existing.Name != perm.Name || existing.Description != perm.Description:
+			diff.PermissionsUpdated = append(diff.PermissionsUpdated, perm.Tag)
+		}
+	}
+
+	declaredRoles := make(map[string]struct{}, len(policy.Roles))
+	for _, role := range policy.Roles {
+		declaredRoles[role.Tag] = struct{}{}
+		existing, ok := state.roles[role.Tag]
+		switch {
+		case !ok:
+			diff.RolesCreated = append(diff.RolesCreated, role.Tag)
+		case existing.Name != role.Name || existing.Description != role.Description:
+			diff.RolesUpdated = append(diff.RolesUpdated, role.Tag)
+		}
+
+		current := state.bindings[role.Tag]
+		desired := make(map[string]struct{}, len(role.Permissions))
+		added := make([]string, 0)
+		for _, tag := range role.Permissions {
+			desired[tag] = struct{}{}
+			if _, ok := current[tag]; !ok {
+				added = append(added, tag)
+			}
+		}
+		removed := make([]string, 0)
+		for tag := range current {
+			if _, ok := desired[tag]; !ok {
+				removed = append(removed, tag)
+			}
+		}
+		slices.Sort(removed)
+		if len(added) > 0 {
+			diff.BindingsAdded = append(diff.BindingsAdded, response.RBACPolicyBindingChange{RoleTag: role.Tag, PermissionTags: added})
+		}
+		if len(removed) > 0 {
+			diff.BindingsRemoved = append(diff.BindingsRemoved, response.RBACPolicyBindingChange{RoleTag: role.Tag, PermissionTags: removed})
+		}
+	}
+
+	for tag := range state.roles {
+		if _, ok := declaredRoles[tag]; !ok {
+			diff.UnmanagedRoles = append(diff.UnmanagedRoles, tag)
+		}
+	}
+	for tag := range state.permissions {
+		if _, ok := declaredPerms[tag]; !ok {
+			diff.UnmanagedPermissions = append(diff.UnmanagedPermissions, tag)
+		}
+	}
+	slices.Sort(diff.UnmanagedRoles)
+	slices.Sort(diff.UnmanagedPermissions)
+
+	diff.HasChanges = len(diff.PermissionsCreated) > 0 || len(diff.PermissionsUpdated) > 0 ||
+		len(diff.RolesCreated) > 0 || len(diff.RolesUpdated) > 0 ||
+		len(diff.BindingsAdded) > 0 || len(diff.BindingsRemoved) > 0
+	return diff
+}
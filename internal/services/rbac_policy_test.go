@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
+	"github.com/TogetherForStudy/jxust-yqlx-server/pkg/constant"
+)
+
+func TestParseRBACPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid policy",
+			data: `
+version: 1
+permissions:
+  - tag: user:get
+    name: 用户资料查看
+roles:
+  - tag: user_basic
+    name: 基本用户
+    permissions: [user:get]
+`,
+		},
+		{
+			name:    "unsupported version",
+			data:    "version: 2\n",
+			wantErr: true,
+		},
+		{
+			name: "unknown field",
+			data: `
+version: 1
+permisions: []
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate permission",
+			data: `
+version: 1
+permissions:
+  - {tag: user:get, name: a}
+  - {tag: user:get, name: b}
+`,
+			wantErr: true,
+		},
+		{
+			name: "undeclared permission binding",
+			data: `
+version: 1
+permissions:
+  - {tag: user:get, name: a}
+roles:
+  - {tag: user_basic, name: 基本用户, permissions: [user:update]}
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRBACPolicy([]byte(tt.data))
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			appErr, ok := apperr.As(err)
+			if !ok || appErr.Code != constant.RBACPolicyInvalid {
+				t.Fatalf("expected RBACPolicyInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiffRBACPolicy(t *testing.T) {
+	state := &rbacState{
+		roles: map[string]models.Role{
+			"user_basic": {ID: 1, RoleTag: "user_basic", Name: "基本用户"},
+			"legacy":     {ID: 2, RoleTag: "legacy", Name: "旧角色"},
+		},
+		permissions: map[string]models.Permission{
+			"user:get":    {ID: 1, PermissionTag: "user:get", Name: "用户资料查看"},
+			"user:update": {ID: 2, PermissionTag: "user:update", Name: "旧名称"},
+			"legacy:get":  {ID: 3, PermissionTag: "legacy:get", Name: "旧权限"},
+		},
+		bindings: map[string]map[string]struct{}{
+			"user_basic": {"user:get": {}, "legacy:get": {}},
+			"legacy":     {},
+		},
+	}
+	policy := &RBACPolicy{
+		Version: RBACPolicyVersion,
+		Permissions: []RBACPolicyPermission{
+			{Tag: "user:get", Name: "用户资料查看"},
+			{Tag: "user:update", Name: "用户资料修改"},
+			{Tag: "chat:study", Name: "学习对话"},
+		},
+		Roles: []RBACPolicyRole{
+			{Tag: "user_basic", Name: "基本用户", Permissions: []string{"user:get", "user:update", "chat:study"}},
+			{Tag: "operator", Name: "运营", Permissions: []string{}},
+		},
+	}
+
+	diff := diffRBACPolicy(policy, state)
+
+	if !diff.HasChanges {
+		t.Fatal("expected changes")
+	}
+	if !slices.Equal(diff.PermissionsCreated, []string{"chat:study"}) {
+		t.Fatalf("permissions created = %v", diff.PermissionsCreated)
+	}
+	if !slices.Equal(diff.PermissionsUpdated, []string{"user:update"}) {
+		t.Fatalf("permissions updated = %v", diff.PermissionsUpdated)
+	}
+	if !slices.Equal(diff.RolesCreated, []string{"operator"}) {
+		t.Fatalf("roles created = %v", diff.RolesCreated)
+	}
+	if len(diff.RolesUpdated) != 0 {
+		t.Fatalf("roles updated = %v", diff.RolesUpdated)
+	}
+	if len(diff.BindingsAdded) != 1 || !slices.Equal(diff.BindingsAdded[0].PermissionTags, []string{"user:update", "chat:study"}) {
+		t.Fatalf("bindings added = %+v", diff.BindingsAdded)
+	}
+	if len(diff.BindingsRemoved) != 1 || !slices.Equal(diff.BindingsRemoved[0].PermissionTags, []string{"legacy:get"}) {
+		t.Fatalf("bindings removed = %+v", diff.BindingsRemoved)
+	}
+	if !slices.Equal(diff.UnmanagedRoles, []string{"legacy"}) {
+		t.Fatalf("unmanaged roles = %v", diff.UnmanagedRoles)
+	}
+	if !slices.Equal(diff.UnmanagedPermissions, []string{"legacy:get"}) {
+		t.Fatalf("unmanaged permissions = %v", diff.UnmanagedPermissions)
+	}
+}
+
+func TestDiffRBACPolicyNoChanges(t *testing.T) {
+	state := &rbacState{
+		roles:       map[string]models.Role{"user_basic": {ID: 1, RoleTag: "user_basic", Name: "基本用户"}},
+		permissions: map[string]models.Permission{"user:get": {ID: 1, PermissionTag: "user:get", Name: "用户资料查看"}},
+		bindings:    map[string]map[string]struct{}{"user_basic": {"user:get": {}}},
+	}
+	policy := &RBACPolicy{
+		Version:     RBACPolicyVersion,
+		Permissions: []RBACPolicyPermission{{Tag: "user:get", Name: "用户资料查看"}},
+		Roles:       []RBACPolicyRole{{Tag: "user_basic", Name: "基本用户", Permissions: []string{"user:get"}}},
+	}
+
+	if diff := diffRBACPolicy(policy, state); diff.HasChanges {
+		t.Fatalf("expected no changes, got %+v", diff)
+	}
+}
+
+func TestDefaultRBACPolicy(t *testing.T) {
+	policy, err := DefaultRBACPolicy()
+	if err != nil {
+		t.Fatalf("DefaultRBACPolicy() error = %v", err)
+	}
+
+	roles := make(map[string]RBACPolicyRole, len(policy.Roles))
+	for _, role := range policy.Roles {
+		roles[role.Tag] = role
+	}
+	for _, tag := range []string{
+		constant.RoleTagUserBasic, constant.RoleTagUserActive, constant.RoleTagUserVerified,
+		constant.RoleTagOperator, constant.RoleTagAdmin,
+	} {
+		if _, ok := roles[tag]; !ok {
+			t.Fatalf("default policy should declare role %s", tag)
+		}
+	}
+	for _, perm := range policy.Permissions {
+		if !slices.Contains(roles[constant.RoleTagAdmin].Permissions, perm.Tag) {
+			t.Fatalf("admin should be bound to %s", perm.Tag)
+		}
+	}
+}
+
+func TestSeedDefaultsAppliesDefaultPolicy(t *testing.T) {
+	db := newHarnessDB(t)
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.UserRole{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	s := &RBACService{db: db, cache: newMemoryStreamCache()}
+	ctx := context.Background()
+
+	if err := s.SeedDefaults(ctx); err != nil {
+		t.Fatalf("SeedDefaults() error = %v", err)
+	}
+	policy, err := DefaultRBACPolicy()
+	if err != nil {
+		t.Fatalf("DefaultRBACPolicy() error = %v", err)
+	}
+	exported, err := s.ExportPolicy(ctx)
+	if err != nil {
+		t.Fatalf("ExportPolicy() error = %v", err)
+	}
+	if !slices.EqualFunc(exported.Roles, policy.Roles, func(a, b RBACPolicyRole) bool {
+		return a.Tag == b.Tag && a.Name == b.Name && slices.Equal(a.Permissions, b.Permissions)
+	}) || !slices.Equal(exported.Permissions, policy.Permissions) {
+		t.Fatal("exported policy should match the default policy file")
+	}
+
+	diff, err := s.DiffPolicy(ctx, policy)
+	if err != nil || diff.HasChanges {
+		t.Fatalf("seeding twice should be a no-op, got %+v, %v", diff, err)
+	}
+}
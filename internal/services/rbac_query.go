@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,46 +14,89 @@ func whereNotDeleted(db *gorm.DB, tableAliases ...string) *gorm.DB {
 	return db
 }
 
+// whereUserRoleActive 过滤已过期的临时角色授权
+func whereUserRoleActive(db *gorm.DB, tableAlias string, now time.Time) *gorm.DB {
+	return db.Where(fmt.Sprintf("(%s.expires_at IS NULL OR %s.expires_at > ?)", tableAlias, tableAlias), now)
+}
+
 func userRoleTagsQuery(db *gorm.DB, userID uint) *gorm.DB {
 	return whereNotDeleted(
-		db.Table("roles").
-			Select("DISTINCT roles.role_tag").
-			Joins("JOIN user_roles ur ON ur.role_id = roles.id").
-			Where("ur.user_id = ?", userID),
+		whereUserRoleActive(
+			db.Table("roles").
+				Select("DISTINCT roles.role_tag").
+				Joins("JOIN user_roles ur ON ur.role_id = roles.id").
+				Where("ur.user_id = ?", userID),
+			"ur", time.Now(),
+		),
 		"roles", "ur",
 	)
 }
 
 func userPermissionTagsQuery(db *gorm.DB, userID uint) *gorm.DB {
 	return whereNotDeleted(
-		db.Table("permissions").
-			Select("DISTINCT permissions.permission_tag").
-			Joins("JOIN role_permissions rp ON rp.permission_id = permissions.id").
-			Joins("JOIN user_roles ur ON ur.role_id = rp.role_id").
-			Where("ur.user_id = ?", userID),
+		whereUserRoleActive(
+			db.Table("permissions").
+				Select("DISTINCT permissions.permission_tag").
+				Joins("JOIN role_permissions rp ON rp.permission_id = permissions.id").
+				Joins("JOIN user_roles ur ON ur.role_id = rp.role_id").
+				Where("ur.user_id = ?", userID),
+			"ur", time.Now(),
+		),
 		"permissions", "rp", "ur",
 	)
 }
 
+// userRoleEarliestExpiryQuery 查询用户有效临时授权中最早的过期时间，用于限制权限快照的缓存时长
+func userRoleEarliestExpiryQuery(db *gorm.DB, userID uint) *gorm.DB {
+	now := time.Now()
+	return whereNotDeleted(
+		db.Table("user_roles ur").
+			Select("MIN(ur.expires_at)").
+			Where("ur.user_id = ?", userID).
+			Where("ur.expires_at IS NOT NULL AND ur.expires_at > ?", now),
+		"ur",
+	)
+}
+
 func usersByRoleTagsQuery(db *gorm.DB, roleTags []string) *gorm.DB {
 	return whereNotDeleted(
-		db.Table("users").
-			Select("DISTINCT users.*").
-			Joins("JOIN user_roles ur ON ur.user_id = users.id").
-			Joins("JOIN roles r ON r.id = ur.role_id").
-			Where("r.role_tag IN ?", roleTags),
+		whereUserRoleActive(
+			db.Table("users").
+				Select("DISTINCT users.*").
+				Joins("JOIN user_roles ur ON ur.user_id = users.id").
+				Joins("JOIN roles r ON r.id = ur.role_id").
+				Where("r.role_tag IN ?", roleTags),
+			"ur", time.Now(),
+		),
 		"users", "ur", "r",
 	)
 }
 
 func backofficeUsersByPhoneQuery(db *gorm.DB, phone string) *gorm.DB {
 	return whereNotDeleted(
-		db.Table("users").
-			Distinct("users.id").
-			Joins("JOIN user_roles ur ON ur.user_id = users.id").
-			Joins("JOIN roles ON roles.id = ur.role_id").
-			Where("users.phone = ?", phone).
-			Where("roles.role_tag IN ?", backofficeRoleTags),
+		whereUserRoleActive(
+			db.Table("users").
+				Distinct("users.id").
+				Joins("JOIN user_roles ur ON ur.user_id = users.id").
+				Joins("JOIN roles ON roles.id = ur.role_id").
+				Where("users.phone = ?", phone).
+				Where("roles.role_tag IN ?", backofficeRoleTags),
+			"ur", time.Now(),
+		),
 		"users", "ur", "roles",
 	)
 }
+
+// expiringUserRolesQuery 查询在指定时间窗口内即将过期的临时角色授权
+func expiringUserRolesQuery(db *gorm.DB, from, to time.Time) *gorm.DB {
+	return whereNotDeleted(
+		db.Table("user_roles ur").
+			Select("ur.id, ur.user_id, ur.role_id, ur.granted_by, ur.expires_at, ur.created_at, "+
+				"roles.role_tag, roles.name AS role_name, users.nickname, users.real_name, users.student_id").
+			Joins("JOIN roles ON roles.id = ur.role_id").
+			Joins("JOIN users ON users.id = ur.user_id").
+			Where("ur.expires_at IS NOT NULL AND ur.expires_at > ? AND ur.expires_at <= ?", from, to).
+			Order("ur.expires_at ASC"),
+		"ur", "roles", "users",
+	)
+}
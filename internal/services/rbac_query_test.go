@@ -6,6 +6,7 @@ import (
 	"database/sql/driver"
 	"strings"
 	"testing"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -179,3 +180,81 @@ func TestRBACQueriesFilterSoftDeletedRecords(t *testing.T) {
 		})
 	}
 }
+
+func TestRBACQueriesFilterExpiredRoleGrants(t *testing.T) {
+	db := newDryRunDB(t)
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "user role tags query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var roleTags []string
+				return userRoleTagsQuery(tx, 123).Find(&roleTags)
+			}),
+			want: []string{"(ur.expires_at is null or ur.expires_at >"},
+		},
+		{
+			name: "user permission tags query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var permissionTags []string
+				return userPermissionTagsQuery(tx, 123).Find(&permissionTags)
+			}),
+			want: []string{"(ur.expires_at is null or ur.expires_at >"},
+		},
+		{
+			name: "users by role tags query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return usersByRoleTagsQuery(tx, []string{"admin"}).Find(&[]struct{}{})
+			}),
+			want: []string{"(ur.expires_at is null or ur.expires_at >"},
+		},
+		{
+			name: "backoffice users by phone query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var userIDs []uint
+				return backofficeUsersByPhoneQuery(tx, "13800138000").Pluck("users.id", &userIDs)
+			}),
+			want: []string{"(ur.expires_at is null or ur.expires_at >"},
+		},
+		{
+			name: "user role earliest expiry query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var expiresAt sql.NullTime
+				return userRoleEarliestExpiryQuery(tx, 123).Scan(&expiresAt)
+			}),
+			want: []string{
+				"min(ur.expires_at)",
+				"ur.expires_at is not null",
+				"ur.deleted_at is null",
+			},
+		},
+		{
+			name: "expiring user roles query",
+			sql: db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return expiringUserRolesQuery(tx, now, now.Add(time.Hour)).Find(&[]struct{}{})
+			}),
+			want: []string{
+				"ur.expires_at <=",
+				"ur.deleted_at is null",
+				"roles.deleted_at is null",
+				"order by ur.expires_at asc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := strings.ToLower(tt.sql)
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Fatalf("expected SQL to contain %q, got: %s", want, tt.sql)
+				}
+			}
+		})
+	}
+}
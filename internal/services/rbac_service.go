@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"slices"
 	"time"
 
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/dto/response"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/apperr"
 	"github.com/TogetherForStudy/jxust-yqlx-server/internal/pkg/cache"
@@ -28,13 +30,25 @@ type UserPermissionSnapshot struct {
 	PermissionTags []string  `json:"permission_tags"`
 	IsAdmin        bool      `json:"is_admin"`
 	CachedAt       time.Time `json:"cached_at"`
+	// ExpiresAt 快照中最早过期的临时角色授权时间，到期后快照失效需重新计算
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// isStale 判断快照是否因临时授权到期而失效
+func (snap *UserPermissionSnapshot) isStale(now time.Time) bool {
+	return snap.ExpiresAt != nil && !now.Before(*snap.ExpiresAt)
+}
+
+// HasPermission 判断快照是否包含指定权限，管理员拥有全部权限
+func (snap *UserPermissionSnapshot) HasPermission(permission string) bool {
+	return snap.IsAdmin || slices.Contains(snap.PermissionTags, permission)
 }
 
 // NewRBACService 创建 RBAC 服务
 func NewRBACService(db *gorm.DB) *RBACService {
 	return &RBACService{
 		db:    db,
-		cache: cache.GlobalCache,
+		cache: cache.GlobalTieredCache,
 	}
 }
 
@@ -43,212 +57,15 @@ func (s *RBACService) cacheKey(userID uint) string {
 	return fmt.Sprintf("rbac:user:%d:permissions", userID)
 }
 
-// SeedDefaults 初始化设计文档中预置的角色/权限与绑定关系
+// SeedDefaults 将预置策略 rbac_default_policy.yaml 应用到数据库
 func (s *RBACService) SeedDefaults(ctx context.Context) error {
-	roleSeeds := []models.Role{
-		{RoleTag: constant.RoleTagUserBasic, Name: "基本用户", Description: "默认角色"},
-		{RoleTag: constant.RoleTagUserActive, Name: "活跃用户", Description: "活跃度达标解锁"},
-		{RoleTag: constant.RoleTagUserVerified, Name: "认证用户", Description: "完成校内身份认证"},
-		{RoleTag: constant.RoleTagOperator, Name: "运营", Description: "运营"},
-		{RoleTag: constant.RoleTagAdmin, Name: "管理", Description: "管理"},
-	}
-
-	permissionSeeds := []models.Permission{
-		{PermissionTag: constant.PermissionUserGet, Name: "用户资料查看", Description: ""},
-		{PermissionTag: constant.PermissionUserUpdate, Name: "用户资料修改", Description: ""},
-		{PermissionTag: constant.PermissionOSSTokenGet, Name: "获取OSS Token", Description: ""},
-		{PermissionTag: constant.PermissionReviewCreate, Name: "发布点评", Description: ""},
-		{PermissionTag: constant.PermissionReviewGetSelf, Name: "查看本人点评", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableGet, Name: "查看课表", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableClassSearch, Name: "搜索班级", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableClassUpdate, Name: "更新本人班级", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableClassUpdateAll, Name: "管理员更新班级", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableUpdate, Name: "更新个人课表", Description: ""},
-		{PermissionTag: constant.PermissionFailRate, Name: "挂科率查询", Description: ""},
-		{PermissionTag: constant.PermissionFailRateManage, Name: "挂科率管理", Description: ""},
-		{PermissionTag: constant.PermissionPointGet, Name: "积分查看", Description: ""},
-		{PermissionTag: constant.PermissionPointSpend, Name: "积分消费", Description: ""},
-		{PermissionTag: constant.PermissionPointManage, Name: "积分管理", Description: ""},
-		{PermissionTag: constant.PermissionStatisticGet, Name: "统计查看", Description: ""},
-		{PermissionTag: constant.PermissionStatisticManage, Name: "后台统计管理", Description: ""},
-		{PermissionTag: constant.PermissionContributionGet, Name: "投稿查看", Description: ""},
-		{PermissionTag: constant.PermissionContributionCreate, Name: "投稿创建", Description: ""},
-		{PermissionTag: constant.PermissionCountdown, Name: "倒数日", Description: ""},
-		{PermissionTag: constant.PermissionStudyTask, Name: "学习任务", Description: ""},
-		{PermissionTag: constant.PermissionMaterialGet, Name: "资料查看", Description: ""},
-		{PermissionTag: constant.PermissionMaterialRate, Name: "资料评分", Description: ""},
-		{PermissionTag: constant.PermissionMaterialDownload, Name: "资料下载", Description: ""},
-		{PermissionTag: constant.PermissionMaterialCategoryGet, Name: "资料分类查看", Description: ""},
-		{PermissionTag: constant.PermissionQuestionProjectManage, Name: "题库项目管理", Description: ""},
-		{PermissionTag: constant.PermissionQuestion, Name: "刷题访问", Description: ""},
-		{PermissionTag: constant.PermissionQuestionManage, Name: "题目管理", Description: ""},
-		{PermissionTag: constant.PermissionPomodoro, Name: "番茄钟", Description: ""},
-		{PermissionTag: constant.PermissionDictionary, Name: "每日一词", Description: ""},
-		{PermissionTag: constant.PermissionChatStudy, Name: "学习对话", Description: ""},
-		{PermissionTag: constant.PermissionOrganizationGet, Name: "组织查询", Description: ""},
-		{PermissionTag: constant.PermissionNotificationGet, Name: "通知查看", Description: ""},
-
-		{PermissionTag: constant.PermissionReviewManage, Name: "点评管理", Description: ""},
-		{PermissionTag: constant.PermissionCourseTableManage, Name: "课表管理", Description: ""},
-		{PermissionTag: constant.PermissionHeroManage, Name: "英雄榜管理", Description: ""},
-		{PermissionTag: constant.PermissionConfigManage, Name: "配置管理", Description: ""},
-		{PermissionTag: constant.PermissionContributionManage, Name: "投稿管理", Description: ""},
-		{PermissionTag: constant.PermissionNotificationGetAdmin, Name: "通知后台查看", Description: ""},
-		{PermissionTag: constant.PermissionNotificationCreate, Name: "通知创建", Description: ""},
-		{PermissionTag: constant.PermissionNotificationPublish, Name: "通知发布", Description: ""},
-		{PermissionTag: constant.PermissionNotificationUpdate, Name: "通知更新", Description: ""},
-		{PermissionTag: constant.PermissionNotificationApprove, Name: "通知审核", Description: ""},
-		{PermissionTag: constant.PermissionNotificationSchedule, Name: "通知排期", Description: ""},
-		{PermissionTag: constant.PermissionNotificationPin, Name: "通知置顶/撤销", Description: ""},
-		{PermissionTag: constant.PermissionNotificationDelete, Name: "通知删除", Description: ""},
-		{PermissionTag: constant.PermissionNotificationPublishAdmin, Name: "通知直发", Description: ""},
-		{PermissionTag: constant.PermissionNotificationCategoryManage, Name: "通知分类管理", Description: ""},
-		{PermissionTag: constant.PermissionFeatureManage, Name: "功能管理", Description: ""},
-		{PermissionTag: constant.PermissionUserManage, Name: "用户管理", Description: ""},
-		{PermissionTag: constant.PermissionMaterialManage, Name: "资料管理", Description: ""},
-		{PermissionTag: constant.PermissionS3Manage, Name: "S3管理", Description: ""},
-		{PermissionTag: constant.PermissionOrganizationManage, Name: "组织管理", Description: ""},
-	}
-
-	allPermissionTags := make([]string, 0, len(permissionSeeds))
-	for _, perm := range permissionSeeds {
-		allPermissionTags = append(allPermissionTags, perm.PermissionTag)
-	}
-
-	roleBindings := map[string][]string{
-		// 基础用户：常规读写
-		constant.RoleTagUserBasic: {
-			constant.PermissionUserGet,
-			constant.PermissionUserUpdate,
-			constant.PermissionOSSTokenGet,
-			constant.PermissionReviewCreate,
-			constant.PermissionReviewGetSelf,
-			constant.PermissionCourseTableGet,
-			constant.PermissionCourseTableClassSearch,
-			constant.PermissionCourseTableClassUpdate,
-			constant.PermissionCourseTableUpdate,
-			constant.PermissionFailRate,
-			constant.PermissionPointGet,
-			constant.PermissionPointSpend,
-			constant.PermissionStatisticGet,
-			constant.PermissionContributionGet,
-			constant.PermissionContributionCreate,
-			constant.PermissionCountdown,
-			constant.PermissionStudyTask,
-			constant.PermissionMaterialGet,
-			constant.PermissionMaterialRate,
-			constant.PermissionMaterialDownload,
-			constant.PermissionMaterialCategoryGet,
-			constant.PermissionNotificationGet,
-			constant.PermissionQuestion,
-			constant.PermissionPomodoro,
-			constant.PermissionDictionary,
-			constant.PermissionChatStudy,
-			constant.PermissionOrganizationGet,
-		},
-		constant.RoleTagUserActive: {
-			constant.PermissionCourseTableClassUpdateAll,
-		},
-		constant.RoleTagUserVerified: {},
-		// 运营：
-		constant.RoleTagOperator: {
-			constant.PermissionContributionManage,
-			constant.PermissionNotificationGetAdmin,
-			constant.PermissionNotificationCreate,
-			constant.PermissionNotificationPublish,
-			constant.PermissionNotificationUpdate,
-			constant.PermissionNotificationApprove,
-			constant.PermissionNotificationSchedule,
-		},
-		// 管理：拥有全部权限
-		constant.RoleTagAdmin: allPermissionTags,
-	}
-
-	// 创建/更新角色
-	for _, role := range roleSeeds {
-		var existing models.Role
-		err := s.db.WithContext(ctx).Where("role_tag = ?", role.RoleTag).First(&existing).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if err := s.db.WithContext(ctx).Create(&role).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("初始化角色失败: %w", err))
-			}
-			continue
-		}
-		if err != nil {
-			return apperr.Wrap(constant.CommonInternal, err)
-		}
-		if existing.Name != role.Name || existing.Description != role.Description {
-			if err := s.db.WithContext(ctx).Model(&existing).Updates(map[string]any{
-				"name":        role.Name,
-				"description": role.Description,
-			}).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新角色信息失败: %w", err))
-			}
-		}
+	policy, err := DefaultRBACPolicy()
+	if err != nil {
+		return fmt.Errorf("解析RBAC预置策略失败: %w", err)
 	}
-
-	// 创建/更新权限
-	for _, perm := range permissionSeeds {
-		var existing models.Permission
-		err := s.db.WithContext(ctx).Where("permission_tag = ?", perm.PermissionTag).First(&existing).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if err := s.db.WithContext(ctx).Create(&perm).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("初始化权限失败: %w", err))
-			}
-			continue
-		}
-		if err != nil {
-			return apperr.Wrap(constant.CommonInternal, err)
-		}
-		if existing.Name != perm.Name || existing.Description != perm.Description {
-			if err := s.db.WithContext(ctx).Model(&existing).Updates(map[string]any{
-				"name":        perm.Name,
-				"description": perm.Description,
-			}).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("更新权限信息失败: %w", err))
-			}
-		}
-	}
-
-	// 绑定角色权限
-	return s.bindRolePermissions(ctx, roleBindings)
-}
-
-// bindRolePermissions 按照绑定关系创建角色-权限关联
-func (s *RBACService) bindRolePermissions(ctx context.Context, bindings map[string][]string) error {
-	for roleTag, permTags := range bindings {
-		var role models.Role
-		if err := s.db.WithContext(ctx).Where("role_tag = ?", roleTag).First(&role).Error; err != nil {
-			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询角色失败[%s]: %w", roleTag, err))
-		}
-
-		var perms []models.Permission
-		if len(permTags) > 0 {
-			if err := s.db.WithContext(ctx).
-				Where("permission_tag IN ?", permTags).
-				Find(&perms).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, fmt.Errorf("查询权限失败: %w", err))
-			}
-		}
-
-		if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, err)
-			}
-			for _, perm := range perms {
-				rp := models.RolePermission{
-					RoleID:       role.ID,
-					PermissionID: perm.ID,
-				}
-				if err := tx.Where("role_id = ? AND permission_id = ?", role.ID, perm.ID).
-					FirstOrCreate(&rp).Error; err != nil {
-					return apperr.Wrap(constant.CommonInternal, err)
-				}
-			}
-			return nil
-		}); err != nil {
-			return apperr.Wrap(constant.CommonInternal, fmt.Errorf("绑定角色权限失败[%s]: %w", roleTag, err))
-		}
+	// 与策略文件共用同一套应用逻辑：创建/更新角色与权限，并重置预置角色的权限绑定
+	if _, err := s.ApplyPolicy(ctx, policy); err != nil {
+		return fmt.Errorf("初始化RBAC预置数据失败: %w", err)
 	}
 	return nil
 }
@@ -274,12 +91,14 @@ func (s *RBACService) ListRolesWithUsers(ctx context.Context) ([]models.Role, ma
 	roleUserCountMap := make(map[uint]int)
 	roleUserIDsMap := make(map[uint][]uint)
 
-	// 为每个角色统计用户数量
+	// 为每个角色统计用户数量（不含已过期的临时授权）
+	now := time.Now()
 	for _, role := range roles {
 		var count int64
 		if err := s.db.WithContext(ctx).
 			Model(&models.UserRole{}).
 			Where("role_id = ?", role.ID).
+			Where("expires_at IS NULL OR expires_at > ?", now).
 			Count(&count).Error; err != nil {
 			return nil, nil, nil, apperr.Wrap(constant.CommonInternal, err)
 		}
@@ -291,6 +110,7 @@ func (s *RBACService) ListRolesWithUsers(ctx context.Context) ([]models.Role, ma
 			if err := s.db.WithContext(ctx).
 				Model(&models.UserRole{}).
 				Where("role_id = ?", role.ID).
+				Where("expires_at IS NULL OR expires_at > ?", now).
 				Pluck("user_id", &userIDs).Error; err != nil {
 				return nil, nil, nil, apperr.Wrap(constant.CommonInternal, err)
 			}
@@ -429,18 +249,19 @@ func (s *RBACService) UpdateRolePermissions(ctx context.Context, roleID uint, pe
 }
 
 // UpdateUserRoles 更新用户角色列表
+// 仍在列表中的角色保留原授权（含过期时间与授权人），只删除被移除的角色并授予新增的角色。
 func (s *RBACService) UpdateUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error; err != nil {
+		removed := tx.Where("user_id = ?", userID)
+		if len(roleIDs) > 0 {
+			removed = removed.Where("role_id NOT IN ?", roleIDs)
+		}
+		if err := removed.Delete(&models.UserRole{}).Error; err != nil {
 			return apperr.Wrap(constant.CommonInternal, err)
 		}
 		for _, rid := range roleIDs {
-			rel := models.UserRole{
-				UserID: userID,
-				RoleID: rid,
-			}
-			if err := tx.Create(&rel).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, err)
+			if err := s.grantRoleTx(tx, userID, rid, 0, nil, false); err != nil {
+				return err
 			}
 		}
 		s.invalidateUserCache(userID)
@@ -478,7 +299,7 @@ func (s *RBACService) GetUserPermissionSnapshot(ctx context.Context, userID uint
 	if s.cache != nil {
 		if cached, err := s.cache.Get(ctx, s.cacheKey(userID)); err == nil && cached != "" {
 			var snap UserPermissionSnapshot
-			if err := json.Unmarshal([]byte(cached), &snap); err == nil {
+			if err := json.Unmarshal([]byte(cached), &snap); err == nil && !snap.isStale(time.Now()) {
 				return &snap, nil
 			}
 		}
@@ -502,16 +323,33 @@ func (s *RBACService) GetUserPermissionSnapshot(ctx context.Context, userID uint
 		return nil, apperr.Wrap(constant.CommonInternal, err)
 	}
 
+	var earliestExpiry sql.NullTime
+	if err := userRoleEarliestExpiryQuery(s.db.WithContext(ctx), userID).Row().Scan(&earliestExpiry); err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+
 	snap := &UserPermissionSnapshot{
 		RoleTags:       roleTags,
 		PermissionTags: permissionTags,
 		IsAdmin:        isAdmin,
 		CachedAt:       time.Now(),
 	}
+	if earliestExpiry.Valid {
+		snap.ExpiresAt = &earliestExpiry.Time
+	}
 
 	if s.cache != nil {
 		if data, err := json.Marshal(snap); err == nil {
-			_ = s.cache.Set(ctx, s.cacheKey(userID), string(data), nil)
+			// 缓存时长不超过最早到期的临时授权，避免过期后仍命中旧权限
+			ttl := cache.DefaultExpiration
+			if snap.ExpiresAt != nil {
+				if untilExpiry := time.Until(*snap.ExpiresAt); untilExpiry < ttl {
+					ttl = untilExpiry
+				}
+			}
+			if ttl > 0 {
+				_ = s.cache.Set(ctx, s.cacheKey(userID), string(data), &ttl)
+			}
 		}
 	}
 
@@ -524,15 +362,7 @@ func (s *RBACService) CheckPermission(ctx context.Context, userID uint, permissi
 	if err != nil {
 		return false, apperr.Wrap(constant.CommonInternal, err)
 	}
-	if snap.IsAdmin {
-		return true, nil
-	}
-	for _, tag := range snap.PermissionTags {
-		if tag == permissionTag {
-			return true, nil
-		}
-	}
-	return false, nil
+	return snap.HasPermission(permissionTag), nil
 }
 
 // GetUserRoleTags 获取用户角色标签
@@ -578,25 +408,74 @@ func (s *RBACService) GetUsersByRoleTags(ctx context.Context, roleTags []string)
 	return users, nil
 }
 
-// GrantRole 授予用户角色（如果不存在）
+// GrantRole 授予用户角色（如果不存在），永久有效。
+// 用户已拥有未过期的该角色时保持原授权不变，不会改动管理员设置的过期时间。
 func (s *RBACService) GrantRole(ctx context.Context, userID uint, roleID uint) error {
+	return s.grantRole(ctx, userID, roleID, 0, nil, false)
+}
+
+// GrantRoleUntil 授予用户角色并指定过期时间，expiresAt 为 nil 表示永久有效。
+// 若用户已拥有该角色，则以本次授权的过期时间为准进行刷新。
+func (s *RBACService) GrantRoleUntil(ctx context.Context, userID, roleID, grantedBy uint, expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return apperr.New(constant.RBACRoleGrantExpiryInvalid)
+	}
+	return s.grantRole(ctx, userID, roleID, grantedBy, expiresAt, true)
+}
+
+// grantRole 授予用户角色；已有授权时，refresh 为 true 或原授权已过期才更新过期时间
+func (s *RBACService) grantRole(ctx context.Context, userID, roleID, grantedBy uint, expiresAt *time.Time, refresh bool) error {
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var rel models.UserRole
-		err := tx.Where("user_id = ? AND role_id = ?", userID, roleID).First(&rel).Error
+		return s.grantRoleTx(tx, userID, roleID, grantedBy, expiresAt, refresh)
+	})
+}
+
+func (s *RBACService) grantRoleTx(tx *gorm.DB, userID, roleID, grantedBy uint, expiresAt *time.Time, refresh bool) error {
+	var role models.Role
+	if err := tx.Select("id").Where("id = ?", roleID).First(&role).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			rel = models.UserRole{
-				UserID: userID,
-				RoleID: roleID,
-			}
-			if err := tx.Create(&rel).Error; err != nil {
-				return apperr.Wrap(constant.CommonInternal, err)
-			}
-			s.invalidateUserCache(userID)
-		} else if err != nil {
+			return apperr.New(constant.RBACRoleNotFound)
+		}
+		return apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	var rel models.UserRole
+	err := tx.Where("user_id = ? AND role_id = ?", userID, roleID).First(&rel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rel = models.UserRole{
+			UserID:    userID,
+			RoleID:    roleID,
+			GrantedBy: grantedBy,
+			ExpiresAt: expiresAt,
+		}
+		if err := tx.Create(&rel).Error; err != nil {
 			return apperr.Wrap(constant.CommonInternal, err)
 		}
+		s.invalidateUserCache(userID)
 		return nil
-	})
+	} else if err != nil {
+		return apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	if sameExpiry(rel.ExpiresAt, expiresAt) || (!refresh && !rel.IsExpired()) {
+		return nil
+	}
+	updates := map[string]any{"expires_at": expiresAt}
+	if grantedBy != 0 {
+		updates["granted_by"] = grantedBy
+	}
+	if err := tx.Model(&rel).Updates(updates).Error; err != nil {
+		return apperr.Wrap(constant.CommonInternal, err)
+	}
+	s.invalidateUserCache(userID)
+	return nil
+}
+
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
 }
 
 // RevokeRole 撤销用户角色
@@ -610,6 +489,57 @@ func (s *RBACService) RevokeRole(ctx context.Context, userID uint, roleID uint)
 	})
 }
 
+// RevokeExpiredRoles 撤销所有已过期的临时角色授权，返回撤销的授权数量
+func (s *RBACService) RevokeExpiredRoles(ctx context.Context) (int, error) {
+	var expired []models.UserRole
+	if err := s.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Find(&expired).Error; err != nil {
+		return 0, apperr.Wrap(constant.CommonInternal, err)
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, 0, len(expired))
+	for _, rel := range expired {
+		ids = append(ids, rel.ID)
+	}
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Delete(&models.UserRole{}).Error; err != nil {
+		return 0, apperr.Wrap(constant.CommonInternal, err)
+	}
+
+	invalidated := make(map[uint]struct{}, len(expired))
+	for _, rel := range expired {
+		if _, ok := invalidated[rel.UserID]; ok {
+			continue
+		}
+		invalidated[rel.UserID] = struct{}{}
+		s.invalidateUserCache(rel.UserID)
+		logger.InfoCtx(ctx, map[string]any{
+			"action":         "revoke_expired_role",
+			"message":        "临时角色授权已到期撤销",
+			"target_user_id": rel.UserID,
+			"role_id":        rel.RoleID,
+		})
+	}
+	return len(expired), nil
+}
+
+// ListExpiringRoleGrants 列出在指定时长内即将到期的临时角色授权
+func (s *RBACService) ListExpiringRoleGrants(ctx context.Context, within time.Duration) ([]response.ExpiringRoleGrantResponse, error) {
+	now := time.Now()
+	var grants []response.ExpiringRoleGrantResponse
+	if err := expiringUserRolesQuery(s.db.WithContext(ctx), now, now.Add(within)).
+		Scan(&grants).Error; err != nil {
+		return nil, apperr.Wrap(constant.CommonInternal, err)
+	}
+	if grants == nil {
+		grants = []response.ExpiringRoleGrantResponse{}
+	}
+	return grants, nil
+}
+
 // invalidateUserCache 清理用户权限缓存
 func (s *RBACService) invalidateUserCache(userID uint) {
 	if s.cache == nil {
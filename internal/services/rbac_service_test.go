@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TogetherForStudy/jxust-yqlx-server/internal/models"
+)
+
+func TestGrantRoleKeepsExistingExpiry(t *testing.T) {
+	db := newHarnessDB(t)
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.UserRole{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	role := models.Role{RoleTag: "user_active", Name: "活跃用户"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	s := &RBACService{db: db, cache: newMemoryStreamCache()}
+	ctx := context.Background()
+
+	loadExpiry := func(userID uint) *time.Time {
+		t.Helper()
+		var rel models.UserRole
+		if err := db.Where("user_id = ? AND role_id = ?", userID, role.ID).First(&rel).Error; err != nil {
+			t.Fatalf("load grant: %v", err)
+		}
+		return rel.ExpiresAt
+	}
+
+	// 管理员授予的临时角色不会被系统授权改为永久
+	expiresAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	if err := s.GrantRoleUntil(ctx, 1, role.ID, 9, &expiresAt); err != nil {
+		t.Fatalf("GrantRoleUntil() error = %v", err)
+	}
+	if err := s.GrantRole(ctx, 1, role.ID); err != nil {
+		t.Fatalf("GrantRole() error = %v", err)
+	}
+	if got := loadExpiry(1); got == nil || !got.Equal(expiresAt) {
+		t.Fatalf("expiry = %v, want %v", got, expiresAt)
+	}
+
+	// 管理接口仍可修改过期时间
+	if err := s.GrantRoleUntil(ctx, 1, role.ID, 9, nil); err != nil {
+		t.Fatalf("GrantRoleUntil() error = %v", err)
+	}
+	if got := loadExpiry(1); got != nil {
+		t.Fatalf("admin grant should make the role permanent, got %v", got)
+	}
+
+	// 已过期的授权视为不存在，系统授权重新生效
+	expired := time.Now().Add(-time.Hour)
+	if err := db.Create(&models.UserRole{UserID: 2, RoleID: role.ID, ExpiresAt: &expired}).Error; err != nil {
+		t.Fatalf("create grant: %v", err)
+	}
+	if err := s.GrantRole(ctx, 2, role.ID); err != nil {
+		t.Fatalf("GrantRole() error = %v", err)
+	}
+	if got := loadExpiry(2); got != nil {
+		t.Fatalf("expired grant should be renewed, got %v", got)
+	}
+}
+
+func TestUpdateUserRolesKeepsTemporaryGrant(t *testing.T) {
+	db := newHarnessDB(t)
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.UserRole{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	basic := models.Role{RoleTag: "user_basic", Name: "基本用户"}
+	active := models.Role{RoleTag: "user_active", Name: "活跃用户"}
+	operator := models.Role{RoleTag: "operator", Name: "运营"}
+	for _, role := range []*models.Role{&basic, &active, &operator} {
+		if err := db.Create(role).Error; err != nil {
+			t.Fatalf("create role: %v", err)
+		}
+	}
+	s := &RBACService{db: db, cache: newMemoryStreamCache()}
+	ctx := context.Background()
+
+	if err := s.GrantRole(ctx, 1, basic.ID); err != nil {
+		t.Fatalf("GrantRole() error = %v", err)
+	}
+	expiresAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	if err := s.GrantRoleUntil(ctx, 1, active.ID, 9, &expiresAt); err != nil {
+		t.Fatalf("GrantRoleUntil() error = %v", err)
+	}
+
+	// 移除基本用户、新增运营，临时授予的活跃用户保持不变
+	if err := s.UpdateUserRoles(ctx, 1, []uint{active.ID, operator.ID}); err != nil {
+		t.Fatalf("UpdateUserRoles() error = %v", err)
+	}
+	var rels []models.UserRole
+	if err := db.Where("user_id = ?", 1).Order("role_id").Find(&rels).Error; err != nil {
+		t.Fatalf("load grants: %v", err)
+	}
+	if len(rels) != 2 || rels[0].RoleID != active.ID || rels[1].RoleID != operator.ID {
+		t.Fatalf("grants = %+v", rels)
+	}
+	if rels[0].ExpiresAt == nil || !rels[0].ExpiresAt.Equal(expiresAt) || rels[0].GrantedBy != 9 {
+		t.Fatalf("temporary grant changed: %+v", rels[0])
+	}
+	if rels[1].ExpiresAt != nil {
+		t.Fatalf("new grant should be permanent, got %v", rels[1].ExpiresAt)
+	}
+
+	if err := s.UpdateUserRoles(ctx, 1, nil); err != nil {
+		t.Fatalf("UpdateUserRoles() error = %v", err)
+	}
+	var remaining int64
+	if err := db.Model(&models.UserRole{}).Where("user_id = ?", 1).Count(&remaining).Error; err != nil || remaining != 0 {
+		t.Fatalf("remaining grants = %d, %v", remaining, err)
+	}
+}
@@ -15,14 +15,16 @@ import (
 )
 
 type ReviewService struct {
-	db            *gorm.DB
-	pointsService *PointsService
+	db                *gorm.DB
+	pointsService     *PointsService
+	moderationService *ModerationService
 }
 
-func NewReviewService(db *gorm.DB, pointsService *PointsService) *ReviewService {
+func NewReviewService(db *gorm.DB, pointsService *PointsService, moderationService *ModerationService) *ReviewService {
 	return &ReviewService{
-		db:            db,
-		pointsService: pointsService,
+		db:                db,
+		pointsService:     pointsService,
+		moderationService: moderationService,
 	}
 }
 
@@ -37,13 +39,19 @@ func (s *ReviewService) CreateReview(ctx context.Context, userID uint, req *requ
 		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("检查评价记录失败: %w", err))
 	}
 
+	// 内容审核：拦截时直接返回，打码时保存打码后的内容
+	decision, err := s.moderationService.Moderate(ctx, constant.ModerationSurfaceTeacherReview, userID, req.Content)
+	if err != nil {
+		return err
+	}
+
 	// 创建评价
 	review := &models.TeacherReview{
 		UserID:      userID,
 		TeacherName: req.TeacherName,
 		Campus:      req.Campus,
 		CourseName:  req.CourseName,
-		Content:     req.Content,
+		Content:     decision.Text,
 		Attitude:    req.Attitude,
 		Status:      models.TeacherReviewStatusPending,
 		CreatedAt:   time.Now(),
@@ -53,6 +61,7 @@ func (s *ReviewService) CreateReview(ctx context.Context, userID uint, req *requ
 	if err := s.db.WithContext(ctx).Create(review).Error; err != nil {
 		return apperr.Wrap(constant.CommonInternal, fmt.Errorf("创建评价失败: %w", err))
 	}
+	s.moderationService.Audit(ctx, decision, moderationTargetID(review.ID))
 	return nil
 }
 
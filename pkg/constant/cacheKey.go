@@ -7,10 +7,28 @@ const (
 	CacheKeyAgentCheckpoint  = "agent:checkpoint:%s"     // checkpointID
 	CacheKeyUserFeatures     = "user_features:%d"        // 用户功能列表缓存
 	CacheKeyFeatureEnabled   = "feature_enabled:%s"      // 功能全局开关缓存
+	CacheKeySystemConfig     = "system_config:%s"        // 系统配置项缓存
+	CacheKeyHeroListAll      = "hero:list_all"           // 英雄榜展示名称列表缓存
+)
+
+// 对话 SSE 断线续传缓冲
+const (
+	CacheKeyChatStreamEvents = "chat:stream:events:%d:%s" // conversationID:turnID 本轮 SSE 事件，score 为序号
+	CacheKeyChatStreamDone   = "chat:stream:done:%d:%s"   // conversationID:turnID 本轮已结束标记
+	CacheKeyChatStreamLatest = "chat:stream:latest:%d"    // conversationID 最近一轮的 turnID
 )
 
 // Cache TTL
 const (
 	UserFeaturesCacheTTL   = 5 * time.Minute  // 用户功能列表缓存5分钟
 	FeatureEnabledCacheTTL = 10 * time.Minute // 功能开关缓存10分钟
+	SystemConfigCacheTTL   = 10 * time.Minute // 系统配置缓存10分钟
+	HeroListAllCacheTTL    = 10 * time.Minute // 英雄榜列表缓存10分钟
+)
+
+// 两级缓存（进程内 LRU + Redis）
+const (
+	LocalCacheCapacity       = 10000              // 进程内缓存最大条目数
+	LocalCacheTTL            = 30 * time.Second   // 进程内缓存最长有效期，兜底 pub/sub 消息丢失的场景
+	CacheInvalidationChannel = "cache:invalidate" // 缓存失效广播频道
 )
@@ -0,0 +1,51 @@
+package constant
+
+const (
+	// ChatAttachmentPathPrefix 对话附件在对象存储中的路径前缀，实际路径为 chat/<user_id>/<resource_id>
+	ChatAttachmentPathPrefix = "chat"
+	// ChatAttachmentURLScheme 持久化在消息中的附件引用前缀，发送给模型前替换为实际内容
+	ChatAttachmentURLScheme = "yqlx-attachment://"
+
+	// ChatAttachmentMaxCount 单条消息最多附件数
+	ChatAttachmentMaxCount = 5
+	// ChatAttachmentMaxImageBytes 单张图片大小上限
+	ChatAttachmentMaxImageBytes = 4 << 20
+	// ChatAttachmentMaxDocumentBytes 单个文档大小上限
+	ChatAttachmentMaxDocumentBytes = 10 << 20
+	// ChatAttachmentMaxUploadBytes 上传请求体大小上限，为最大附件留出 multipart 编码的余量
+	ChatAttachmentMaxUploadBytes = ChatAttachmentMaxDocumentBytes + 1<<20
+	// ChatAttachmentMaxDocumentRunes 单个文档提取文本的字符上限，超出部分截断
+	ChatAttachmentMaxDocumentRunes = 20000
+	// ChatAttachmentChunkRunes 文档文本分块大小（字符）
+	ChatAttachmentChunkRunes = 2000
+	// ChatAttachmentMaxHistoryImages 发送给模型的历史图片上限，更早的图片以文字占位
+	ChatAttachmentMaxHistoryImages = 3
+	// ChatAttachmentImageTokenEstimate 单张图片的 token 估算值，用于历史消息预算
+	ChatAttachmentImageTokenEstimate = 1000
+)
+
+// ChatAttachmentImageMIMETypes 支持的图片类型
+var ChatAttachmentImageMIMETypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/webp": {},
+	"image/gif":  {},
+}
+
+// ChatAttachmentDocumentMIMETypes 支持的文档类型
+var ChatAttachmentDocumentMIMETypes = map[string]struct{}{
+	"application/pdf": {},
+	"text/plain":      {},
+	"text/markdown":   {},
+	"text/csv":        {},
+}
+
+// ChatAttachmentSniffedMIMETypes 按文件内容识别出的类型（http.DetectContentType）及其允许声明的附件类型
+var ChatAttachmentSniffedMIMETypes = map[string][]string{
+	"image/jpeg":      {"image/jpeg"},
+	"image/png":       {"image/png"},
+	"image/webp":      {"image/webp"},
+	"image/gif":       {"image/gif"},
+	"application/pdf": {"application/pdf"},
+	"text/plain":      {"text/plain", "text/markdown", "text/csv"},
+}
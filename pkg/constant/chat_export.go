@@ -0,0 +1,15 @@
+package constant
+
+const (
+	// ChatExportFormatJSON 默认导出格式，返回统一响应包装的 JSON
+	ChatExportFormatJSON = "json"
+	// ChatExportFormatMarkdown Markdown，工具调用渲染为可折叠区块
+	ChatExportFormatMarkdown = "markdown"
+	// ChatExportFormatHTML 独立 HTML 页面，内联样式，无外部依赖
+	ChatExportFormatHTML = "html"
+	// ChatExportFormatJSONL OpenAI 微调数据集格式，每行一个 {"messages": [...]}
+	ChatExportFormatJSONL = "jsonl"
+
+	// ChatBulkExportMaxDays 管理员批量导出允许的最大日期跨度
+	ChatBulkExportMaxDays = 92
+)
@@ -0,0 +1,30 @@
+package constant
+
+const (
+	// ChatFeedbackRatingUp 赞
+	ChatFeedbackRatingUp int8 = 1
+	// ChatFeedbackRatingDown 踩，进入复核队列
+	ChatFeedbackRatingDown int8 = -1
+
+	// ChatFeedbackStatsMaxDays 评价统计允许的最大日期跨度
+	ChatFeedbackStatsMaxDays = 92
+)
+
+// 评价标签
+const (
+	ChatFeedbackTagHelpful    = "helpful"    // 有帮助
+	ChatFeedbackTagAccurate   = "accurate"   // 准确
+	ChatFeedbackTagWrong      = "wrong"      // 内容错误
+	ChatFeedbackTagUnsafe     = "unsafe"     // 不安全或不当内容
+	ChatFeedbackTagIncomplete = "incomplete" // 回答不完整
+	ChatFeedbackTagOffTopic   = "off_topic"  // 答非所问
+	ChatFeedbackTagOutdated   = "outdated"   // 信息过时
+	ChatFeedbackTagToolError  = "tool_error" // 工具调用出错
+)
+
+// 复核状态
+const (
+	ChatFeedbackReviewPending   int8 = 0 // 待复核
+	ChatFeedbackReviewResolved  int8 = 1 // 已处理
+	ChatFeedbackReviewDismissed int8 = 2 // 已忽略
+)
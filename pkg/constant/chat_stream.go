@@ -0,0 +1,14 @@
+package constant
+
+import "time"
+
+const (
+	// ChatTurnTimeout 单轮 Agent 运行的最长时间，运行与 HTTP 请求解耦，客户端断开后继续生成
+	ChatTurnTimeout = 10 * time.Minute
+	// ChatStreamBufferTTL 进行中一轮的事件缓冲有效期，覆盖整轮运行时间
+	ChatStreamBufferTTL = ChatTurnTimeout + 5*time.Minute
+	// ChatStreamFinishedTTL 一轮结束后事件缓冲的保留时间，供刚断线的客户端补齐
+	ChatStreamFinishedTTL = 5 * time.Minute
+	// ChatStreamPollInterval 续传时轮询新事件的间隔
+	ChatStreamPollInterval = 300 * time.Millisecond
+)
@@ -0,0 +1,12 @@
+package constant
+
+import "time"
+
+const (
+	// ChatToolConfirmationConfigKey SystemConfig 中保存 Agent 工具执行确认策略的 key（JSON）
+	ChatToolConfirmationConfigKey = "chat.tool_confirmation"
+	// ChatToolConfirmationReloadInterval 工具执行确认策略本地缓存刷新间隔
+	ChatToolConfirmationReloadInterval = 30 * time.Second
+	// ChatToolConfirmApprove 恢复中断时 resume_input 为该值表示同意执行，其他输入均视为拒绝
+	ChatToolConfirmApprove = "approve"
+)
@@ -12,17 +12,20 @@ type ErrorMeta struct {
 const SuccessCode ResCode = 0
 
 const (
-	CommonRouteNotFound      ResCode = 10001
-	CommonMethodNotAllowed   ResCode = 10002
-	CommonBadRequest         ResCode = 10003
-	CommonNotFound           ResCode = 10004
-	CommonConflict           ResCode = 10005
-	CommonForbidden          ResCode = 10006
-	CommonUnauthorized       ResCode = 10007
-	CommonInternal           ResCode = 10008
-	CommonServiceUnavailable ResCode = 10009
-	CommonUserNotFound       ResCode = 10010
-	CommonRequestPanicked    ResCode = 10011
+	CommonRouteNotFound         ResCode = 10001
+	CommonMethodNotAllowed      ResCode = 10002
+	CommonBadRequest            ResCode = 10003
+	CommonNotFound              ResCode = 10004
+	CommonConflict              ResCode = 10005
+	CommonForbidden             ResCode = 10006
+	CommonUnauthorized          ResCode = 10007
+	CommonInternal              ResCode = 10008
+	CommonServiceUnavailable    ResCode = 10009
+	CommonUserNotFound          ResCode = 10010
+	CommonRequestPanicked       ResCode = 10011
+	CommonIdempotencyKeyReused  ResCode = 10012
+	CommonIdempotencyProcessing ResCode = 10013
+	CommonTooManyRequests       ResCode = 10014
 )
 
 const (
@@ -55,8 +58,24 @@ const (
 
 // 12xxx: 会话相关
 const (
-	ConversationNotFound        ResCode = 12001
-	ConversationMessageRequired ResCode = 12002
+	ConversationNotFound                    ResCode = 12001
+	ConversationMessageRequired             ResCode = 12002
+	ConversationModelNotAllowed             ResCode = 12003
+	ConversationMessageNotFound             ResCode = 12004
+	ConversationMessageNotRegenerable       ResCode = 12005
+	ConversationAttachmentTooMany           ResCode = 12006
+	ConversationAttachmentTooLarge          ResCode = 12007
+	ConversationAttachmentTypeUnsupported   ResCode = 12008
+	ConversationAttachmentNotFound          ResCode = 12009
+	ConversationAttachmentVisionUnavailable ResCode = 12010
+	ConversationAttachmentExtractFailed     ResCode = 12011
+	ConversationShareNotFound               ResCode = 12012
+	ConversationShareExpired                ResCode = 12013
+	ConversationPersonaNotFound             ResCode = 12014
+	ConversationPersonaNameExists           ResCode = 12015
+	ConversationStreamNotFound              ResCode = 12016
+	ConversationFeedbackNotAllowed          ResCode = 12017
+	ConversationFeedbackNotFound            ResCode = 12018
 )
 
 // 13xxx: 配置相关
@@ -154,6 +173,8 @@ const (
 // 32xxx: 词典相关
 const (
 	DictionaryRandomWordFailed ResCode = 32001
+	DictionaryWordNotFound     ResCode = 32002
+	DictionaryLookupFailed     ResCode = 32003
 )
 
 // 33xxx: 挂科率相关
@@ -190,101 +211,160 @@ const (
 	OrganizationNotFound ResCode = 37001
 )
 
+// 38xxx: 角色权限相关
+const (
+	RBACRoleNotFound           ResCode = 38001
+	RBACRoleGrantExpiryInvalid ResCode = 38002
+	RBACPolicyInvalid          ResCode = 38003
+)
+
+// 39xxx: AI 对话用量相关
+const (
+	LLMQuotaExceeded              ResCode = 39001
+	LLMQuotaPurchaseLimitExceeded ResCode = 39002
+)
+
+// 40xxx: 内容审核相关
+const (
+	ModerationContentBlocked ResCode = 40001
+	ModerationWordNotFound   ResCode = 40002
+	ModerationRecordNotFound ResCode = 40003
+)
+
+// 41xxx: MCP 服务相关
+const (
+	MCPServerNotFound      ResCode = 41001
+	MCPServerNameExists    ResCode = 41002
+	MCPServerSecretMissing ResCode = 41003
+)
+
 var ErrorMetaMap = map[ResCode]ErrorMeta{
-	SuccessCode:                         {HTTPStatus: http.StatusOK, Message: "Success"},
-	CommonRouteNotFound:                 {HTTPStatus: http.StatusNotFound, Message: "路由不存在"},
-	CommonMethodNotAllowed:              {HTTPStatus: http.StatusMethodNotAllowed, Message: "请求方法不允许"},
-	CommonBadRequest:                    {HTTPStatus: http.StatusBadRequest, Message: "请求参数错误"},
-	CommonNotFound:                      {HTTPStatus: http.StatusNotFound, Message: "资源不存在"},
-	CommonConflict:                      {HTTPStatus: http.StatusConflict, Message: "请求冲突"},
-	CommonForbidden:                     {HTTPStatus: http.StatusForbidden, Message: "权限不足"},
-	CommonUnauthorized:                  {HTTPStatus: http.StatusUnauthorized, Message: "未授权"},
-	CommonInternal:                      {HTTPStatus: http.StatusInternalServerError, Message: "服务器内部错误"},
-	CommonServiceUnavailable:            {HTTPStatus: http.StatusServiceUnavailable, Message: "服务暂不可用"},
-	CommonUserNotFound:                  {HTTPStatus: http.StatusNotFound, Message: "用户不存在"},
-	CommonRequestPanicked:               {HTTPStatus: http.StatusInternalServerError, Message: "服务器内部异常"},
-	AuthMissingUserContext:              {HTTPStatus: http.StatusUnauthorized, Message: "未获取到用户信息"},
-	AuthInvalidAuthorizationHeader:      {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Authorization 头"},
-	AuthInvalidToken:                    {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Token"},
-	AuthInvalidTokenType:                {HTTPStatus: http.StatusUnauthorized, Message: "Token 类型无效"},
-	AuthInvalidTokenClaims:              {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Token Claims"},
-	AuthAccountBlocked:                  {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被封禁"},
-	AuthSessionInvalid:                  {HTTPStatus: http.StatusUnauthorized, Message: "当前会话已失效"},
-	AuthCacheUnavailable:                {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权缓存未初始化"},
-	AuthStateReadFailed:                 {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权状态读取失败"},
-	AuthStateParseFailed:                {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权状态解析失败"},
-	AuthRefreshTokenInvalid:             {HTTPStatus: http.StatusUnauthorized, Message: "无效的 RefreshToken"},
-	AuthRefreshTokenTypeInvalid:         {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 类型无效"},
-	AuthRefreshTokenSessionNotFound:     {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 会话不存在"},
-	AuthRefreshTokenExpired:             {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 已失效"},
-	AuthMissingSessionInfo:              {HTTPStatus: http.StatusUnauthorized, Message: "缺少会话信息"},
-	AuthUnsupportedTestUserType:         {HTTPStatus: http.StatusBadRequest, Message: "不支持的测试用户类型"},
-	AuthWechatLoginFailed:               {HTTPStatus: http.StatusBadGateway, Message: "微信登录失败"},
-	AuthAccountDisabled:                 {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被禁用"},
-	AuthAccountTempBanned:               {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被临时封禁"},
-	AuthAccountKicked:                   {HTTPStatus: http.StatusUnauthorized, Message: "账号已被下线，请稍后重试"},
-	AuthAdminLoginFailed:                {HTTPStatus: http.StatusUnauthorized, Message: "手机号或密码错误"},
-	AuthAdminPasswordInvalid:            {HTTPStatus: http.StatusBadRequest, Message: "后台密码必须至少8位且包含字母和数字"},
-	AuthAdminTargetRoleInvalid:          {HTTPStatus: http.StatusBadRequest, Message: "目标用户不是后台账号"},
-	AuthAdminPhoneConflict:              {HTTPStatus: http.StatusConflict, Message: "后台登录手机号已被占用"},
-	AuthAdminPhoneRequired:              {HTTPStatus: http.StatusBadRequest, Message: "后台登录手机号不能为空"},
-	ConversationNotFound:                {HTTPStatus: http.StatusNotFound, Message: "会话不存在"},
-	ConversationMessageRequired:         {HTTPStatus: http.StatusBadRequest, Message: "新会话必须提供消息内容"},
-	ConfigKeyExists:                     {HTTPStatus: http.StatusConflict, Message: "配置键已存在"},
-	ConfigKeyNotFound:                   {HTTPStatus: http.StatusNotFound, Message: "配置项不存在"},
-	ContributionNotFound:                {HTTPStatus: http.StatusNotFound, Message: "投稿不存在"},
-	ContributionForbidden:               {HTTPStatus: http.StatusForbidden, Message: "无权限"},
-	ContributionReviewStatusInvalid:     {HTTPStatus: http.StatusConflict, Message: "只能审核待审核状态的投稿"},
-	CountdownTargetDateInvalid:          {HTTPStatus: http.StatusBadRequest, Message: "目标日期格式错误"},
-	CountdownNotAccessible:              {HTTPStatus: http.StatusNotFound, Message: "倒数日不存在或无权限访问"},
-	CourseTableClassNotSet:              {HTTPStatus: http.StatusConflict, Message: "用户尚未设置班级信息"},
-	CourseTableScheduleNotFound:         {HTTPStatus: http.StatusNotFound, Message: "未找到该班级在指定学期的课程表"},
-	CourseTableClassNotFound:            {HTTPStatus: http.StatusNotFound, Message: "指定的班级不存在"},
-	CourseTablePersonalScheduleNotFound: {HTTPStatus: http.StatusNotFound, Message: "未找到个人课表数据"},
-	CourseTableBindLimitReached:         {HTTPStatus: http.StatusConflict, Message: "仅可绑定2次"},
-	FeatureNotFound:                     {HTTPStatus: http.StatusNotFound, Message: "功能不存在"},
-	FeatureIdentifierExists:             {HTTPStatus: http.StatusConflict, Message: "功能标识已存在"},
-	HeroNameExists:                      {HTTPStatus: http.StatusConflict, Message: "名称已存在"},
-	HeroNotFound:                        {HTTPStatus: http.StatusNotFound, Message: "未找到"},
-	MaterialNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "资料不存在"},
-	MaterialDescriptionNotFound:         {HTTPStatus: http.StatusNotFound, Message: "资料描述不存在"},
-	NotificationNotFound:                {HTTPStatus: http.StatusNotFound, Message: "通知不存在"},
-	NotificationDeletedCannotModify:     {HTTPStatus: http.StatusConflict, Message: "已删除的通知不能修改"},
-	NotificationNoPermissionModify:      {HTTPStatus: http.StatusForbidden, Message: "无权限修改"},
-	NotificationDraftOnlyPublish:        {HTTPStatus: http.StatusConflict, Message: "只能发布草稿状态的通知"},
-	NotificationNotPublished:            {HTTPStatus: http.StatusConflict, Message: "通知未发布"},
-	NotificationReviewStatusInvalid:     {HTTPStatus: http.StatusConflict, Message: "只能审核待审核状态的通知"},
-	NotificationAlreadyReviewed:         {HTTPStatus: http.StatusConflict, Message: "您已经审核过该通知"},
-	NotificationOnlyPublishedCanPin:     {HTTPStatus: http.StatusConflict, Message: "只有已发布的通知才能置顶"},
-	NotificationAlreadyPinned:           {HTTPStatus: http.StatusConflict, Message: "通知已经置顶"},
-	NotificationNotPinned:               {HTTPStatus: http.StatusConflict, Message: "通知未置顶"},
-	CategoryNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "分类不存在"},
-	PointsInsufficient:                  {HTTPStatus: http.StatusConflict, Message: "积分不足"},
-	QuestionNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "题目不存在"},
-	QuestionDisabled:                    {HTTPStatus: http.StatusConflict, Message: "题目已禁用"},
-	ReviewDuplicate:                     {HTTPStatus: http.StatusConflict, Message: "您已经评价过该教师的这门课程"},
-	ReviewApproved:                      {HTTPStatus: http.StatusConflict, Message: "评价已审核通过，无需重复审核"},
-	ReviewNotFound:                      {HTTPStatus: http.StatusNotFound, Message: "评价不存在"},
-	StudyTaskDeadlineInvalid:            {HTTPStatus: http.StatusBadRequest, Message: "截止时间格式错误"},
-	StudyTaskDateInvalid:                {HTTPStatus: http.StatusBadRequest, Message: "截止日期格式错误"},
-	StudyTaskNotAccessible:              {HTTPStatus: http.StatusNotFound, Message: "学习任务不存在或无权限访问"},
-	URIInvalid:                          {HTTPStatus: http.StatusBadRequest, Message: "uri 必须以 / 开头，并且不可为空"},
-	TokenSecretMissing:                  {HTTPStatus: http.StatusInternalServerError, Message: "对象存储配置缺失"},
-	DictionaryRandomWordFailed:          {HTTPStatus: http.StatusInternalServerError, Message: "获取随机单词失败"},
-	FailRateQueryFailed:                 {HTTPStatus: http.StatusInternalServerError, Message: "查询挂科率失败"},
-	StatServiceUnavailable:              {HTTPStatus: http.StatusServiceUnavailable, Message: "统计服务暂不可用"},
-	StoreFileUploadFailed:               {HTTPStatus: http.StatusBadRequest, Message: "上传文件失败"},
-	StoreFileOpenFailed:                 {HTTPStatus: http.StatusInternalServerError, Message: "打开上传文件失败"},
-	StoreInvalidTags:                    {HTTPStatus: http.StatusBadRequest, Message: "标签格式错误"},
-	StoreFileStoreFailed:                {HTTPStatus: http.StatusInternalServerError, Message: "存储文件失败"},
-	StoreFileDeleteFailed:               {HTTPStatus: http.StatusInternalServerError, Message: "删除文件失败"},
-	StoreFileListFailed:                 {HTTPStatus: http.StatusInternalServerError, Message: "获取文件列表失败"},
-	StoreExpiredFileListFailed:          {HTTPStatus: http.StatusInternalServerError, Message: "获取过期文件列表失败"},
-	StoreFileURLFailed:                  {HTTPStatus: http.StatusInternalServerError, Message: "生成文件链接失败"},
-	StoreFileNotFound:                   {HTTPStatus: http.StatusNotFound, Message: "文件不存在"},
-	StoreFileStreamFailed:               {HTTPStatus: http.StatusInternalServerError, Message: "文件流传输失败"},
-	UserActivityQueryFailed:             {HTTPStatus: http.StatusInternalServerError, Message: "查询登录天数失败"},
-	OrganizationNotFound:                {HTTPStatus: http.StatusNotFound, Message: "组织不存在"},
+	SuccessCode:                             {HTTPStatus: http.StatusOK, Message: "Success"},
+	CommonRouteNotFound:                     {HTTPStatus: http.StatusNotFound, Message: "路由不存在"},
+	CommonMethodNotAllowed:                  {HTTPStatus: http.StatusMethodNotAllowed, Message: "请求方法不允许"},
+	CommonBadRequest:                        {HTTPStatus: http.StatusBadRequest, Message: "请求参数错误"},
+	CommonNotFound:                          {HTTPStatus: http.StatusNotFound, Message: "资源不存在"},
+	CommonConflict:                          {HTTPStatus: http.StatusConflict, Message: "请求冲突"},
+	CommonForbidden:                         {HTTPStatus: http.StatusForbidden, Message: "权限不足"},
+	CommonUnauthorized:                      {HTTPStatus: http.StatusUnauthorized, Message: "未授权"},
+	CommonInternal:                          {HTTPStatus: http.StatusInternalServerError, Message: "服务器内部错误"},
+	CommonServiceUnavailable:                {HTTPStatus: http.StatusServiceUnavailable, Message: "服务暂不可用"},
+	CommonUserNotFound:                      {HTTPStatus: http.StatusNotFound, Message: "用户不存在"},
+	CommonRequestPanicked:                   {HTTPStatus: http.StatusInternalServerError, Message: "服务器内部异常"},
+	CommonIdempotencyKeyReused:              {HTTPStatus: http.StatusUnprocessableEntity, Message: "幂等性Key已被用于不同的请求"},
+	CommonIdempotencyProcessing:             {HTTPStatus: http.StatusConflict, Message: "相同请求正在处理中，请稍后重试"},
+	CommonTooManyRequests:                   {HTTPStatus: http.StatusTooManyRequests, Message: "请求过于频繁，请稍后再试"},
+	AuthMissingUserContext:                  {HTTPStatus: http.StatusUnauthorized, Message: "未获取到用户信息"},
+	AuthInvalidAuthorizationHeader:          {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Authorization 头"},
+	AuthInvalidToken:                        {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Token"},
+	AuthInvalidTokenType:                    {HTTPStatus: http.StatusUnauthorized, Message: "Token 类型无效"},
+	AuthInvalidTokenClaims:                  {HTTPStatus: http.StatusUnauthorized, Message: "无效的 Token Claims"},
+	AuthAccountBlocked:                      {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被封禁"},
+	AuthSessionInvalid:                      {HTTPStatus: http.StatusUnauthorized, Message: "当前会话已失效"},
+	AuthCacheUnavailable:                    {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权缓存未初始化"},
+	AuthStateReadFailed:                     {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权状态读取失败"},
+	AuthStateParseFailed:                    {HTTPStatus: http.StatusServiceUnavailable, Message: "鉴权状态解析失败"},
+	AuthRefreshTokenInvalid:                 {HTTPStatus: http.StatusUnauthorized, Message: "无效的 RefreshToken"},
+	AuthRefreshTokenTypeInvalid:             {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 类型无效"},
+	AuthRefreshTokenSessionNotFound:         {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 会话不存在"},
+	AuthRefreshTokenExpired:                 {HTTPStatus: http.StatusUnauthorized, Message: "RefreshToken 已失效"},
+	AuthMissingSessionInfo:                  {HTTPStatus: http.StatusUnauthorized, Message: "缺少会话信息"},
+	AuthUnsupportedTestUserType:             {HTTPStatus: http.StatusBadRequest, Message: "不支持的测试用户类型"},
+	AuthWechatLoginFailed:                   {HTTPStatus: http.StatusBadGateway, Message: "微信登录失败"},
+	AuthAccountDisabled:                     {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被禁用"},
+	AuthAccountTempBanned:                   {HTTPStatus: http.StatusUnauthorized, Message: "用户账号已被临时封禁"},
+	AuthAccountKicked:                       {HTTPStatus: http.StatusUnauthorized, Message: "账号已被下线，请稍后重试"},
+	AuthAdminLoginFailed:                    {HTTPStatus: http.StatusUnauthorized, Message: "手机号或密码错误"},
+	AuthAdminPasswordInvalid:                {HTTPStatus: http.StatusBadRequest, Message: "后台密码必须至少8位且包含字母和数字"},
+	AuthAdminTargetRoleInvalid:              {HTTPStatus: http.StatusBadRequest, Message: "目标用户不是后台账号"},
+	AuthAdminPhoneConflict:                  {HTTPStatus: http.StatusConflict, Message: "后台登录手机号已被占用"},
+	AuthAdminPhoneRequired:                  {HTTPStatus: http.StatusBadRequest, Message: "后台登录手机号不能为空"},
+	ConversationNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "会话不存在"},
+	ConversationMessageRequired:             {HTTPStatus: http.StatusBadRequest, Message: "新会话必须提供消息内容"},
+	ConversationModelNotAllowed:             {HTTPStatus: http.StatusBadRequest, Message: "模型不存在或无权使用"},
+	ConversationMessageNotFound:             {HTTPStatus: http.StatusNotFound, Message: "消息不存在"},
+	ConversationMessageNotRegenerable:       {HTTPStatus: http.StatusBadRequest, Message: "只能从用户或助手消息重新生成"},
+	ConversationAttachmentTooMany:           {HTTPStatus: http.StatusBadRequest, Message: "附件数量超出限制"},
+	ConversationAttachmentTooLarge:          {HTTPStatus: http.StatusBadRequest, Message: "附件大小超出限制"},
+	ConversationAttachmentTypeUnsupported:   {HTTPStatus: http.StatusBadRequest, Message: "不支持的附件类型"},
+	ConversationAttachmentNotFound:          {HTTPStatus: http.StatusNotFound, Message: "附件不存在"},
+	ConversationAttachmentVisionUnavailable: {HTTPStatus: http.StatusBadRequest, Message: "当前没有支持图片的模型"},
+	ConversationAttachmentExtractFailed:     {HTTPStatus: http.StatusBadRequest, Message: "附件内容解析失败"},
+	ConversationShareNotFound:               {HTTPStatus: http.StatusNotFound, Message: "分享不存在或已撤销"},
+	ConversationShareExpired:                {HTTPStatus: http.StatusGone, Message: "分享已过期"},
+	ConversationPersonaNotFound:             {HTTPStatus: http.StatusNotFound, Message: "人设不存在或已停用"},
+	ConversationPersonaNameExists:           {HTTPStatus: http.StatusConflict, Message: "人设名称已存在"},
+	ConversationStreamNotFound:              {HTTPStatus: http.StatusNotFound, Message: "对话流不存在或已过期"},
+	ConversationFeedbackNotAllowed:          {HTTPStatus: http.StatusBadRequest, Message: "只能评价助手的回答"},
+	ConversationFeedbackNotFound:            {HTTPStatus: http.StatusNotFound, Message: "评价不存在"},
+	ConfigKeyExists:                         {HTTPStatus: http.StatusConflict, Message: "配置键已存在"},
+	ConfigKeyNotFound:                       {HTTPStatus: http.StatusNotFound, Message: "配置项不存在"},
+	ContributionNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "投稿不存在"},
+	ContributionForbidden:                   {HTTPStatus: http.StatusForbidden, Message: "无权限"},
+	ContributionReviewStatusInvalid:         {HTTPStatus: http.StatusConflict, Message: "只能审核待审核状态的投稿"},
+	CountdownTargetDateInvalid:              {HTTPStatus: http.StatusBadRequest, Message: "目标日期格式错误"},
+	CountdownNotAccessible:                  {HTTPStatus: http.StatusNotFound, Message: "倒数日不存在或无权限访问"},
+	CourseTableClassNotSet:                  {HTTPStatus: http.StatusConflict, Message: "用户尚未设置班级信息"},
+	CourseTableScheduleNotFound:             {HTTPStatus: http.StatusNotFound, Message: "未找到该班级在指定学期的课程表"},
+	CourseTableClassNotFound:                {HTTPStatus: http.StatusNotFound, Message: "指定的班级不存在"},
+	CourseTablePersonalScheduleNotFound:     {HTTPStatus: http.StatusNotFound, Message: "未找到个人课表数据"},
+	CourseTableBindLimitReached:             {HTTPStatus: http.StatusConflict, Message: "仅可绑定2次"},
+	FeatureNotFound:                         {HTTPStatus: http.StatusNotFound, Message: "功能不存在"},
+	FeatureIdentifierExists:                 {HTTPStatus: http.StatusConflict, Message: "功能标识已存在"},
+	HeroNameExists:                          {HTTPStatus: http.StatusConflict, Message: "名称已存在"},
+	HeroNotFound:                            {HTTPStatus: http.StatusNotFound, Message: "未找到"},
+	MaterialNotFound:                        {HTTPStatus: http.StatusNotFound, Message: "资料不存在"},
+	MaterialDescriptionNotFound:             {HTTPStatus: http.StatusNotFound, Message: "资料描述不存在"},
+	NotificationNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "通知不存在"},
+	NotificationDeletedCannotModify:         {HTTPStatus: http.StatusConflict, Message: "已删除的通知不能修改"},
+	NotificationNoPermissionModify:          {HTTPStatus: http.StatusForbidden, Message: "无权限修改"},
+	NotificationDraftOnlyPublish:            {HTTPStatus: http.StatusConflict, Message: "只能发布草稿状态的通知"},
+	NotificationNotPublished:                {HTTPStatus: http.StatusConflict, Message: "通知未发布"},
+	NotificationReviewStatusInvalid:         {HTTPStatus: http.StatusConflict, Message: "只能审核待审核状态的通知"},
+	NotificationAlreadyReviewed:             {HTTPStatus: http.StatusConflict, Message: "您已经审核过该通知"},
+	NotificationOnlyPublishedCanPin:         {HTTPStatus: http.StatusConflict, Message: "只有已发布的通知才能置顶"},
+	NotificationAlreadyPinned:               {HTTPStatus: http.StatusConflict, Message: "通知已经置顶"},
+	NotificationNotPinned:                   {HTTPStatus: http.StatusConflict, Message: "通知未置顶"},
+	CategoryNotFound:                        {HTTPStatus: http.StatusNotFound, Message: "分类不存在"},
+	PointsInsufficient:                      {HTTPStatus: http.StatusConflict, Message: "积分不足"},
+	QuestionNotFound:                        {HTTPStatus: http.StatusNotFound, Message: "题目不存在"},
+	QuestionDisabled:                        {HTTPStatus: http.StatusConflict, Message: "题目已禁用"},
+	ReviewDuplicate:                         {HTTPStatus: http.StatusConflict, Message: "您已经评价过该教师的这门课程"},
+	ReviewApproved:                          {HTTPStatus: http.StatusConflict, Message: "评价已审核通过，无需重复审核"},
+	ReviewNotFound:                          {HTTPStatus: http.StatusNotFound, Message: "评价不存在"},
+	StudyTaskDeadlineInvalid:                {HTTPStatus: http.StatusBadRequest, Message: "截止时间格式错误"},
+	StudyTaskDateInvalid:                    {HTTPStatus: http.StatusBadRequest, Message: "截止日期格式错误"},
+	StudyTaskNotAccessible:                  {HTTPStatus: http.StatusNotFound, Message: "学习任务不存在或无权限访问"},
+	URIInvalid:                              {HTTPStatus: http.StatusBadRequest, Message: "uri 必须以 / 开头，并且不可为空"},
+	TokenSecretMissing:                      {HTTPStatus: http.StatusInternalServerError, Message: "对象存储配置缺失"},
+	DictionaryRandomWordFailed:              {HTTPStatus: http.StatusInternalServerError, Message: "获取随机单词失败"},
+	DictionaryWordNotFound:                  {HTTPStatus: http.StatusNotFound, Message: "未找到该单词"},
+	DictionaryLookupFailed:                  {HTTPStatus: http.StatusInternalServerError, Message: "查询单词失败"},
+	FailRateQueryFailed:                     {HTTPStatus: http.StatusInternalServerError, Message: "查询挂科率失败"},
+	StatServiceUnavailable:                  {HTTPStatus: http.StatusServiceUnavailable, Message: "统计服务暂不可用"},
+	StoreFileUploadFailed:                   {HTTPStatus: http.StatusBadRequest, Message: "上传文件失败"},
+	StoreFileOpenFailed:                     {HTTPStatus: http.StatusInternalServerError, Message: "打开上传文件失败"},
+	StoreInvalidTags:                        {HTTPStatus: http.StatusBadRequest, Message: "标签格式错误"},
+	StoreFileStoreFailed:                    {HTTPStatus: http.StatusInternalServerError, Message: "存储文件失败"},
+	StoreFileDeleteFailed:                   {HTTPStatus: http.StatusInternalServerError, Message: "删除文件失败"},
+	StoreFileListFailed:                     {HTTPStatus: http.StatusInternalServerError, Message: "获取文件列表失败"},
+	StoreExpiredFileListFailed:              {HTTPStatus: http.StatusInternalServerError, Message: "获取过期文件列表失败"},
+	StoreFileURLFailed:                      {HTTPStatus: http.StatusInternalServerError, Message: "生成文件链接失败"},
+	StoreFileNotFound:                       {HTTPStatus: http.StatusNotFound, Message: "文件不存在"},
+	StoreFileStreamFailed:                   {HTTPStatus: http.StatusInternalServerError, Message: "文件流传输失败"},
+	UserActivityQueryFailed:                 {HTTPStatus: http.StatusInternalServerError, Message: "查询登录天数失败"},
+	OrganizationNotFound:                    {HTTPStatus: http.StatusNotFound, Message: "组织不存在"},
+	RBACRoleNotFound:                        {HTTPStatus: http.StatusNotFound, Message: "角色不存在"},
+	RBACRoleGrantExpiryInvalid:              {HTTPStatus: http.StatusBadRequest, Message: "授权过期时间必须晚于当前时间"},
+	RBACPolicyInvalid:                       {HTTPStatus: http.StatusBadRequest, Message: "RBAC策略文件无效"},
+	LLMQuotaExceeded:                        {HTTPStatus: http.StatusTooManyRequests, Message: "今日AI对话额度已用完，可使用积分购买额外额度"},
+	LLMQuotaPurchaseLimitExceeded:           {HTTPStatus: http.StatusBadRequest, Message: "超出每日可购买的额度上限"},
+	ModerationContentBlocked:                {HTTPStatus: http.StatusBadRequest, Message: "内容包含违规信息，请修改后重试"},
+	ModerationWordNotFound:                  {HTTPStatus: http.StatusNotFound, Message: "敏感词不存在"},
+	ModerationRecordNotFound:                {HTTPStatus: http.StatusNotFound, Message: "审核记录不存在"},
+	MCPServerNotFound:                       {HTTPStatus: http.StatusNotFound, Message: "MCP 服务不存在"},
+	MCPServerNameExists:                     {HTTPStatus: http.StatusConflict, Message: "MCP 服务名称已存在或为保留名称"},
+	MCPServerSecretMissing:                  {HTTPStatus: http.StatusInternalServerError, Message: "未配置 LLM_MCP_SECRET_KEY，无法保存 MCP 服务请求头"},
 }
 
 func LookupErrorMeta(code ResCode) (ErrorMeta, bool) {
@@ -9,4 +9,9 @@ const (
 	IdempotencyExpiration    = 10 * time.Minute
 	IdempotencyLockTimeout   = 30 * time.Second
 	IdempotencyStatusPending = "pending"
+	IdempotencyStatusDone    = "completed"
+	// IdempotencyRetryAfter 请求处理中时建议客户端的重试间隔（秒）
+	IdempotencyRetryAfter = 2
+	// IdempotencyFingerprintMaxBody 计算请求指纹时最多读取的请求体字节数，超出部分以 Content-Length 参与计算
+	IdempotencyFingerprintMaxBody = 1 << 20
 )
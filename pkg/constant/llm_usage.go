@@ -0,0 +1,10 @@
+package constant
+
+import "time"
+
+const (
+	// LLMQuotaConfigKey SystemConfig 中保存 AI 对话额度策略覆盖配置的 key（JSON）
+	LLMQuotaConfigKey = "llm.quota"
+	// LLMQuotaPolicyReloadInterval 额度策略本地缓存刷新间隔
+	LLMQuotaPolicyReloadInterval = 30 * time.Second
+)
@@ -0,0 +1,30 @@
+package constant
+
+import "time"
+
+const (
+	// MCPResourcePollInterval 已订阅 MCP 资源的变更检测间隔
+	MCPResourcePollInterval = 30 * time.Second
+	// MCPResourceRefreshDelay 工具调用后延迟检测该用户订阅的资源，期间的多次调用合并为一次检测
+	MCPResourceRefreshDelay = time.Second
+	// MCPRequestMaxBodyBytes MCP 请求体大小上限
+	MCPRequestMaxBodyBytes = 1 << 20
+	// MCPServerHealthCheckTimeout 外部 MCP 服务健康检查（连接、初始化并列出工具）的超时时间
+	MCPServerHealthCheckTimeout = 10 * time.Second
+	// MCPServerConnectTimeout 对话加载工具时并发连接外部 MCP 服务的总超时时间
+	MCPServerConnectTimeout = 5 * time.Second
+)
+
+// MCP 工具调用审计结果状态
+const (
+	MCPToolCallStatusSuccess   = "success"    // 调用成功
+	MCPToolCallStatusToolError = "tool_error" // 工具返回 isError 结果
+	MCPToolCallStatusError     = "error"      // 处理器返回错误（参数错误、权限不足等）
+)
+
+// 外部 MCP 服务健康状态
+const (
+	MCPServerHealthUnknown   = "unknown"   // 尚未检查
+	MCPServerHealthHealthy   = "healthy"   // 最近一次检查成功
+	MCPServerHealthUnhealthy = "unhealthy" // 最近一次检查失败，对话时不加载
+)
@@ -0,0 +1,41 @@
+package constant
+
+import "time"
+
+const (
+	// ModerationPolicyConfigKey SystemConfig 中保存各场景审核策略覆盖配置的 key（JSON）
+	ModerationPolicyConfigKey = "moderation.policy"
+	// ModerationReloadInterval 敏感词词典与审核策略的本地缓存刷新间隔
+	ModerationReloadInterval = 30 * time.Second
+	// ModerationExcerptMaxRunes 审核记录中保存的原文摘录长度
+	ModerationExcerptMaxRunes = 200
+	// ModerationWordImportMax 单次导入敏感词的最大数量
+	ModerationWordImportMax = 1000
+)
+
+// 审核场景
+const (
+	ModerationSurfaceChatInput     = "chat_input"     // 用户发给 AI 的消息
+	ModerationSurfaceChatOutput    = "chat_output"    // AI 的回答
+	ModerationSurfaceTeacherReview = "teacher_review" // 教师评价内容
+	ModerationSurfaceContribution  = "contribution"   // 用户投稿
+	ModerationSurfaceMaterialDesc  = "material_desc"  // 资料描述
+)
+
+// 命中后的处理方式
+const (
+	ModerationActionBlock  = "block"  // 拒绝提交
+	ModerationActionMask   = "mask"   // 将命中的词替换为 *
+	ModerationActionReview = "review" // 原样放行并进入人工复核
+)
+
+// 审核记录复核状态
+const (
+	ModerationReviewPending   int8 = 0 // 待复核
+	ModerationReviewApproved  int8 = 1 // 确认无问题
+	ModerationReviewConfirmed int8 = 2 // 确认违规
+	ModerationReviewNone      int8 = 3 // 无需复核（已拦截或已打码）
+)
+
+// ChatModerationBlockedReply 助手回答被拦截时保存并下发的替换内容
+const ChatModerationBlockedReply = "该回答包含不当内容，已被屏蔽。"
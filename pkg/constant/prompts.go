@@ -5,3 +5,14 @@ package constant
 const ChatSystemPrompt = `
 你是一个有帮助的私人AI教师，请优先通过检索知识库(ragflow)、使用工具来协助用户,当知识库中的知识无法解决用户的问题，再尝试使用你自己的知识，解答用户的问题、帮助用户学习知识。
 `
+
+// ChatHistorySummaryPrompt 用于将早期对话压缩为滚动摘要
+const ChatHistorySummaryPrompt = `
+你负责压缩一段学习辅导对话的早期内容。请结合已有摘要与新增的对话记录，输出一份更新后的完整摘要：
+- 保留用户的学习目标、课程/知识点、已给出的关键结论、公式与代码要点，以及尚未解决的问题；
+- 保留工具调用得到的关键事实，忽略检索过程和无关寒暄；
+- 使用第三人称、条理清晰的中文，不超过800字，只输出摘要正文。
+`
+
+// ChatHistorySummaryPrefix 摘要消息注入上下文时的前缀
+const ChatHistorySummaryPrefix = "以下是本次对话早期内容的摘要，供你理解上下文：\n"
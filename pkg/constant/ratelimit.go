@@ -0,0 +1,28 @@
+package constant
+
+import "time"
+
+// 限流策略名称，可通过 SystemConfig 按名称覆盖默认规则
+const (
+	RateLimitPolicyChatConversation   = "chat_conversation"
+	RateLimitPolicyMaterialDownload   = "material_download"
+	RateLimitPolicyReviewCreate       = "review_create"
+	RateLimitPolicyContributionCreate = "contribution_create"
+	RateLimitPolicyChatShareView      = "chat_share_view"
+)
+
+// 限流计数维度
+const (
+	RateLimitKeyByUser  = "user"  // 按用户ID（未登录时退化为IP）
+	RateLimitKeyByIP    = "ip"    // 按客户端IP
+	RateLimitKeyByRoute = "route" // 按路由全局计数
+)
+
+const (
+	// RateLimitConfigKey SystemConfig 中保存限流策略覆盖配置的 key（JSON）
+	RateLimitConfigKey = "rate_limit.policies"
+	// RateLimitCachePrefix 限流计数 key 前缀
+	RateLimitCachePrefix = "ratelimit:"
+	// RateLimitPolicyReloadInterval 限流策略本地缓存刷新间隔
+	RateLimitPolicyReloadInterval = 30 * time.Second
+)
@@ -46,6 +46,7 @@ const (
 	PermissionQuestionManage             = "question.manage"
 	PermissionFailRateManage             = "failrate.manage"
 	PermissionStatisticManage            = "statistic.manage"
+	PermissionChatExport                 = "chat.export" // admin
 	PermissionHeroManage                 = "hero.manage"
 	PermissionConfigManage               = "config.manage"
 	PermissionPointManage                = "point.manage"
@@ -16,13 +16,28 @@ func getGinContext(ctx context.Context) *gin.Context {
 	return nil
 }
 
+// rbacContextKey 非 gin 请求（如 MCP 工具调用）中注入权限信息使用的 context key
+type rbacContextKey string
+
+// WithUserRBAC 将用户角色、权限信息注入到普通 context 中，供脱离 *gin.Context 的服务层调用使用
+func WithUserRBAC(ctx context.Context, roles, permissions []string, isAdmin bool) context.Context {
+	ctx = context.WithValue(ctx, rbacContextKey("user_roles"), roles)
+	ctx = context.WithValue(ctx, rbacContextKey("user_permissions"), permissions)
+	return context.WithValue(ctx, rbacContextKey("is_admin"), isAdmin)
+}
+
+// getRBACValue 优先从 *gin.Context 读取，否则读取 WithUserRBAC 注入的值
+func getRBACValue(ctx context.Context, key string) (any, bool) {
+	if c := getGinContext(ctx); c != nil {
+		return c.Get(key)
+	}
+	value := ctx.Value(rbacContextKey(key))
+	return value, value != nil
+}
+
 // GetUserRoles 从 context 中获取用户角色信息
 func GetUserRoles(ctx context.Context) []string {
-	c := getGinContext(ctx)
-	if c == nil {
-		return nil
-	}
-	if roles, ok := c.Get("user_roles"); ok {
+	if roles, ok := getRBACValue(ctx, "user_roles"); ok {
 		if roleList, ok := roles.([]string); ok {
 			return roleList
 		}
@@ -38,11 +53,7 @@ func HasUserRole(ctx context.Context, role string) bool {
 
 // IsAdmin 检查 context 中的用户是否是管理员
 func IsAdmin(ctx context.Context) bool {
-	c := getGinContext(ctx)
-	if c == nil {
-		return false
-	}
-	if isAdmin, ok := c.Get("is_admin"); ok {
+	if isAdmin, ok := getRBACValue(ctx, "is_admin"); ok {
 		if admin, ok := isAdmin.(bool); ok {
 			return admin
 		}
@@ -53,11 +64,7 @@ func IsAdmin(ctx context.Context) bool {
 
 // GetUserPermissions 从 context 中获取用户权限信息
 func GetUserPermissions(ctx context.Context) []string {
-	c := getGinContext(ctx)
-	if c == nil {
-		return nil
-	}
-	if permissions, ok := c.Get("user_permissions"); ok {
+	if permissions, ok := getRBACValue(ctx, "user_permissions"); ok {
 		if permList, ok := permissions.([]string); ok {
 			return permList
 		}
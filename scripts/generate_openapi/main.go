@@ -344,7 +344,7 @@ func (g *generator) successResponse(op operation) map[string]any {
 				"text/event-stream": map[string]any{
 					"schema": map[string]any{
 						"type":        "string",
-						"description": "事件流文本，事件类型包括 start/resume_start/content/reasoning/tool_call/tool_result/interrupt/end。",
+						"description": "事件流文本，每个事件带 `id: <turn>:<seq>` 行用于断线续传，事件类型包括 start/resume_start/content/reasoning/tool_call/tool_result/interrupt/end/error。",
 					},
 				},
 			},
@@ -420,6 +420,7 @@ func buildTags() []any {
 		tag("Dictionary", "词典"),
 		tag("Organizations", "组织"),
 		tag("Features", "功能管理"),
+		tag("Moderation", "内容审核"),
 		tag("AdminUsers", "管理员用户操作"),
 		tag("RBAC", "角色权限管理"),
 		tag("Proxy", "MinIO 反向代理"),
@@ -752,9 +753,26 @@ func chatStreamRequestSchema() schemaBuilder {
 		field("message", refBuilder("ChatMessage")),
 		field("checkpoint_id", stringSchema()),
 		field("resume_input", stringSchema()),
+		field("attachments", arraySchema(stringSchema())),
 	)
 }
 
+func chatAttachmentUploadSchema() schemaBuilder {
+	return func(*generator) map[string]any {
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"file": map[string]any{
+					"type":        "string",
+					"format":      "binary",
+					"description": "图片（jpeg/png/webp/gif，≤4MB）或文档（pdf/txt/md/csv，≤10MB）",
+				},
+			},
+			"required": []string{"file"},
+		}
+	}
+}
+
 func multipartUploadSchema() schemaBuilder {
 	return func(*generator) map[string]any {
 		return map[string]any{
@@ -1107,6 +1125,14 @@ func buildOperations() []operation {
 			withRequestBodySchema("application/json", mapSchema(anySchema())),
 			withRawJSONResponse(mapSchema(anySchema())),
 		),
+		op("POST", "/api/admin/mcp", "MCP", "后台 MCP HTTP 入口",
+			withAuthOnly(),
+			withRouterMethod("ANY"),
+			withDescription("Gin 路由使用 `Any(\"/api/admin/mcp\")`，仅运营和管理员角色可访问，本规范用 POST 代表该入口。提供审核队列和统计工具，每个工具仍按对应权限标签校验。"),
+			withRequestBodySchema("application/json", mapSchema(anySchema())),
+			withRawJSONResponse(mapSchema(anySchema())),
+			withErrors(403),
+		),
 		op("POST", "/api/v0/auth/wechat-login", "Auth", "微信登录",
 			withJSONBodyType[req.WechatLoginRequest](),
 			withEnvelopeType[resp.WechatLoginResponse](),
@@ -1143,6 +1169,10 @@ func buildOperations() []operation {
 		op("GET", "/api/v0/heroes/", "Heroes", "获取英雄榜",
 			withEnvelopeResponse(arraySchema(stringSchema())),
 		),
+		op("GET", "/api/v0/shares/{slug}", "Chat", "公开查看对话分享",
+			withParams(pathStringParam("slug", "分享标识")),
+			withEnvelopeType[chatdto.SharedConversationResponse](),
+		),
 		op("POST", "/api/v0/auth/logout", "Auth", "退出当前设备登录",
 			withAuthOnly(),
 			withEnvelopeResponse(messageSchema()),
@@ -1791,6 +1821,11 @@ func buildOperations() []operation {
 			withQueryType[chatdto.ListConversationsRequest](),
 			withEnvelopeType[chatdto.ConversationListResponse](),
 		),
+		op("GET", "/api/v0/chat/conversations/search", "Chat", "全文检索对话标题与消息",
+			withSecurity(constant.PermissionChatStudy),
+			withQueryType[chatdto.SearchConversationsRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[chatdto.ConversationSearchHit]())),
+		),
 		op("GET", "/api/v0/chat/conversations/{id}", "Chat", "获取对话历史消息",
 			withSecurity(constant.PermissionChatStudy),
 			withParams(pathIntParam("id", "对话 ID")),
@@ -1808,15 +1843,95 @@ func buildOperations() []operation {
 			withEnvelopeResponse(stringSchema()),
 		),
 		op("GET", "/api/v0/chat/conversations/{id}/export", "Chat", "导出对话",
+			withDescription("format 为 json（默认）时返回统一响应包装的 JSON；markdown、html、jsonl 时以附件形式返回对应文件。"),
 			withSecurity(constant.PermissionChatStudy),
 			withParams(pathIntParam("id", "对话 ID")),
+			withQueryType[chatdto.ExportConversationRequest](),
 			withEnvelopeResponse(exportConversationSchema()),
 		),
+		op("GET", "/api/v0/chat/conversations/{id}/stream", "Chat", "断线续传进行中或刚结束的一轮对话（流式）",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(
+				pathIntParam("id", "对话 ID"),
+				parameterSpec{Name: "Last-Event-ID", In: "header", Schema: stringSchema(), Description: "最后收到的事件 ID（`<turn>:<seq>`），为空时从最近一轮开头重放"},
+				queryParam("last_event_id", false, stringSchema(), "同 Last-Event-ID 请求头，供无法设置请求头的客户端使用"),
+			),
+			withDescription("事件与原始流一致，带 `id: <turn>:<seq>` 行；本轮结束后缓冲保留 5 分钟，过期返回 404（业务码 12016）。"),
+			withSSEResponse(),
+		),
+		op("PUT", "/api/v0/chat/conversations/{id}/model", "Chat", "为对话指定模型",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID")),
+			withJSONBodyType[chatdto.SetConversationModelRequest](),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("POST", "/api/v0/chat/conversations/{id}/messages/{message_id}/regenerate", "Chat", "从指定消息重新生成回复（流式，保留原分支）",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID"), pathIntParam("message_id", "消息 ID")),
+			withJSONBodyType[chatdto.RegenerateMessageRequest](),
+			withSSEResponse(),
+		),
+		op("PUT", "/api/v0/chat/conversations/{id}/active-branch", "Chat", "切换对话当前分支",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID")),
+			withJSONBodyType[chatdto.SwitchBranchRequest](),
+			withEnvelopeResponse(arraySchema(refBuilder("ChatMessage"))),
+		),
+		op("PUT", "/api/v0/chat/conversations/{id}/messages/{message_id}/feedback", "Chat", "评价助手回答",
+			withDescription("rating 为 1（有帮助）或 -1（没帮助），重复提交覆盖之前的评价并重新进入复核队列。"),
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID"), pathIntParam("message_id", "消息 ID")),
+			withJSONBodyType[chatdto.SubmitMessageFeedbackRequest](),
+			withEnvelopeType[chatdto.MessageFeedbackResponse](),
+		),
+		op("DELETE", "/api/v0/chat/conversations/{id}/messages/{message_id}/feedback", "Chat", "撤回对助手回答的评价",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID"), pathIntParam("message_id", "消息 ID")),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("POST", "/api/v0/chat/conversations/{id}/shares", "Chat", "为对话当前分支创建分享链接",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathIntParam("id", "对话 ID")),
+			withJSONBodyType[chatdto.CreateConversationShareRequest](),
+			withEnvelopeType[chatdto.ConversationShareResponse](),
+		),
+		op("GET", "/api/v0/chat/shares", "Chat", "列出我的对话分享",
+			withSecurity(constant.PermissionChatStudy),
+			withQueryType[chatdto.ListConversationSharesRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[chatdto.ConversationShareResponse]())),
+		),
+		op("DELETE", "/api/v0/chat/shares/{slug}", "Chat", "撤销对话分享",
+			withSecurity(constant.PermissionChatStudy),
+			withParams(pathStringParam("slug", "分享标识")),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("POST", "/api/v0/chat/attachments", "Chat", "上传对话附件",
+			withSecurity(constant.PermissionChatStudy),
+			withRequestBodySchema("multipart/form-data", chatAttachmentUploadSchema()),
+			withEnvelopeType[chatdto.ChatAttachmentRef](),
+		),
+		op("GET", "/api/v0/chat/models", "Chat", "获取可选模型列表",
+			withSecurity(constant.PermissionChatStudy),
+			withEnvelopeResponse(arraySchema(typeSchema[chatdto.ChatModelResponse]())),
+		),
+		op("GET", "/api/v0/chat/personas", "Chat", "获取可选助手人设",
+			withSecurity(constant.PermissionChatStudy),
+			withEnvelopeResponse(arraySchema(typeSchema[chatdto.ChatPersonaResponse]())),
+		),
 		op("POST", "/api/v0/chat/conversation", "Chat", "发起流式对话",
 			withSecurity(constant.PermissionChatStudy),
 			withRequestBodySchema("application/json", chatStreamRequestSchema()),
 			withSSEResponse(),
 		),
+		op("GET", "/api/v0/chat/usage", "Chat", "获取当日 AI 对话用量与额度",
+			withSecurity(constant.PermissionChatStudy),
+			withEnvelopeType[chatdto.LLMUsageResponse](),
+		),
+		op("POST", "/api/v0/chat/usage/quota", "Chat", "使用积分购买当日额外 AI 对话额度",
+			withSecurity(constant.PermissionPointSpend),
+			withJSONBodyType[chatdto.PurchaseLLMQuotaRequest](),
+			withEnvelopeType[chatdto.LLMUsageResponse](),
+		),
 		op("GET", "/api/v0/stat/system/online", "Stats", "获取系统在线人数",
 			withSecurity(constant.PermissionStatisticGet),
 			withEnvelopeType[resp.SystemOnlineStatResponse](),
@@ -1863,6 +1978,146 @@ func buildOperations() []operation {
 			withSecurity(constant.PermissionStatisticManage),
 			withEnvelopeType[resp.AllProjectsOnlineStatResponse](),
 		),
+		op("GET", "/api/v0/admin/stats/llm-usage", "AdminStats", "管理员按用户统计 AI 对话 token 用量",
+			withSecurity(constant.PermissionStatisticManage),
+			withQueryType[chatdto.LLMUsageReportRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[chatdto.LLMUsageReportItem]())),
+		),
+		op("GET", "/api/v0/admin/chat/personas", "AdminChat", "管理员列出助手人设",
+			withSecurity(constant.PermissionConfigManage),
+			withEnvelopeResponse(arraySchema(typeSchema[models.ChatPersona]())),
+		),
+		op("POST", "/api/v0/admin/chat/personas", "AdminChat", "管理员创建助手人设",
+			withSecurity(constant.PermissionConfigManage),
+			withIdempotency(),
+			withJSONBodyType[chatdto.CreateChatPersonaRequest](),
+			withEnvelopeType[models.ChatPersona](),
+		),
+		op("PUT", "/api/v0/admin/chat/personas/{id}", "AdminChat", "管理员更新助手人设",
+			withDescription("修改 system_prompt 时版本号加一并保留历史版本，已有对话继续使用创建时的版本。"),
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "人设 ID")),
+			withJSONBodyType[chatdto.UpdateChatPersonaRequest](),
+			withEnvelopeType[models.ChatPersona](),
+		),
+		op("DELETE", "/api/v0/admin/chat/personas/{id}", "AdminChat", "管理员删除助手人设",
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "人设 ID")),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("GET", "/api/v0/admin/chat/personas/{id}/versions", "AdminChat", "管理员查看人设提示词历史版本",
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "人设 ID")),
+			withEnvelopeResponse(arraySchema(typeSchema[models.ChatPersonaPromptVersion]())),
+		),
+		op("GET", "/api/v0/admin/chat/feedback", "AdminChat", "管理员查看回答评价复核队列",
+			withDescription("默认只返回待复核的差评；每条附带被评价回答之前的对话上下文。"),
+			withSecurity(constant.PermissionStatisticManage),
+			withQueryType[chatdto.ListFeedbackReviewRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[chatdto.FeedbackReviewItem]())),
+		),
+		op("PUT", "/api/v0/admin/chat/feedback/{id}/review", "AdminChat", "管理员处理回答评价",
+			withSecurity(constant.PermissionStatisticManage),
+			withParams(pathIntParam("id", "评价 ID")),
+			withJSONBodyType[chatdto.ReviewFeedbackRequest](),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("GET", "/api/v0/admin/chat/feedback/stats", "AdminChat", "管理员按人设、模型、工具统计回答评价",
+			withSecurity(constant.PermissionStatisticManage),
+			withQueryType[chatdto.FeedbackStatsRequest](),
+			withEnvelopeType[chatdto.FeedbackStatsResponse](),
+		),
+		op("GET", "/api/v0/admin/moderation/words", "Moderation", "管理员分页查询敏感词",
+			withSecurity(constant.PermissionConfigManage),
+			withQueryType[req.ListSensitiveWordsRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[models.SensitiveWord]())),
+		),
+		op("POST", "/api/v0/admin/moderation/words", "Moderation", "管理员批量添加敏感词",
+			withDescription("已存在（不区分大小写）或重复的词会被跳过；本实例立即生效，其他实例在 30 秒内生效。"),
+			withSecurity(constant.PermissionConfigManage),
+			withIdempotency(),
+			withJSONBodyType[req.CreateSensitiveWordsRequest](),
+			withEnvelopeType[resp.CreateSensitiveWordsResponse](),
+		),
+		op("PUT", "/api/v0/admin/moderation/words/{id}", "Moderation", "管理员更新敏感词",
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "敏感词 ID")),
+			withJSONBodyType[req.UpdateSensitiveWordRequest](),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("DELETE", "/api/v0/admin/moderation/words/{id}", "Moderation", "管理员删除敏感词",
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "敏感词 ID")),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("GET", "/api/v0/admin/moderation/policy", "Moderation", "查看当前生效的各场景审核策略",
+			withDescription("默认策略可通过系统配置 moderation.policy（JSON）按场景覆盖。"),
+			withSecurity(constant.PermissionConfigManage),
+			withEnvelopeResponse(mapSchema(objSchema(
+				field("enabled", boolSchema()),
+				field("action", stringSchema()),
+				field("use_classifier", boolSchema()),
+			))),
+		),
+		op("POST", "/api/v0/admin/moderation/check", "Moderation", "按场景策略试审文本",
+			withDescription("不写入审核记录，用于验证敏感词与策略。"),
+			withSecurity(constant.PermissionConfigManage),
+			withJSONBodyType[req.CheckModerationRequest](),
+			withEnvelopeType[resp.ModerationCheckResponse](),
+		),
+		op("GET", "/api/v0/admin/moderation/records", "Moderation", "管理员分页查询审核命中记录",
+			withSecurity(constant.PermissionReviewManage),
+			withQueryType[req.ListModerationRecordsRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[models.ModerationRecord]())),
+		),
+		op("PUT", "/api/v0/admin/moderation/records/{id}/review", "Moderation", "管理员人工复核审核记录",
+			withSecurity(constant.PermissionReviewManage),
+			withParams(pathIntParam("id", "记录 ID")),
+			withJSONBodyType[req.ReviewModerationRecordRequest](),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("GET", "/api/v0/admin/mcp/tool-calls", "MCP", "管理员分页查询 MCP 工具调用审计记录",
+			withDescription("记录每次 /api/mcp 工具调用的用户、工具、参数摘要（SHA-256）、耗时与结果状态，不保存参数原文。"),
+			withSecurity(constant.PermissionStatisticManage),
+			withQueryType[req.ListMCPToolCallsRequest](),
+			withEnvelopeResponse(pageSchema(typeSchema[models.MCPToolCallAudit]())),
+		),
+		op("GET", "/api/v0/admin/mcp/servers", "MCP", "管理员列出外部 MCP 服务",
+			withDescription("认证请求头加密保存，只返回请求头名称 header_names。"),
+			withSecurity(constant.PermissionConfigManage),
+			withEnvelopeResponse(arraySchema(typeSchema[resp.MCPServerResponse]())),
+		),
+		op("POST", "/api/v0/admin/mcp/servers", "MCP", "管理员接入外部 MCP 服务",
+			withDescription("仅支持 streamable HTTP 地址；名称不能为内置服务 yqlx、ragflow。接入后由定时任务每 5 分钟检查一次健康状态。"),
+			withSecurity(constant.PermissionConfigManage),
+			withIdempotency(),
+			withJSONBodyType[req.CreateMCPServerRequest](),
+			withEnvelopeType[resp.MCPServerResponse](),
+		),
+		op("PUT", "/api/v0/admin/mcp/servers/{id}", "MCP", "管理员更新外部 MCP 服务",
+			withDescription("修改地址或请求头后健康状态重置为 unknown。"),
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "服务 ID")),
+			withJSONBodyType[req.UpdateMCPServerRequest](),
+			withEnvelopeType[resp.MCPServerResponse](),
+		),
+		op("DELETE", "/api/v0/admin/mcp/servers/{id}", "MCP", "管理员删除外部 MCP 服务",
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "服务 ID")),
+			withEnvelopeResponse(stringSchema()),
+		),
+		op("POST", "/api/v0/admin/mcp/servers/{id}/health", "MCP", "立即检查外部 MCP 服务",
+			withDescription("连接服务并列出工具，结果写入 health_status 与 discovered_tools。"),
+			withSecurity(constant.PermissionConfigManage),
+			withParams(pathIntParam("id", "服务 ID")),
+			withEnvelopeType[resp.MCPServerResponse](),
+		),
+		op("GET", "/api/v0/admin/chat/conversations/export", "AdminChat", "管理员按日期区间批量导出匿名化对话",
+			withDescription("以 ZIP 流式返回，包含 manifest.json；用户以 user-0001 形式编号，内容中的姓名、学号、手机号、邮箱与身份证号已脱敏。"),
+			withSecurity(constant.PermissionChatExport),
+			withQueryType[chatdto.ExportConversationsArchiveRequest](),
+			withBinaryResponse("application/zip"),
+		),
 		op("GET", "/api/v0/dictionary/word", "Dictionary", "随机获取词典条目",
 			withSecurity(constant.PermissionDictionary),
 			withEnvelopeType[models.Dictionary](),
@@ -2118,6 +2373,49 @@ func buildOperations() []operation {
 			withJSONBodyType[req.UpdateUserRolesRequest](),
 			withEnvelopeResponse(objSchema(field("user_id", int64Schema()))),
 		),
+		op("POST", "/api/v0/admin/rbac/users/{id}/role-grants", "RBAC", "授予用户角色（支持临时授权）",
+			withSecurity(constant.PermissionUserManage),
+			withParams(pathIntParam("id", "用户 ID")),
+			withJSONBodyType[req.GrantUserRoleRequest](),
+			withEnvelopeResponse(objSchema(
+				field("user_id", int64Schema()),
+				field("role_id", int64Schema()),
+				field("expires_at", stringSchema()),
+			)),
+		),
+		op("DELETE", "/api/v0/admin/rbac/users/{id}/role-grants/{role_id}", "RBAC", "撤销用户角色",
+			withSecurity(constant.PermissionUserManage),
+			withParams(pathIntParam("id", "用户 ID"), pathIntParam("role_id", "角色 ID")),
+			withEnvelopeResponse(objSchema(
+				field("user_id", int64Schema()),
+				field("role_id", int64Schema()),
+			)),
+		),
+		op("GET", "/api/v0/admin/rbac/role-grants/expiring", "RBAC", "查询即将到期的临时角色授权",
+			withSecurity(constant.PermissionUserManage),
+			withParams(queryParam("within_hours", false, int32Schema(), "查询未来多少小时内到期，默认72")),
+			withEnvelopeResponse(objSchema(
+				field("within_hours", int32Schema()),
+				field("grants", arraySchema(typeSchema[resp.ExpiringRoleGrantResponse]())),
+			)),
+		),
+		op("GET", "/api/v0/admin/rbac/policy", "RBAC", "导出 RBAC 策略文件",
+			withSecurity(constant.PermissionUserManage),
+			withDescription("成功时直接返回 YAML 文件，失败时返回统一 JSON 信封。"),
+			withBinaryResponse("application/yaml"),
+		),
+		op("POST", "/api/v0/admin/rbac/policy/diff", "RBAC", "预览 RBAC 策略差异",
+			withSecurity(constant.PermissionUserManage),
+			withDescription("请求体为 YAML 策略文件，仅对比不写入数据库。"),
+			withRequestBodySchema("application/yaml", stringSchema()),
+			withEnvelopeType[resp.RBACPolicyDiffResponse](),
+		),
+		op("POST", "/api/v0/admin/rbac/policy/apply", "RBAC", "应用 RBAC 策略文件",
+			withSecurity(constant.PermissionUserManage),
+			withDescription("请求体为 YAML 策略文件，在单个事务中创建/更新角色与权限并重置策略中角色的权限绑定；策略未声明的角色与权限保持不变。"),
+			withRequestBodySchema("application/yaml", stringSchema()),
+			withEnvelopeType[resp.RBACPolicyDiffResponse](),
+		),
 		op("GET", "/api/v0/admin/rbac/users/{id}/permissions", "RBAC", "获取用户权限列表",
 			withSecurity(constant.PermissionUserManage),
 			withParams(pathIntParam("id", "用户 ID")),